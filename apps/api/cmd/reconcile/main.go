@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"pablosmm/backend/internal/config"
+	"pablosmm/backend/internal/db"
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/service/jobs"
+	"pablosmm/backend/internal/service/reconcile"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/joho/godotenv"
+)
+
+// Runs a wallet reconciliation and prints what it found. Exits with status 1
+// when discrepancies remain open, so it can be used from cron.
+func main() {
+	fix := flag.Bool("fix", false, "correct balance mismatches within reconcile_autofix_max_cents")
+	flag.Parse()
+
+	godotenv.Load(".env")
+
+	cfg := config.Load()
+	database, err := db.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+
+	ctx := context.Background()
+	var report reconcile.Report
+	var runErr error
+	ran := jobs.New(database).RunOnce(ctx, reconcile.JobName, func(ctx context.Context) {
+		report, runErr = reconcile.New(database).Run(ctx, reconcile.TriggerCLI, *fix)
+	})
+	if !ran {
+		log.Fatalf("Another reconciliation is already running")
+	}
+	if runErr != nil {
+		log.Fatalf("Reconciliation failed: %v", runErr)
+	}
+
+	fmt.Printf("Run #%d: checked %d wallets, %d discrepancies, %d fixed\n",
+		report.RunID, report.CheckedWallets, report.Discrepancies, report.Fixed)
+
+	open, err := database.Queries.ListReconcileDiscrepancies(ctx, sqlc.ListReconcileDiscrepanciesParams{
+		RunID:  pgtype.Int4{Int32: report.RunID, Valid: true},
+		Status: pgtype.Text{String: "open", Valid: true},
+		Lim:    1000,
+	})
+	if err != nil {
+		log.Fatalf("Failed to list discrepancies: %v", err)
+	}
+	for _, d := range open {
+		fmt.Printf("  #%d %-24s user=%-6d %s\n", d.ID, d.Kind, d.UserID.Int32, d.Message)
+	}
+	if len(open) > 0 {
+		database.Close()
+		os.Exit(1)
+	}
+}
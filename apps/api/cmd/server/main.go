@@ -12,8 +12,17 @@ import (
 	"pablosmm/backend/internal/config"
 	"pablosmm/backend/internal/db"
 	"pablosmm/backend/internal/server"
+	"pablosmm/backend/internal/service/bonus"
+	"pablosmm/backend/internal/service/invoice"
+	"pablosmm/backend/internal/service/jobs"
+	"pablosmm/backend/internal/service/metadata"
+	"pablosmm/backend/internal/service/reconcile"
+	"pablosmm/backend/internal/service/referral"
+	"pablosmm/backend/internal/service/refill"
 	"pablosmm/backend/internal/service/smm"
 	"pablosmm/backend/internal/service/syncer"
+	"pablosmm/backend/internal/service/verifier"
+	"pablosmm/backend/internal/service/watchdog"
 
 	"github.com/joho/godotenv"
 )
@@ -33,10 +42,23 @@ func main() {
 	defer database.Close()
 
 	smmService := smm.New(database, cfg)
+	jobRunner := jobs.New(database)
 	syncerService := syncer.New(database, smmService)
-	syncerService.Start(context.Background())
+	syncerService.Start(context.Background(), jobRunner)
 
-	srv := server.New(cfg, database, smmService)
+	metaService := metadata.New()
+	refillService := refill.New(database, smmService)
+	refillService.Start(context.Background(), jobRunner)
+	verifierService := verifier.New(database, smmService, metaService, refillService)
+	verifierService.Start(context.Background(), jobRunner)
+	watchdogService := watchdog.New(database, smmService, syncerService)
+	watchdogService.Start(context.Background(), jobRunner)
+	reconcile.New(database).Start(context.Background(), jobRunner)
+	bonus.New(database).Start(context.Background(), jobRunner)
+	referral.New(database).Start(context.Background(), jobRunner)
+	invoice.New(database).Start(context.Background(), jobRunner)
+
+	srv := server.New(cfg, database, smmService, metaService, refillService)
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
@@ -36,7 +36,7 @@ type GetOrderStatusForAPIParams struct {
 }
 
 type GetOrderStatusForAPIRow struct {
-	AmountCents int32  `json:"amount_cents"`
+	AmountCents int64  `json:"amount_cents"`
 	StartCount  int32  `json:"start_count"`
 	Status      string `json:"status"`
 	Remains     int32  `json:"remains"`
@@ -55,7 +55,7 @@ func (q *Queries) GetOrderStatusForAPI(ctx context.Context, arg GetOrderStatusFo
 }
 
 const getUserByAPIKey = `-- name: GetUserByAPIKey :one
-SELECT u.id, COALESCE(w.balance, 0)::int as balance, COALESCE(u.currency, 'INR')::text as currency 
+SELECT u.id, COALESCE(w.balance - w.held_cents, 0)::bigint as balance, COALESCE(u.currency, 'INR')::text as currency 
 FROM users u 
 LEFT JOIN wallets w ON u.id = w.user_id 
 WHERE u.api_key = $1 AND u.api_key_enabled = TRUE
@@ -63,7 +63,7 @@ WHERE u.api_key = $1 AND u.api_key_enabled = TRUE
 
 type GetUserByAPIKeyRow struct {
 	ID       int32  `json:"id"`
-	Balance  int32  `json:"balance"`
+	Balance  int64  `json:"balance"`
 	Currency string `json:"currency"`
 }
 
@@ -75,17 +75,23 @@ func (q *Queries) GetUserByAPIKey(ctx context.Context, apiKey pgtype.Text) (GetU
 }
 
 const insertAPIOrder = `-- name: InsertAPIOrder :one
-INSERT INTO orders (user_id, service_id, quantity, amount_cents, status, created_at, link) 
-VALUES ($1, $2, $3, $4, $5, NOW(), $6) RETURNING id
+INSERT INTO orders (user_id, service_id, quantity, amount_cents, status, created_at, link, provider_key, canonical_link, target_kind, target_id, own_start_count, refills_remaining) 
+VALUES ($1, $2, $3, $4, $5, NOW(), $6, $7, $8, $9, $10, $11, $12) RETURNING id
 `
 
 type InsertAPIOrderParams struct {
-	UserID      int32       `json:"user_id"`
-	ServiceID   string      `json:"service_id"`
-	Quantity    int32       `json:"quantity"`
-	AmountCents int32       `json:"amount_cents"`
-	Status      string      `json:"status"`
-	Link        pgtype.Text `json:"link"`
+	UserID           int32       `json:"user_id"`
+	ServiceID        string      `json:"service_id"`
+	Quantity         int32       `json:"quantity"`
+	AmountCents      int64       `json:"amount_cents"`
+	Status           string      `json:"status"`
+	Link             pgtype.Text `json:"link"`
+	ProviderKey      pgtype.Text `json:"provider_key"`
+	CanonicalLink    pgtype.Text `json:"canonical_link"`
+	TargetKind       pgtype.Text `json:"target_kind"`
+	TargetID         pgtype.Text `json:"target_id"`
+	OwnStartCount    pgtype.Int4 `json:"own_start_count"`
+	RefillsRemaining pgtype.Int4 `json:"refills_remaining"`
 }
 
 func (q *Queries) InsertAPIOrder(ctx context.Context, arg InsertAPIOrderParams) (int32, error) {
@@ -96,6 +102,12 @@ func (q *Queries) InsertAPIOrder(ctx context.Context, arg InsertAPIOrderParams)
 		arg.AmountCents,
 		arg.Status,
 		arg.Link,
+		arg.ProviderKey,
+		arg.CanonicalLink,
+		arg.TargetKind,
+		arg.TargetID,
+		arg.OwnStartCount,
+		arg.RefillsRemaining,
 	)
 	var id int32
 	err := row.Scan(&id)
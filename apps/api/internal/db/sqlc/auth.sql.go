@@ -49,8 +49,8 @@ func (q *Queries) CheckUserExists(ctx context.Context, arg CheckUserExistsParams
 }
 
 const createGoogleUser = `-- name: CreateGoogleUser :one
-INSERT INTO users (name, email, google_id, avatar_url, role, username)
-VALUES ($1, $2, $3, $4, 'user', $5)
+INSERT INTO users (name, email, google_id, avatar_url, role, username, signup_ip)
+VALUES ($1, $2, $3, $4, 'user', $5, $6)
 RETURNING id, role
 `
 
@@ -60,6 +60,7 @@ type CreateGoogleUserParams struct {
 	GoogleID  pgtype.Text `json:"google_id"`
 	AvatarUrl pgtype.Text `json:"avatar_url"`
 	Username  pgtype.Text `json:"username"`
+	SignupIp  string      `json:"signup_ip"`
 }
 
 type CreateGoogleUserRow struct {
@@ -74,15 +75,17 @@ func (q *Queries) CreateGoogleUser(ctx context.Context, arg CreateGoogleUserPara
 		arg.GoogleID,
 		arg.AvatarUrl,
 		arg.Username,
+		arg.SignupIp,
 	)
 	var i CreateGoogleUserRow
 	err := row.Scan(&i.ID, &i.Role)
 	return i, err
 }
 
-const createUser = `-- name: CreateUser :exec
-INSERT INTO users (name, email, username, mobile, password_hash, role)
-VALUES ($1, $2, $3, $4, $5, 'user')
+const createUser = `-- name: CreateUser :one
+INSERT INTO users (name, email, username, mobile, password_hash, role, signup_ip)
+VALUES ($1, $2, $3, $4, $5, 'user', $6)
+RETURNING id
 `
 
 type CreateUserParams struct {
@@ -91,17 +94,21 @@ type CreateUserParams struct {
 	Username     pgtype.Text `json:"username"`
 	Mobile       pgtype.Text `json:"mobile"`
 	PasswordHash pgtype.Text `json:"password_hash"`
+	SignupIp     string      `json:"signup_ip"`
 }
 
-func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) error {
-	_, err := q.db.Exec(ctx, createUser,
+func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (int32, error) {
+	row := q.db.QueryRow(ctx, createUser,
 		arg.Name,
 		arg.Email,
 		arg.Username,
 		arg.Mobile,
 		arg.PasswordHash,
+		arg.SignupIp,
 	)
-	return err
+	var id int32
+	err := row.Scan(&id)
+	return id, err
 }
 
 const getOrderStatsForUser = `-- name: GetOrderStatsForUser :one
@@ -148,14 +155,14 @@ SELECT
     COALESCE(u.currency, 'USD')::text as currency,
     u.created_at, 
     COALESCE(u.password_hash, '')::text as password_hash,
-    COALESCE(w.balance, 0)::int as balance,
+    COALESCE(w.balance, 0)::bigint as balance,
     (SELECT COUNT(*)::int FROM orders o WHERE o.user_id = u.id) as order_count,
     (SELECT COALESCE(SUM(
         CASE 
             WHEN o.status IN ('failed', 'canceled', 'refunded') THEN 0 
             ELSE o.amount_cents 
         END
-    ), 0)::int FROM orders o WHERE o.user_id = u.id) as total_spend
+    ), 0)::bigint FROM orders o WHERE o.user_id = u.id) as total_spend
 FROM users u
 LEFT JOIN wallets w ON u.id = w.user_id
 WHERE u.id = $1
@@ -171,9 +178,9 @@ type GetUserDataForMeRow struct {
 	Currency     string             `json:"currency"`
 	CreatedAt    pgtype.Timestamptz `json:"created_at"`
 	PasswordHash string             `json:"password_hash"`
-	Balance      int32              `json:"balance"`
+	Balance      int64              `json:"balance"`
 	OrderCount   int32              `json:"order_count"`
-	TotalSpend   int32              `json:"total_spend"`
+	TotalSpend   int64              `json:"total_spend"`
 }
 
 func (q *Queries) GetUserDataForMe(ctx context.Context, id int32) (GetUserDataForMeRow, error) {
@@ -23,29 +23,224 @@ type Account struct {
 	SessionState      pgtype.Text `json:"session_state"`
 }
 
+type AdminAuditLog struct {
+	ID         int32              `json:"id"`
+	AdminID    pgtype.Int4        `json:"admin_id"`
+	Action     string             `json:"action"`
+	EntityType string             `json:"entity_type"`
+	EntityID   string             `json:"entity_id"`
+	Details    []byte             `json:"details"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type BonusGrant struct {
+	ID              int32              `json:"id"`
+	UserID          int32              `json:"user_id"`
+	TierID          pgtype.Int4        `json:"tier_id"`
+	WalletRequestID pgtype.Int4        `json:"wallet_request_id"`
+	DepositCents    int64              `json:"deposit_cents"`
+	AmountCents     int64              `json:"amount_cents"`
+	Withdrawable    bool               `json:"withdrawable"`
+	ExpiresAt       pgtype.Timestamptz `json:"expires_at"`
+	Status          string             `json:"status"`
+	ExpiredCents    int64              `json:"expired_cents"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	ExpiredAt       pgtype.Timestamptz `json:"expired_at"`
+	PromoCodeID     pgtype.Int4        `json:"promo_code_id"`
+	RemainingCents  int64              `json:"remaining_cents"`
+}
+
+type CatalogRefillPolicy struct {
+	CatalogID         int32              `json:"catalog_id"`
+	AutoRefillEnabled bool               `json:"auto_refill_enabled"`
+	AutoRefillDropPct float64            `json:"auto_refill_drop_pct"`
+	RefillPeriodDays  int32              `json:"refill_period_days"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
+	MinHoursBetween   int32              `json:"min_hours_between"`
+	AllowedStatuses   []string           `json:"allowed_statuses"`
+	MaxRefills        pgtype.Int4        `json:"max_refills"`
+}
+
+type DepositBonusTier struct {
+	ID               int32              `json:"id"`
+	Name             string             `json:"name"`
+	MinDepositCents  int64              `json:"min_deposit_cents"`
+	BonusPct         float64            `json:"bonus_pct"`
+	MaxBonusCents    pgtype.Int8        `json:"max_bonus_cents"`
+	Methods          []string           `json:"methods"`
+	StartsAt         pgtype.Timestamptz `json:"starts_at"`
+	EndsAt           pgtype.Timestamptz `json:"ends_at"`
+	Withdrawable     bool               `json:"withdrawable"`
+	ExpiresAfterDays pgtype.Int4        `json:"expires_after_days"`
+	IsActive         bool               `json:"is_active"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+}
+
 type GlobalSetting struct {
 	Key       string             `json:"key"`
 	Value     string             `json:"value"`
 	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
 }
 
+type Invoice struct {
+	ID              int32              `json:"id"`
+	InvoiceNumber   string             `json:"invoice_number"`
+	UserID          int32              `json:"user_id"`
+	TransactionID   int32              `json:"transaction_id"`
+	PaymentMethod   string             `json:"payment_method"`
+	CustomerName    string             `json:"customer_name"`
+	CustomerEmail   string             `json:"customer_email"`
+	CustomerGstin   string             `json:"customer_gstin"`
+	CustomerAddress string             `json:"customer_address"`
+	PlaceOfSupply   string             `json:"place_of_supply"`
+	SellerName      string             `json:"seller_name"`
+	SellerGstin     string             `json:"seller_gstin"`
+	SellerAddress   string             `json:"seller_address"`
+	SellerState     string             `json:"seller_state"`
+	SacCode         string             `json:"sac_code"`
+	GstPct          float64            `json:"gst_pct"`
+	TotalCents      int64              `json:"total_cents"`
+	TaxableCents    int64              `json:"taxable_cents"`
+	CgstCents       int64              `json:"cgst_cents"`
+	SgstCents       int64              `json:"sgst_cents"`
+	IgstCents       int64              `json:"igst_cents"`
+	IssuedAt        pgtype.Timestamptz `json:"issued_at"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+}
+
+type InvoiceCounter struct {
+	FinancialYear string `json:"financial_year"`
+	LastNumber    int32  `json:"last_number"`
+}
+
+type InvoiceFailure struct {
+	TransactionID int32              `json:"transaction_id"`
+	UserID        int32              `json:"user_id"`
+	Error         string             `json:"error"`
+	Attempts      int32              `json:"attempts"`
+	FirstFailedAt pgtype.Timestamptz `json:"first_failed_at"`
+	LastFailedAt  pgtype.Timestamptz `json:"last_failed_at"`
+}
+
+type JobLease struct {
+	Name       string             `json:"name"`
+	Holder     string             `json:"holder"`
+	Running    bool               `json:"running"`
+	StartedAt  pgtype.Timestamptz `json:"started_at"`
+	FinishedAt pgtype.Timestamptz `json:"finished_at"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+}
+
+type LedgerAccount struct {
+	ID        int32              `json:"id"`
+	Code      string             `json:"code"`
+	Kind      string             `json:"kind"`
+	Name      string             `json:"name"`
+	UserID    pgtype.Int4        `json:"user_id"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type LedgerEntry struct {
+	ID          int32 `json:"id"`
+	JournalID   int32 `json:"journal_id"`
+	AccountID   int32 `json:"account_id"`
+	AmountCents int64 `json:"amount_cents"`
+}
+
+type LedgerJournal struct {
+	ID            int32              `json:"id"`
+	Kind          string             `json:"kind"`
+	Description   string             `json:"description"`
+	ReferenceType string             `json:"reference_type"`
+	ReferenceID   string             `json:"reference_id"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+}
+
+type LinkRule struct {
+	ID           int32              `json:"id"`
+	Platform     string             `json:"platform"`
+	ServiceType  string             `json:"service_type"`
+	Variant      string             `json:"variant"`
+	AllowedKinds []string           `json:"allowed_kinds"`
+	Message      string             `json:"message"`
+	IsActive     bool               `json:"is_active"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+}
+
 type Order struct {
-	ID               int32              `json:"id"`
-	UserID           int32              `json:"user_id"`
-	ServiceID        string             `json:"service_id"`
-	Quantity         int32              `json:"quantity"`
-	AmountCents      int32              `json:"amount_cents"`
-	Status           string             `json:"status"`
-	ProviderResp     []byte             `json:"provider_resp"`
-	ProviderOrderID  pgtype.Text        `json:"provider_order_id"`
-	RefundedAmount   pgtype.Int4        `json:"refunded_amount"`
-	CreatedAt        pgtype.Timestamptz `json:"created_at"`
-	Remains          pgtype.Int4        `json:"remains"`
-	StartCount       pgtype.Int4        `json:"start_count"`
-	Link             pgtype.Text        `json:"link"`
-	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
-	RefillsRemaining pgtype.Int4        `json:"refills_remaining"`
-	ProviderKey      pgtype.Text        `json:"provider_key"`
+	ID                int32              `json:"id"`
+	UserID            int32              `json:"user_id"`
+	ServiceID         string             `json:"service_id"`
+	Quantity          int32              `json:"quantity"`
+	AmountCents       int64              `json:"amount_cents"`
+	Status            string             `json:"status"`
+	ProviderResp      []byte             `json:"provider_resp"`
+	ProviderOrderID   pgtype.Text        `json:"provider_order_id"`
+	RefundedAmount    pgtype.Int8        `json:"refunded_amount"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+	Remains           pgtype.Int4        `json:"remains"`
+	StartCount        pgtype.Int4        `json:"start_count"`
+	Link              pgtype.Text        `json:"link"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
+	RefillsRemaining  pgtype.Int4        `json:"refills_remaining"`
+	ProviderKey       pgtype.Text        `json:"provider_key"`
+	CanonicalLink     pgtype.Text        `json:"canonical_link"`
+	TargetKind        pgtype.Text        `json:"target_kind"`
+	TargetID          pgtype.Text        `json:"target_id"`
+	OwnStartCount     pgtype.Int4        `json:"own_start_count"`
+	CompletedAt       pgtype.Timestamptz `json:"completed_at"`
+	LastRefillAt      pgtype.Timestamptz `json:"last_refill_at"`
+	WatchdogStage     pgtype.Text        `json:"watchdog_stage"`
+	WatchdogCheckedAt pgtype.Timestamptz `json:"watchdog_checked_at"`
+	NextSyncAt        pgtype.Timestamptz `json:"next_sync_at"`
+	LastSyncedAt      pgtype.Timestamptz `json:"last_synced_at"`
+	PromoCodeID       pgtype.Int4        `json:"promo_code_id"`
+	DiscountCents     int64              `json:"discount_cents"`
+	DeliveryCheckedAt pgtype.Timestamptz `json:"delivery_checked_at"`
+}
+
+type OrderDeliveryCheck struct {
+	ID            int32              `json:"id"`
+	OrderID       int32              `json:"order_id"`
+	StartCount    int32              `json:"start_count"`
+	Delivered     int32              `json:"delivered"`
+	ExpectedCount int32              `json:"expected_count"`
+	ObservedCount int32              `json:"observed_count"`
+	DropPct       float64            `json:"drop_pct"`
+	CheckedAt     pgtype.Timestamptz `json:"checked_at"`
+}
+
+type OrderEscalation struct {
+	ID             int32              `json:"id"`
+	OrderID        int32              `json:"order_id"`
+	Reason         string             `json:"reason"`
+	Status         string             `json:"status"`
+	ResolutionNote pgtype.Text        `json:"resolution_note"`
+	ResolvedBy     pgtype.Int4        `json:"resolved_by"`
+	ResolvedAt     pgtype.Timestamptz `json:"resolved_at"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+}
+
+type OrderEvent struct {
+	ID        int32              `json:"id"`
+	OrderID   int32              `json:"order_id"`
+	EventType string             `json:"event_type"`
+	Message   string             `json:"message"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type OrderRefund struct {
+	ID          int32              `json:"id"`
+	OrderID     int32              `json:"order_id"`
+	UserID      int32              `json:"user_id"`
+	Kind        string             `json:"kind"`
+	AmountCents int64              `json:"amount_cents"`
+	Formula     string             `json:"formula"`
+	RuleID      pgtype.Int4        `json:"rule_id"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
 }
 
 type OrderRequest struct {
@@ -57,6 +252,12 @@ type OrderRequest struct {
 	CreatedAt        pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
 	ProviderResponse pgtype.Text        `json:"provider_response"`
+	ProviderRefillID pgtype.Text        `json:"provider_refill_id"`
+	RefillStatus     pgtype.Text        `json:"refill_status"`
+	DecisionReason   pgtype.Text        `json:"decision_reason"`
+	DecidedBy        pgtype.Int4        `json:"decided_by"`
+	DecidedAt        pgtype.Timestamptz `json:"decided_at"`
+	ProviderOutcome  pgtype.Text        `json:"provider_outcome"`
 }
 
 type PabloCatalog struct {
@@ -73,6 +274,134 @@ type PabloCatalog struct {
 	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
 }
 
+type PromoCode struct {
+	ID             int32              `json:"id"`
+	Code           string             `json:"code"`
+	Description    string             `json:"description"`
+	AppliesTo      string             `json:"applies_to"`
+	ValueType      string             `json:"value_type"`
+	ValuePct       float64            `json:"value_pct"`
+	ValueCents     int64              `json:"value_cents"`
+	MaxValueCents  pgtype.Int8        `json:"max_value_cents"`
+	MinAmountCents int64              `json:"min_amount_cents"`
+	MaxUses        pgtype.Int4        `json:"max_uses"`
+	MaxUsesPerUser pgtype.Int4        `json:"max_uses_per_user"`
+	Platforms      []string           `json:"platforms"`
+	CatalogIds     []int32            `json:"catalog_ids"`
+	StartsAt       pgtype.Timestamptz `json:"starts_at"`
+	ExpiresAt      pgtype.Timestamptz `json:"expires_at"`
+	IsActive       bool               `json:"is_active"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+}
+
+type PromoRedemption struct {
+	ID              int32              `json:"id"`
+	PromoCodeID     int32              `json:"promo_code_id"`
+	UserID          int32              `json:"user_id"`
+	OrderID         pgtype.Int4        `json:"order_id"`
+	WalletRequestID pgtype.Int4        `json:"wallet_request_id"`
+	BaseCents       int64              `json:"base_cents"`
+	ValueCents      int64              `json:"value_cents"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+}
+
+type ProviderStatusMapping struct {
+	ID          int32              `json:"id"`
+	ProviderKey string             `json:"provider_key"`
+	RawStatus   string             `json:"raw_status"`
+	LocalStatus string             `json:"local_status"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
+type ProviderUnmappedStatus struct {
+	ProviderKey string             `json:"provider_key"`
+	RawStatus   string             `json:"raw_status"`
+	Occurrences int32              `json:"occurrences"`
+	LastOrderID pgtype.Int4        `json:"last_order_id"`
+	FirstSeenAt pgtype.Timestamptz `json:"first_seen_at"`
+	LastSeenAt  pgtype.Timestamptz `json:"last_seen_at"`
+}
+
+type ReconcileDiscrepancy struct {
+	ID             int32              `json:"id"`
+	RunID          int32              `json:"run_id"`
+	Kind           string             `json:"kind"`
+	UserID         pgtype.Int4        `json:"user_id"`
+	ReferenceType  string             `json:"reference_type"`
+	ReferenceID    string             `json:"reference_id"`
+	ExpectedCents  int64              `json:"expected_cents"`
+	ActualCents    int64              `json:"actual_cents"`
+	Message        string             `json:"message"`
+	Status         string             `json:"status"`
+	ResolutionNote pgtype.Text        `json:"resolution_note"`
+	ResolvedBy     pgtype.Int4        `json:"resolved_by"`
+	ResolvedAt     pgtype.Timestamptz `json:"resolved_at"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+}
+
+type ReconcileRun struct {
+	ID               int32              `json:"id"`
+	Trigger          string             `json:"trigger"`
+	Status           string             `json:"status"`
+	CheckedWallets   int32              `json:"checked_wallets"`
+	DiscrepancyCount int32              `json:"discrepancy_count"`
+	FixedCount       int32              `json:"fixed_count"`
+	Error            pgtype.Text        `json:"error"`
+	StartedAt        pgtype.Timestamptz `json:"started_at"`
+	FinishedAt       pgtype.Timestamptz `json:"finished_at"`
+}
+
+type Referral struct {
+	ReferredID int32              `json:"referred_id"`
+	ReferrerID int32              `json:"referrer_id"`
+	SignupIp   string             `json:"signup_ip"`
+	Flags      []string           `json:"flags"`
+	Status     string             `json:"status"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	ReviewedAt pgtype.Timestamptz `json:"reviewed_at"`
+	ReviewedBy pgtype.Int4        `json:"reviewed_by"`
+}
+
+type ReferralCommission struct {
+	ID          int32              `json:"id"`
+	ReferrerID  int32              `json:"referrer_id"`
+	ReferredID  int32              `json:"referred_id"`
+	SourceType  string             `json:"source_type"`
+	SourceID    int32              `json:"source_id"`
+	BaseCents   int64              `json:"base_cents"`
+	RatePct     float64            `json:"rate_pct"`
+	AmountCents int64              `json:"amount_cents"`
+	Status      string             `json:"status"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	ReleasedAt  pgtype.Timestamptz `json:"released_at"`
+}
+
+type ReferralPayout struct {
+	ID          int32              `json:"id"`
+	UserID      int32              `json:"user_id"`
+	AmountCents int64              `json:"amount_cents"`
+	Method      string             `json:"method"`
+	Details     string             `json:"details"`
+	Status      string             `json:"status"`
+	AdminNote   string             `json:"admin_note"`
+	ProcessedBy pgtype.Int4        `json:"processed_by"`
+	ProcessedAt pgtype.Timestamptz `json:"processed_at"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+type RefundRule struct {
+	ID                    int32              `json:"id"`
+	CatalogID             pgtype.Int4        `json:"catalog_id"`
+	CancelFeePct          float64            `json:"cancel_fee_pct"`
+	MinNonRefundableCents int64              `json:"min_non_refundable_cents"`
+	Rounding              string             `json:"rounding"`
+	PartialFormula        string             `json:"partial_formula"`
+	CreatedAt             pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt             pgtype.Timestamptz `json:"updated_at"`
+}
+
 type ServiceOverride struct {
 	ID                  int32              `json:"id"`
 	SourceServiceID     string             `json:"source_service_id"`
@@ -107,24 +436,39 @@ type Session struct {
 }
 
 type SmmProvider struct {
-	ID        int32              `json:"id"`
-	Key       string             `json:"key"`
-	Name      string             `json:"name"`
-	ApiUrl    string             `json:"api_url"`
-	ApiKey    string             `json:"api_key"`
-	Currency  string             `json:"currency"`
-	IsActive  bool               `json:"is_active"`
-	CreatedAt pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	ID                  int32              `json:"id"`
+	Key                 string             `json:"key"`
+	Name                string             `json:"name"`
+	ApiUrl              string             `json:"api_url"`
+	ApiKey              string             `json:"api_key"`
+	Currency            string             `json:"currency"`
+	IsActive            bool               `json:"is_active"`
+	CreatedAt           pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt           pgtype.Timestamptz `json:"updated_at"`
+	RefillCooldownHours int32              `json:"refill_cooldown_hours"`
+	SupportsMultiRefill bool               `json:"supports_multi_refill"`
+	MaxConcurrency      int32              `json:"max_concurrency"`
+}
+
+type SubAccount struct {
+	ID         int32              `json:"id"`
+	OwnerID    int32              `json:"owner_id"`
+	UserID     int32              `json:"user_id"`
+	Status     string             `json:"status"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	AcceptedAt pgtype.Timestamptz `json:"accepted_at"`
 }
 
 type Transaction struct {
-	ID          int32              `json:"id"`
-	UserID      pgtype.Int4        `json:"user_id"`
-	Amount      pgtype.Numeric     `json:"amount"`
-	Type        string             `json:"type"`
-	Description pgtype.Text        `json:"description"`
-	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	ID            int32              `json:"id"`
+	UserID        pgtype.Int4        `json:"user_id"`
+	Amount        pgtype.Numeric     `json:"amount"`
+	Type          string             `json:"type"`
+	Description   pgtype.Text        `json:"description"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	Kind          string             `json:"kind"`
+	ReferenceType string             `json:"reference_type"`
+	ReferenceID   string             `json:"reference_id"`
 }
 
 type UpiNotification struct {
@@ -139,21 +483,35 @@ type UpiNotification struct {
 }
 
 type User struct {
-	ID            int32              `json:"id"`
-	Name          pgtype.Text        `json:"name"`
-	Email         pgtype.Text        `json:"email"`
-	EmailVerified pgtype.Timestamptz `json:"email_verified"`
-	Image         pgtype.Text        `json:"image"`
-	Role          string             `json:"role"`
-	CreatedAt     pgtype.Timestamptz `json:"created_at"`
-	Username      pgtype.Text        `json:"username"`
-	PasswordHash  pgtype.Text        `json:"password_hash"`
-	Mobile        pgtype.Text        `json:"mobile"`
-	GoogleID      pgtype.Text        `json:"google_id"`
-	AvatarUrl     pgtype.Text        `json:"avatar_url"`
-	ApiKey        pgtype.Text        `json:"api_key"`
-	ApiKeyEnabled pgtype.Bool        `json:"api_key_enabled"`
-	Currency      pgtype.Text        `json:"currency"`
+	ID             int32              `json:"id"`
+	Name           pgtype.Text        `json:"name"`
+	Email          pgtype.Text        `json:"email"`
+	EmailVerified  pgtype.Timestamptz `json:"email_verified"`
+	Image          pgtype.Text        `json:"image"`
+	Role           string             `json:"role"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	Username       pgtype.Text        `json:"username"`
+	PasswordHash   pgtype.Text        `json:"password_hash"`
+	Mobile         pgtype.Text        `json:"mobile"`
+	GoogleID       pgtype.Text        `json:"google_id"`
+	AvatarUrl      pgtype.Text        `json:"avatar_url"`
+	ApiKey         pgtype.Text        `json:"api_key"`
+	ApiKeyEnabled  pgtype.Bool        `json:"api_key_enabled"`
+	Currency       pgtype.Text        `json:"currency"`
+	ReferralCode   pgtype.Text        `json:"referral_code"`
+	SignupIp       string             `json:"signup_ip"`
+	BillingName    string             `json:"billing_name"`
+	Gstin          string             `json:"gstin"`
+	BillingAddress string             `json:"billing_address"`
+	BillingState   string             `json:"billing_state"`
+}
+
+type UserTwoFactor struct {
+	UserID    int32              `json:"user_id"`
+	Secret    string             `json:"secret"`
+	EnabledAt pgtype.Timestamptz `json:"enabled_at"`
+	LastStep  int64              `json:"last_step"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
 }
 
 type VerificationToken struct {
@@ -165,8 +523,31 @@ type VerificationToken struct {
 type Wallet struct {
 	ID        int32              `json:"id"`
 	UserID    int32              `json:"user_id"`
-	Balance   int32              `json:"balance"`
+	Balance   int64              `json:"balance"`
 	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	HeldCents int64              `json:"held_cents"`
+}
+
+type WalletAdjustment struct {
+	ID          int32              `json:"id"`
+	UserID      int32              `json:"user_id"`
+	AdminID     pgtype.Int4        `json:"admin_id"`
+	AmountCents int64              `json:"amount_cents"`
+	Description string             `json:"description"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	TopUp       bool               `json:"top_up"`
+}
+
+type WalletHold struct {
+	ID            int32              `json:"id"`
+	UserID        int32              `json:"user_id"`
+	AmountCents   int64              `json:"amount_cents"`
+	ReferenceType string             `json:"reference_type"`
+	ReferenceID   string             `json:"reference_id"`
+	Description   string             `json:"description"`
+	Status        string             `json:"status"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	ResolvedAt    pgtype.Timestamptz `json:"resolved_at"`
 }
 
 type WalletRequest struct {
@@ -179,4 +560,21 @@ type WalletRequest struct {
 	CreatedAt     pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
 	UniqueAmount  pgtype.Numeric     `json:"unique_amount"`
+	PromoCodeID   pgtype.Int4        `json:"promo_code_id"`
+	PayAmount     pgtype.Numeric     `json:"pay_amount"`
+	PayCurrency   pgtype.Text        `json:"pay_currency"`
+}
+
+type WalletTransfer struct {
+	ID          int32              `json:"id"`
+	SenderID    int32              `json:"sender_id"`
+	RecipientID int32              `json:"recipient_id"`
+	AmountCents int64              `json:"amount_cents"`
+	Note        string             `json:"note"`
+	Status      string             `json:"status"`
+	AdminNote   string             `json:"admin_note"`
+	ProcessedBy pgtype.Int4        `json:"processed_by"`
+	ProcessedAt pgtype.Timestamptz `json:"processed_at"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
 }
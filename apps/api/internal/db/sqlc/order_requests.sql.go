@@ -11,6 +11,22 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const cancelOrderWithRefund = `-- name: CancelOrderWithRefund :exec
+UPDATE orders
+SET status = 'canceled', refunded_amount = COALESCE(refunded_amount, 0) + $2
+WHERE id = $1
+`
+
+type CancelOrderWithRefundParams struct {
+	ID             int32       `json:"id"`
+	RefundedAmount pgtype.Int8 `json:"refunded_amount"`
+}
+
+func (q *Queries) CancelOrderWithRefund(ctx context.Context, arg CancelOrderWithRefundParams) error {
+	_, err := q.db.Exec(ctx, cancelOrderWithRefund, arg.ID, arg.RefundedAmount)
+	return err
+}
+
 const createOrderRequest = `-- name: CreateOrderRequest :one
 INSERT INTO order_requests (order_id, user_id, request_type, provider_response, status)
 VALUES ($1, $2, $3, $4, 'pending')
@@ -54,6 +70,32 @@ func (q *Queries) CreateOrderRequest(ctx context.Context, arg CreateOrderRequest
 	return i, err
 }
 
+const decideOrderRequest = `-- name: DecideOrderRequest :execrows
+UPDATE order_requests
+SET status = $2, decision_reason = $3, decided_by = $4, decided_at = NOW()
+WHERE id = $1 AND status = 'pending'
+`
+
+type DecideOrderRequestParams struct {
+	ID             int32       `json:"id"`
+	Status         pgtype.Text `json:"status"`
+	DecisionReason pgtype.Text `json:"decision_reason"`
+	DecidedBy      pgtype.Int4 `json:"decided_by"`
+}
+
+func (q *Queries) DecideOrderRequest(ctx context.Context, arg DecideOrderRequestParams) (int64, error) {
+	result, err := q.db.Exec(ctx, decideOrderRequest,
+		arg.ID,
+		arg.Status,
+		arg.DecisionReason,
+		arg.DecidedBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const decrementOrderRefills = `-- name: DecrementOrderRefills :exec
 UPDATE orders
 SET refills_remaining = refills_remaining - 1
@@ -65,8 +107,129 @@ func (q *Queries) DecrementOrderRefills(ctx context.Context, id int32) error {
 	return err
 }
 
+const getOrderForApproval = `-- name: GetOrderForApproval :one
+SELECT
+	id,
+	user_id,
+	service_id,
+	status,
+	amount_cents,
+	quantity,
+	COALESCE(remains, 0)::int as remains,
+	COALESCE(refunded_amount, 0)::bigint as refunded_amount,
+	COALESCE(provider_order_id, '')::text as provider_order_id,
+	COALESCE(provider_key, '')::text as provider_key
+FROM orders
+WHERE id = $1
+FOR UPDATE
+`
+
+type GetOrderForApprovalRow struct {
+	ID              int32  `json:"id"`
+	UserID          int32  `json:"user_id"`
+	ServiceID       string `json:"service_id"`
+	Status          string `json:"status"`
+	AmountCents     int64  `json:"amount_cents"`
+	Quantity        int32  `json:"quantity"`
+	Remains         int32  `json:"remains"`
+	RefundedAmount  int64  `json:"refunded_amount"`
+	ProviderOrderID string `json:"provider_order_id"`
+	ProviderKey     string `json:"provider_key"`
+}
+
+func (q *Queries) GetOrderForApproval(ctx context.Context, id int32) (GetOrderForApprovalRow, error) {
+	row := q.db.QueryRow(ctx, getOrderForApproval, id)
+	var i GetOrderForApprovalRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ServiceID,
+		&i.Status,
+		&i.AmountCents,
+		&i.Quantity,
+		&i.Remains,
+		&i.RefundedAmount,
+		&i.ProviderOrderID,
+		&i.ProviderKey,
+	)
+	return i, err
+}
+
+const getOrderRefills = `-- name: GetOrderRefills :many
+SELECT id, status, provider_refill_id, refill_status, created_at, updated_at, decision_reason
+FROM order_requests
+WHERE order_id = $1 AND request_type = 'refill'
+ORDER BY created_at DESC
+`
+
+type GetOrderRefillsRow struct {
+	ID               int32              `json:"id"`
+	Status           pgtype.Text        `json:"status"`
+	ProviderRefillID pgtype.Text        `json:"provider_refill_id"`
+	RefillStatus     pgtype.Text        `json:"refill_status"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+	DecisionReason   pgtype.Text        `json:"decision_reason"`
+}
+
+func (q *Queries) GetOrderRefills(ctx context.Context, orderID int32) ([]GetOrderRefillsRow, error) {
+	rows, err := q.db.Query(ctx, getOrderRefills, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetOrderRefillsRow
+	for rows.Next() {
+		var i GetOrderRefillsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Status,
+			&i.ProviderRefillID,
+			&i.RefillStatus,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DecisionReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOrderRequestForDecision = `-- name: GetOrderRequestForDecision :one
+SELECT id, order_id, user_id, request_type, status, created_at, updated_at, provider_response, provider_refill_id, refill_status, decision_reason, decided_by, decided_at, provider_outcome FROM order_requests
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetOrderRequestForDecision(ctx context.Context, id int32) (OrderRequest, error) {
+	row := q.db.QueryRow(ctx, getOrderRequestForDecision, id)
+	var i OrderRequest
+	err := row.Scan(
+		&i.ID,
+		&i.OrderID,
+		&i.UserID,
+		&i.RequestType,
+		&i.Status,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ProviderResponse,
+		&i.ProviderRefillID,
+		&i.RefillStatus,
+		&i.DecisionReason,
+		&i.DecidedBy,
+		&i.DecidedAt,
+		&i.ProviderOutcome,
+	)
+	return i, err
+}
+
 const getPendingOrderRequestsByOrder = `-- name: GetPendingOrderRequestsByOrder :many
-SELECT id, order_id, user_id, request_type, status, created_at, updated_at, provider_response FROM order_requests
+SELECT id, order_id, user_id, request_type, status, created_at, updated_at, provider_response, provider_refill_id, refill_status, decision_reason, decided_by, decided_at, provider_outcome FROM order_requests
 WHERE order_id = $1 AND status = 'pending'
 `
 
@@ -88,6 +251,12 @@ func (q *Queries) GetPendingOrderRequestsByOrder(ctx context.Context, orderID in
 			&i.CreatedAt,
 			&i.UpdatedAt,
 			&i.ProviderResponse,
+			&i.ProviderRefillID,
+			&i.RefillStatus,
+			&i.DecisionReason,
+			&i.DecidedBy,
+			&i.DecidedAt,
+			&i.ProviderOutcome,
 		); err != nil {
 			return nil, err
 		}
@@ -99,6 +268,93 @@ func (q *Queries) GetPendingOrderRequestsByOrder(ctx context.Context, orderID in
 	return items, nil
 }
 
+const getRefillsForPolling = `-- name: GetRefillsForPolling :many
+SELECT req.id, req.order_id,
+       COALESCE(req.provider_refill_id, '')::text as provider_refill_id,
+       COALESCE(req.refill_status, '')::text as refill_status,
+       COALESCE(o.provider_key, '')::text as provider_key
+FROM order_requests req
+JOIN orders o ON req.order_id = o.id
+WHERE req.request_type = 'refill'
+  AND req.provider_refill_id IS NOT NULL
+  AND req.refill_status IN ('pending', 'in_progress')
+  AND req.created_at > NOW() - INTERVAL '30 days'
+ORDER BY req.created_at ASC
+LIMIT $1
+`
+
+type GetRefillsForPollingRow struct {
+	ID               int32  `json:"id"`
+	OrderID          int32  `json:"order_id"`
+	ProviderRefillID string `json:"provider_refill_id"`
+	RefillStatus     string `json:"refill_status"`
+	ProviderKey      string `json:"provider_key"`
+}
+
+func (q *Queries) GetRefillsForPolling(ctx context.Context, limit int32) ([]GetRefillsForPollingRow, error) {
+	rows, err := q.db.Query(ctx, getRefillsForPolling, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRefillsForPollingRow
+	for rows.Next() {
+		var i GetRefillsForPollingRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrderID,
+			&i.ProviderRefillID,
+			&i.RefillStatus,
+			&i.ProviderKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const incrementOrderRefills = `-- name: IncrementOrderRefills :exec
+UPDATE orders
+SET refills_remaining = refills_remaining + 1
+WHERE id = $1
+`
+
+func (q *Queries) IncrementOrderRefills(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, incrementOrderRefills, id)
+	return err
+}
+
+const insertOrderRequestWithStatus = `-- name: InsertOrderRequestWithStatus :one
+INSERT INTO order_requests (order_id, user_id, request_type, provider_response, status)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id
+`
+
+type InsertOrderRequestWithStatusParams struct {
+	OrderID          int32       `json:"order_id"`
+	UserID           int32       `json:"user_id"`
+	RequestType      string      `json:"request_type"`
+	ProviderResponse pgtype.Text `json:"provider_response"`
+	Status           pgtype.Text `json:"status"`
+}
+
+func (q *Queries) InsertOrderRequestWithStatus(ctx context.Context, arg InsertOrderRequestWithStatusParams) (int32, error) {
+	row := q.db.QueryRow(ctx, insertOrderRequestWithStatus,
+		arg.OrderID,
+		arg.UserID,
+		arg.RequestType,
+		arg.ProviderResponse,
+		arg.Status,
+	)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
 const listPendingOrderRequests = `-- name: ListPendingOrderRequests :many
 SELECT req.id, req.order_id, req.user_id, req.request_type, req.status, req.created_at, req.updated_at,
        req.provider_response,
@@ -163,6 +419,40 @@ func (q *Queries) ListPendingOrderRequests(ctx context.Context) ([]ListPendingOr
 	return items, nil
 }
 
+const setOrderRequestRefill = `-- name: SetOrderRequestRefill :exec
+UPDATE order_requests
+SET provider_refill_id = $2, refill_status = $3
+WHERE id = $1
+`
+
+type SetOrderRequestRefillParams struct {
+	ID               int32       `json:"id"`
+	ProviderRefillID pgtype.Text `json:"provider_refill_id"`
+	RefillStatus     pgtype.Text `json:"refill_status"`
+}
+
+func (q *Queries) SetOrderRequestRefill(ctx context.Context, arg SetOrderRequestRefillParams) error {
+	_, err := q.db.Exec(ctx, setOrderRequestRefill, arg.ID, arg.ProviderRefillID, arg.RefillStatus)
+	return err
+}
+
+const setOrderRequestResponse = `-- name: SetOrderRequestResponse :exec
+UPDATE order_requests
+SET provider_response = $2, provider_outcome = $3
+WHERE id = $1
+`
+
+type SetOrderRequestResponseParams struct {
+	ID               int32       `json:"id"`
+	ProviderResponse pgtype.Text `json:"provider_response"`
+	ProviderOutcome  pgtype.Text `json:"provider_outcome"`
+}
+
+func (q *Queries) SetOrderRequestResponse(ctx context.Context, arg SetOrderRequestResponseParams) error {
+	_, err := q.db.Exec(ctx, setOrderRequestResponse, arg.ID, arg.ProviderResponse, arg.ProviderOutcome)
+	return err
+}
+
 const updateOrderRequestStatus = `-- name: UpdateOrderRequestStatus :exec
 UPDATE order_requests
 SET status = $2
@@ -178,3 +468,22 @@ func (q *Queries) UpdateOrderRequestStatus(ctx context.Context, arg UpdateOrderR
 	_, err := q.db.Exec(ctx, updateOrderRequestStatus, arg.ID, arg.Status)
 	return err
 }
+
+const updateRefillStatus = `-- name: UpdateRefillStatus :execrows
+UPDATE order_requests
+SET refill_status = $2
+WHERE id = $1 AND refill_status IN ('pending', 'in_progress') AND refill_status <> $2
+`
+
+type UpdateRefillStatusParams struct {
+	ID           int32       `json:"id"`
+	RefillStatus pgtype.Text `json:"refill_status"`
+}
+
+func (q *Queries) UpdateRefillStatus(ctx context.Context, arg UpdateRefillStatusParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateRefillStatus, arg.ID, arg.RefillStatus)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
@@ -20,6 +20,24 @@ func (q *Queries) CancelOrder(ctx context.Context, id int32) error {
 	return err
 }
 
+const countActiveOrdersForTarget = `-- name: CountActiveOrdersForTarget :one
+SELECT COUNT(*) FROM orders
+WHERE service_id = $1 AND target_id = $2
+AND status IN ('pending', 'submitted', 'active', 'processing', 'in_progress')
+`
+
+type CountActiveOrdersForTargetParams struct {
+	ServiceID string      `json:"service_id"`
+	TargetID  pgtype.Text `json:"target_id"`
+}
+
+func (q *Queries) CountActiveOrdersForTarget(ctx context.Context, arg CountActiveOrdersForTargetParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countActiveOrdersForTarget, arg.ServiceID, arg.TargetID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const deleteOrder = `-- name: DeleteOrder :exec
 DELETE FROM orders WHERE id = $1
 `
@@ -44,12 +62,22 @@ SELECT
 	COALESCE(o.start_count, 0)::int as start_count,
 	COALESCE(o.link, '')::text as link,
 	u.email,
-	COALESCE(o.refunded_amount, 0)::int as refunded_amount,
+	COALESCE(o.refunded_amount, 0)::bigint as refunded_amount,
 	COALESCE(o.provider_order_id, '')::text as provider_order_id,
-	COALESCE(o.refills_remaining, 3)::int as refills_remaining,
+	o.refills_remaining,
 	COALESCE(so.refill_limit, 3)::int as service_refill_limit,
-	COALESCE(so.refill, false)::boolean as service_refill_enabled
+	COALESCE(so.refill, false)::boolean as service_refill_enabled,
+	COALESCE(o.canonical_link, '')::text as canonical_link,
+	COALESCE(o.target_kind, '')::text as target_kind,
+	COALESCE(o.target_id, '')::text as target_id,
+	o.own_start_count,
+	COALESCE(o.provider_status, '')::text as provider_status,
+	o.sync_error_count,
+	COALESCE(o.last_sync_error, '')::text as last_sync_error,
+	o.discount_cents,
+	COALESCE(pc.code, '')::text as promo_code
 FROM orders o
+LEFT JOIN promo_codes pc ON pc.id = o.promo_code_id
 LEFT JOIN service_overrides so ON (
 	o.service_id = so.source_service_id 
 	OR split_part(o.service_id, ':', 2) = so.source_service_id
@@ -59,18 +87,20 @@ LEFT JOIN service_overrides so ON (
 JOIN users u ON o.user_id = u.id
 WHERE ($1::text IS NULL OR o.status = $1)
 AND ($2::int IS NULL OR o.user_id = $2)
+AND ($3::text IS NULL OR o.target_id = $3)
 ORDER BY o.created_at DESC
 `
 
 type GetAdminOrdersParams struct {
 	StatusFilter pgtype.Text `json:"status_filter"`
 	UserID       pgtype.Int4 `json:"user_id"`
+	TargetID     pgtype.Text `json:"target_id"`
 }
 
 type GetAdminOrdersRow struct {
 	ID                   int32              `json:"id"`
 	ServiceID            string             `json:"service_id"`
-	AmountCents          int32              `json:"amount_cents"`
+	AmountCents          int64              `json:"amount_cents"`
 	Quantity             int32              `json:"quantity"`
 	Status               string             `json:"status"`
 	CreatedAt            pgtype.Timestamptz `json:"created_at"`
@@ -81,15 +111,24 @@ type GetAdminOrdersRow struct {
 	StartCount           int32              `json:"start_count"`
 	Link                 string             `json:"link"`
 	Email                pgtype.Text        `json:"email"`
-	RefundedAmount       int32              `json:"refunded_amount"`
+	RefundedAmount       int64              `json:"refunded_amount"`
 	ProviderOrderID_2    string             `json:"provider_order_id_2"`
-	RefillsRemaining     int32              `json:"refills_remaining"`
+	RefillsRemaining     pgtype.Int4        `json:"refills_remaining"`
 	ServiceRefillLimit   int32              `json:"service_refill_limit"`
 	ServiceRefillEnabled bool               `json:"service_refill_enabled"`
+	CanonicalLink        string             `json:"canonical_link"`
+	TargetKind           string             `json:"target_kind"`
+	TargetID             string             `json:"target_id"`
+	OwnStartCount        pgtype.Int4        `json:"own_start_count"`
+	ProviderStatus       string             `json:"provider_status"`
+	SyncErrorCount       int32              `json:"sync_error_count"`
+	LastSyncError        string             `json:"last_sync_error"`
+	DiscountCents        int64              `json:"discount_cents"`
+	PromoCode            string             `json:"promo_code"`
 }
 
 func (q *Queries) GetAdminOrders(ctx context.Context, arg GetAdminOrdersParams) ([]GetAdminOrdersRow, error) {
-	rows, err := q.db.Query(ctx, getAdminOrders, arg.StatusFilter, arg.UserID)
+	rows, err := q.db.Query(ctx, getAdminOrders, arg.StatusFilter, arg.UserID, arg.TargetID)
 	if err != nil {
 		return nil, err
 	}
@@ -116,6 +155,15 @@ func (q *Queries) GetAdminOrders(ctx context.Context, arg GetAdminOrdersParams)
 			&i.RefillsRemaining,
 			&i.ServiceRefillLimit,
 			&i.ServiceRefillEnabled,
+			&i.CanonicalLink,
+			&i.TargetKind,
+			&i.TargetID,
+			&i.OwnStartCount,
+			&i.ProviderStatus,
+			&i.SyncErrorCount,
+			&i.LastSyncError,
+			&i.DiscountCents,
+			&i.PromoCode,
 		); err != nil {
 			return nil, err
 		}
@@ -141,7 +189,7 @@ type GetOrderForCancelParams struct {
 
 type GetOrderForCancelRow struct {
 	Status          string `json:"status"`
-	AmountCents     int32  `json:"amount_cents"`
+	AmountCents     int64  `json:"amount_cents"`
 	ProviderOrderID string `json:"provider_order_id"`
 	ProviderKey     string `json:"provider_key"`
 }
@@ -159,14 +207,14 @@ func (q *Queries) GetOrderForCancel(ctx context.Context, arg GetOrderForCancelPa
 }
 
 const getOrderForRefundAdmin = `-- name: GetOrderForRefundAdmin :one
-SELECT status, amount_cents, COALESCE(refunded_amount, 0)::int as refunded_amount, user_id, COALESCE(provider_key, '')::text as provider_key 
+SELECT status, amount_cents, COALESCE(refunded_amount, 0)::bigint as refunded_amount, user_id, COALESCE(provider_key, '')::text as provider_key 
 FROM orders WHERE id = $1 FOR UPDATE
 `
 
 type GetOrderForRefundAdminRow struct {
 	Status         string `json:"status"`
-	AmountCents    int32  `json:"amount_cents"`
-	RefundedAmount int32  `json:"refunded_amount"`
+	AmountCents    int64  `json:"amount_cents"`
+	RefundedAmount int64  `json:"refunded_amount"`
 	UserID         int32  `json:"user_id"`
 	ProviderKey    string `json:"provider_key"`
 }
@@ -198,7 +246,7 @@ SELECT
 	COALESCE(o.remains, 0)::int as remains,
 	COALESCE(o.start_count, 0)::int as start_count,
 	COALESCE(o.link, '')::text as link,
-	(SELECT COALESCE(balance, 0)::int FROM wallets WHERE user_id = o.user_id) as user_balance,
+	(SELECT COALESCE(balance, 0)::bigint FROM wallets WHERE user_id = o.user_id) as user_balance,
 	COALESCE(so.service_type, '')::text as service_type,
 	COALESCE(so.category, '')::text as category,
 	EXISTS(SELECT 1 FROM order_requests WHERE order_id = o.id AND request_type = 'cancel' AND status = 'pending')::boolean as pending_cancel
@@ -225,7 +273,7 @@ type GetOrdersParams struct {
 type GetOrdersRow struct {
 	ID              int32              `json:"id"`
 	ServiceID       string             `json:"service_id"`
-	AmountCents     int32              `json:"amount_cents"`
+	AmountCents     int64              `json:"amount_cents"`
 	Quantity        int32              `json:"quantity"`
 	Status          string             `json:"status"`
 	CreatedAt       pgtype.Timestamptz `json:"created_at"`
@@ -235,7 +283,7 @@ type GetOrdersRow struct {
 	Remains         int32              `json:"remains"`
 	StartCount      int32              `json:"start_count"`
 	Link            string             `json:"link"`
-	UserBalance     int32              `json:"user_balance"`
+	UserBalance     int64              `json:"user_balance"`
 	ServiceType     string             `json:"service_type"`
 	Category        string             `json:"category"`
 	PendingCancel   bool               `json:"pending_cancel"`
@@ -294,7 +342,7 @@ SELECT
 	COALESCE(o.link, '')::text as link,
 	COALESCE(so.service_type, '')::text as service_type,
 	COALESCE(so.category, '')::text as category,
-	COALESCE(o.refills_remaining, 3)::int as refills_remaining
+	o.refills_remaining
 FROM orders o
 LEFT JOIN service_overrides so ON (
 	o.service_id = so.source_service_id 
@@ -313,7 +361,7 @@ type GetSingleOrderParams struct {
 type GetSingleOrderRow struct {
 	ID               int32              `json:"id"`
 	ServiceID        string             `json:"service_id"`
-	AmountCents      int32              `json:"amount_cents"`
+	AmountCents      int64              `json:"amount_cents"`
 	Quantity         int32              `json:"quantity"`
 	Status           string             `json:"status"`
 	CreatedAt        pgtype.Timestamptz `json:"created_at"`
@@ -325,7 +373,7 @@ type GetSingleOrderRow struct {
 	Link             string             `json:"link"`
 	ServiceType      string             `json:"service_type"`
 	Category         string             `json:"category"`
-	RefillsRemaining int32              `json:"refills_remaining"`
+	RefillsRemaining pgtype.Int4        `json:"refills_remaining"`
 }
 
 func (q *Queries) GetSingleOrder(ctx context.Context, arg GetSingleOrderParams) (GetSingleOrderRow, error) {
@@ -352,15 +400,15 @@ func (q *Queries) GetSingleOrder(ctx context.Context, arg GetSingleOrderParams)
 }
 
 const insertOrder = `-- name: InsertOrder :one
-INSERT INTO orders (user_id, service_id, amount_cents, quantity, link, status, provider_order_id, provider_resp, refills_remaining, provider_key)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+INSERT INTO orders (user_id, service_id, amount_cents, quantity, link, status, provider_order_id, provider_resp, refills_remaining, provider_key, canonical_link, target_kind, target_id, own_start_count, promo_code_id, discount_cents)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 RETURNING id
 `
 
 type InsertOrderParams struct {
 	UserID           int32       `json:"user_id"`
 	ServiceID        string      `json:"service_id"`
-	AmountCents      int32       `json:"amount_cents"`
+	AmountCents      int64       `json:"amount_cents"`
 	Quantity         int32       `json:"quantity"`
 	Link             pgtype.Text `json:"link"`
 	Status           string      `json:"status"`
@@ -368,6 +416,12 @@ type InsertOrderParams struct {
 	ProviderResp     []byte      `json:"provider_resp"`
 	RefillsRemaining pgtype.Int4 `json:"refills_remaining"`
 	ProviderKey      pgtype.Text `json:"provider_key"`
+	CanonicalLink    pgtype.Text `json:"canonical_link"`
+	TargetKind       pgtype.Text `json:"target_kind"`
+	TargetID         pgtype.Text `json:"target_id"`
+	OwnStartCount    pgtype.Int4 `json:"own_start_count"`
+	PromoCodeID      pgtype.Int4 `json:"promo_code_id"`
+	DiscountCents    int64       `json:"discount_cents"`
 }
 
 func (q *Queries) InsertOrder(ctx context.Context, arg InsertOrderParams) (int32, error) {
@@ -382,12 +436,28 @@ func (q *Queries) InsertOrder(ctx context.Context, arg InsertOrderParams) (int32
 		arg.ProviderResp,
 		arg.RefillsRemaining,
 		arg.ProviderKey,
+		arg.CanonicalLink,
+		arg.TargetKind,
+		arg.TargetID,
+		arg.OwnStartCount,
+		arg.PromoCodeID,
+		arg.DiscountCents,
 	)
 	var id int32
 	err := row.Scan(&id)
 	return id, err
 }
 
+const lockOrderTarget = `-- name: LockOrderTarget :exec
+SELECT pg_advisory_xact_lock(hashtext($1::text))
+`
+
+// Serializes orders for one service and target until the transaction ends
+func (q *Queries) LockOrderTarget(ctx context.Context, lockKey string) error {
+	_, err := q.db.Exec(ctx, lockOrderTarget, lockKey)
+	return err
+}
+
 const updateOrderProvider = `-- name: UpdateOrderProvider :exec
 UPDATE orders SET provider_resp = $1, provider_order_id = $2, status = $3 WHERE id = $4
 `
@@ -429,7 +499,7 @@ UPDATE orders SET status = $1, refunded_amount = $2 WHERE id = $3 RETURNING COAL
 
 type UpdateOrderRefundAdminParams struct {
 	Status         string      `json:"status"`
-	RefundedAmount pgtype.Int4 `json:"refunded_amount"`
+	RefundedAmount pgtype.Int8 `json:"refunded_amount"`
 	ID             int32       `json:"id"`
 }
 
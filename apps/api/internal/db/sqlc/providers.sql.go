@@ -19,7 +19,7 @@ func (q *Queries) DeleteSmmProvider(ctx context.Context, id int32) error {
 }
 
 const getActiveSmmProviders = `-- name: GetActiveSmmProviders :many
-SELECT id, key, name, api_url, api_key, currency, is_active, created_at, updated_at
+SELECT id, key, name, api_url, api_key, currency, is_active, created_at, updated_at, refill_cooldown_hours, supports_multi_refill, max_concurrency
 FROM smm_providers
 WHERE is_active = TRUE
 ORDER BY id ASC
@@ -44,6 +44,9 @@ func (q *Queries) GetActiveSmmProviders(ctx context.Context) ([]SmmProvider, err
 			&i.IsActive,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.RefillCooldownHours,
+			&i.SupportsMultiRefill,
+			&i.MaxConcurrency,
 		); err != nil {
 			return nil, err
 		}
@@ -56,7 +59,7 @@ func (q *Queries) GetActiveSmmProviders(ctx context.Context) ([]SmmProvider, err
 }
 
 const getSmmProviderByKey = `-- name: GetSmmProviderByKey :one
-SELECT id, key, name, api_url, api_key, currency, is_active, created_at, updated_at
+SELECT id, key, name, api_url, api_key, currency, is_active, created_at, updated_at, refill_cooldown_hours, supports_multi_refill, max_concurrency
 FROM smm_providers
 WHERE key = $1
 `
@@ -74,12 +77,15 @@ func (q *Queries) GetSmmProviderByKey(ctx context.Context, key string) (SmmProvi
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RefillCooldownHours,
+		&i.SupportsMultiRefill,
+		&i.MaxConcurrency,
 	)
 	return i, err
 }
 
 const listSmmProvidersAdmin = `-- name: ListSmmProvidersAdmin :many
-SELECT id, key, name, api_url, api_key, currency, is_active, created_at, updated_at
+SELECT id, key, name, api_url, api_key, currency, is_active, created_at, updated_at, refill_cooldown_hours, supports_multi_refill, max_concurrency
 FROM smm_providers
 ORDER BY id ASC
 `
@@ -103,6 +109,9 @@ func (q *Queries) ListSmmProvidersAdmin(ctx context.Context) ([]SmmProvider, err
 			&i.IsActive,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.RefillCooldownHours,
+			&i.SupportsMultiRefill,
+			&i.MaxConcurrency,
 		); err != nil {
 			return nil, err
 		}
@@ -115,8 +124,8 @@ func (q *Queries) ListSmmProvidersAdmin(ctx context.Context) ([]SmmProvider, err
 }
 
 const upsertSmmProvider = `-- name: UpsertSmmProvider :one
-INSERT INTO smm_providers (key, name, api_url, api_key, currency, is_active, updated_at)
-VALUES ($1, $2, $3, $4, $5, $6, CURRENT_TIMESTAMP)
+INSERT INTO smm_providers (key, name, api_url, api_key, currency, is_active, refill_cooldown_hours, supports_multi_refill, max_concurrency, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, CURRENT_TIMESTAMP)
 ON CONFLICT (key)
 DO UPDATE SET
     name = EXCLUDED.name,
@@ -124,17 +133,23 @@ DO UPDATE SET
     api_key = EXCLUDED.api_key,
     currency = EXCLUDED.currency,
     is_active = EXCLUDED.is_active,
+    refill_cooldown_hours = EXCLUDED.refill_cooldown_hours,
+    supports_multi_refill = EXCLUDED.supports_multi_refill,
+    max_concurrency = EXCLUDED.max_concurrency,
     updated_at = CURRENT_TIMESTAMP
-RETURNING id, key, name, api_url, api_key, currency, is_active, created_at, updated_at
+RETURNING id, key, name, api_url, api_key, currency, is_active, created_at, updated_at, refill_cooldown_hours, supports_multi_refill, max_concurrency
 `
 
 type UpsertSmmProviderParams struct {
-	Key      string `json:"key"`
-	Name     string `json:"name"`
-	ApiUrl   string `json:"api_url"`
-	ApiKey   string `json:"api_key"`
-	Currency string `json:"currency"`
-	IsActive bool   `json:"is_active"`
+	Key                 string `json:"key"`
+	Name                string `json:"name"`
+	ApiUrl              string `json:"api_url"`
+	ApiKey              string `json:"api_key"`
+	Currency            string `json:"currency"`
+	IsActive            bool   `json:"is_active"`
+	RefillCooldownHours int32  `json:"refill_cooldown_hours"`
+	SupportsMultiRefill bool   `json:"supports_multi_refill"`
+	MaxConcurrency      int32  `json:"max_concurrency"`
 }
 
 func (q *Queries) UpsertSmmProvider(ctx context.Context, arg UpsertSmmProviderParams) (SmmProvider, error) {
@@ -145,6 +160,9 @@ func (q *Queries) UpsertSmmProvider(ctx context.Context, arg UpsertSmmProviderPa
 		arg.ApiKey,
 		arg.Currency,
 		arg.IsActive,
+		arg.RefillCooldownHours,
+		arg.SupportsMultiRefill,
+		arg.MaxConcurrency,
 	)
 	var i SmmProvider
 	err := row.Scan(
@@ -157,6 +175,9 @@ func (q *Queries) UpsertSmmProvider(ctx context.Context, arg UpsertSmmProviderPa
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RefillCooldownHours,
+		&i.SupportsMultiRefill,
+		&i.MaxConcurrency,
 	)
 	return i, err
 }
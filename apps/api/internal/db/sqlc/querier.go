@@ -11,54 +11,158 @@ import (
 )
 
 type Querier interface {
-	ApproveCryptomusWalletRequest(ctx context.Context, id int32) error
+	AcceptSubAccount(ctx context.Context, arg AcceptSubAccountParams) (int64, error)
+	// Clears the report once a mapping covers the status. An empty provider
+	// key clears the status for every provider.
+	ApproveCryptomusWalletRequest(ctx context.Context, id int32) (int64, error)
 	BulkUpsertServiceOverride(ctx context.Context, arg BulkUpsertServiceOverrideParams) error
 	CancelOrder(ctx context.Context, id int32) error
+	CancelOrderWithRefund(ctx context.Context, arg CancelOrderWithRefundParams) error
+	// Turns held funds into a debit
+	CaptureWalletFunds(ctx context.Context, arg CaptureWalletFundsParams) error
 	CheckGoogleUser(ctx context.Context, arg CheckGoogleUserParams) (CheckGoogleUserRow, error)
 	CheckPendingRequestCount(ctx context.Context, userID pgtype.Int4) (int64, error)
 	CheckTransactionIDExists(ctx context.Context, transactionID pgtype.Text) (int32, error)
 	CheckUPINotificationExists(ctx context.Context, utr pgtype.Text) (int32, error)
 	CheckUniqueAmount(ctx context.Context, uniqueAmount pgtype.Numeric) (int64, error)
 	CheckUserExists(ctx context.Context, arg CheckUserExistsParams) (bool, error)
-	CountWalletTransactions(ctx context.Context, userID pgtype.Int4) (int64, error)
+	// Takes the job's lease unless a run holds one that has not run out, or a
+	// run started less than min_gap_seconds ago
+	ClaimJobLease(ctx context.Context, arg ClaimJobLeaseParams) (int64, error)
+	CountActiveOrdersForTarget(ctx context.Context, arg CountActiveOrdersForTargetParams) (int64, error)
+	CountOrderRefills(ctx context.Context, orderID int32) (int64, error)
+	CountOwnedSubAccounts(ctx context.Context, ownerID int32) (int64, error)
+	CountPromoRedemptions(ctx context.Context, arg CountPromoRedemptionsParams) (CountPromoRedemptionsRow, error)
+	CountReferralsFromIP(ctx context.Context, arg CountReferralsFromIPParams) (int64, error)
+	CountWalletTransactions(ctx context.Context, arg CountWalletTransactionsParams) (int64, error)
+	CountWallets(ctx context.Context) (int64, error)
 	CreateCatalogService(ctx context.Context, arg CreateCatalogServiceParams) (PabloCatalog, error)
+	// amount is the INR credit, pay_amount what is charged in pay_currency
 	CreateCryptomusWalletRequest(ctx context.Context, arg CreateCryptomusWalletRequestParams) (int32, error)
+	CreateDepositBonusTier(ctx context.Context, arg CreateDepositBonusTierParams) (DepositBonusTier, error)
 	CreateGoogleUser(ctx context.Context, arg CreateGoogleUserParams) (CreateGoogleUserRow, error)
+	CreateLinkRule(ctx context.Context, arg CreateLinkRuleParams) (LinkRule, error)
 	CreateOrderRequest(ctx context.Context, arg CreateOrderRequestParams) (CreateOrderRequestRow, error)
-	CreateUser(ctx context.Context, arg CreateUserParams) error
+	CreatePromoCode(ctx context.Context, arg CreatePromoCodeParams) (PromoCode, error)
+	CreateUser(ctx context.Context, arg CreateUserParams) (int32, error)
 	CreditWallet(ctx context.Context, arg CreditWalletParams) error
-	DebitWallet(ctx context.Context, arg DebitWalletParams) error
+	// Only succeeds when the available balance covers the debit
+	DebitWallet(ctx context.Context, arg DebitWalletParams) (int64, error)
+	DecideOrderRequest(ctx context.Context, arg DecideOrderRequestParams) (int64, error)
 	DecrementOrderRefills(ctx context.Context, id int32) error
 	DeleteCatalogService(ctx context.Context, id int32) error
+	DeleteDepositBonusTier(ctx context.Context, id int32) (int64, error)
+	DeleteInvoiceFailure(ctx context.Context, transactionID int32) error
+	DeleteLinkRule(ctx context.Context, id int32) error
 	DeleteOrder(ctx context.Context, id int32) error
+	// Codes that have been used are kept for reporting; deactivate them instead
+	DeletePromoCode(ctx context.Context, id int32) (int64, error)
+	DeleteProviderStatusMapping(ctx context.Context, id int32) (int64, error)
+	DeleteRefundRule(ctx context.Context, catalogID pgtype.Int4) error
 	DeleteSmmProvider(ctx context.Context, id int32) error
+	// Either side can end a link or turn down an invitation
+	DeleteSubAccount(ctx context.Context, arg DeleteSubAccountParams) (int64, error)
+	DeleteTwoFactor(ctx context.Context, userID int32) error
+	DeleteUnmappedStatuses(ctx context.Context, arg DeleteUnmappedStatusesParams) error
+	EnableTwoFactor(ctx context.Context, arg EnableTwoFactorParams) (int64, error)
+	EnsureLedgerAccount(ctx context.Context, arg EnsureLedgerAccountParams) (int32, error)
+	ExpireBonusGrant(ctx context.Context, arg ExpireBonusGrantParams) (int64, error)
 	FindMatchingWalletRequest(ctx context.Context, uniqueAmount pgtype.Numeric) (FindMatchingWalletRequestRow, error)
 	FindMatchingWalletRequestByUTR(ctx context.Context, transactionID pgtype.Text) (FindMatchingWalletRequestByUTRRow, error)
+	// An exact username match wins over an email that happens to look like one
+	FindTransferRecipient(ctx context.Context, login string) (FindTransferRecipientRow, error)
+	FinishJobLease(ctx context.Context, arg FinishJobLeaseParams) error
+	FinishReconcileRun(ctx context.Context, arg FinishReconcileRunParams) error
+	// Only applies when the cached balance is still the one the run observed
+	FixWalletBalance(ctx context.Context, arg FixWalletBalanceParams) (int64, error)
 	GenerateAPIKey(ctx context.Context, arg GenerateAPIKeyParams) error
 	GetActiveCatalogServices(ctx context.Context) ([]PabloCatalog, error)
 	GetActiveSmmProviders(ctx context.Context) ([]SmmProvider, error)
+	GetActiveWalletHoldForUpdate(ctx context.Context, arg GetActiveWalletHoldForUpdateParams) (WalletHold, error)
 	GetAdminOrders(ctx context.Context, arg GetAdminOrdersParams) ([]GetAdminOrdersRow, error)
 	GetAllCatalogServices(ctx context.Context) ([]PabloCatalog, error)
 	GetAllMoneyTransactions(ctx context.Context, userID pgtype.Int4) ([]GetAllMoneyTransactionsRow, error)
 	GetAllServiceOverrides(ctx context.Context) ([]GetAllServiceOverridesRow, error)
 	GetAllSettings(ctx context.Context) ([]GetAllSettingsRow, error)
+	GetBillingProfile(ctx context.Context, id int32) (GetBillingProfileRow, error)
+	GetBonusGrantForUpdate(ctx context.Context, id int32) (BonusGrant, error)
+	GetCatalogRefillPolicy(ctx context.Context, catalogID int32) (CatalogRefillPolicy, error)
 	GetCatalogService(ctx context.Context, id int32) (PabloCatalog, error)
+	GetDeliveryChecksByOrder(ctx context.Context, orderID int32) ([]OrderDeliveryCheck, error)
+	GetDeliveryDashboard(ctx context.Context) ([]GetDeliveryDashboardRow, error)
+	// The highest current tier a deposit reaches with its payment method
+	GetDepositBonusTierFor(ctx context.Context, arg GetDepositBonusTierForParams) (DepositBonusTier, error)
+	// Approved requests without exactly one deposit credit, reversed ones not
+	// debited back in full, and credits for requests that were never approved.
+	// credited_cents is net of reversals.
+	GetDepositCreditMismatches(ctx context.Context, since pgtype.Timestamptz) ([]GetDepositCreditMismatchesRow, error)
 	GetDepositStatus(ctx context.Context, arg GetDepositStatusParams) (pgtype.Text, error)
+	GetInvoice(ctx context.Context, id int32) (Invoice, error)
+	GetLinkRulesForPlatform(ctx context.Context, arg GetLinkRulesForPlatformParams) ([]LinkRule, error)
+	// Unspent active bonus credit that may be spent but not withdrawn
+	GetLockedBonusCents(ctx context.Context, userID int32) (int64, error)
+	// Wallets whose opening ledger balance, copied from wallets.balance when the
+	// ledger started, differs from what came before: their transactions less
+	// the orders, which were charged without one. API orders that failed then
+	// were credited back without one too, so they are left out.
+	GetOpeningBalanceMismatches(ctx context.Context) ([]GetOpeningBalanceMismatchesRow, error)
+	GetOrderEvents(ctx context.Context, orderID int32) ([]OrderEvent, error)
+	GetOrderForApproval(ctx context.Context, id int32) (GetOrderForApprovalRow, error)
+	GetOrderForAutoRefill(ctx context.Context, id int32) (GetOrderForAutoRefillRow, error)
 	GetOrderForCancel(ctx context.Context, arg GetOrderForCancelParams) (GetOrderForCancelRow, error)
 	GetOrderForRefundAdmin(ctx context.Context, id int32) (GetOrderForRefundAdminRow, error)
 	GetOrderForSyncUpdate(ctx context.Context, id int32) (GetOrderForSyncUpdateRow, error)
+	GetOrderHeldCents(ctx context.Context, referenceID string) (int64, error)
+	GetOrderRefills(ctx context.Context, orderID int32) ([]GetOrderRefillsRow, error)
+	GetOrderRefunds(ctx context.Context, orderID int32) ([]OrderRefund, error)
+	GetOrderRequestForDecision(ctx context.Context, id int32) (OrderRequest, error)
 	GetOrderStatsForUser(ctx context.Context, userID int32) (GetOrderStatsForUserRow, error)
 	GetOrderStatusForAPI(ctx context.Context, arg GetOrderStatusForAPIParams) (GetOrderStatusForAPIRow, error)
 	GetOrders(ctx context.Context, arg GetOrdersParams) ([]GetOrdersRow, error)
-	GetOrdersForSync(ctx context.Context) ([]GetOrdersForSyncRow, error)
+	GetOrdersForSync(ctx context.Context, limit int32) ([]GetOrdersForSyncRow, error)
+	// Completed orders on refillable services with a delivery to measure, least
+	// recently attempted first
+	GetOrdersForVerification(ctx context.Context, arg GetOrdersForVerificationParams) ([]GetOrdersForVerificationRow, error)
 	GetPasswordHash(ctx context.Context, id int32) (string, error)
 	GetPendingOrderRequestsByOrder(ctx context.Context, orderID int32) ([]OrderRequest, error)
 	GetProfileStats(ctx context.Context, userID int32) (GetProfileStatsRow, error)
-	GetProfileTotalSpend(ctx context.Context, userID int32) (int32, error)
+	GetProfileTotalSpend(ctx context.Context, userID int32) (int64, error)
+	GetPromoCodeByCode(ctx context.Context, code string) (PromoCode, error)
+	GetPromoCodeForUpdate(ctx context.Context, id int32) (PromoCode, error)
+	// Use of every code since a point in time. For order codes base is the
+	// order value before discounts; for deposit codes it is the deposits.
+	GetPromoCodeStats(ctx context.Context, since pgtype.Timestamptz) ([]GetPromoCodeStatsRow, error)
+	GetProviderDeliveryStats(ctx context.Context, days int32) ([]GetProviderDeliveryStatsRow, error)
 	GetRecentMoneyTransactions(ctx context.Context, userID pgtype.Int4) ([]GetRecentMoneyTransactionsRow, error)
+	GetReconcileDiscrepancy(ctx context.Context, id int32) (ReconcileDiscrepancy, error)
+	GetReconcileRun(ctx context.Context, id int32) (ReconcileRun, error)
+	GetReferralCode(ctx context.Context, id int32) (string, error)
+	GetReferralForUpdate(ctx context.Context, referredID int32) (Referral, error)
+	GetReferralPayoutForUpdate(ctx context.Context, id int32) (ReferralPayout, error)
+	// Per referrer: referrals by review state, commission earned and held, the
+	// referred value it was earned on, and what has been withdrawn
+	GetReferralReport(ctx context.Context, limit int32) ([]GetReferralReportRow, error)
+	// The affiliate balance is the available commissions less payouts that are
+	// pending or paid
+	GetReferralSummary(ctx context.Context, userID int32) (GetReferralSummaryRow, error)
+	GetReferrerByCode(ctx context.Context, referralCode pgtype.Text) (GetReferrerByCodeRow, error)
+	GetRefillRequestForUser(ctx context.Context, arg GetRefillRequestForUserParams) (GetRefillRequestForUserRow, error)
+	GetRefillsForPolling(ctx context.Context, limit int32) ([]GetRefillsForPollingRow, error)
+	// Orders whose refunded_amount differs from the refund credits in the
+	// user's transactions, matched by order or by order_refunds row
+	GetRefundCreditMismatches(ctx context.Context) ([]GetRefundCreditMismatchesRow, error)
+	GetRefundRuleForService(ctx context.Context, catalogID pgtype.Int4) (RefundRule, error)
+	// Orders are only timed when completed_at is after created_at; completion
+	// times backfilled from the order's own timestamps read as 0 minutes
+	GetServiceDeliveryStats(ctx context.Context, days int32) ([]GetServiceDeliveryStatsRow, error)
 	GetSetting(ctx context.Context, key string) (string, error)
 	GetSingleOrder(ctx context.Context, arg GetSingleOrderParams) (GetSingleOrderRow, error)
 	GetSmmProviderByKey(ctx context.Context, key string) (SmmProvider, error)
+	// The agency link of an account, active or invited
+	GetSubAccountByUser(ctx context.Context, userID int32) (SubAccount, error)
+	// What a user has sent, or is waiting to send, since a point in time
+	GetTransferredSince(ctx context.Context, arg GetTransferredSinceParams) (int64, error)
+	GetTwoFactor(ctx context.Context, userID int32) (UserTwoFactor, error)
 	GetUnmatchedUPINotification(ctx context.Context, utr pgtype.Text) (GetUnmatchedUPINotificationRow, error)
 	GetUserAdmin(ctx context.Context, id int32) (GetUserAdminRow, error)
 	GetUserByAPIKey(ctx context.Context, apiKey pgtype.Text) (GetUserByAPIKeyRow, error)
@@ -68,30 +172,147 @@ type Querier interface {
 	GetUserProfile(ctx context.Context, email pgtype.Text) (GetUserProfileRow, error)
 	GetUserTransactionsAdmin(ctx context.Context, userID pgtype.Int4) ([]GetUserTransactionsAdminRow, error)
 	GetUsers(ctx context.Context, arg GetUsersParams) ([]GetUsersRow, error)
-	GetWalletBalance(ctx context.Context, userID int32) (int32, error)
+	GetWalletBalance(ctx context.Context, userID int32) (int64, error)
+	GetWalletFunds(ctx context.Context, id int32) (GetWalletFundsRow, error)
+	GetWalletFundsForUpdate(ctx context.Context, userID int32) (GetWalletFundsForUpdateRow, error)
+	GetWalletLedgerBalance(ctx context.Context, userID pgtype.Int4) (int64, error)
+	GetWalletLedgerMismatches(ctx context.Context) ([]GetWalletLedgerMismatchesRow, error)
+	GetWalletLedgerTotal(ctx context.Context) (int64, error)
 	GetWalletRequestAmount(ctx context.Context, id int32) (pgtype.Numeric, error)
 	GetWalletRequestForUpdateAdmin(ctx context.Context, id int32) (GetWalletRequestForUpdateAdminRow, error)
+	GetWalletRequestPromoCode(ctx context.Context, id int32) (pgtype.Int4, error)
 	GetWalletRequestStatus(ctx context.Context, id int32) (pgtype.Text, error)
 	GetWalletRequestStatusForUpdate(ctx context.Context, id int32) (pgtype.Text, error)
 	GetWalletTransactions(ctx context.Context, arg GetWalletTransactionsParams) ([]Transaction, error)
+	GetWalletTransferForUpdate(ctx context.Context, id int32) (WalletTransfer, error)
+	GetWatchdogCandidates(ctx context.Context, arg GetWatchdogCandidatesParams) ([]GetWatchdogCandidatesRow, error)
+	// Whether two accounts are an agency and one of its active sub-accounts,
+	// or two active sub-accounts of the same agency
+	InSameAgency(ctx context.Context, arg InSameAgencyParams) (bool, error)
+	IncrementOrderRefills(ctx context.Context, id int32) error
 	IncrementServicePurchaseCount(ctx context.Context, sourceServiceID string) error
 	InsertAPIOrder(ctx context.Context, arg InsertAPIOrderParams) (int32, error)
+	InsertAdminAudit(ctx context.Context, arg InsertAdminAuditParams) error
+	InsertBonusGrant(ctx context.Context, arg InsertBonusGrantParams) (int32, error)
+	InsertDeliveryCheck(ctx context.Context, arg InsertDeliveryCheckParams) (OrderDeliveryCheck, error)
+	InsertInvoice(ctx context.Context, arg InsertInvoiceParams) (Invoice, error)
+	InsertLedgerEntry(ctx context.Context, arg InsertLedgerEntryParams) error
+	InsertLedgerJournal(ctx context.Context, arg InsertLedgerJournalParams) (int32, error)
 	InsertOrder(ctx context.Context, arg InsertOrderParams) (int32, error)
+	InsertOrderEscalation(ctx context.Context, arg InsertOrderEscalationParams) error
+	InsertOrderEvent(ctx context.Context, arg InsertOrderEventParams) error
+	InsertOrderRefund(ctx context.Context, arg InsertOrderRefundParams) (OrderRefund, error)
+	InsertOrderRequestWithStatus(ctx context.Context, arg InsertOrderRequestWithStatusParams) (int32, error)
+	InsertPromoRedemption(ctx context.Context, arg InsertPromoRedemptionParams) (int32, error)
+	InsertReconcileDiscrepancy(ctx context.Context, arg InsertReconcileDiscrepancyParams) (int32, error)
+	InsertReconcileRun(ctx context.Context, trigger string) (int32, error)
+	InsertReferral(ctx context.Context, arg InsertReferralParams) error
+	InsertReferralCommission(ctx context.Context, arg InsertReferralCommissionParams) (int32, error)
+	InsertReferralPayout(ctx context.Context, arg InsertReferralPayoutParams) (ReferralPayout, error)
+	InsertSubAccount(ctx context.Context, arg InsertSubAccountParams) (SubAccount, error)
 	InsertTransaction(ctx context.Context, arg InsertTransactionParams) error
 	InsertUPINotificationMatched(ctx context.Context, arg InsertUPINotificationMatchedParams) error
 	InsertUPINotificationUnmatched(ctx context.Context, arg InsertUPINotificationUnmatchedParams) error
+	InsertWalletAdjustment(ctx context.Context, arg InsertWalletAdjustmentParams) (int32, error)
+	InsertWalletHold(ctx context.Context, arg InsertWalletHoldParams) (int32, error)
 	InsertWalletRequest(ctx context.Context, arg InsertWalletRequestParams) (int32, error)
+	InsertWalletTransfer(ctx context.Context, arg InsertWalletTransferParams) (WalletTransfer, error)
+	ListActiveWalletHolds(ctx context.Context, userID int32) ([]WalletHold, error)
+	ListAdminAudit(ctx context.Context, arg ListAdminAuditParams) ([]ListAdminAuditRow, error)
+	ListBonusGrants(ctx context.Context, arg ListBonusGrantsParams) ([]BonusGrant, error)
+	ListCatalogRefillPolicies(ctx context.Context) ([]CatalogRefillPolicy, error)
+	// Tiers a deposit made now could earn
+	ListCurrentDepositBonusTiers(ctx context.Context) ([]DepositBonusTier, error)
+	ListDepositBonusTiers(ctx context.Context) ([]DepositBonusTier, error)
+	ListDueBonusGrants(ctx context.Context, limit int32) ([]BonusGrant, error)
+	ListHeldReferralCommissions(ctx context.Context, referredID int32) ([]ReferralCommission, error)
+	ListInvoiceFailures(ctx context.Context) ([]ListInvoiceFailuresRow, error)
+	ListInvoices(ctx context.Context, arg ListInvoicesParams) ([]Invoice, error)
+	ListJobLeases(ctx context.Context) ([]JobLease, error)
+	ListLedgerSystemBalances(ctx context.Context) ([]ListLedgerSystemBalancesRow, error)
+	ListLinkRules(ctx context.Context) ([]LinkRule, error)
+	ListOrderEscalations(ctx context.Context, status string) ([]ListOrderEscalationsRow, error)
+	ListOrderRefundCredits(ctx context.Context, orderID int32) ([]Transaction, error)
 	ListPendingOrderRequests(ctx context.Context) ([]ListPendingOrderRequestsRow, error)
+	ListPromoCodes(ctx context.Context) ([]PromoCode, error)
+	ListPromoRedemptions(ctx context.Context, arg ListPromoRedemptionsParams) ([]ListPromoRedemptionsRow, error)
+	ListProviderStatusMappings(ctx context.Context) ([]ProviderStatusMapping, error)
+	ListReconcileDiscrepancies(ctx context.Context, arg ListReconcileDiscrepanciesParams) ([]ListReconcileDiscrepanciesRow, error)
+	ListReconcileRuns(ctx context.Context, limit int32) ([]ReconcileRun, error)
+	ListReferralCommissions(ctx context.Context, arg ListReferralCommissionsParams) ([]ReferralCommission, error)
+	// Approved deposits by referred users, made after they signed up through
+	// the referral, that have no commission yet. The base is what the wallet
+	// was credited.
+	ListReferralDepositSources(ctx context.Context, limit int32) ([]ListReferralDepositSourcesRow, error)
+	// Finished orders by referred users, net of refunds, that have no
+	// commission yet
+	ListReferralOrderSources(ctx context.Context, limit int32) ([]ListReferralOrderSourcesRow, error)
+	ListReferralPayouts(ctx context.Context, arg ListReferralPayoutsParams) ([]ListReferralPayoutsRow, error)
+	ListReferrals(ctx context.Context, arg ListReferralsParams) ([]ListReferralsRow, error)
+	ListRefundRules(ctx context.Context) ([]RefundRule, error)
 	ListSmmProvidersAdmin(ctx context.Context) ([]SmmProvider, error)
+	// Orders whose hold is still active well after they were placed
+	ListStaleOrderHolds(ctx context.Context, arg ListStaleOrderHoldsParams) ([]int32, error)
+	// Links a user owns or belongs to, invitations included
+	ListSubAccounts(ctx context.Context, ownerID int32) ([]ListSubAccountsRow, error)
+	// Deposits and admin credits marked as top-ups since a point in time that
+	// have no invoice yet
+	ListUninvoicedTopUps(ctx context.Context, arg ListUninvoicedTopUpsParams) ([]ListUninvoicedTopUpsRow, error)
+	ListUnmappedStatuses(ctx context.Context) ([]ProviderUnmappedStatus, error)
 	ListWalletRequestsAdmin(ctx context.Context) ([]ListWalletRequestsAdminRow, error)
+	// Transfers sent or received by a user, or everyone's without user_id
+	ListWalletTransfers(ctx context.Context, arg ListWalletTransfersParams) ([]ListWalletTransfersRow, error)
+	// Serializes orders for one service and target until the transaction ends
+	LockOrderTarget(ctx context.Context, lockKey string) error
+	LockUserForUpdate(ctx context.Context, id int32) error
+	MarkDeliveryChecked(ctx context.Context, id int32) error
+	MarkOrderNeedsAttention(ctx context.Context, id int32) (int64, error)
+	MarkOrderRefilled(ctx context.Context, id int32) error
 	MarkUPINotificationMatched(ctx context.Context, arg MarkUPINotificationMatchedParams) error
+	MarkWalletAdjustmentTopUp(ctx context.Context, id int32) error
+	NextInvoiceNumber(ctx context.Context, financialYear string) (int32, error)
+	RecordInvoiceFailure(ctx context.Context, arg RecordInvoiceFailureParams) error
+	RecordOrderSyncError(ctx context.Context, arg RecordOrderSyncErrorParams) (int32, error)
+	RecordUnmappedStatus(ctx context.Context, arg RecordUnmappedStatusParams) error
 	RejectWalletRequest(ctx context.Context, id int32) error
+	ReleaseWalletFunds(ctx context.Context, arg ReleaseWalletFundsParams) error
+	RenewJobLease(ctx context.Context, arg RenewJobLeaseParams) (int64, error)
+	// Only succeeds when the available balance covers the hold
+	ReserveWalletFunds(ctx context.Context, arg ReserveWalletFundsParams) (int64, error)
+	ResolveOrderEscalation(ctx context.Context, arg ResolveOrderEscalationParams) (int64, error)
+	ResolveReconcileDiscrepancy(ctx context.Context, arg ResolveReconcileDiscrepancyParams) (int64, error)
+	ResolveWalletHold(ctx context.Context, arg ResolveWalletHoldParams) (int64, error)
+	ResumeOrderSync(ctx context.Context, id int32) (int64, error)
+	// Claws back the commission earned on a source, returning the status it had
+	ReverseReferralCommission(ctx context.Context, arg ReverseReferralCommissionParams) (ReverseReferralCommissionRow, error)
+	ReverseWalletRequest(ctx context.Context, id int32) (int64, error)
+	ScheduleOrderSync(ctx context.Context, arg ScheduleOrderSyncParams) error
+	SetOrderRequestRefill(ctx context.Context, arg SetOrderRequestRefillParams) error
+	SetOrderRequestResponse(ctx context.Context, arg SetOrderRequestResponseParams) error
+	SetOrderWatchdogStage(ctx context.Context, arg SetOrderWatchdogStageParams) error
+	SetReferralCommissionStatus(ctx context.Context, arg SetReferralCommissionStatusParams) error
+	// Starts or restarts setting up two-factor authentication; a secret that is
+	// already turned on is left alone
+	SetTwoFactorSecret(ctx context.Context, arg SetTwoFactorSecretParams) (int64, error)
+	// Cuts the unspent part of a wallet's active bonuses down to what its
+	// balance still covers. Bonus credit is spent after deposited money, and
+	// the bonus expiring soonest is spent first.
+	SpendBonusGrants(ctx context.Context, userID int32) error
+	SupersedeOpenDiscrepancies(ctx context.Context) (int64, error)
+	// Orders looked at but not yet overdue wait out the re-check interval like
+	// the rest, so they do not crowd newer candidates out of the batch
+	TouchOrderWatchdogCheck(ctx context.Context, ids []int32) error
+	// Held until the claiming transaction ends
+	TryJobXactLock(ctx context.Context, name string) (bool, error)
 	UpdateAPIOrderStatusFailed(ctx context.Context, id int32) error
 	UpdateAPIOrderStatusSubmitted(ctx context.Context, arg UpdateAPIOrderStatusSubmittedParams) error
+	UpdateBillingProfile(ctx context.Context, arg UpdateBillingProfileParams) error
 	UpdateCatalogService(ctx context.Context, arg UpdateCatalogServiceParams) (PabloCatalog, error)
 	UpdateCryptomusTransactionID(ctx context.Context, arg UpdateCryptomusTransactionIDParams) error
+	UpdateDepositBonusTier(ctx context.Context, arg UpdateDepositBonusTierParams) (DepositBonusTier, error)
 	UpdateDepositUTR(ctx context.Context, arg UpdateDepositUTRParams) (int64, error)
 	UpdateGoogleInfo(ctx context.Context, arg UpdateGoogleInfoParams) error
+	UpdateLinkRule(ctx context.Context, arg UpdateLinkRuleParams) (LinkRule, error)
 	UpdateOrderProvider(ctx context.Context, arg UpdateOrderProviderParams) error
 	UpdateOrderRefillsAdmin(ctx context.Context, arg UpdateOrderRefillsAdminParams) error
 	UpdateOrderRefundAdmin(ctx context.Context, arg UpdateOrderRefundAdminParams) (string, error)
@@ -100,12 +321,23 @@ type Querier interface {
 	UpdateOrderSyncWithRefund(ctx context.Context, arg UpdateOrderSyncWithRefundParams) error
 	UpdatePassword(ctx context.Context, arg UpdatePasswordParams) error
 	UpdateProfile(ctx context.Context, arg UpdateProfileParams) error
+	UpdatePromoCode(ctx context.Context, arg UpdatePromoCodeParams) (PromoCode, error)
+	UpdateReferralPayoutStatus(ctx context.Context, arg UpdateReferralPayoutStatusParams) (int64, error)
+	UpdateReferralStatus(ctx context.Context, arg UpdateReferralStatusParams) error
+	UpdateRefillStatus(ctx context.Context, arg UpdateRefillStatusParams) (int64, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) error
 	UpdateWalletRequestStatusAndTxn(ctx context.Context, arg UpdateWalletRequestStatusAndTxnParams) error
+	UpdateWalletTransferStatus(ctx context.Context, arg UpdateWalletTransferStatusParams) (int64, error)
+	UpsertCatalogRefillPolicy(ctx context.Context, arg UpsertCatalogRefillPolicyParams) (CatalogRefillPolicy, error)
+	UpsertProviderStatusMapping(ctx context.Context, arg UpsertProviderStatusMappingParams) (ProviderStatusMapping, error)
+	UpsertRefundRule(ctx context.Context, arg UpsertRefundRuleParams) (RefundRule, error)
 	UpsertServiceOverride(ctx context.Context, arg UpsertServiceOverrideParams) error
 	UpsertSetting(ctx context.Context, arg UpsertSettingParams) error
 	UpsertSmmProvider(ctx context.Context, arg UpsertSmmProviderParams) (SmmProvider, error)
 	UpsertWalletBalance(ctx context.Context, arg UpsertWalletBalanceParams) error
+	// Records an accepted code; fails when a code of this or a later step was
+	// already used
+	UseTwoFactorStep(ctx context.Context, arg UseTwoFactorStepParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
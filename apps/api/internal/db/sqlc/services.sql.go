@@ -204,12 +204,12 @@ SELECT COALESCE(SUM(
         WHEN status IN ('failed', 'canceled', 'refunded') THEN 0 
         ELSE amount_cents - COALESCE(refunded_amount, 0) 
     END
-), 0)::int FROM orders WHERE user_id = $1
+), 0)::bigint FROM orders WHERE user_id = $1
 `
 
-func (q *Queries) GetProfileTotalSpend(ctx context.Context, userID int32) (int32, error) {
+func (q *Queries) GetProfileTotalSpend(ctx context.Context, userID int32) (int64, error) {
 	row := q.db.QueryRow(ctx, getProfileTotalSpend, userID)
-	var column_1 int32
+	var column_1 int64
 	err := row.Scan(&column_1)
 	return column_1, err
 }
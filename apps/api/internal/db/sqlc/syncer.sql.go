@@ -12,14 +12,16 @@ import (
 )
 
 const getOrderForSyncUpdate = `-- name: GetOrderForSyncUpdate :one
-SELECT amount_cents, user_id, quantity, status FROM orders WHERE id = $1
+SELECT amount_cents, user_id, quantity, status, service_id, COALESCE(refunded_amount, 0)::bigint as refunded_amount FROM orders WHERE id = $1 FOR UPDATE
 `
 
 type GetOrderForSyncUpdateRow struct {
-	AmountCents int32  `json:"amount_cents"`
-	UserID      int32  `json:"user_id"`
-	Quantity    int32  `json:"quantity"`
-	Status      string `json:"status"`
+	AmountCents    int64  `json:"amount_cents"`
+	UserID         int32  `json:"user_id"`
+	Quantity       int32  `json:"quantity"`
+	Status         string `json:"status"`
+	ServiceID      string `json:"service_id"`
+	RefundedAmount int64  `json:"refunded_amount"`
 }
 
 func (q *Queries) GetOrderForSyncUpdate(ctx context.Context, id int32) (GetOrderForSyncUpdateRow, error) {
@@ -30,29 +32,33 @@ func (q *Queries) GetOrderForSyncUpdate(ctx context.Context, id int32) (GetOrder
 		&i.UserID,
 		&i.Quantity,
 		&i.Status,
+		&i.ServiceID,
+		&i.RefundedAmount,
 	)
 	return i, err
 }
 
 const getOrdersForSync = `-- name: GetOrdersForSync :many
-SELECT id, provider_order_id, status, COALESCE(provider_key, '')::text as provider_key 
-FROM orders 
-WHERE status IN ('pending', 'processing', 'submitted', 'in_progress', 'active', 'canceled', 'failed', 'completed', 'refunded') 
-AND provider_order_id IS NOT NULL 
+SELECT id, COALESCE(provider_order_id, '')::text as provider_order_id, status, COALESCE(provider_key, '')::text as provider_key, created_at
+FROM orders
+WHERE status IN ('pending', 'processing', 'submitted', 'in_progress', 'active')
+AND provider_order_id IS NOT NULL
 AND provider_order_id != ''
-AND created_at > NOW() - INTERVAL '7 days'
-LIMIT 100
+AND (next_sync_at IS NULL OR next_sync_at <= NOW())
+ORDER BY next_sync_at ASC NULLS FIRST
+LIMIT $1
 `
 
 type GetOrdersForSyncRow struct {
-	ID              int32       `json:"id"`
-	ProviderOrderID pgtype.Text `json:"provider_order_id"`
-	Status          string      `json:"status"`
-	ProviderKey     string      `json:"provider_key"`
+	ID              int32              `json:"id"`
+	ProviderOrderID string             `json:"provider_order_id"`
+	Status          string             `json:"status"`
+	ProviderKey     string             `json:"provider_key"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
 }
 
-func (q *Queries) GetOrdersForSync(ctx context.Context) ([]GetOrdersForSyncRow, error) {
-	rows, err := q.db.Query(ctx, getOrdersForSync)
+func (q *Queries) GetOrdersForSync(ctx context.Context, limit int32) ([]GetOrdersForSyncRow, error) {
+	rows, err := q.db.Query(ctx, getOrdersForSync, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -65,6 +71,7 @@ func (q *Queries) GetOrdersForSync(ctx context.Context) ([]GetOrdersForSyncRow,
 			&i.ProviderOrderID,
 			&i.Status,
 			&i.ProviderKey,
+			&i.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -76,17 +83,83 @@ func (q *Queries) GetOrdersForSync(ctx context.Context) ([]GetOrdersForSyncRow,
 	return items, nil
 }
 
+const markOrderNeedsAttention = `-- name: MarkOrderNeedsAttention :execrows
+UPDATE orders
+SET status = 'needs_attention', next_sync_at = NULL
+WHERE id = $1 AND status IN ('pending', 'processing', 'submitted', 'in_progress', 'active')
+`
+
+func (q *Queries) MarkOrderNeedsAttention(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, markOrderNeedsAttention, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const recordOrderSyncError = `-- name: RecordOrderSyncError :one
+UPDATE orders
+SET sync_error_count = sync_error_count + 1, last_sync_error = $2
+WHERE id = $1
+RETURNING sync_error_count
+`
+
+type RecordOrderSyncErrorParams struct {
+	ID            int32       `json:"id"`
+	LastSyncError pgtype.Text `json:"last_sync_error"`
+}
+
+func (q *Queries) RecordOrderSyncError(ctx context.Context, arg RecordOrderSyncErrorParams) (int32, error) {
+	row := q.db.QueryRow(ctx, recordOrderSyncError, arg.ID, arg.LastSyncError)
+	var sync_error_count int32
+	err := row.Scan(&sync_error_count)
+	return sync_error_count, err
+}
+
+const resumeOrderSync = `-- name: ResumeOrderSync :execrows
+UPDATE orders
+SET status = 'processing', sync_error_count = 0, last_sync_error = NULL, next_sync_at = NULL
+WHERE id = $1 AND status = 'needs_attention'
+`
+
+func (q *Queries) ResumeOrderSync(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, resumeOrderSync, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const scheduleOrderSync = `-- name: ScheduleOrderSync :exec
+UPDATE orders
+SET next_sync_at = $2, last_synced_at = NOW()
+WHERE id = $1
+`
+
+type ScheduleOrderSyncParams struct {
+	ID         int32              `json:"id"`
+	NextSyncAt pgtype.Timestamptz `json:"next_sync_at"`
+}
+
+func (q *Queries) ScheduleOrderSync(ctx context.Context, arg ScheduleOrderSyncParams) error {
+	_, err := q.db.Exec(ctx, scheduleOrderSync, arg.ID, arg.NextSyncAt)
+	return err
+}
+
 const updateOrderSyncNoRefund = `-- name: UpdateOrderSyncNoRefund :exec
 UPDATE orders 
-SET status = $1, remains = $2, start_count = $3 
+SET status = $1, remains = $2, start_count = $3, provider_status = $5,
+    sync_error_count = 0, last_sync_error = NULL,
+    completed_at = CASE WHEN $1 IN ('completed', 'partial') THEN COALESCE(completed_at, NOW()) ELSE completed_at END
 WHERE id = $4
 `
 
 type UpdateOrderSyncNoRefundParams struct {
-	Status     string      `json:"status"`
-	Remains    pgtype.Int4 `json:"remains"`
-	StartCount pgtype.Int4 `json:"start_count"`
-	ID         int32       `json:"id"`
+	Status         string      `json:"status"`
+	Remains        pgtype.Int4 `json:"remains"`
+	StartCount     pgtype.Int4 `json:"start_count"`
+	ID             int32       `json:"id"`
+	ProviderStatus pgtype.Text `json:"provider_status"`
 }
 
 func (q *Queries) UpdateOrderSyncNoRefund(ctx context.Context, arg UpdateOrderSyncNoRefundParams) error {
@@ -95,13 +168,16 @@ func (q *Queries) UpdateOrderSyncNoRefund(ctx context.Context, arg UpdateOrderSy
 		arg.Remains,
 		arg.StartCount,
 		arg.ID,
+		arg.ProviderStatus,
 	)
 	return err
 }
 
 const updateOrderSyncWithRefund = `-- name: UpdateOrderSyncWithRefund :exec
 UPDATE orders 
-SET status = $1, remains = $2, start_count = $3, refunded_amount = COALESCE(refunded_amount, 0) + $4
+SET status = $1, remains = $2, start_count = $3, refunded_amount = COALESCE(refunded_amount, 0) + $4,
+    provider_status = $6, sync_error_count = 0, last_sync_error = NULL,
+    completed_at = CASE WHEN $1 IN ('completed', 'partial') THEN COALESCE(completed_at, NOW()) ELSE completed_at END
 WHERE id = $5
 `
 
@@ -109,8 +185,9 @@ type UpdateOrderSyncWithRefundParams struct {
 	Status         string      `json:"status"`
 	Remains        pgtype.Int4 `json:"remains"`
 	StartCount     pgtype.Int4 `json:"start_count"`
-	RefundedAmount pgtype.Int4 `json:"refunded_amount"`
+	RefundedAmount pgtype.Int8 `json:"refunded_amount"`
 	ID             int32       `json:"id"`
+	ProviderStatus pgtype.Text `json:"provider_status"`
 }
 
 func (q *Queries) UpdateOrderSyncWithRefund(ctx context.Context, arg UpdateOrderSyncWithRefundParams) error {
@@ -120,6 +197,7 @@ func (q *Queries) UpdateOrderSyncWithRefund(ctx context.Context, arg UpdateOrder
 		arg.StartCount,
 		arg.RefundedAmount,
 		arg.ID,
+		arg.ProviderStatus,
 	)
 	return err
 }
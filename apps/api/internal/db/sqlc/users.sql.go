@@ -14,14 +14,14 @@ import (
 const getUserAdmin = `-- name: GetUserAdmin :one
 SELECT 
 	u.id, u.name, COALESCE(u.username, '')::text as username, u.email, COALESCE(u.mobile, '')::text as mobile, u.role, COALESCE(u.currency, 'USD')::text as currency, u.created_at,
-	COALESCE(w.balance, 0)::int as balance,
+	COALESCE(w.balance, 0)::bigint as balance,
 	(SELECT COUNT(*)::int FROM orders o WHERE o.user_id = u.id) as order_count,
 	(SELECT COALESCE(SUM(
 		CASE 
 			WHEN o.status IN ('failed', 'canceled', 'refunded') THEN 0 
 			ELSE o.amount_cents 
 		END
-	), 0)::int FROM orders o WHERE o.user_id = u.id) as total_spend
+	), 0)::bigint FROM orders o WHERE o.user_id = u.id) as total_spend
 FROM users u
 LEFT JOIN wallets w ON u.id = w.user_id
 WHERE u.id = $1
@@ -36,9 +36,9 @@ type GetUserAdminRow struct {
 	Role       string             `json:"role"`
 	Currency   string             `json:"currency"`
 	CreatedAt  pgtype.Timestamptz `json:"created_at"`
-	Balance    int32              `json:"balance"`
+	Balance    int64              `json:"balance"`
 	OrderCount int32              `json:"order_count"`
-	TotalSpend int32              `json:"total_spend"`
+	TotalSpend int64              `json:"total_spend"`
 }
 
 func (q *Queries) GetUserAdmin(ctx context.Context, id int32) (GetUserAdminRow, error) {
@@ -71,7 +71,7 @@ LIMIT 5
 type GetUserOrdersAdminRow struct {
 	ID          int32              `json:"id"`
 	ServiceID   string             `json:"service_id"`
-	AmountCents int32              `json:"amount_cents"`
+	AmountCents int64              `json:"amount_cents"`
 	Status      string             `json:"status"`
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
 }
@@ -103,7 +103,7 @@ func (q *Queries) GetUserOrdersAdmin(ctx context.Context, userID int32) ([]GetUs
 }
 
 const getUserProfile = `-- name: GetUserProfile :one
-SELECT u.id, u.name, u.email, u.role, COALESCE(u.api_key, '')::text as api_key, COALESCE(w.balance, 0)::int as balance
+SELECT u.id, u.name, u.email, u.role, COALESCE(u.api_key, '')::text as api_key, COALESCE(w.balance, 0)::bigint as balance
 FROM users u LEFT JOIN wallets w ON u.id = w.user_id WHERE u.email = $1
 `
 
@@ -113,7 +113,7 @@ type GetUserProfileRow struct {
 	Email   pgtype.Text `json:"email"`
 	Role    string      `json:"role"`
 	ApiKey  string      `json:"api_key"`
-	Balance int32       `json:"balance"`
+	Balance int64       `json:"balance"`
 }
 
 func (q *Queries) GetUserProfile(ctx context.Context, email pgtype.Text) (GetUserProfileRow, error) {
@@ -131,7 +131,7 @@ func (q *Queries) GetUserProfile(ctx context.Context, email pgtype.Text) (GetUse
 }
 
 const getUserTransactionsAdmin = `-- name: GetUserTransactionsAdmin :many
-SELECT id, amount, type, description, created_at 
+SELECT id, amount, type, description, created_at, kind, reference_type, reference_id
 FROM transactions 
 WHERE user_id = $1 
 ORDER BY created_at DESC 
@@ -139,11 +139,14 @@ LIMIT 10
 `
 
 type GetUserTransactionsAdminRow struct {
-	ID          int32              `json:"id"`
-	Amount      pgtype.Numeric     `json:"amount"`
-	Type        string             `json:"type"`
-	Description pgtype.Text        `json:"description"`
-	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	ID            int32              `json:"id"`
+	Amount        pgtype.Numeric     `json:"amount"`
+	Type          string             `json:"type"`
+	Description   pgtype.Text        `json:"description"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	Kind          string             `json:"kind"`
+	ReferenceType string             `json:"reference_type"`
+	ReferenceID   string             `json:"reference_id"`
 }
 
 func (q *Queries) GetUserTransactionsAdmin(ctx context.Context, userID pgtype.Int4) ([]GetUserTransactionsAdminRow, error) {
@@ -161,6 +164,9 @@ func (q *Queries) GetUserTransactionsAdmin(ctx context.Context, userID pgtype.In
 			&i.Type,
 			&i.Description,
 			&i.CreatedAt,
+			&i.Kind,
+			&i.ReferenceType,
+			&i.ReferenceID,
 		); err != nil {
 			return nil, err
 		}
@@ -175,14 +181,14 @@ func (q *Queries) GetUserTransactionsAdmin(ctx context.Context, userID pgtype.In
 const getUsers = `-- name: GetUsers :many
 SELECT 
 	u.id, u.name, COALESCE(u.username, '')::text as username, u.email, COALESCE(u.mobile, '')::text as mobile, u.role, COALESCE(u.currency, 'USD')::text as currency, u.created_at,
-	COALESCE(w.balance, 0)::int as balance,
+	COALESCE(w.balance, 0)::bigint as balance,
 	(SELECT COUNT(*)::int FROM orders o WHERE o.user_id = u.id) as order_count,
 	(SELECT COALESCE(SUM(
 		CASE 
 			WHEN o.status IN ('failed', 'canceled', 'refunded') THEN 0 
 			ELSE o.amount_cents 
 		END
-	), 0)::int FROM orders o WHERE o.user_id = u.id) as total_spend
+	), 0)::bigint FROM orders o WHERE o.user_id = u.id) as total_spend
 FROM users u
 LEFT JOIN wallets w ON u.id = w.user_id
 WHERE ($3::text = '' OR u.name ILIKE '%' || $3 || '%' OR u.email ILIKE '%' || $3 || '%')
@@ -205,9 +211,9 @@ type GetUsersRow struct {
 	Role       string             `json:"role"`
 	Currency   string             `json:"currency"`
 	CreatedAt  pgtype.Timestamptz `json:"created_at"`
-	Balance    int32              `json:"balance"`
+	Balance    int64              `json:"balance"`
 	OrderCount int32              `json:"order_count"`
-	TotalSpend int32              `json:"total_spend"`
+	TotalSpend int64              `json:"total_spend"`
 }
 
 func (q *Queries) GetUsers(ctx context.Context, arg GetUsersParams) ([]GetUsersRow, error) {
@@ -11,13 +11,16 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const approveCryptomusWalletRequest = `-- name: ApproveCryptomusWalletRequest :exec
-UPDATE wallet_requests SET status='approved', updated_at=NOW() WHERE id=$1
+const approveCryptomusWalletRequest = `-- name: ApproveCryptomusWalletRequest :execrows
+UPDATE wallet_requests SET status='approved', updated_at=NOW() WHERE id=$1 AND status='pending'
 `
 
-func (q *Queries) ApproveCryptomusWalletRequest(ctx context.Context, id int32) error {
-	_, err := q.db.Exec(ctx, approveCryptomusWalletRequest, id)
-	return err
+func (q *Queries) ApproveCryptomusWalletRequest(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, approveCryptomusWalletRequest, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const checkPendingRequestCount = `-- name: CheckPendingRequestCount :one
@@ -69,29 +72,55 @@ func (q *Queries) CheckUniqueAmount(ctx context.Context, uniqueAmount pgtype.Num
 }
 
 const countWalletTransactions = `-- name: CountWalletTransactions :one
-SELECT COUNT(*) FROM transactions WHERE user_id = $1
+SELECT COUNT(*) FROM transactions
+WHERE user_id = $1
+AND ($2::text IS NULL OR kind = $2)
+AND ($3::text IS NULL OR reference_type = $3)
+AND ($4::text IS NULL OR reference_id = $4)
 `
 
-func (q *Queries) CountWalletTransactions(ctx context.Context, userID pgtype.Int4) (int64, error) {
-	row := q.db.QueryRow(ctx, countWalletTransactions, userID)
+type CountWalletTransactionsParams struct {
+	UserID        pgtype.Int4 `json:"user_id"`
+	Kind          pgtype.Text `json:"kind"`
+	ReferenceType pgtype.Text `json:"reference_type"`
+	ReferenceID   pgtype.Text `json:"reference_id"`
+}
+
+func (q *Queries) CountWalletTransactions(ctx context.Context, arg CountWalletTransactionsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countWalletTransactions,
+		arg.UserID,
+		arg.Kind,
+		arg.ReferenceType,
+		arg.ReferenceID,
+	)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
 }
 
 const createCryptomusWalletRequest = `-- name: CreateCryptomusWalletRequest :one
-INSERT INTO wallet_requests (user_id, amount, method, status)
-VALUES ($1, $2, 'cryptomus', 'pending')
+INSERT INTO wallet_requests (user_id, amount, pay_amount, pay_currency, promo_code_id, method, status)
+VALUES ($1, $2, $3, $4, $5, 'cryptomus', 'pending')
 RETURNING id
 `
 
 type CreateCryptomusWalletRequestParams struct {
-	UserID pgtype.Int4    `json:"user_id"`
-	Amount pgtype.Numeric `json:"amount"`
+	UserID      pgtype.Int4    `json:"user_id"`
+	Amount      pgtype.Numeric `json:"amount"`
+	PayAmount   pgtype.Numeric `json:"pay_amount"`
+	PayCurrency pgtype.Text    `json:"pay_currency"`
+	PromoCodeID pgtype.Int4    `json:"promo_code_id"`
 }
 
+// amount is the INR credit, pay_amount what is charged in pay_currency
 func (q *Queries) CreateCryptomusWalletRequest(ctx context.Context, arg CreateCryptomusWalletRequestParams) (int32, error) {
-	row := q.db.QueryRow(ctx, createCryptomusWalletRequest, arg.UserID, arg.Amount)
+	row := q.db.QueryRow(ctx, createCryptomusWalletRequest,
+		arg.UserID,
+		arg.Amount,
+		arg.PayAmount,
+		arg.PayCurrency,
+		arg.PromoCodeID,
+	)
 	var id int32
 	err := row.Scan(&id)
 	return id, err
@@ -102,7 +131,7 @@ UPDATE wallets SET balance = balance + $1 WHERE user_id = $2
 `
 
 type CreditWalletParams struct {
-	Balance int32 `json:"balance"`
+	Balance int64 `json:"balance"`
 	UserID  int32 `json:"user_id"`
 }
 
@@ -111,18 +140,22 @@ func (q *Queries) CreditWallet(ctx context.Context, arg CreditWalletParams) erro
 	return err
 }
 
-const debitWallet = `-- name: DebitWallet :exec
-UPDATE wallets SET balance = balance - $1 WHERE user_id = $2
+const debitWallet = `-- name: DebitWallet :execrows
+UPDATE wallets SET balance = balance - $1 WHERE user_id = $2 AND balance - held_cents >= $1
 `
 
 type DebitWalletParams struct {
-	Balance int32 `json:"balance"`
+	Balance int64 `json:"balance"`
 	UserID  int32 `json:"user_id"`
 }
 
-func (q *Queries) DebitWallet(ctx context.Context, arg DebitWalletParams) error {
-	_, err := q.db.Exec(ctx, debitWallet, arg.Balance, arg.UserID)
-	return err
+// Only succeeds when the available balance covers the debit
+func (q *Queries) DebitWallet(ctx context.Context, arg DebitWalletParams) (int64, error) {
+	result, err := q.db.Exec(ctx, debitWallet, arg.Balance, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const findMatchingWalletRequest = `-- name: FindMatchingWalletRequest :one
@@ -172,7 +205,7 @@ func (q *Queries) FindMatchingWalletRequestByUTR(ctx context.Context, transactio
 }
 
 const getAllMoneyTransactions = `-- name: GetAllMoneyTransactions :many
-SELECT id, amount, type, description, created_at
+SELECT id, amount, type, description, created_at, kind, reference_type, reference_id
 FROM transactions
 WHERE user_id = $1 AND type = 'credit'
 ORDER BY created_at DESC
@@ -180,11 +213,14 @@ LIMIT 200
 `
 
 type GetAllMoneyTransactionsRow struct {
-	ID          int32              `json:"id"`
-	Amount      pgtype.Numeric     `json:"amount"`
-	Type        string             `json:"type"`
-	Description pgtype.Text        `json:"description"`
-	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	ID            int32              `json:"id"`
+	Amount        pgtype.Numeric     `json:"amount"`
+	Type          string             `json:"type"`
+	Description   pgtype.Text        `json:"description"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	Kind          string             `json:"kind"`
+	ReferenceType string             `json:"reference_type"`
+	ReferenceID   string             `json:"reference_id"`
 }
 
 func (q *Queries) GetAllMoneyTransactions(ctx context.Context, userID pgtype.Int4) ([]GetAllMoneyTransactionsRow, error) {
@@ -202,6 +238,9 @@ func (q *Queries) GetAllMoneyTransactions(ctx context.Context, userID pgtype.Int
 			&i.Type,
 			&i.Description,
 			&i.CreatedAt,
+			&i.Kind,
+			&i.ReferenceType,
+			&i.ReferenceID,
 		); err != nil {
 			return nil, err
 		}
@@ -230,7 +269,7 @@ func (q *Queries) GetDepositStatus(ctx context.Context, arg GetDepositStatusPara
 }
 
 const getRecentMoneyTransactions = `-- name: GetRecentMoneyTransactions :many
-SELECT id, amount, type, description, created_at
+SELECT id, amount, type, description, created_at, kind, reference_type, reference_id
 FROM transactions
 WHERE user_id = $1 AND type = 'credit'
 ORDER BY created_at DESC
@@ -238,11 +277,14 @@ LIMIT 3
 `
 
 type GetRecentMoneyTransactionsRow struct {
-	ID          int32              `json:"id"`
-	Amount      pgtype.Numeric     `json:"amount"`
-	Type        string             `json:"type"`
-	Description pgtype.Text        `json:"description"`
-	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	ID            int32              `json:"id"`
+	Amount        pgtype.Numeric     `json:"amount"`
+	Type          string             `json:"type"`
+	Description   pgtype.Text        `json:"description"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	Kind          string             `json:"kind"`
+	ReferenceType string             `json:"reference_type"`
+	ReferenceID   string             `json:"reference_id"`
 }
 
 func (q *Queries) GetRecentMoneyTransactions(ctx context.Context, userID pgtype.Int4) ([]GetRecentMoneyTransactionsRow, error) {
@@ -260,6 +302,9 @@ func (q *Queries) GetRecentMoneyTransactions(ctx context.Context, userID pgtype.
 			&i.Type,
 			&i.Description,
 			&i.CreatedAt,
+			&i.Kind,
+			&i.ReferenceType,
+			&i.ReferenceID,
 		); err != nil {
 			return nil, err
 		}
@@ -299,9 +344,9 @@ const getWalletBalance = `-- name: GetWalletBalance :one
 SELECT balance FROM wallets WHERE user_id = $1
 `
 
-func (q *Queries) GetWalletBalance(ctx context.Context, userID int32) (int32, error) {
+func (q *Queries) GetWalletBalance(ctx context.Context, userID int32) (int64, error) {
 	row := q.db.QueryRow(ctx, getWalletBalance, userID)
-	var balance int32
+	var balance int64
 	err := row.Scan(&balance)
 	return balance, err
 }
@@ -318,19 +363,25 @@ func (q *Queries) GetWalletRequestAmount(ctx context.Context, id int32) (pgtype.
 }
 
 const getWalletRequestForUpdateAdmin = `-- name: GetWalletRequestForUpdateAdmin :one
-SELECT user_id, amount, status FROM wallet_requests WHERE id=$1 FOR UPDATE
+SELECT user_id, amount, method, status FROM wallet_requests WHERE id=$1 FOR UPDATE
 `
 
 type GetWalletRequestForUpdateAdminRow struct {
 	UserID pgtype.Int4    `json:"user_id"`
 	Amount pgtype.Numeric `json:"amount"`
+	Method string         `json:"method"`
 	Status pgtype.Text    `json:"status"`
 }
 
 func (q *Queries) GetWalletRequestForUpdateAdmin(ctx context.Context, id int32) (GetWalletRequestForUpdateAdminRow, error) {
 	row := q.db.QueryRow(ctx, getWalletRequestForUpdateAdmin, id)
 	var i GetWalletRequestForUpdateAdminRow
-	err := row.Scan(&i.UserID, &i.Amount, &i.Status)
+	err := row.Scan(
+		&i.UserID,
+		&i.Amount,
+		&i.Method,
+		&i.Status,
+	)
 	return i, err
 }
 
@@ -357,17 +408,33 @@ func (q *Queries) GetWalletRequestStatusForUpdate(ctx context.Context, id int32)
 }
 
 const getWalletTransactions = `-- name: GetWalletTransactions :many
-SELECT id, user_id, amount, type, description, created_at FROM transactions WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3
+SELECT id, user_id, amount, type, description, created_at, kind, reference_type, reference_id FROM transactions
+WHERE user_id = $1
+AND ($2::text IS NULL OR kind = $2)
+AND ($3::text IS NULL OR reference_type = $3)
+AND ($4::text IS NULL OR reference_id = $4)
+ORDER BY created_at DESC, id DESC
+LIMIT $5 OFFSET $6
 `
 
 type GetWalletTransactionsParams struct {
-	UserID pgtype.Int4 `json:"user_id"`
-	Limit  int32       `json:"limit"`
-	Offset int32       `json:"offset"`
+	UserID        pgtype.Int4 `json:"user_id"`
+	Kind          pgtype.Text `json:"kind"`
+	ReferenceType pgtype.Text `json:"reference_type"`
+	ReferenceID   pgtype.Text `json:"reference_id"`
+	Lim           int32       `json:"lim"`
+	Off           int32       `json:"off"`
 }
 
 func (q *Queries) GetWalletTransactions(ctx context.Context, arg GetWalletTransactionsParams) ([]Transaction, error) {
-	rows, err := q.db.Query(ctx, getWalletTransactions, arg.UserID, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, getWalletTransactions,
+		arg.UserID,
+		arg.Kind,
+		arg.ReferenceType,
+		arg.ReferenceID,
+		arg.Lim,
+		arg.Off,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -382,6 +449,9 @@ func (q *Queries) GetWalletTransactions(ctx context.Context, arg GetWalletTransa
 			&i.Type,
 			&i.Description,
 			&i.CreatedAt,
+			&i.Kind,
+			&i.ReferenceType,
+			&i.ReferenceID,
 		); err != nil {
 			return nil, err
 		}
@@ -394,15 +464,18 @@ func (q *Queries) GetWalletTransactions(ctx context.Context, arg GetWalletTransa
 }
 
 const insertTransaction = `-- name: InsertTransaction :exec
-INSERT INTO transactions (user_id, amount, type, description)
-VALUES ($1, $2, $3, $4)
+INSERT INTO transactions (user_id, amount, type, kind, description, reference_type, reference_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 `
 
 type InsertTransactionParams struct {
-	UserID      pgtype.Int4    `json:"user_id"`
-	Amount      pgtype.Numeric `json:"amount"`
-	Type        string         `json:"type"`
-	Description pgtype.Text    `json:"description"`
+	UserID        pgtype.Int4    `json:"user_id"`
+	Amount        pgtype.Numeric `json:"amount"`
+	Type          string         `json:"type"`
+	Kind          string         `json:"kind"`
+	Description   pgtype.Text    `json:"description"`
+	ReferenceType string         `json:"reference_type"`
+	ReferenceID   string         `json:"reference_id"`
 }
 
 func (q *Queries) InsertTransaction(ctx context.Context, arg InsertTransactionParams) error {
@@ -410,7 +483,10 @@ func (q *Queries) InsertTransaction(ctx context.Context, arg InsertTransactionPa
 		arg.UserID,
 		arg.Amount,
 		arg.Type,
+		arg.Kind,
 		arg.Description,
+		arg.ReferenceType,
+		arg.ReferenceID,
 	)
 	return err
 }
@@ -461,9 +537,34 @@ func (q *Queries) InsertUPINotificationUnmatched(ctx context.Context, arg Insert
 	return err
 }
 
+const insertWalletAdjustment = `-- name: InsertWalletAdjustment :one
+INSERT INTO wallet_adjustments (user_id, admin_id, amount_cents, description)
+VALUES ($1, $2, $3, $4)
+RETURNING id
+`
+
+type InsertWalletAdjustmentParams struct {
+	UserID      int32       `json:"user_id"`
+	AdminID     pgtype.Int4 `json:"admin_id"`
+	AmountCents int64       `json:"amount_cents"`
+	Description string      `json:"description"`
+}
+
+func (q *Queries) InsertWalletAdjustment(ctx context.Context, arg InsertWalletAdjustmentParams) (int32, error) {
+	row := q.db.QueryRow(ctx, insertWalletAdjustment,
+		arg.UserID,
+		arg.AdminID,
+		arg.AmountCents,
+		arg.Description,
+	)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
 const insertWalletRequest = `-- name: InsertWalletRequest :one
-INSERT INTO wallet_requests (user_id, amount, unique_amount, method, transaction_id, status)
-VALUES ($1, $2, $3, $4, $5, 'pending')
+INSERT INTO wallet_requests (user_id, amount, unique_amount, method, transaction_id, promo_code_id, status)
+VALUES ($1, $2, $3, $4, $5, $6, 'pending')
 RETURNING id
 `
 
@@ -473,6 +574,7 @@ type InsertWalletRequestParams struct {
 	UniqueAmount  pgtype.Numeric `json:"unique_amount"`
 	Method        string         `json:"method"`
 	TransactionID pgtype.Text    `json:"transaction_id"`
+	PromoCodeID   pgtype.Int4    `json:"promo_code_id"`
 }
 
 func (q *Queries) InsertWalletRequest(ctx context.Context, arg InsertWalletRequestParams) (int32, error) {
@@ -482,6 +584,7 @@ func (q *Queries) InsertWalletRequest(ctx context.Context, arg InsertWalletReque
 		arg.UniqueAmount,
 		arg.Method,
 		arg.TransactionID,
+		arg.PromoCodeID,
 	)
 	var id int32
 	err := row.Scan(&id)
@@ -549,6 +652,15 @@ func (q *Queries) MarkUPINotificationMatched(ctx context.Context, arg MarkUPINot
 	return err
 }
 
+const markWalletAdjustmentTopUp = `-- name: MarkWalletAdjustmentTopUp :exec
+UPDATE wallet_adjustments SET top_up = TRUE WHERE id = $1
+`
+
+func (q *Queries) MarkWalletAdjustmentTopUp(ctx context.Context, id int32) error {
+	_, err := q.db.Exec(ctx, markWalletAdjustmentTopUp, id)
+	return err
+}
+
 const rejectWalletRequest = `-- name: RejectWalletRequest :exec
 UPDATE wallet_requests SET status='rejected', updated_at=NOW() WHERE id=$1 AND status='pending'
 `
@@ -558,6 +670,18 @@ func (q *Queries) RejectWalletRequest(ctx context.Context, id int32) error {
 	return err
 }
 
+const reverseWalletRequest = `-- name: ReverseWalletRequest :execrows
+UPDATE wallet_requests SET status='reversed', updated_at=NOW() WHERE id=$1 AND status='approved'
+`
+
+func (q *Queries) ReverseWalletRequest(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, reverseWalletRequest, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateCryptomusTransactionID = `-- name: UpdateCryptomusTransactionID :exec
 UPDATE wallet_requests SET transaction_id=$1 WHERE id=$2
 `
@@ -616,7 +740,7 @@ DO UPDATE SET balance = wallets.balance + $2, updated_at = NOW()
 
 type UpsertWalletBalanceParams struct {
 	UserID  int32 `json:"user_id"`
-	Balance int32 `json:"balance"`
+	Balance int64 `json:"balance"`
 }
 
 func (q *Queries) UpsertWalletBalance(ctx context.Context, arg UpsertWalletBalanceParams) error {
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pablosmm/backend/internal/db/sqlc"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// audit records an admin decision. Pass the transaction's queries so the
+// entry is only kept when the action itself commits.
+func (h *Handler) audit(ctx context.Context, q *sqlc.Queries, r *http.Request, action, entityType, entityID string, details map[string]interface{}) error {
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+	b, _ := json.Marshal(details)
+
+	return q.InsertAdminAudit(ctx, sqlc.InsertAdminAuditParams{
+		AdminID:    adminIDFromRequest(r),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Details:    b,
+	})
+}
+
+// adminIDFromRequest returns the admin set by AdminAuthMiddleware
+func adminIDFromRequest(r *http.Request) pgtype.Int4 {
+	if id, ok := r.Context().Value("userID").(int); ok {
+		return pgtype.Int4{Int32: int32(id), Valid: true}
+	}
+	return pgtype.Int4{}
+}
+
+// GetAdminAuditLog lists recent admin decisions, optionally for one entity
+func (h *Handler) GetAdminAuditLog(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+	entityType := r.URL.Query().Get("entity_type")
+	entityID := r.URL.Query().Get("entity_id")
+
+	rows, err := h.db.Queries.ListAdminAudit(context.Background(), sqlc.ListAdminAuditParams{
+		Limit:      int32(limit),
+		EntityType: pgtype.Text{String: entityType, Valid: entityType != ""},
+		EntityID:   pgtype.Text{String: entityID, Valid: entityID != ""},
+	})
+	if err != nil {
+		log.Printf("Failed to list audit log: %v", err)
+		http.Error(w, "Failed to load audit log", http.StatusInternalServerError)
+		return
+	}
+
+	type auditEntry struct {
+		ID         int32           `json:"id"`
+		AdminID    *int32          `json:"admin_id"`
+		AdminEmail string          `json:"admin_email"`
+		Action     string          `json:"action"`
+		EntityType string          `json:"entity_type"`
+		EntityID   string          `json:"entity_id"`
+		Details    json.RawMessage `json:"details"`
+		CreatedAt  string          `json:"created_at"`
+	}
+
+	res := make([]auditEntry, len(rows))
+	for i, row := range rows {
+		res[i] = auditEntry{
+			ID:         row.ID,
+			AdminEmail: row.AdminEmail,
+			Action:     row.Action,
+			EntityType: row.EntityType,
+			EntityID:   row.EntityID,
+			Details:    json.RawMessage(row.Details),
+			CreatedAt:  row.CreatedAt.Time.Format(time.RFC3339),
+		}
+		if row.AdminID.Valid {
+			id := row.AdminID.Int32
+			res[i].AdminID = &id
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": res,
+	})
+}
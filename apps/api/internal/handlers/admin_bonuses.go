@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/service/bonus"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type DepositBonusTierPayload struct {
+	Name             string     `json:"name"`
+	MinDepositCents  int64      `json:"min_deposit_cents"`
+	BonusPct         float64    `json:"bonus_pct"`
+	MaxBonusCents    *int64     `json:"max_bonus_cents"`
+	Methods          []string   `json:"methods"`
+	StartsAt         *time.Time `json:"starts_at"`
+	EndsAt           *time.Time `json:"ends_at"`
+	Withdrawable     bool       `json:"withdrawable"`
+	ExpiresAfterDays *int32     `json:"expires_after_days"`
+	IsActive         *bool      `json:"is_active"`
+}
+
+func (p *DepositBonusTierPayload) validate() string {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.MinDepositCents <= 0 {
+		return "min_deposit_cents must be positive"
+	}
+	if p.BonusPct <= 0 || p.BonusPct > 100 {
+		return "bonus_pct must be above 0 and at most 100"
+	}
+	if p.MaxBonusCents != nil && *p.MaxBonusCents <= 0 {
+		return "max_bonus_cents must be positive"
+	}
+	if p.ExpiresAfterDays != nil && *p.ExpiresAfterDays <= 0 {
+		return "expires_after_days must be positive"
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return "ends_at must be after starts_at"
+	}
+	methods := []string{}
+	for _, m := range p.Methods {
+		m = bonus.NormalizeMethod(m)
+		if !slices.Contains(bonus.Methods, m) {
+			return "unknown payment method: " + m
+		}
+		if !slices.Contains(methods, m) {
+			methods = append(methods, m)
+		}
+	}
+	p.Methods = methods
+	return ""
+}
+
+func (p *DepositBonusTierPayload) params() sqlc.CreateDepositBonusTierParams {
+	arg := sqlc.CreateDepositBonusTierParams{
+		Name:            p.Name,
+		MinDepositCents: p.MinDepositCents,
+		BonusPct:        p.BonusPct,
+		Methods:         p.Methods,
+		Withdrawable:    p.Withdrawable,
+		IsActive:        p.IsActive == nil || *p.IsActive,
+	}
+	if p.MaxBonusCents != nil {
+		arg.MaxBonusCents = pgtype.Int8{Int64: *p.MaxBonusCents, Valid: true}
+	}
+	if p.StartsAt != nil {
+		arg.StartsAt = pgtype.Timestamptz{Time: *p.StartsAt, Valid: true}
+	}
+	if p.EndsAt != nil {
+		arg.EndsAt = pgtype.Timestamptz{Time: *p.EndsAt, Valid: true}
+	}
+	if p.ExpiresAfterDays != nil {
+		arg.ExpiresAfterDays = pgtype.Int4{Int32: *p.ExpiresAfterDays, Valid: true}
+	}
+	return arg
+}
+
+func (h *Handler) ListDepositBonusTiersAdmin(w http.ResponseWriter, r *http.Request) {
+	tiers, err := h.db.Queries.ListDepositBonusTiers(context.Background())
+	if err != nil {
+		log.Printf("Error fetching bonus tiers: %v", err)
+		http.Error(w, "Failed to fetch bonus tiers", http.StatusInternalServerError)
+		return
+	}
+	if tiers == nil {
+		tiers = []sqlc.DepositBonusTier{}
+	}
+	json.NewEncoder(w).Encode(tiers)
+}
+
+func (h *Handler) CreateDepositBonusTierAdmin(w http.ResponseWriter, r *http.Request) {
+	var p DepositBonusTierPayload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if msg := p.validate(); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	tier, err := h.db.Queries.CreateDepositBonusTier(ctx, p.params())
+	if err != nil {
+		log.Printf("Failed to create bonus tier: %v", err)
+		http.Error(w, "Failed to create bonus tier", http.StatusInternalServerError)
+		return
+	}
+
+	h.audit(ctx, h.db.Queries, r, "bonus_tier.create", "deposit_bonus_tier", strconv.Itoa(int(tier.ID)), map[string]interface{}{
+		"tier": tier,
+	})
+	json.NewEncoder(w).Encode(tier)
+}
+
+// UpdateDepositBonusTierAdmin changes a tier for future deposits; bonuses
+// already granted keep the terms they were granted with
+func (h *Handler) UpdateDepositBonusTierAdmin(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	var p DepositBonusTierPayload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if msg := p.validate(); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	arg := p.params()
+	tier, err := h.db.Queries.UpdateDepositBonusTier(ctx, sqlc.UpdateDepositBonusTierParams{
+		ID:               int32(id),
+		Name:             arg.Name,
+		MinDepositCents:  arg.MinDepositCents,
+		BonusPct:         arg.BonusPct,
+		MaxBonusCents:    arg.MaxBonusCents,
+		Methods:          arg.Methods,
+		StartsAt:         arg.StartsAt,
+		EndsAt:           arg.EndsAt,
+		Withdrawable:     arg.Withdrawable,
+		ExpiresAfterDays: arg.ExpiresAfterDays,
+		IsActive:         arg.IsActive,
+	})
+	if err != nil {
+		log.Printf("Failed to update bonus tier %d: %v", id, err)
+		http.Error(w, "Failed to update bonus tier", http.StatusInternalServerError)
+		return
+	}
+
+	h.audit(ctx, h.db.Queries, r, "bonus_tier.update", "deposit_bonus_tier", strconv.Itoa(id), map[string]interface{}{
+		"tier": tier,
+	})
+	json.NewEncoder(w).Encode(tier)
+}
+
+func (h *Handler) DeleteDepositBonusTierAdmin(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	n, err := h.db.Queries.DeleteDepositBonusTier(ctx, int32(id))
+	if err != nil {
+		http.Error(w, "Failed to delete bonus tier", http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "Bonus tier not found", http.StatusNotFound)
+		return
+	}
+
+	h.audit(ctx, h.db.Queries, r, "bonus_tier.delete", "deposit_bonus_tier", strconv.Itoa(id), nil)
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// ListBonusGrantsAdmin lists granted bonuses, newest first. Filters:
+// ?user_id= and ?status= (active or expired).
+func (h *Handler) ListBonusGrantsAdmin(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	arg := sqlc.ListBonusGrantsParams{Lim: 500}
+	if v := q.Get("user_id"); v != "" {
+		userID, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid user_id", http.StatusBadRequest)
+			return
+		}
+		arg.UserID = pgtype.Int4{Int32: int32(userID), Valid: true}
+	}
+	switch status := q.Get("status"); status {
+	case "":
+	case "active", "expired":
+		arg.Status = pgtype.Text{String: status, Valid: true}
+	default:
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+
+	grants, err := h.db.Queries.ListBonusGrants(context.Background(), arg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if grants == nil {
+		grants = []sqlc.BonusGrant{}
+	}
+	json.NewEncoder(w).Encode(grants)
+}
@@ -3,11 +3,13 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/jackc/pgx/v5/pgtype"
 	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/service/smm"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -33,6 +35,10 @@ type CatalogServiceResponse struct {
 	IsActive          bool    `json:"is_active"`
 	ProviderID        string  `json:"provider_id"`
 	ProviderServiceID string  `json:"provider_service_id"`
+	// Measured delivery of this service and of its current provider, to
+	// help decide where to route it
+	DeliveryStats *smm.DeliveryStats `json:"delivery_stats"`
+	ProviderStats *smm.DeliveryStats `json:"provider_stats"`
 }
 
 func (h *Handler) GetCatalogServicesAdmin(w http.ResponseWriter, r *http.Request) {
@@ -42,6 +48,15 @@ func (h *Handler) GetCatalogServicesAdmin(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	serviceStats, err := h.smm.ServiceDeliveryStats(context.Background(), smm.DeliveryStatsWindowDays)
+	if err != nil {
+		log.Printf("Failed to load service delivery stats: %v", err)
+	}
+	providerStats, err := h.smm.ProviderDeliveryStats(context.Background(), smm.DeliveryStatsWindowDays)
+	if err != nil {
+		log.Printf("Failed to load provider delivery stats: %v", err)
+	}
+
 	var res []CatalogServiceResponse
 	for _, s := range services {
 		var price float64
@@ -49,7 +64,7 @@ func (h *Handler) GetCatalogServicesAdmin(w http.ResponseWriter, r *http.Request
 			f, _ := s.SellPriceInr.Float64Value()
 			price = f.Float64
 		}
-		res = append(res, CatalogServiceResponse{
+		item := CatalogServiceResponse{
 			ID:                s.ID,
 			Name:              s.Name,
 			VariantName:       s.VariantName.String,
@@ -59,7 +74,14 @@ func (h *Handler) GetCatalogServicesAdmin(w http.ResponseWriter, r *http.Request
 			IsActive:          s.IsActive.Bool,
 			ProviderID:        s.ProviderID.String,
 			ProviderServiceID: s.ProviderServiceID.String,
-		})
+		}
+		if stats, ok := serviceStats[strconv.Itoa(int(s.ID))]; ok {
+			item.DeliveryStats = &stats
+		}
+		if stats, ok := providerStats[s.ProviderID.String]; ok {
+			item.ProviderStats = &stats
+		}
+		res = append(res, item)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"pablosmm/backend/internal/service/smm"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// GetDeliveryDashboard lists the latest delivery check for every verified
+// order, worst drops first
+func (h *Handler) GetDeliveryDashboard(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.db.Queries.GetDeliveryDashboard(context.Background())
+	if err != nil {
+		log.Printf("Error fetching delivery dashboard: %v", err)
+		http.Error(w, "Failed to fetch delivery checks", http.StatusInternalServerError)
+		return
+	}
+
+	type DeliveryRes struct {
+		OrderID       int     `json:"orderId"`
+		ServiceID     string  `json:"serviceId"`
+		Status        string  `json:"status"`
+		Link          string  `json:"link"`
+		UserEmail     string  `json:"userEmail"`
+		ExpectedCount int     `json:"expectedCount"`
+		ObservedCount int     `json:"observedCount"`
+		DropPct       float64 `json:"dropPct"`
+		Checks        int     `json:"checks"`
+		CheckedAt     string  `json:"checkedAt"`
+	}
+
+	minDrop, _ := strconv.ParseFloat(r.URL.Query().Get("min_drop"), 64)
+
+	orders := []DeliveryRes{}
+	var totalDrop float64
+	dropped := 0
+	for _, row := range rows {
+		if row.DropPct < minDrop {
+			continue
+		}
+		orders = append(orders, DeliveryRes{
+			OrderID:       int(row.OrderID),
+			ServiceID:     row.ServiceID,
+			Status:        row.Status,
+			Link:          row.CanonicalLink,
+			UserEmail:     row.Email.String,
+			ExpectedCount: int(row.ExpectedCount),
+			ObservedCount: int(row.ObservedCount),
+			DropPct:       row.DropPct,
+			Checks:        int(row.CheckCount),
+			CheckedAt:     row.CheckedAt.Time.Format(time.RFC3339),
+		})
+		totalDrop += row.DropPct
+		if row.DropPct >= 10 {
+			dropped++
+		}
+	}
+
+	sort.Slice(orders, func(i, j int) bool { return orders[i].DropPct > orders[j].DropPct })
+
+	avgDrop := 0.0
+	if len(orders) > 0 {
+		avgDrop = totalDrop / float64(len(orders))
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"orders": orders,
+		"summary": map[string]interface{}{
+			"verifiedOrders": len(orders),
+			"averageDropPct": avgDrop,
+			"droppedOrders":  dropped,
+		},
+	})
+}
+
+// GetOrderDeliveryChecks returns the delivery check history for one order
+func (h *Handler) GetOrderDeliveryChecks(w http.ResponseWriter, r *http.Request) {
+	orderID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	checks, err := h.db.Queries.GetDeliveryChecksByOrder(context.Background(), int32(orderID))
+	if err != nil {
+		log.Printf("Error fetching delivery checks for order %d: %v", orderID, err)
+		http.Error(w, "Failed to fetch delivery checks", http.StatusInternalServerError)
+		return
+	}
+
+	type CheckRes struct {
+		StartCount    int     `json:"startCount"`
+		Delivered     int     `json:"delivered"`
+		ExpectedCount int     `json:"expectedCount"`
+		ObservedCount int     `json:"observedCount"`
+		DropPct       float64 `json:"dropPct"`
+		CheckedAt     string  `json:"checkedAt"`
+	}
+
+	history := []CheckRes{}
+	for _, c := range checks {
+		history = append(history, CheckRes{
+			StartCount:    int(c.StartCount),
+			Delivered:     int(c.Delivered),
+			ExpectedCount: int(c.ExpectedCount),
+			ObservedCount: int(c.ObservedCount),
+			DropPct:       c.DropPct,
+			CheckedAt:     c.CheckedAt.Time.Format(time.RFC3339),
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"orderId": orderID,
+		"checks":  history,
+	})
+}
+
+// GetDeliveryStatsAdmin reports measured completion times and partial and
+// cancel rates per catalog service and per provider
+func (h *Handler) GetDeliveryStatsAdmin(w http.ResponseWriter, r *http.Request) {
+	days := smm.DeliveryStatsWindowDays
+	if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d > 0 && d <= 365 {
+		days = d
+	}
+
+	ctx := context.Background()
+	services, err := h.smm.ServiceDeliveryStats(ctx, days)
+	if err != nil {
+		log.Printf("Error fetching service delivery stats: %v", err)
+		http.Error(w, "Failed to fetch delivery stats", http.StatusInternalServerError)
+		return
+	}
+	providers, err := h.smm.ProviderDeliveryStats(ctx, days)
+	if err != nil {
+		log.Printf("Error fetching provider delivery stats: %v", err)
+		http.Error(w, "Failed to fetch delivery stats", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"days":      days,
+		"services":  services,
+		"providers": providers,
+	})
+}
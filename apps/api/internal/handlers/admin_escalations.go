@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// GetOrderEscalations lists orders the watchdog handed to admins
+func (h *Handler) GetOrderEscalations(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "resolved" {
+		status = "open"
+	}
+
+	rows, err := h.db.Queries.ListOrderEscalations(context.Background(), status)
+	if err != nil {
+		log.Printf("Failed to list escalations: %v", err)
+		http.Error(w, "Failed to load escalations", http.StatusInternalServerError)
+		return
+	}
+
+	type escalation struct {
+		ID              int32  `json:"id"`
+		OrderID         int32  `json:"order_id"`
+		Reason          string `json:"reason"`
+		Status          string `json:"status"`
+		ResolutionNote  string `json:"resolution_note,omitempty"`
+		ResolvedAt      string `json:"resolved_at,omitempty"`
+		CreatedAt       string `json:"created_at"`
+		ServiceID       string `json:"service_id"`
+		OrderStatus     string `json:"order_status"`
+		Quantity        int32  `json:"quantity"`
+		Charge          string `json:"charge"`
+		ProviderOrderID string `json:"provider_order_id"`
+		ProviderKey     string `json:"provider_key"`
+		Email           string `json:"email"`
+	}
+
+	res := make([]escalation, len(rows))
+	for i, row := range rows {
+		res[i] = escalation{
+			ID:              row.ID,
+			OrderID:         row.OrderID,
+			Reason:          row.Reason,
+			Status:          row.Status,
+			ResolutionNote:  row.ResolutionNote.String,
+			CreatedAt:       row.CreatedAt.Time.Format(time.RFC3339),
+			ServiceID:       row.ServiceID,
+			OrderStatus:     row.OrderStatus,
+			Quantity:        row.Quantity,
+			Charge:          money.Paise(row.AmountCents).Decimal(),
+			ProviderOrderID: row.ProviderOrderID,
+			ProviderKey:     row.ProviderKey,
+			Email:           row.Email,
+		}
+		if row.ResolvedAt.Valid {
+			res[i].ResolvedAt = row.ResolvedAt.Time.Format(time.RFC3339)
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"escalations": res,
+	})
+}
+
+// ResolveOrderEscalation closes an escalation. The admin settles the order
+// itself through the usual refund or status endpoints.
+func (h *Handler) ResolveOrderEscalation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid escalation ID", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		Note string `json:"note"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	body.Note = strings.TrimSpace(body.Note)
+
+	ctx := context.Background()
+	tx, err := h.db.Pool.Begin(ctx)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.db.Queries.WithTx(tx)
+
+	n, err := qtx.ResolveOrderEscalation(ctx, sqlc.ResolveOrderEscalationParams{
+		ID:             int32(id),
+		ResolutionNote: pgtype.Text{String: body.Note, Valid: body.Note != ""},
+		ResolvedBy:     adminIDFromRequest(r),
+	})
+	if err != nil {
+		log.Printf("Failed to resolve escalation %d: %v", id, err)
+		http.Error(w, "Failed to resolve escalation", http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "Escalation not found or already resolved", http.StatusConflict)
+		return
+	}
+
+	if err := h.audit(ctx, qtx, r, "escalation_resolved", "order_escalation", strconv.Itoa(id), map[string]interface{}{
+		"note": body.Note,
+	}); err != nil {
+		http.Error(w, "Failed to record audit entry", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "Failed to commit", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Escalation resolved",
+	})
+}
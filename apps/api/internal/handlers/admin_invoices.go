@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pablosmm/backend/internal/money"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ListInvoicesAdmin lists issued invoices, newest first. Filters: ?user_id=
+// and ?month=YYYY-MM.
+func (h *Handler) ListInvoicesAdmin(w http.ResponseWriter, r *http.Request) {
+	var userID pgtype.Int4
+	if v := r.URL.Query().Get("user_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid user_id", http.StatusBadRequest)
+			return
+		}
+		userID = pgtype.Int4{Int32: int32(id), Valid: true}
+	}
+	h.writeInvoices(w, r, userID)
+}
+
+// GetInvoiceHTMLAdmin returns any invoice as a printable page
+func (h *Handler) GetInvoiceHTMLAdmin(w http.ResponseWriter, r *http.Request) {
+	if inv, ok := h.invoiceFromURL(w, r); ok {
+		writeInvoiceHTML(w, inv)
+	}
+}
+
+// GetInvoicePDFAdmin downloads any invoice as a PDF
+func (h *Handler) GetInvoicePDFAdmin(w http.ResponseWriter, r *http.Request) {
+	if inv, ok := h.invoiceFromURL(w, r); ok {
+		writeInvoicePDF(w, inv)
+	}
+}
+
+type InvoiceFailureRes struct {
+	TransactionID int32   `json:"transactionId"`
+	UserID        int32   `json:"userId"`
+	Username      string  `json:"username"`
+	Amount        float64 `json:"amount"`
+	Error         string  `json:"error"`
+	Attempts      int32   `json:"attempts"`
+	FirstFailedAt string  `json:"firstFailedAt"`
+	LastFailedAt  string  `json:"lastFailedAt"`
+}
+
+// ListInvoiceFailuresAdmin lists top-ups the invoicing job could not
+// invoice, oldest first. They are retried every run.
+func (h *Handler) ListInvoiceFailuresAdmin(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.db.Queries.ListInvoiceFailures(context.Background())
+	if err != nil {
+		log.Printf("Error fetching invoice failures: %v", err)
+		http.Error(w, "Failed to fetch invoice failures", http.StatusInternalServerError)
+		return
+	}
+	res := make([]InvoiceFailureRes, 0, len(rows))
+	for _, f := range rows {
+		res = append(res, InvoiceFailureRes{
+			TransactionID: f.TransactionID,
+			UserID:        f.UserID,
+			Username:      f.Username,
+			Amount:        money.Paise(f.AmountCents).Major(),
+			Error:         f.Error,
+			Attempts:      f.Attempts,
+			FirstFailedAt: f.FirstFailedAt.Time.Format(time.RFC3339),
+			LastFailedAt:  f.LastFailedAt.Time.Format(time.RFC3339),
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/ledger"
+)
+
+// GetLedgerCheck reports the balances of the system ledger accounts and
+// every wallet whose cached balance disagrees with its ledger entries
+func (h *Handler) GetLedgerCheck(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	accounts, err := h.db.Queries.ListLedgerSystemBalances(ctx)
+	if err != nil {
+		log.Printf("Error fetching ledger balances: %v", err)
+		http.Error(w, "Failed to fetch ledger", http.StatusInternalServerError)
+		return
+	}
+	walletTotal, err := h.db.Queries.GetWalletLedgerTotal(ctx)
+	if err != nil {
+		log.Printf("Error fetching wallet ledger total: %v", err)
+		http.Error(w, "Failed to fetch ledger", http.StatusInternalServerError)
+		return
+	}
+	mismatches, err := h.db.Queries.GetWalletLedgerMismatches(ctx)
+	if err != nil {
+		log.Printf("Error checking wallet balances: %v", err)
+		http.Error(w, "Failed to check wallets", http.StatusInternalServerError)
+		return
+	}
+
+	type AccountRes struct {
+		Code    string  `json:"code"`
+		Kind    string  `json:"kind"`
+		Name    string  `json:"name"`
+		Balance float64 `json:"balance"`
+	}
+	type MismatchRes struct {
+		UserID        int     `json:"userId"`
+		WalletBalance float64 `json:"walletBalance"`
+		LedgerBalance float64 `json:"ledgerBalance"`
+		Difference    float64 `json:"difference"`
+	}
+
+	accountList := []AccountRes{}
+	for _, a := range accounts {
+		accountList = append(accountList, AccountRes{
+			Code:    a.Code,
+			Kind:    a.Kind,
+			Name:    a.Name,
+			Balance: money.Paise(ledger.NormalBalance(a.Kind, a.DebitBalance)).Major(),
+		})
+	}
+	mismatchList := []MismatchRes{}
+	for _, m := range mismatches {
+		mismatchList = append(mismatchList, MismatchRes{
+			UserID:        int(m.UserID),
+			WalletBalance: money.Paise(m.CachedBalance).Major(),
+			LedgerBalance: money.Paise(m.LedgerBalance).Major(),
+			Difference:    money.Paise(m.CachedBalance - m.LedgerBalance).Major(),
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"accounts":     accountList,
+		"walletsTotal": money.Paise(-walletTotal).Major(),
+		"mismatches":   mismatchList,
+		"balanced":     len(mismatchList) == 0,
+	})
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/service/links"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type LinkRulePayload struct {
+	Platform     string   `json:"platform"`
+	ServiceType  string   `json:"serviceType"`
+	Variant      string   `json:"variant"`
+	AllowedKinds []string `json:"allowedKinds"`
+	Message      string   `json:"message"`
+	IsActive     *bool    `json:"isActive"`
+}
+
+var validTargetKinds = map[string]bool{
+	links.KindProfile: true,
+	links.KindPost:    true,
+	links.KindReel:    true,
+	links.KindVideo:   true,
+	links.KindShort:   true,
+	links.KindChannel: true,
+	links.KindStory:   true,
+	links.KindInvite:  true,
+	links.KindOther:   true,
+}
+
+// matchLinkRule picks the most specific active rule for a service. An exact
+// service type beats '*', and a variant pattern beats '*'.
+func matchLinkRule(rules []sqlc.LinkRule, serviceType, variant string) *sqlc.LinkRule {
+	variantLower := strings.ToLower(variant)
+
+	var best *sqlc.LinkRule
+	bestScore := -1
+	for i := range rules {
+		rule := &rules[i]
+		score := 0
+		if rule.ServiceType != "*" {
+			score += 2
+		}
+		if rule.Variant != "*" {
+			if !strings.Contains(variantLower, strings.ToLower(rule.Variant)) {
+				continue
+			}
+			score++
+		}
+		if score > bestScore {
+			best = rule
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// checkLinkRule enforces the link rule for the service on a parsed target
+func (h *Handler) checkLinkRule(platform, serviceType, variant string, target *links.Target) error {
+	rules, err := h.db.Queries.GetLinkRulesForPlatform(context.Background(), sqlc.GetLinkRulesForPlatformParams{
+		Platform:    links.PlatformKey(platform),
+		ServiceType: strings.ToLower(serviceType),
+	})
+	if err != nil {
+		// Rejecting is safer than placing an order the rules may not allow
+		log.Printf("Failed to load link rules for %s/%s: %v", platform, serviceType, err)
+		return fmt.Errorf("could not check the link right now, please try again")
+	}
+
+	rule := matchLinkRule(rules, serviceType, variant)
+	if rule == nil {
+		return nil
+	}
+
+	for _, kind := range rule.AllowedKinds {
+		if kind == target.Kind {
+			return nil
+		}
+	}
+
+	if rule.Message != "" {
+		return fmt.Errorf("%s", rule.Message)
+	}
+	return fmt.Errorf("this service needs a %s link, but the link you entered points to a %s", strings.Join(rule.AllowedKinds, " or "), target.Kind)
+}
+
+func (p *LinkRulePayload) normalize() error {
+	p.Platform = links.PlatformKey(p.Platform)
+	p.ServiceType = strings.ToLower(strings.TrimSpace(p.ServiceType))
+	p.Variant = strings.TrimSpace(p.Variant)
+	if p.Platform == "" {
+		return fmt.Errorf("platform is required")
+	}
+	if p.ServiceType == "" {
+		p.ServiceType = "*"
+	}
+	if p.Variant == "" {
+		p.Variant = "*"
+	}
+	if len(p.AllowedKinds) == 0 {
+		return fmt.Errorf("at least one allowed link kind is required")
+	}
+	for i, kind := range p.AllowedKinds {
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if !validTargetKinds[kind] {
+			return fmt.Errorf("unknown link kind: %s", kind)
+		}
+		p.AllowedKinds[i] = kind
+	}
+	return nil
+}
+
+func (h *Handler) ListLinkRulesAdmin(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.db.Queries.ListLinkRules(context.Background())
+	if err != nil {
+		log.Printf("Error fetching link rules: %v", err)
+		http.Error(w, "Failed to fetch link rules", http.StatusInternalServerError)
+		return
+	}
+	if rules == nil {
+		rules = []sqlc.LinkRule{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rules": rules,
+	})
+}
+
+func (h *Handler) CreateLinkRuleAdmin(w http.ResponseWriter, r *http.Request) {
+	var p LinkRulePayload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if err := p.normalize(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.db.Queries.CreateLinkRule(context.Background(), sqlc.CreateLinkRuleParams{
+		Platform:     p.Platform,
+		ServiceType:  p.ServiceType,
+		Variant:      p.Variant,
+		AllowedKinds: p.AllowedKinds,
+		Message:      p.Message,
+		IsActive:     p.IsActive == nil || *p.IsActive,
+	})
+	if err != nil {
+		log.Printf("Failed to create link rule: %v", err)
+		http.Error(w, "Failed to create link rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+func (h *Handler) UpdateLinkRuleAdmin(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var p LinkRulePayload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if err := p.normalize(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.db.Queries.UpdateLinkRule(context.Background(), sqlc.UpdateLinkRuleParams{
+		ID:           int32(id),
+		Platform:     p.Platform,
+		ServiceType:  p.ServiceType,
+		Variant:      p.Variant,
+		AllowedKinds: p.AllowedKinds,
+		Message:      p.Message,
+		IsActive:     p.IsActive == nil || *p.IsActive,
+	})
+	if err != nil {
+		log.Printf("Failed to update link rule %d: %v", id, err)
+		http.Error(w, "Failed to update link rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
+
+func (h *Handler) DeleteLinkRuleAdmin(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.Queries.DeleteLinkRule(context.Background(), int32(id)); err != nil {
+		http.Error(w, "Failed to delete link rule", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
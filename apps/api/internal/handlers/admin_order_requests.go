@@ -3,13 +3,20 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/ledger"
+	"pablosmm/backend/internal/service/refill"
+	"pablosmm/backend/internal/service/refund"
+	"pablosmm/backend/internal/service/smm"
 )
 
 // GetAdminOrderRequests returns pending order requests for admin
@@ -63,41 +70,282 @@ func (h *Handler) GetAdminOrderRequests(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// ApproveOrderRequest runs the requested action: a cancel is sent to the
+// provider and the unused part of the order refunded, a refill is sent to the
+// provider unless it already was when the user asked for it.
 func (h *Handler) ApproveOrderRequest(w http.ResponseWriter, r *http.Request) {
 	reqIDStr := chi.URLParam(r, "id")
 	reqID, _ := strconv.Atoi(reqIDStr)
 
-	err := h.db.Queries.UpdateOrderRequestStatus(context.Background(), sqlc.UpdateOrderRequestStatusParams{
-		ID:     int32(reqID),
-		Status: pgtype.Text{String: "approved", Valid: true},
-	})
+	var body struct {
+		Force bool   `json:"force"`
+		Note  string `json:"note"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	ctx := context.Background()
+	tx, err := h.db.Pool.Begin(ctx)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.db.Queries.WithTx(tx)
 
+	req, err := qtx.GetOrderRequestForDecision(ctx, int32(reqID))
 	if err != nil {
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	}
+	if req.Status.String != "pending" {
+		http.Error(w, "Request already decided", http.StatusConflict)
+		return
+	}
+
+	order, err := qtx.GetOrderForApproval(ctx, req.OrderID)
+	if err != nil {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	}
+
+	details := map[string]interface{}{
+		"order_id":     order.ID,
+		"request_type": req.RequestType,
+	}
+	if body.Note != "" {
+		details["note"] = body.Note
+	}
+
+	var eventMessage string
+	switch req.RequestType {
+	case "cancel":
+		if order.Status == "canceled" || order.Status == "refunded" || order.Status == "completed" {
+			http.Error(w, "Order already finalized", http.StatusBadRequest)
+			return
+		}
+
+		if order.ProviderOrderID != "" {
+			resp, cancelErr := h.smm.CancelOrder(order.ProviderKey, order.ProviderOrderID)
+			if cancelErr == nil {
+				if errorMsg, ok := resp["error"].(string); ok {
+					cancelErr = fmt.Errorf("%s", errorMsg)
+				}
+			}
+			if cancelErr != nil {
+				if !body.Force {
+					http.Error(w, fmt.Sprintf("Provider refused cancellation: %v", cancelErr), http.StatusBadGateway)
+					return
+				}
+				details["provider_error"] = cancelErr.Error()
+			}
+			details["provider_response"] = resp
+		}
+
+		rule, err := refund.RuleFor(ctx, h.db.Queries, order.ServiceID)
+		if err != nil {
+			log.Printf("Failed to load refund rule for order #%d: %v", order.ID, err)
+			rule = refund.DefaultRule()
+		}
+		result := refund.Calculate(rule, refund.Input{
+			Kind:          refund.KindCancel,
+			AmountCents:   order.AmountCents,
+			Quantity:      int(order.Quantity),
+			Remains:       refund.UndeliveredQuantity(order.Status, int(order.Quantity), int(order.Remains)),
+			RefundedCents: order.RefundedAmount,
+		})
+		refundCents := result.Cents
+		err = refund.Apply(ctx, qtx, order.ID, order.UserID, refund.KindCancel, rule, result, fmt.Sprintf("Refund for canceled Order #%d", order.ID))
+		if err != nil {
+			log.Printf("Refund failed for order #%d: %v", order.ID, err)
+			http.Error(w, "Failed to refund wallet", http.StatusInternalServerError)
+			return
+		}
+
+		err = qtx.CancelOrderWithRefund(ctx, sqlc.CancelOrderWithRefundParams{
+			ID:             order.ID,
+			RefundedAmount: pgtype.Int8{Int64: refundCents, Valid: true},
+		})
+		if err != nil {
+			http.Error(w, "Failed to update order", http.StatusInternalServerError)
+			return
+		}
+		if err := ledger.SettleOrder(ctx, qtx, order.ID); err != nil {
+			log.Printf("Settle failed for order #%d: %v", order.ID, err)
+			http.Error(w, "Failed to settle order", http.StatusInternalServerError)
+			return
+		}
+
+		details["refund_cents"] = refundCents
+		details["refund_formula"] = result.Formula
+		eventMessage = fmt.Sprintf("Cancellation approved, %s refunded to wallet", money.Paise(refundCents))
+
+	case "refill":
+		// The customer's request has usually been sent already; only send it
+		// when that did not get through
+		if req.ProviderOutcome.String != refill.OutcomeAccepted && order.ProviderOrderID != "" {
+			resp, refillErr := h.smm.RefillOrder(order.ProviderKey, order.ProviderOrderID)
+			if refillErr == nil {
+				if errorMsg, ok := resp["error"].(string); ok {
+					refillErr = fmt.Errorf("%s", errorMsg)
+				}
+			}
+			if refillErr != nil {
+				http.Error(w, fmt.Sprintf("Provider refused refill: %v", refillErr), http.StatusBadGateway)
+				return
+			}
+			respJSON, _ := json.Marshal(resp)
+			err = qtx.SetOrderRequestResponse(ctx, sqlc.SetOrderRequestResponseParams{
+				ID:               req.ID,
+				ProviderResponse: pgtype.Text{String: string(respJSON), Valid: true},
+				ProviderOutcome:  pgtype.Text{String: refill.OutcomeAccepted, Valid: true},
+			})
+			if err != nil {
+				http.Error(w, "Failed to store refill", http.StatusInternalServerError)
+				return
+			}
+
+			if refillID := smm.IDString(resp["refill"]); refillID != "" {
+				err = qtx.SetOrderRequestRefill(ctx, sqlc.SetOrderRequestRefillParams{
+					ID:               req.ID,
+					ProviderRefillID: pgtype.Text{String: refillID, Valid: true},
+					RefillStatus:     pgtype.Text{String: "pending", Valid: true},
+				})
+				if err != nil {
+					http.Error(w, "Failed to store refill", http.StatusInternalServerError)
+					return
+				}
+				details["provider_refill_id"] = refillID
+			}
+			if err := qtx.MarkOrderRefilled(ctx, order.ID); err != nil {
+				http.Error(w, "Failed to update order", http.StatusInternalServerError)
+				return
+			}
+			details["provider_response"] = resp
+		}
+		eventMessage = "Refill approved"
+
+	default:
+		http.Error(w, "Unsupported request type", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := qtx.DecideOrderRequest(ctx, sqlc.DecideOrderRequestParams{
+		ID:             req.ID,
+		Status:         pgtype.Text{String: "approved", Valid: true},
+		DecisionReason: pgtype.Text{String: body.Note, Valid: body.Note != ""},
+		DecidedBy:      adminIDFromRequest(r),
+	}); err != nil {
 		log.Printf("Failed to approve request: %v", err)
 		http.Error(w, "Failed to approve request", http.StatusInternalServerError)
 		return
 	}
 
+	qtx.InsertOrderEvent(ctx, sqlc.InsertOrderEventParams{
+		OrderID:   order.ID,
+		EventType: req.RequestType + "_approved",
+		Message:   eventMessage,
+	})
+
+	if err := h.audit(ctx, qtx, r, "order_request.approve", "order_request", strconv.Itoa(int(req.ID)), details); err != nil {
+		log.Printf("Failed to write audit log: %v", err)
+		http.Error(w, "Failed to approve request", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "Commit failed", http.StatusInternalServerError)
+		return
+	}
+
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status": "success",
 	})
 }
 
+// RejectOrderRequest closes a request with a reason shown to the user. A
+// rejected refill gives the order its refill credit back.
 func (h *Handler) RejectOrderRequest(w http.ResponseWriter, r *http.Request) {
 	reqIDStr := chi.URLParam(r, "id")
 	reqID, _ := strconv.Atoi(reqIDStr)
 
-	err := h.db.Queries.UpdateOrderRequestStatus(context.Background(), sqlc.UpdateOrderRequestStatusParams{
-		ID:     int32(reqID),
-		Status: pgtype.Text{String: "rejected", Valid: true},
-	})
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	body.Reason = strings.TrimSpace(body.Reason)
+	if body.Reason == "" {
+		http.Error(w, "A rejection reason is required", http.StatusBadRequest)
+		return
+	}
 
+	ctx := context.Background()
+	tx, err := h.db.Pool.Begin(ctx)
 	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.db.Queries.WithTx(tx)
+
+	req, err := qtx.GetOrderRequestForDecision(ctx, int32(reqID))
+	if err != nil {
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	}
+	if req.Status.String != "pending" {
+		http.Error(w, "Request already decided", http.StatusConflict)
+		return
+	}
+
+	if _, err := qtx.DecideOrderRequest(ctx, sqlc.DecideOrderRequestParams{
+		ID:             req.ID,
+		Status:         pgtype.Text{String: "rejected", Valid: true},
+		DecisionReason: pgtype.Text{String: body.Reason, Valid: true},
+		DecidedBy:      adminIDFromRequest(r),
+	}); err != nil {
 		log.Printf("Failed to reject request: %v", err)
 		http.Error(w, "Failed to reject request", http.StatusInternalServerError)
 		return
 	}
 
+	if req.RequestType == "refill" {
+		if err := qtx.IncrementOrderRefills(ctx, req.OrderID); err != nil {
+			http.Error(w, "Failed to restore refill", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	label := "Request"
+	switch req.RequestType {
+	case "cancel":
+		label = "Cancellation"
+	case "refill":
+		label = "Refill"
+	}
+	qtx.InsertOrderEvent(ctx, sqlc.InsertOrderEventParams{
+		OrderID:   req.OrderID,
+		EventType: req.RequestType + "_rejected",
+		Message:   fmt.Sprintf("%s rejected: %s", label, body.Reason),
+	})
+
+	err = h.audit(ctx, qtx, r, "order_request.reject", "order_request", strconv.Itoa(int(req.ID)), map[string]interface{}{
+		"order_id":     req.OrderID,
+		"request_type": req.RequestType,
+		"reason":       body.Reason,
+	})
+	if err != nil {
+		log.Printf("Failed to write audit log: %v", err)
+		http.Error(w, "Failed to reject request", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "Commit failed", http.StatusInternalServerError)
+		return
+	}
+
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status": "success",
 	})
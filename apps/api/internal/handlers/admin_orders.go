@@ -13,6 +13,8 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/refund"
 )
 
 // RefundOrder refunds an order manually
@@ -46,8 +48,8 @@ func (h *Handler) RefundOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	status := orderRow.Status
-	amountCents := int(orderRow.AmountCents)
-	refundedCents := int(orderRow.RefundedAmount)
+	amountCents := orderRow.AmountCents
+	refundedCents := orderRow.RefundedAmount
 	userID := int(orderRow.UserID)
 
 	// Calculate remaining refundable amount
@@ -60,51 +62,40 @@ func (h *Handler) RefundOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Build Refund Amount
-	refundAmountCents := remainingCents // Default to full remaining
-	isPartial := false
-
+	requestedCents := int64(0)
 	if req.Amount > 0 {
-		reqCents := int(req.Amount * 100)
-		if reqCents < remainingCents {
-			refundAmountCents = reqCents
-			isPartial = true
-		} else if reqCents > remainingCents {
-			// Cap at remaining
-			refundAmountCents = remainingCents
+		requested, err := money.FromMajor(req.Amount, money.Base, money.RoundNearest)
+		if err != nil {
+			http.Error(w, "Invalid amount", http.StatusBadRequest)
+			return
 		}
+		requestedCents = requested.Minor
 	}
-
-	// 2. Credit Wallet
-	err = qtx.UpsertWalletBalance(context.Background(), sqlc.UpsertWalletBalanceParams{
-		UserID:  int32(userID),
-		Balance: int32(refundAmountCents),
+	rule := refund.DefaultRule()
+	result := refund.Calculate(rule, refund.Input{
+		Kind:           refund.KindAdmin,
+		AmountCents:    amountCents,
+		RefundedCents:  refundedCents,
+		RequestedCents: requestedCents,
 	})
-	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to refund wallet"})
-		return
-	}
+	refundAmountCents := result.Cents
+	isPartial := refundAmountCents < remainingCents
 
-	// 3. Log Transaction
+	// 2. Credit wallet, log the transaction and record the refund
 	desc := fmt.Sprintf("Refund for Order #%d", orderID)
 	if isPartial {
 		desc = fmt.Sprintf("Partial Refund for Order #%d", orderID)
 	}
-	err = qtx.InsertTransaction(context.Background(), sqlc.InsertTransactionParams{
-		UserID:      pgtype.Int4{Int32: int32(userID), Valid: true},
-		Amount:      func() pgtype.Numeric { n := pgtype.Numeric{}; n.Scan(fmt.Sprintf("%f", float64(refundAmountCents)/100.0)); return n }(),
-		Type:        "credit",
-		Description: pgtype.Text{String: desc, Valid: true},
-	})
+	err = refund.Apply(context.Background(), qtx, int32(orderID), int32(userID), refund.KindAdmin, rule, result, desc)
 	if err != nil {
+		log.Printf("Admin refund failed for order #%d: %v", orderID, err)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to log transaction"})
+		json.NewEncoder(w).Encode(map[string]string{"error": "Failed to refund wallet"})
 		return
 	}
 
-	// 4. Update Order
+	// 3. Update Order
 	// Update refunded_amount. Only mark 'refunded' if fully refunded.
 	newRefundedTotal := refundedCents + refundAmountCents
 	newStatus := status
@@ -117,7 +108,7 @@ func (h *Handler) RefundOrder(w http.ResponseWriter, r *http.Request) {
 
 	providerOrderID, err := qtx.UpdateOrderRefundAdmin(context.Background(), sqlc.UpdateOrderRefundAdminParams{
 		Status:         newStatus,
-		RefundedAmount: pgtype.Int4{Int32: int32(newRefundedTotal), Valid: true},
+		RefundedAmount: pgtype.Int8{Int64: newRefundedTotal, Valid: true},
 		ID:             int32(orderID),
 	})
 
@@ -135,7 +126,7 @@ func (h *Handler) RefundOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 5. Provider Cancellation (Only on Full Refund/Cancellation)
+	// 4. Provider Cancellation (Only on Full Refund/Cancellation)
 	// Only attempt if fully refunded and status changed to refunded
 	if newStatus == "refunded" && providerOrderID != "" {
 		go func(pKey, pID string) {
@@ -152,7 +143,7 @@ func (h *Handler) RefundOrder(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
 		"status":  "success",
-		"message": fmt.Sprintf("Refunded %.2f successfully", float64(refundAmountCents)/100.0),
+		"message": fmt.Sprintf("Refunded %s successfully", money.Paise(refundAmountCents)),
 	})
 }
 
@@ -161,6 +152,7 @@ func (h *Handler) GetAdminOrders(w http.ResponseWriter, r *http.Request) {
 	// Filter params
 	statusFilter := r.URL.Query().Get("status")
 	userFilter := r.URL.Query().Get("user_id")
+	targetFilter := r.URL.Query().Get("target")
 
 	var sqlStatusFilter pgtype.Text
 	if statusFilter == "" || statusFilter == "all" {
@@ -176,9 +168,15 @@ func (h *Handler) GetAdminOrders(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	var sqlTargetFilter pgtype.Text
+	if targetFilter != "" {
+		sqlTargetFilter = pgtype.Text{String: targetFilter, Valid: true}
+	}
+
 	ordersRow, err := h.db.Queries.GetAdminOrders(context.Background(), sqlc.GetAdminOrdersParams{
 		StatusFilter: sqlStatusFilter,
 		UserID:       sqlUserFilter,
+		TargetID:     sqlTargetFilter,
 	})
 	if err != nil {
 		log.Printf("Error fetching admin orders: %v", err)
@@ -187,23 +185,32 @@ func (h *Handler) GetAdminOrders(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type AdminOrderRes struct {
-		ID               int     `json:"id"`
-		ServiceID        string  `json:"serviceId"`
-		DisplayID        string  `json:"displayId"`
-		DisplayName      string  `json:"serviceName"`
-		UserEmail        string  `json:"userEmail"`
-		Amount           float64 `json:"charge"`
-		Quantity         int     `json:"quantity"`
-		Status           string  `json:"status"`
-		Date             string  `json:"date"`
-		Link             string  `json:"link"`
-		Remains          int     `json:"remains"`
-		StartCount       int     `json:"startCount"`
-		RefundedAmount   float64 `json:"refundedAmount"`
+		ID                   int     `json:"id"`
+		ServiceID            string  `json:"serviceId"`
+		DisplayID            string  `json:"displayId"`
+		DisplayName          string  `json:"serviceName"`
+		UserEmail            string  `json:"userEmail"`
+		Amount               float64 `json:"charge"`
+		Quantity             int     `json:"quantity"`
+		Status               string  `json:"status"`
+		Date                 string  `json:"date"`
+		Link                 string  `json:"link"`
+		Remains              int     `json:"remains"`
+		StartCount           int     `json:"startCount"`
+		RefundedAmount       float64 `json:"refundedAmount"`
 		ProviderOrderID      string  `json:"providerOrderId"`
-		RefillsRemaining     int     `json:"refillsRemaining"`
+		RefillsRemaining     *int    `json:"refillsRemaining"`
 		ServiceRefillLimit   int     `json:"serviceRefillLimit"`
 		ServiceRefillEnabled bool    `json:"serviceRefillEnabled"`
+		CanonicalLink        string  `json:"canonicalLink"`
+		TargetKind           string  `json:"targetKind"`
+		TargetID             string  `json:"targetId"`
+		OwnStartCount        *int    `json:"ownStartCount"`
+		ProviderStatus       string  `json:"providerStatus"`
+		SyncErrorCount       int     `json:"syncErrorCount"`
+		LastSyncError        string  `json:"lastSyncError"`
+		Discount             float64 `json:"discount"`
+		PromoCode            string  `json:"promoCode"`
 	}
 
 	orders := []AdminOrderRes{}
@@ -211,7 +218,7 @@ func (h *Handler) GetAdminOrders(w http.ResponseWriter, r *http.Request) {
 		var o AdminOrderRes
 		o.ID = int(row.ID)
 		o.ServiceID = row.ServiceID
-		o.Amount = float64(row.AmountCents) / 100.0
+		o.Amount = money.Paise(row.AmountCents).Major()
 		o.Quantity = int(row.Quantity)
 		o.Status = row.Status
 		o.Date = row.CreatedAt.Time.Format(time.RFC3339)
@@ -219,11 +226,26 @@ func (h *Handler) GetAdminOrders(w http.ResponseWriter, r *http.Request) {
 		o.Remains = int(row.Remains)
 		o.StartCount = int(row.StartCount)
 		o.UserEmail = row.Email.String
-		o.RefundedAmount = float64(row.RefundedAmount) / 100.0
+		o.RefundedAmount = money.Paise(row.RefundedAmount).Major()
 		o.ProviderOrderID = row.ProviderOrderID
-		o.RefillsRemaining = int(row.RefillsRemaining)
 		o.ServiceRefillLimit = int(row.ServiceRefillLimit)
 		o.ServiceRefillEnabled = row.ServiceRefillEnabled
+		o.CanonicalLink = row.CanonicalLink
+		o.TargetKind = row.TargetKind
+		o.TargetID = row.TargetID
+		o.ProviderStatus = row.ProviderStatus
+		o.SyncErrorCount = int(row.SyncErrorCount)
+		o.LastSyncError = row.LastSyncError
+		o.Discount = money.Paise(row.DiscountCents).Major()
+		o.PromoCode = row.PromoCode
+		if row.OwnStartCount.Valid {
+			ownStart := int(row.OwnStartCount.Int32)
+			o.OwnStartCount = &ownStart
+		}
+		if row.RefillsRemaining.Valid {
+			refills := int(row.RefillsRemaining.Int32)
+			o.RefillsRemaining = &refills
+		}
 
 		o.DisplayID = row.DisplayID
 		if o.DisplayID == "" {
@@ -249,7 +271,8 @@ func (h *Handler) GetAdminOrders(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// UpdateOrderRefills allows admin to manually set the refills_remaining
+// UpdateOrderRefills allows admin to manually set the refills_remaining;
+// null lifts the limit
 func (h *Handler) UpdateOrderRefills(w http.ResponseWriter, r *http.Request) {
 	orderIDStr := chi.URLParam(r, "id")
 	orderID, err := strconv.Atoi(orderIDStr)
@@ -261,7 +284,7 @@ func (h *Handler) UpdateOrderRefills(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Refills int `json:"refills_remaining"`
+		Refills *int `json:"refills_remaining"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		w.Header().Set("Content-Type", "application/json")
@@ -270,9 +293,13 @@ func (h *Handler) UpdateOrderRefills(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	refills := pgtype.Int4{}
+	if req.Refills != nil {
+		refills = pgtype.Int4{Int32: int32(*req.Refills), Valid: true}
+	}
 	err = h.db.Queries.UpdateOrderRefillsAdmin(context.Background(), sqlc.UpdateOrderRefillsAdminParams{
 		ID:               int32(orderID),
-		RefillsRemaining: pgtype.Int4{Int32: int32(req.Refills), Valid: true},
+		RefillsRemaining: refills,
 	})
 	if err != nil {
 		log.Printf("Failed to update refills: %v", err)
@@ -288,3 +315,52 @@ func (h *Handler) UpdateOrderRefills(w http.ResponseWriter, r *http.Request) {
 		"message": "Refills updated successfully",
 	})
 }
+
+// ResumeOrderSync puts a needs_attention order back into the status sync,
+// typically after the admin fixed its provider order ID
+func (h *Handler) ResumeOrderSync(w http.ResponseWriter, r *http.Request) {
+	orderID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	tx, err := h.db.Pool.Begin(ctx)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := h.db.Queries.WithTx(tx)
+
+	n, err := qtx.ResumeOrderSync(ctx, int32(orderID))
+	if err != nil {
+		log.Printf("Failed to resume sync for order %d: %v", orderID, err)
+		http.Error(w, "Failed to resume sync", http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "Order is not waiting for attention", http.StatusConflict)
+		return
+	}
+
+	qtx.InsertOrderEvent(ctx, sqlc.InsertOrderEventParams{
+		OrderID:   int32(orderID),
+		EventType: "sync_resumed",
+		Message:   "Status sync resumed by an admin",
+	})
+	if err := h.audit(ctx, qtx, r, "order_sync_resumed", "order", strconv.Itoa(orderID), nil); err != nil {
+		http.Error(w, "Failed to record audit entry", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "Failed to commit", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"status":  "success",
+		"message": "Order returned to status sync",
+	})
+}
@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/promo"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type PromoCodePayload struct {
+	Code           string     `json:"code"`
+	Description    string     `json:"description"`
+	AppliesTo      string     `json:"applies_to"`
+	ValueType      string     `json:"value_type"`
+	ValuePct       float64    `json:"value_pct"`
+	ValueCents     int64      `json:"value_cents"`
+	MaxValueCents  *int64     `json:"max_value_cents"`
+	MinAmountCents int64      `json:"min_amount_cents"`
+	MaxUses        *int32     `json:"max_uses"`
+	MaxUsesPerUser *int32     `json:"max_uses_per_user"`
+	Platforms      []string   `json:"platforms"`
+	CatalogIDs     []int32    `json:"catalog_ids"`
+	StartsAt       *time.Time `json:"starts_at"`
+	ExpiresAt      *time.Time `json:"expires_at"`
+	IsActive       *bool      `json:"is_active"`
+}
+
+func (p *PromoCodePayload) validate() string {
+	p.Code = promo.NormalizeCode(p.Code)
+	p.Description = strings.TrimSpace(p.Description)
+	if p.Code == "" || strings.ContainsAny(p.Code, " \t\n") {
+		return "code must be a single word"
+	}
+	switch p.AppliesTo {
+	case promo.AppliesToOrder, promo.AppliesToDeposit:
+	default:
+		return "applies_to must be order or deposit"
+	}
+	switch p.ValueType {
+	case promo.ValuePercent:
+		if p.ValuePct <= 0 || p.ValuePct > 100 {
+			return "value_pct must be above 0 and at most 100"
+		}
+		p.ValueCents = 0
+	case promo.ValueFixed:
+		if p.ValueCents <= 0 {
+			return "value_cents must be positive"
+		}
+		p.ValuePct = 0
+	default:
+		return "value_type must be percent or fixed"
+	}
+	if p.MaxValueCents != nil && *p.MaxValueCents <= 0 {
+		return "max_value_cents must be positive"
+	}
+	if p.MinAmountCents < 0 {
+		return "min_amount_cents cannot be negative"
+	}
+	if p.MaxUses != nil && *p.MaxUses <= 0 {
+		return "max_uses must be positive"
+	}
+	if p.MaxUsesPerUser != nil && *p.MaxUsesPerUser <= 0 {
+		return "max_uses_per_user must be positive"
+	}
+	if p.StartsAt != nil && p.ExpiresAt != nil && !p.ExpiresAt.After(*p.StartsAt) {
+		return "expires_at must be after starts_at"
+	}
+
+	platforms := []string{}
+	for _, pl := range p.Platforms {
+		pl = promo.NormalizePlatform(pl)
+		if pl != "" && !slices.Contains(platforms, pl) {
+			platforms = append(platforms, pl)
+		}
+	}
+	catalogIDs := []int32{}
+	for _, id := range p.CatalogIDs {
+		if id <= 0 {
+			return "catalog_ids must be positive"
+		}
+		if !slices.Contains(catalogIDs, id) {
+			catalogIDs = append(catalogIDs, id)
+		}
+	}
+	if p.AppliesTo == promo.AppliesToDeposit && (len(platforms) > 0 || len(catalogIDs) > 0) {
+		return "platforms and catalog_ids only apply to order codes"
+	}
+	p.Platforms = platforms
+	p.CatalogIDs = catalogIDs
+	return ""
+}
+
+func (p *PromoCodePayload) params() sqlc.CreatePromoCodeParams {
+	arg := sqlc.CreatePromoCodeParams{
+		Code:           p.Code,
+		Description:    p.Description,
+		AppliesTo:      p.AppliesTo,
+		ValueType:      p.ValueType,
+		ValuePct:       p.ValuePct,
+		ValueCents:     p.ValueCents,
+		MinAmountCents: p.MinAmountCents,
+		Platforms:      p.Platforms,
+		CatalogIds:     p.CatalogIDs,
+		IsActive:       p.IsActive == nil || *p.IsActive,
+	}
+	if p.MaxValueCents != nil {
+		arg.MaxValueCents = pgtype.Int8{Int64: *p.MaxValueCents, Valid: true}
+	}
+	if p.MaxUses != nil {
+		arg.MaxUses = pgtype.Int4{Int32: *p.MaxUses, Valid: true}
+	}
+	if p.MaxUsesPerUser != nil {
+		arg.MaxUsesPerUser = pgtype.Int4{Int32: *p.MaxUsesPerUser, Valid: true}
+	}
+	if p.StartsAt != nil {
+		arg.StartsAt = pgtype.Timestamptz{Time: *p.StartsAt, Valid: true}
+	}
+	if p.ExpiresAt != nil {
+		arg.ExpiresAt = pgtype.Timestamptz{Time: *p.ExpiresAt, Valid: true}
+	}
+	return arg
+}
+
+func (h *Handler) ListPromoCodesAdmin(w http.ResponseWriter, r *http.Request) {
+	codes, err := h.db.Queries.ListPromoCodes(context.Background())
+	if err != nil {
+		log.Printf("Error fetching promo codes: %v", err)
+		http.Error(w, "Failed to fetch promo codes", http.StatusInternalServerError)
+		return
+	}
+	if codes == nil {
+		codes = []sqlc.PromoCode{}
+	}
+	json.NewEncoder(w).Encode(codes)
+}
+
+func (h *Handler) CreatePromoCodeAdmin(w http.ResponseWriter, r *http.Request) {
+	var p PromoCodePayload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if msg := p.validate(); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	if _, err := h.db.Queries.GetPromoCodeByCode(ctx, p.Code); err == nil {
+		http.Error(w, "Promo code already exists", http.StatusConflict)
+		return
+	}
+	code, err := h.db.Queries.CreatePromoCode(ctx, p.params())
+	if err != nil {
+		log.Printf("Failed to create promo code: %v", err)
+		http.Error(w, "Failed to create promo code", http.StatusInternalServerError)
+		return
+	}
+
+	h.audit(ctx, h.db.Queries, r, "promo_code.create", "promo_code", strconv.Itoa(int(code.ID)), map[string]interface{}{
+		"promo_code": code,
+	})
+	json.NewEncoder(w).Encode(code)
+}
+
+// UpdatePromoCodeAdmin changes a code for future uses; discounts and credit
+// already given are unaffected
+func (h *Handler) UpdatePromoCodeAdmin(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	var p PromoCodePayload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if msg := p.validate(); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	if existing, err := h.db.Queries.GetPromoCodeByCode(ctx, p.Code); err == nil && existing.ID != int32(id) {
+		http.Error(w, "Promo code already exists", http.StatusConflict)
+		return
+	}
+	arg := p.params()
+	code, err := h.db.Queries.UpdatePromoCode(ctx, sqlc.UpdatePromoCodeParams{
+		ID:             int32(id),
+		Code:           arg.Code,
+		Description:    arg.Description,
+		AppliesTo:      arg.AppliesTo,
+		ValueType:      arg.ValueType,
+		ValuePct:       arg.ValuePct,
+		ValueCents:     arg.ValueCents,
+		MaxValueCents:  arg.MaxValueCents,
+		MinAmountCents: arg.MinAmountCents,
+		MaxUses:        arg.MaxUses,
+		MaxUsesPerUser: arg.MaxUsesPerUser,
+		Platforms:      arg.Platforms,
+		CatalogIds:     arg.CatalogIds,
+		StartsAt:       arg.StartsAt,
+		ExpiresAt:      arg.ExpiresAt,
+		IsActive:       arg.IsActive,
+	})
+	if err != nil {
+		log.Printf("Failed to update promo code %d: %v", id, err)
+		http.Error(w, "Failed to update promo code", http.StatusInternalServerError)
+		return
+	}
+
+	h.audit(ctx, h.db.Queries, r, "promo_code.update", "promo_code", strconv.Itoa(id), map[string]interface{}{
+		"promo_code": code,
+	})
+	json.NewEncoder(w).Encode(code)
+}
+
+// DeletePromoCodeAdmin deletes a code nobody has used yet
+func (h *Handler) DeletePromoCodeAdmin(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	n, err := h.db.Queries.DeletePromoCode(ctx, int32(id))
+	if err != nil {
+		http.Error(w, "Failed to delete promo code", http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "Promo code not found or already used; deactivate it instead", http.StatusConflict)
+		return
+	}
+
+	h.audit(ctx, h.db.Queries, r, "promo_code.delete", "promo_code", strconv.Itoa(id), nil)
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// ListPromoRedemptionsAdmin lists uses of promo codes, newest first.
+// Filters: ?promo_code_id= and ?user_id=.
+func (h *Handler) ListPromoRedemptionsAdmin(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	arg := sqlc.ListPromoRedemptionsParams{Lim: 500}
+	if v := q.Get("promo_code_id"); v != "" {
+		codeID, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid promo_code_id", http.StatusBadRequest)
+			return
+		}
+		arg.PromoCodeID = pgtype.Int4{Int32: int32(codeID), Valid: true}
+	}
+	if v := q.Get("user_id"); v != "" {
+		userID, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid user_id", http.StatusBadRequest)
+			return
+		}
+		arg.UserID = pgtype.Int4{Int32: int32(userID), Valid: true}
+	}
+
+	rows, err := h.db.Queries.ListPromoRedemptions(context.Background(), arg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rows == nil {
+		rows = []sqlc.ListPromoRedemptionsRow{}
+	}
+	json.NewEncoder(w).Encode(rows)
+}
+
+// PromoCodeStatsRes is the use of one code. For order codes Base is the
+// order value before discounts and Value the discount given; for deposit
+// codes they are the deposits and the credit paid.
+type PromoCodeStatsRes struct {
+	ID          int32   `json:"id"`
+	Code        string  `json:"code"`
+	AppliesTo   string  `json:"applies_to"`
+	IsActive    bool    `json:"is_active"`
+	Redemptions int64   `json:"redemptions"`
+	Users       int64   `json:"users"`
+	Base        float64 `json:"base"`
+	Value       float64 `json:"value"`
+	Net         float64 `json:"net"`
+}
+
+// GetPromoCodeAnalyticsAdmin reports the uses, users, discounts and credit
+// of every promo code over the last ?days= days (default 30)
+func (h *Handler) GetPromoCodeAnalyticsAdmin(w http.ResponseWriter, r *http.Request) {
+	days := 30
+	if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d > 0 && d <= 365 {
+		days = d
+	}
+	since := pgtype.Timestamptz{Time: time.Now().AddDate(0, 0, -days), Valid: true}
+
+	rows, err := h.db.Queries.GetPromoCodeStats(context.Background(), since)
+	if err != nil {
+		log.Printf("Error fetching promo code stats: %v", err)
+		http.Error(w, "Failed to fetch promo code analytics", http.StatusInternalServerError)
+		return
+	}
+
+	codes := make([]PromoCodeStatsRes, 0, len(rows))
+	var discountCents, orderCents, creditCents, depositCents int64
+	for _, row := range rows {
+		net := row.BaseCents - row.ValueCents
+		if row.AppliesTo == promo.AppliesToDeposit {
+			net = row.BaseCents + row.ValueCents
+			depositCents += row.BaseCents
+			creditCents += row.ValueCents
+		} else {
+			orderCents += row.BaseCents
+			discountCents += row.ValueCents
+		}
+		codes = append(codes, PromoCodeStatsRes{
+			ID:          row.ID,
+			Code:        row.Code,
+			AppliesTo:   row.AppliesTo,
+			IsActive:    row.IsActive,
+			Redemptions: row.Redemptions,
+			Users:       row.Users,
+			Base:        money.Paise(row.BaseCents).Major(),
+			Value:       money.Paise(row.ValueCents).Major(),
+			Net:         money.Paise(net).Major(),
+		})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"days":  days,
+		"codes": codes,
+		"totals": map[string]float64{
+			"order_value":    money.Paise(orderCents).Major(),
+			"discount":       money.Paise(discountCents).Major(),
+			"net_order":      money.Paise(orderCents - discountCents).Major(),
+			"deposits":       money.Paise(depositCents).Major(),
+			"deposit_credit": money.Paise(creditCents).Major(),
+		},
+	})
+}
@@ -36,6 +36,10 @@ type SanitizedSmmProvider struct {
 	IsActive  bool               `json:"is_active"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+
+	RefillCooldownHours int32 `json:"refill_cooldown_hours"`
+	SupportsMultiRefill bool  `json:"supports_multi_refill"`
+	MaxConcurrency      int32 `json:"max_concurrency"`
 }
 
 func (h *Handler) GetAdminServices(w http.ResponseWriter, r *http.Request) {
@@ -76,6 +80,9 @@ func (h *Handler) ListProvidersAdmin(w http.ResponseWriter, r *http.Request) {
 			ApiKey:   apiKey,
 			Currency: provider.DefaultCurrency,
 			IsActive: true,
+
+			RefillCooldownHours: 24,
+			MaxConcurrency:      provider.DefaultMaxConcurrency,
 		})
 		if err != nil {
 			log.Printf("ERROR: Failed to auto-seed default TOPSMM provider to DB: %v", err)
@@ -88,6 +95,9 @@ func (h *Handler) ListProvidersAdmin(w http.ResponseWriter, r *http.Request) {
 					ApiKey:   apiKey,
 					Currency: provider.DefaultCurrency,
 					IsActive: true,
+
+					RefillCooldownHours: 24,
+					MaxConcurrency:      provider.DefaultMaxConcurrency,
 				},
 			}
 		} else {
@@ -107,6 +117,10 @@ func (h *Handler) ListProvidersAdmin(w http.ResponseWriter, r *http.Request) {
 			IsActive:  p.IsActive,
 			CreatedAt: p.CreatedAt,
 			UpdatedAt: p.UpdatedAt,
+
+			RefillCooldownHours: p.RefillCooldownHours,
+			SupportsMultiRefill: p.SupportsMultiRefill,
+			MaxConcurrency:      p.MaxConcurrency,
 		}
 	}
 
@@ -122,6 +136,10 @@ func (h *Handler) UpsertProviderAdmin(w http.ResponseWriter, r *http.Request) {
 		ApiKey   string `json:"api_key"`
 		Currency string `json:"currency"`
 		IsActive bool   `json:"is_active"`
+
+		RefillCooldownHours *int32 `json:"refill_cooldown_hours"`
+		SupportsMultiRefill *bool  `json:"supports_multi_refill"`
+		MaxConcurrency      *int32 `json:"max_concurrency"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -146,6 +164,28 @@ func (h *Handler) UpsertProviderAdmin(w http.ResponseWriter, r *http.Request) {
 		body.Currency = "USD"
 	}
 
+	refillCooldown := int32(24)
+	supportsMultiRefill := false
+	maxConcurrency := int32(provider.DefaultMaxConcurrency)
+	if existing, err := h.db.Queries.GetSmmProviderByKey(context.Background(), body.Key); err == nil {
+		refillCooldown = existing.RefillCooldownHours
+		supportsMultiRefill = existing.SupportsMultiRefill
+		maxConcurrency = existing.MaxConcurrency
+	}
+	if body.RefillCooldownHours != nil {
+		refillCooldown = *body.RefillCooldownHours
+	}
+	if body.SupportsMultiRefill != nil {
+		supportsMultiRefill = *body.SupportsMultiRefill
+	}
+	if body.MaxConcurrency != nil {
+		if *body.MaxConcurrency < 1 {
+			http.Error(w, "max_concurrency must be at least 1", http.StatusBadRequest)
+			return
+		}
+		maxConcurrency = *body.MaxConcurrency
+	}
+
 	provider, err := h.db.Queries.UpsertSmmProvider(context.Background(), sqlc.UpsertSmmProviderParams{
 		Key:      body.Key,
 		Name:     body.Name,
@@ -153,6 +193,10 @@ func (h *Handler) UpsertProviderAdmin(w http.ResponseWriter, r *http.Request) {
 		ApiKey:   finalApiKey,
 		Currency: body.Currency,
 		IsActive: body.IsActive,
+
+		RefillCooldownHours: refillCooldown,
+		SupportsMultiRefill: supportsMultiRefill,
+		MaxConcurrency:      maxConcurrency,
 	})
 
 	if err != nil {
@@ -172,6 +216,10 @@ func (h *Handler) UpsertProviderAdmin(w http.ResponseWriter, r *http.Request) {
 		IsActive:  provider.IsActive,
 		CreatedAt: provider.CreatedAt,
 		UpdatedAt: provider.UpdatedAt,
+
+		RefillCooldownHours: provider.RefillCooldownHours,
+		SupportsMultiRefill: provider.SupportsMultiRefill,
+		MaxConcurrency:      provider.MaxConcurrency,
 	})
 }
 
@@ -216,8 +264,8 @@ func (h *Handler) CurateServicesAdmin(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
 	var body struct {
-		ProviderName string                 `json:"providerName"`
-		Updates      []CurateServiceUpdate  `json:"updates"`
+		ProviderName string                `json:"providerName"`
+		Updates      []CurateServiceUpdate `json:"updates"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -370,7 +418,7 @@ func (h *Handler) CurateServicesAdmin(w http.ResponseWriter, r *http.Request) {
 		if item.Category != "" && item.Category != "other" && item.Category != "default" {
 			tags = append(tags, "category:"+item.Category)
 		}
-		
+
 		if item.VariantName != nil && *item.VariantName != "" {
 			tags = append(tags, "variant_name:"+*item.VariantName)
 		}
@@ -543,4 +591,3 @@ func (h *Handler) ClearPendingProviderSubmissions(w http.ResponseWriter, r *http
 	h.smm.InvalidateCache()
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
-
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/service/jobs"
+	"pablosmm/backend/internal/service/ledger"
+	"pablosmm/backend/internal/service/reconcile"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func (h *Handler) ListReconcileRunsAdmin(w http.ResponseWriter, r *http.Request) {
+	runs, err := h.db.Queries.ListReconcileRuns(context.Background(), 50)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if runs == nil {
+		runs = []sqlc.ReconcileRun{}
+	}
+	json.NewEncoder(w).Encode(runs)
+}
+
+// RunReconcileAdmin runs a reconciliation now. Auto-fix follows the
+// reconcile_autofix setting unless ?fix=true or ?fix=false is given.
+func (h *Handler) RunReconcileAdmin(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	rec := reconcile.New(h.db)
+	autoFix := rec.LoadConfig(ctx).AutoFix
+	if v := r.URL.Query().Get("fix"); v != "" {
+		autoFix = v == "true"
+	}
+
+	var report reconcile.Report
+	var runErr error
+	ran := jobs.New(h.db).RunOnce(ctx, reconcile.JobName, func(ctx context.Context) {
+		report, runErr = rec.Run(ctx, reconcile.TriggerManual, autoFix)
+	})
+	if !ran {
+		http.Error(w, "A reconciliation is already running", http.StatusConflict)
+		return
+	}
+	if runErr != nil {
+		log.Printf("Manual reconciliation failed: %v", runErr)
+		http.Error(w, "Reconciliation failed", http.StatusInternalServerError)
+		return
+	}
+
+	h.audit(ctx, h.db.Queries, r, "reconcile.run", "reconcile_run", strconv.Itoa(int(report.RunID)), map[string]interface{}{
+		"auto_fix":      autoFix,
+		"discrepancies": report.Discrepancies,
+		"fixed":         report.Fixed,
+	})
+	json.NewEncoder(w).Encode(report)
+}
+
+// ListReconcileDiscrepanciesAdmin lists discrepancies, open ones by default.
+// Filters: ?status= (or "all"), ?kind= and ?run_id=.
+func (h *Handler) ListReconcileDiscrepanciesAdmin(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	arg := sqlc.ListReconcileDiscrepanciesParams{Lim: 500}
+
+	switch status := q.Get("status"); status {
+	case "":
+		arg.Status = pgtype.Text{String: "open", Valid: true}
+	case "all":
+	case "open", "fixed", "ignored", "superseded":
+		arg.Status = pgtype.Text{String: status, Valid: true}
+	default:
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+	switch kind := q.Get("kind"); kind {
+	case "":
+	case reconcile.KindBalance, reconcile.KindRefund, reconcile.KindDeposit, reconcile.KindOpening:
+		arg.Kind = pgtype.Text{String: kind, Valid: true}
+	default:
+		http.Error(w, "Invalid kind", http.StatusBadRequest)
+		return
+	}
+	if v := q.Get("run_id"); v != "" {
+		runID, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid run_id", http.StatusBadRequest)
+			return
+		}
+		arg.RunID = pgtype.Int4{Int32: int32(runID), Valid: true}
+	}
+
+	rows, err := h.db.Queries.ListReconcileDiscrepancies(context.Background(), arg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rows == nil {
+		rows = []sqlc.ListReconcileDiscrepanciesRow{}
+	}
+	json.NewEncoder(w).Encode(rows)
+}
+
+// GetReconcileDiscrepancyAdmin returns a discrepancy with what an admin needs
+// to investigate it: the wallet and its ledger balance, the order's refunds
+// and refund credits, or the deposit credits of the wallet request
+func (h *Handler) GetReconcileDiscrepancyAdmin(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	ctx := context.Background()
+	d, err := h.db.Queries.GetReconcileDiscrepancy(ctx, int32(id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "Discrepancy not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	details := map[string]interface{}{}
+	var txns []sqlc.Transaction
+	switch d.Kind {
+	case reconcile.KindBalance, reconcile.KindOpening:
+		if d.UserID.Valid {
+			walletBalance, err := h.db.Queries.GetWalletBalance(ctx, d.UserID.Int32)
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			ledgerBalance, err := h.db.Queries.GetWalletLedgerBalance(ctx, d.UserID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			details["current_wallet_cents"] = walletBalance
+			details["current_ledger_cents"] = ledgerBalance
+			txns, err = h.db.Queries.GetWalletTransactions(ctx, sqlc.GetWalletTransactionsParams{
+				UserID: d.UserID,
+				Lim:    50,
+			})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	case reconcile.KindRefund:
+		orderID, err := strconv.Atoi(d.ReferenceID)
+		if err != nil {
+			http.Error(w, "Invalid order reference", http.StatusInternalServerError)
+			return
+		}
+		refunds, err := h.db.Queries.GetOrderRefunds(ctx, int32(orderID))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if refunds == nil {
+			refunds = []sqlc.OrderRefund{}
+		}
+		details["refunds"] = refunds
+		txns, err = h.db.Queries.ListOrderRefundCredits(ctx, int32(orderID))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	case reconcile.KindDeposit:
+		if d.UserID.Valid {
+			txns, err = h.db.Queries.GetWalletTransactions(ctx, sqlc.GetWalletTransactionsParams{
+				UserID:        d.UserID,
+				Kind:          pgtype.Text{String: ledger.KindDeposit, Valid: true},
+				ReferenceType: pgtype.Text{String: ledger.RefWalletRequest, Valid: true},
+				ReferenceID:   pgtype.Text{String: d.ReferenceID, Valid: true},
+				Lim:           50,
+			})
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	transactions := make([]TransactionRes, 0, len(txns))
+	for _, t := range txns {
+		transactions = append(transactions, newTransactionRes(t.ID, t.Amount, t.Type, t.Kind, t.ReferenceType, t.ReferenceID, t.Description, t.CreatedAt))
+	}
+	details["transactions"] = transactions
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"discrepancy": d,
+		"details":     details,
+	})
+}
+
+type ResolveDiscrepancyPayload struct {
+	Note string `json:"note"`
+}
+
+// FixReconcileDiscrepancyAdmin sets the wallet of a balance mismatch to its
+// ledger balance, refusing if either changed since the run
+func (h *Handler) FixReconcileDiscrepancyAdmin(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	var p ResolveDiscrepancyPayload
+	json.NewDecoder(r.Body).Decode(&p)
+
+	ctx := context.Background()
+	err = reconcile.New(h.db).Fix(ctx, int32(id), adminIDFromRequest(r), p.Note)
+	switch {
+	case err == nil:
+	case errors.Is(err, pgx.ErrNoRows):
+		http.Error(w, "Discrepancy not found", http.StatusNotFound)
+		return
+	case errors.Is(err, reconcile.ErrNotFixable):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, reconcile.ErrNotOpen), errors.Is(err, reconcile.ErrStale):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	default:
+		log.Printf("Error fixing discrepancy %d: %v", id, err)
+		http.Error(w, "Failed to fix discrepancy", http.StatusInternalServerError)
+		return
+	}
+
+	h.audit(ctx, h.db.Queries, r, "reconcile.fix", "reconcile_discrepancy", strconv.Itoa(id), map[string]interface{}{
+		"note": p.Note,
+	})
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+}
+
+// IgnoreReconcileDiscrepancyAdmin closes a discrepancy without changing
+// anything; a note explaining why is required
+func (h *Handler) IgnoreReconcileDiscrepancyAdmin(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	var p ResolveDiscrepancyPayload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil || p.Note == "" {
+		http.Error(w, "A note is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	tx, err := h.db.Pool.Begin(ctx)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := h.db.Queries.WithTx(tx)
+
+	n, err := qtx.ResolveReconcileDiscrepancy(ctx, sqlc.ResolveReconcileDiscrepancyParams{
+		ID:             int32(id),
+		Status:         "ignored",
+		ResolutionNote: pgtype.Text{String: p.Note, Valid: true},
+		ResolvedBy:     adminIDFromRequest(r),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "Discrepancy is not open", http.StatusConflict)
+		return
+	}
+	if err := h.audit(ctx, qtx, r, "reconcile.ignore", "reconcile_discrepancy", strconv.Itoa(id), map[string]interface{}{
+		"note": p.Note,
+	}); err != nil {
+		http.Error(w, "Failed to write audit log", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "Failed to commit", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+}
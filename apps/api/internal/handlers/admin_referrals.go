@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/ledger"
+	"pablosmm/backend/internal/service/referral"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ListReferralsAdmin lists referrals, newest first, with the commission
+// each has earned and the self-referral flags raised at signup.
+// Filters: ?status= and ?referrer_id=.
+func (h *Handler) ListReferralsAdmin(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	arg := sqlc.ListReferralsParams{Lim: 500}
+	if v := q.Get("status"); v != "" {
+		arg.Status = pgtype.Text{String: v, Valid: true}
+	}
+	if v := q.Get("referrer_id"); v != "" {
+		referrerID, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid referrer_id", http.StatusBadRequest)
+			return
+		}
+		arg.ReferrerID = pgtype.Int4{Int32: int32(referrerID), Valid: true}
+	}
+
+	rows, err := h.db.Queries.ListReferrals(context.Background(), arg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rows == nil {
+		rows = []sqlc.ListReferralsRow{}
+	}
+	json.NewEncoder(w).Encode(rows)
+}
+
+type ReferralStatusPayload struct {
+	Status string `json:"status"`
+}
+
+// SetReferralStatusAdmin activates a held referral, releasing its held
+// commissions, or blocks it, rejecting them. {id} is the referred user.
+func (h *Handler) SetReferralStatusAdmin(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	var p ReferralStatusPayload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if p.Status != referral.StatusActive && p.Status != referral.StatusBlocked {
+		http.Error(w, "status must be active or blocked", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	tx, err := h.db.Pool.Begin(ctx)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := h.db.Queries.WithTx(tx)
+
+	err = referral.Review(ctx, qtx, int32(id), p.Status, adminIDFromRequest(r).Int32)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Referral not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to review referral of user %d: %v", id, err)
+		http.Error(w, "Failed to update referral", http.StatusInternalServerError)
+		return
+	}
+	if err := h.audit(ctx, qtx, r, "referral.review", "referral", strconv.Itoa(id), map[string]interface{}{"status": p.Status}); err != nil {
+		http.Error(w, "Failed to record audit", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "Failed to update referral", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// ReferralReportRes is one referrer's referrals and earnings. Base is the
+// referred deposits or spend their available commission was earned on.
+type ReferralReportRes struct {
+	UserID       int32   `json:"user_id"`
+	Email        string  `json:"email"`
+	ReferralCode string  `json:"referral_code"`
+	Referrals    int64   `json:"referrals"`
+	Flagged      int64   `json:"flagged"`
+	Held         int64   `json:"held"`
+	Blocked      int64   `json:"blocked"`
+	Base         float64 `json:"base"`
+	Earned       float64 `json:"earned"`
+	HeldEarnings float64 `json:"held_earnings"`
+	Withdrawn    float64 `json:"withdrawn"`
+	Balance      float64 `json:"balance"`
+}
+
+// GetReferralReportAdmin summarises every referrer, top earners first
+func (h *Handler) GetReferralReportAdmin(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.db.Queries.GetReferralReport(context.Background(), 500)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	res := make([]ReferralReportRes, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, ReferralReportRes{
+			UserID:       row.ID,
+			Email:        row.Email,
+			ReferralCode: row.ReferralCode,
+			Referrals:    row.Referrals,
+			Flagged:      row.Flagged,
+			Held:         row.Held,
+			Blocked:      row.Blocked,
+			Base:         money.Paise(row.BaseCents).Major(),
+			Earned:       money.Paise(row.EarnedCents).Major(),
+			HeldEarnings: money.Paise(row.HeldCents).Major(),
+			Withdrawn:    money.Paise(row.WithdrawnCents).Major(),
+			Balance:      money.Paise(row.EarnedCents - row.WithdrawnCents).Major(),
+		})
+	}
+	json.NewEncoder(w).Encode(res)
+}
+
+// ListReferralPayoutsAdmin lists withdrawals from affiliate balances,
+// newest first. Filters: ?status= and ?user_id=.
+func (h *Handler) ListReferralPayoutsAdmin(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	arg := sqlc.ListReferralPayoutsParams{Lim: 500}
+	if v := q.Get("status"); v != "" {
+		arg.Status = pgtype.Text{String: v, Valid: true}
+	}
+	if v := q.Get("user_id"); v != "" {
+		userID, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid user_id", http.StatusBadRequest)
+			return
+		}
+		arg.UserID = pgtype.Int4{Int32: int32(userID), Valid: true}
+	}
+
+	rows, err := h.db.Queries.ListReferralPayouts(context.Background(), arg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rows == nil {
+		rows = []sqlc.ListReferralPayoutsRow{}
+	}
+	json.NewEncoder(w).Encode(rows)
+}
+
+type ReferralPayoutDecisionPayload struct {
+	AdminNote string `json:"admin_note"`
+}
+
+// ApproveReferralPayoutAdmin marks a payout request as paid, once the money
+// has been sent outside the platform
+func (h *Handler) ApproveReferralPayoutAdmin(w http.ResponseWriter, r *http.Request) {
+	h.decideReferralPayout(w, r, referral.PayoutPaid, "referral_payout.approve")
+}
+
+// RejectReferralPayoutAdmin turns down a payout request; the amount goes
+// back to the affiliate balance
+func (h *Handler) RejectReferralPayoutAdmin(w http.ResponseWriter, r *http.Request) {
+	h.decideReferralPayout(w, r, referral.PayoutRejected, "referral_payout.reject")
+}
+
+func (h *Handler) decideReferralPayout(w http.ResponseWriter, r *http.Request, status, action string) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	var p ReferralPayoutDecisionPayload
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx := context.Background()
+	tx, err := h.db.Pool.Begin(ctx)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := h.db.Queries.WithTx(tx)
+
+	payout, err := qtx.GetReferralPayoutForUpdate(ctx, int32(id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Payout not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	n, err := qtx.UpdateReferralPayoutStatus(ctx, sqlc.UpdateReferralPayoutStatusParams{
+		ID:          payout.ID,
+		Status:      status,
+		AdminNote:   p.AdminNote,
+		ProcessedBy: adminIDFromRequest(r),
+	})
+	if err != nil {
+		http.Error(w, "Failed to update payout", http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "Payout is not pending", http.StatusConflict)
+		return
+	}
+	if status == referral.PayoutPaid {
+		err = ledger.PayCommission(ctx, qtx, payout.ID, money.Paise(payout.AmountCents), fmt.Sprintf("Referral payout #%d to user %d", payout.ID, payout.UserID))
+		if err != nil {
+			log.Printf("Failed to post referral payout #%d: %v", payout.ID, err)
+			http.Error(w, "Failed to update payout", http.StatusInternalServerError)
+			return
+		}
+	}
+	details := map[string]interface{}{"status": status, "amount_cents": payout.AmountCents, "admin_note": p.AdminNote}
+	if err := h.audit(ctx, qtx, r, action, "referral_payout", strconv.Itoa(id), details); err != nil {
+		http.Error(w, "Failed to record audit", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "Failed to update payout", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
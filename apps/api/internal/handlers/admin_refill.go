@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"pablosmm/backend/internal/db/sqlc"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type RefillPolicyPayload struct {
+	AutoRefillEnabled bool     `json:"auto_refill_enabled"`
+	AutoRefillDropPct float64  `json:"auto_refill_drop_pct"`
+	RefillPeriodDays  int32    `json:"refill_period_days"`
+	MinHoursBetween   int32    `json:"min_hours_between"`
+	AllowedStatuses   []string `json:"allowed_statuses"`
+	// MaxRefills is null for unlimited refills
+	MaxRefills *int32 `json:"max_refills"`
+}
+
+func (h *Handler) ListRefillPoliciesAdmin(w http.ResponseWriter, r *http.Request) {
+	policies, err := h.db.Queries.ListCatalogRefillPolicies(context.Background())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if policies == nil {
+		policies = []sqlc.CatalogRefillPolicy{}
+	}
+	json.NewEncoder(w).Encode(policies)
+}
+
+func (h *Handler) UpsertRefillPolicyAdmin(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var p RefillPolicyPayload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if p.AutoRefillDropPct <= 0 || p.AutoRefillDropPct > 100 {
+		http.Error(w, "auto_refill_drop_pct must be between 0 and 100", http.StatusBadRequest)
+		return
+	}
+	if p.RefillPeriodDays <= 0 {
+		http.Error(w, "refill_period_days must be positive", http.StatusBadRequest)
+		return
+	}
+	if p.MinHoursBetween < 0 || (p.MaxRefills != nil && *p.MaxRefills < 0) {
+		http.Error(w, "min_hours_between and max_refills cannot be negative", http.StatusBadRequest)
+		return
+	}
+	if len(p.AllowedStatuses) == 0 {
+		p.AllowedStatuses = []string{"completed", "partial"}
+	}
+	var maxRefills pgtype.Int4
+	if p.MaxRefills != nil {
+		maxRefills = pgtype.Int4{Int32: *p.MaxRefills, Valid: true}
+	}
+
+	policy, err := h.db.Queries.UpsertCatalogRefillPolicy(context.Background(), sqlc.UpsertCatalogRefillPolicyParams{
+		CatalogID:         int32(id),
+		AutoRefillEnabled: p.AutoRefillEnabled,
+		AutoRefillDropPct: p.AutoRefillDropPct,
+		RefillPeriodDays:  p.RefillPeriodDays,
+		MinHoursBetween:   p.MinHoursBetween,
+		AllowedStatuses:   p.AllowedStatuses,
+		MaxRefills:        maxRefills,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.smm.InvalidateCache()
+	json.NewEncoder(w).Encode(policy)
+}
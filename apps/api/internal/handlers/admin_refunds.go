@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/service/refund"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type RefundRulePayload struct {
+	CancelFeePct          float64 `json:"cancel_fee_pct"`
+	MinNonRefundableCents int64   `json:"min_non_refundable_cents"`
+	Rounding              string  `json:"rounding"`
+	PartialFormula        string  `json:"partial_formula"`
+}
+
+func (p *RefundRulePayload) validate() string {
+	if p.Rounding == "" {
+		p.Rounding = refund.RoundDown
+	}
+	if p.PartialFormula == "" {
+		p.PartialFormula = refund.PartialProportional
+	}
+	if p.CancelFeePct < 0 || p.CancelFeePct > 100 {
+		return "cancel_fee_pct must be between 0 and 100"
+	}
+	if p.MinNonRefundableCents < 0 {
+		return "min_non_refundable_cents cannot be negative"
+	}
+	switch p.Rounding {
+	case refund.RoundDown, refund.RoundUp, refund.RoundNearest:
+	default:
+		return "rounding must be down, up or nearest"
+	}
+	switch p.PartialFormula {
+	case refund.PartialProportional, refund.PartialProportionalAfterFee, refund.PartialNone:
+	default:
+		return "partial_formula must be proportional, proportional_after_fee or none"
+	}
+	return ""
+}
+
+func (h *Handler) ListRefundRulesAdmin(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.db.Queries.ListRefundRules(context.Background())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rules == nil {
+		rules = []sqlc.RefundRule{}
+	}
+	json.NewEncoder(w).Encode(rules)
+}
+
+// UpsertGlobalRefundRuleAdmin sets the rule used by services without their own
+func (h *Handler) UpsertGlobalRefundRuleAdmin(w http.ResponseWriter, r *http.Request) {
+	h.upsertRefundRule(w, r, pgtype.Int4{})
+}
+
+func (h *Handler) UpsertServiceRefundRuleAdmin(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	h.upsertRefundRule(w, r, pgtype.Int4{Int32: int32(id), Valid: true})
+}
+
+func (h *Handler) upsertRefundRule(w http.ResponseWriter, r *http.Request, catalogID pgtype.Int4) {
+	var p RefundRulePayload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	if msg := p.validate(); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.db.Queries.UpsertRefundRule(context.Background(), sqlc.UpsertRefundRuleParams{
+		CatalogID:             catalogID,
+		CancelFeePct:          p.CancelFeePct,
+		MinNonRefundableCents: p.MinNonRefundableCents,
+		Rounding:              p.Rounding,
+		PartialFormula:        p.PartialFormula,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(rule)
+}
+
+func (h *Handler) DeleteServiceRefundRuleAdmin(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.db.Queries.DeleteRefundRule(context.Background(), pgtype.Int4{Int32: int32(id), Valid: true}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// GetOrderRefundsAdmin lists the refunds applied to an order with their formulas
+func (h *Handler) GetOrderRefundsAdmin(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	refunds, err := h.db.Queries.GetOrderRefunds(context.Background(), int32(id))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if refunds == nil {
+		refunds = []sqlc.OrderRefund{}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"refunds": refunds,
+	})
+}
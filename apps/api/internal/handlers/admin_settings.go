@@ -10,7 +10,7 @@ import (
 
 func (h *Handler) GetSettings(w http.ResponseWriter, r *http.Request) {
 	// Only admin allowed, which should be protected by middleware in server.go
-	
+
 	settings, err := h.db.Queries.GetAllSettings(context.Background())
 	if err != nil {
 		http.Error(w, "Failed to get settings", http.StatusInternalServerError)
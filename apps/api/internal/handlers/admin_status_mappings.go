@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/service/syncer"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type StatusMappingPayload struct {
+	ProviderKey string `json:"provider_key"` // empty applies to every provider
+	RawStatus   string `json:"raw_status"`
+	LocalStatus string `json:"local_status"`
+}
+
+func (h *Handler) ListStatusMappingsAdmin(w http.ResponseWriter, r *http.Request) {
+	mappings, err := h.db.Queries.ListProviderStatusMappings(context.Background())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if mappings == nil {
+		mappings = []sqlc.ProviderStatusMapping{}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"mappings":       mappings,
+		"local_statuses": syncer.LocalStatuses,
+	})
+}
+
+// UpsertStatusMappingAdmin maps a raw provider status and drops it from the
+// unmapped report. The syncer picks it up on its next run.
+func (h *Handler) UpsertStatusMappingAdmin(w http.ResponseWriter, r *http.Request) {
+	var p StatusMappingPayload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, "Invalid payload", http.StatusBadRequest)
+		return
+	}
+	p.ProviderKey = strings.TrimSpace(p.ProviderKey)
+	p.RawStatus = syncer.NormalizeRawStatus(p.RawStatus)
+	if p.RawStatus == "" {
+		http.Error(w, "raw_status is required", http.StatusBadRequest)
+		return
+	}
+	valid := false
+	for _, s := range syncer.LocalStatuses {
+		if p.LocalStatus == s {
+			valid = true
+		}
+	}
+	if !valid {
+		http.Error(w, "local_status must be one of "+strings.Join(syncer.LocalStatuses, ", "), http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	mapping, err := h.db.Queries.UpsertProviderStatusMapping(ctx, sqlc.UpsertProviderStatusMappingParams{
+		ProviderKey: p.ProviderKey,
+		RawStatus:   p.RawStatus,
+		LocalStatus: p.LocalStatus,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	err = h.db.Queries.DeleteUnmappedStatuses(ctx, sqlc.DeleteUnmappedStatusesParams{
+		RawStatus:   p.RawStatus,
+		ProviderKey: p.ProviderKey,
+	})
+	if err != nil {
+		log.Printf("Failed to clear unmapped status %q: %v", p.RawStatus, err)
+	}
+
+	json.NewEncoder(w).Encode(mapping)
+}
+
+func (h *Handler) DeleteStatusMappingAdmin(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	n, err := h.db.Queries.DeleteProviderStatusMapping(context.Background(), int32(id))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "Mapping not found", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// GetUnmappedStatusesAdmin reports raw statuses seen during sync that no
+// mapping covers. Orders with such a status keep their previous status.
+func (h *Handler) GetUnmappedStatusesAdmin(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.db.Queries.ListUnmappedStatuses(context.Background())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rows == nil {
+		rows = []sqlc.ProviderUnmappedStatus{}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"statuses": rows,
+	})
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/service/transfer"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ListWalletTransfersAdmin lists transfers between users, newest first.
+// Filters: ?status= and ?user_id= (either side).
+func (h *Handler) ListWalletTransfersAdmin(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	arg := sqlc.ListWalletTransfersParams{Lim: 500}
+	if v := q.Get("status"); v != "" {
+		arg.Status = pgtype.Text{String: v, Valid: true}
+	}
+	if v := q.Get("user_id"); v != "" {
+		userID, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid user_id", http.StatusBadRequest)
+			return
+		}
+		arg.UserID = pgtype.Int4{Int32: int32(userID), Valid: true}
+	}
+
+	rows, err := h.db.Queries.ListWalletTransfers(context.Background(), arg)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rows == nil {
+		rows = []sqlc.ListWalletTransfersRow{}
+	}
+	json.NewEncoder(w).Encode(rows)
+}
+
+type WalletTransferDecisionPayload struct {
+	AdminNote string `json:"admin_note"`
+}
+
+// ApproveWalletTransferAdmin sends a transfer that was waiting for approval
+func (h *Handler) ApproveWalletTransferAdmin(w http.ResponseWriter, r *http.Request) {
+	h.decideWalletTransfer(w, r, true, "wallet_transfer.approve")
+}
+
+// RejectWalletTransferAdmin turns down a transfer and frees the amount held
+// for it
+func (h *Handler) RejectWalletTransferAdmin(w http.ResponseWriter, r *http.Request) {
+	h.decideWalletTransfer(w, r, false, "wallet_transfer.reject")
+}
+
+func (h *Handler) decideWalletTransfer(w http.ResponseWriter, r *http.Request, approve bool, action string) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	var p WalletTransferDecisionPayload
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx := context.Background()
+	tx, err := h.db.Pool.Begin(ctx)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := h.db.Queries.WithTx(tx)
+
+	t, err := transfer.Decide(ctx, qtx, int32(id), approve, adminIDFromRequest(r), p.AdminNote)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Transfer not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, transfer.ErrNotPending) {
+		http.Error(w, "Transfer is not pending", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to decide transfer #%d: %v", id, err)
+		http.Error(w, "Failed to update transfer", http.StatusInternalServerError)
+		return
+	}
+	details := map[string]interface{}{
+		"sender_id":    t.SenderID,
+		"recipient_id": t.RecipientID,
+		"amount_cents": t.AmountCents,
+		"admin_note":   p.AdminNote,
+	}
+	if err := h.audit(ctx, qtx, r, action, "wallet_transfer", strconv.Itoa(id), details); err != nil {
+		http.Error(w, "Failed to record audit", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "Failed to update transfer", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
@@ -5,14 +5,19 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/ledger"
+	"pablosmm/backend/internal/service/refill"
 	"pablosmm/backend/internal/service/smm"
-	
+
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -63,10 +68,10 @@ func (h *Handler) SmmApiV2(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var userID int
-	var balanceCents int
+	var balance money.Money
 	userRow, err := h.db.Queries.GetUserByAPIKey(r.Context(), pgtype.Text{String: key, Valid: true})
 	userID = int(userRow.ID)
-	balanceCents = int(userRow.Balance)
+	balance = money.Paise(userRow.Balance)
 
 	if err != nil {
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid API key"})
@@ -76,7 +81,7 @@ func (h *Handler) SmmApiV2(w http.ResponseWriter, r *http.Request) {
 	switch action {
 	case "balance":
 		json.NewEncoder(w).Encode(map[string]string{
-			"balance":  fmt.Sprintf("%.4f", float64(balanceCents)/100.0),
+			"balance":  fmt.Sprintf("%.4f", balance.Major()),
 			"currency": "INR", // Internal base is INR
 		})
 		return
@@ -105,12 +110,12 @@ func (h *Handler) SmmApiV2(w http.ResponseWriter, r *http.Request) {
 		for _, s := range services {
 			// SMM panels expect price corresponding to wallet. Wallet is INR based. Let's return price in INR.
 			rateINR := s.RatePer1000
-			
+
 			name := s.DisplayName
 			if name == "" {
 				name = s.ProviderName
 			}
-			
+
 			res = append(res, ApiServiceOutput{
 				Service:  s.ID,
 				Name:     name,
@@ -142,8 +147,8 @@ func (h *Handler) SmmApiV2(w http.ResponseWriter, r *http.Request) {
 				json.NewEncoder(w).Encode(map[string]string{"error": "Incorrect order ID"})
 				return
 			}
-			
-			var oCharge int
+
+			var oCharge int64
 			var oStartCount int
 			var oStatus string
 			var oRemains int
@@ -151,33 +156,35 @@ func (h *Handler) SmmApiV2(w http.ResponseWriter, r *http.Request) {
 				ID:     int32(orderID),
 				UserID: int32(userID),
 			})
-			oCharge = int(oRow.AmountCents)
+			oCharge = oRow.AmountCents
 			oStartCount = int(oRow.StartCount)
 			oStatus = oRow.Status
 			oRemains = int(oRow.Remains)
-			
+
 			if err != nil {
 				json.NewEncoder(w).Encode(map[string]string{"error": "Incorrect order ID"})
 				return
 			}
-			
+
 			statusMap := map[string]string{
-				"pending": "Pending",
-				"processing": "Processing",
+				"pending":     "Pending",
+				"processing":  "Processing",
 				"in_progress": "In progress",
-				"completed": "Completed",
-				"partial": "Partial",
-				"canceled": "Canceled",
-				"refunded": "Refunded",
-				"failed": "Fail",
-				"submitted": "In progress",
-				"active": "In progress",
+				"completed":   "Completed",
+				"partial":     "Partial",
+				"canceled":    "Canceled",
+				"refunded":    "Refunded",
+				"failed":      "Fail",
+				"submitted":   "In progress",
+				"active":      "In progress",
 			}
 			mappedStatus, exists := statusMap[oStatus]
-			if !exists { mappedStatus = "Pending" }
-			
+			if !exists {
+				mappedStatus = "Pending"
+			}
+
 			json.NewEncoder(w).Encode(map[string]string{
-				"charge":      fmt.Sprintf("%.4f", float64(oCharge)/100.0),
+				"charge":      fmt.Sprintf("%.4f", money.Paise(oCharge).Major()),
 				"start_count": strconv.Itoa(oStartCount),
 				"status":      mappedStatus,
 				"remains":     strconv.Itoa(oRemains),
@@ -192,24 +199,24 @@ func (h *Handler) SmmApiV2(w http.ResponseWriter, r *http.Request) {
 		serviceID := r.FormValue("service")
 		link := r.FormValue("link")
 		quantityStr := r.FormValue("quantity")
-		
+
 		if serviceID == "" || link == "" || quantityStr == "" {
 			json.NewEncoder(w).Encode(map[string]string{"error": "Incorrect request"})
 			return
 		}
-		
+
 		quantity, err := strconv.Atoi(quantityStr)
 		if err != nil || quantity <= 0 {
 			json.NewEncoder(w).Encode(map[string]string{"error": "Incorrect quantity"})
 			return
 		}
-		
+
 		services, err := h.smm.FetchServices()
 		if err != nil {
 			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to retrieve service data"})
 			return
 		}
-		
+
 		var selectedService *smm.NormalizedSmmService
 		for _, s := range services {
 			if s.ID == serviceID {
@@ -217,109 +224,218 @@ func (h *Handler) SmmApiV2(w http.ResponseWriter, r *http.Request) {
 				break
 			}
 		}
-		
+
 		if selectedService == nil {
 			json.NewEncoder(w).Encode(map[string]string{"error": "Service not found"})
 			return
 		}
-		
+
 		if quantity < selectedService.Min || quantity > selectedService.Max {
 			json.NewEncoder(w).Encode(map[string]string{"error": "Incorrect quantity"})
 			return
 		}
-		
-		rateINR := selectedService.RatePer1000
-		totalINR := (rateINR * float64(quantity)) / 1000.0
-		amountCents := int(totalINR * 100) 
-		if amountCents <= 0 { amountCents = 1 }
-		
-		if balanceCents < amountCents {
+
+		price, err := selectedService.Price(quantity)
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]string{"error": "Incorrect quantity"})
+			return
+		}
+
+		if balance.Cmp(price) < 0 {
 			json.NewEncoder(w).Encode(map[string]string{"error": "Not enough funds on balance"})
 			return
 		}
-		
-		if err := validateLink(selectedService.Platform, selectedService.ServiceType, link); err != nil {
+
+		target, err := h.validateLink(selectedService, link)
+		if err != nil {
 			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 			return
 		}
-		
+		err = checkDuplicateTarget(context.Background(), h.db.Queries, selectedService.ID, target)
+		var duplicate *duplicateTargetError
+		if errors.As(err, &duplicate) {
+			json.NewEncoder(w).Encode(map[string]string{"error": duplicate.Error()})
+			return
+		}
+		if err != nil {
+			log.Printf("API v2: failed to check for duplicate orders: %v", err)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Database error"})
+			return
+		}
+		ownStartCount, err := h.preflightTarget(selectedService, target)
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
 		tx, err := h.db.Pool.Begin(context.Background())
 		if err != nil {
 			json.NewEncoder(w).Encode(map[string]string{"error": "Database error"})
 			return
 		}
 		defer tx.Rollback(context.Background())
-		
+
 		qtx := h.db.Queries.WithTx(tx)
-		
-		err = qtx.DebitWallet(context.Background(), sqlc.DebitWalletParams{
-			Balance: int32(amountCents),
-			UserID: int32(userID),
-		})
+		err = claimTarget(context.Background(), qtx, selectedService.ID, target)
+		if errors.As(err, &duplicate) {
+			json.NewEncoder(w).Encode(map[string]string{"error": duplicate.Error()})
+			return
+		}
 		if err != nil {
-			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to debit wallet"})
+			log.Printf("API v2: failed to check for duplicate orders: %v", err)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Database error"})
 			return
 		}
-		
+
 		var newOrderID int32
 		newOrderID, err = qtx.InsertAPIOrder(context.Background(), sqlc.InsertAPIOrderParams{
-			UserID:      int32(userID),
-			ServiceID:   selectedService.ID,
-			Quantity:    int32(quantity),
-			AmountCents: int32(amountCents),
-			Status:      "pending",
-			Link:        pgtype.Text{String: link, Valid: true},
+			UserID:           int32(userID),
+			ServiceID:        selectedService.ID,
+			Quantity:         int32(quantity),
+			AmountCents:      price.Minor,
+			Status:           "pending",
+			Link:             pgtype.Text{String: link, Valid: true},
+			ProviderKey:      pgtype.Text{String: selectedService.Source, Valid: true},
+			CanonicalLink:    pgtype.Text{String: target.Canonical, Valid: true},
+			TargetKind:       pgtype.Text{String: target.Kind, Valid: true},
+			TargetID:         pgtype.Text{String: target.Key(), Valid: true},
+			OwnStartCount:    ownStartCount,
+			RefillsRemaining: selectedService.OrderRefills(),
 		})
 		if err != nil {
 			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to create order"})
 			return
 		}
-		
-		tx.Commit(context.Background())
-		
-		resp, placeErr := h.smm.PlaceOrder(selectedService.Source, selectedService.SourceServiceID, quantityStr, link)
+
+		err = ledger.HoldOrder(context.Background(), qtx, int32(userID), newOrderID, price)
+		if errors.Is(err, ledger.ErrInsufficientFunds) {
+			json.NewEncoder(w).Encode(map[string]string{"error": "Not enough funds on balance"})
+			return
+		}
+		if err != nil {
+			log.Printf("API v2: failed to hold funds for order: %v", err)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to reserve funds"})
+			return
+		}
+
+		if err := tx.Commit(context.Background()); err != nil {
+			json.NewEncoder(w).Encode(map[string]string{"error": "Database error"})
+			return
+		}
+
+		resp, placeErr := h.smm.PlaceOrder(selectedService.Source, selectedService.SourceServiceID, quantityStr, target.Canonical)
 		var providerError string
 		if placeErr != nil {
 			providerError = placeErr.Error()
 		} else if errStr, ok := resp["error"].(string); ok && errStr != "" {
 			providerError = errStr
 		}
-		
+
 		if providerError != "" {
 			rtx, _ := h.db.Pool.Begin(context.Background())
 			if rtx != nil {
+				defer rtx.Rollback(context.Background())
 				qrtx := h.db.Queries.WithTx(rtx)
-				qrtx.CreditWallet(context.Background(), sqlc.CreditWalletParams{
-					Balance: int32(amountCents),
-					UserID: int32(userID),
-				})
-				qrtx.UpdateAPIOrderStatusFailed(context.Background(), newOrderID)
-				rtx.Commit(context.Background())
+				rerr := ledger.ReleaseOrder(context.Background(), qrtx, newOrderID)
+				if rerr == nil {
+					rerr = qrtx.UpdateAPIOrderStatusFailed(context.Background(), newOrderID)
+				}
+				if rerr == nil {
+					rerr = rtx.Commit(context.Background())
+				}
+				if rerr != nil {
+					log.Printf("CRITICAL: API v2 failed to release Order #%d: %v", newOrderID, rerr)
+				}
 			}
 			json.NewEncoder(w).Encode(map[string]string{"error": providerError})
 			return
 		}
-		
+
 		respJSON, _ := json.Marshal(resp)
 		var providerOrderID string
-		if id, ok := resp["order"].(string); ok { 
-			providerOrderID = id 
-		} else if id, ok := resp["order"].(float64); ok { 
-			providerOrderID = fmt.Sprintf("%.0f", id) 
+		if id, ok := resp["order"].(string); ok {
+			providerOrderID = id
+		} else if id, ok := resp["order"].(float64); ok {
+			providerOrderID = fmt.Sprintf("%.0f", id)
 		} else {
 			providerOrderID = fmt.Sprintf("%v", resp["order"])
 		}
-		
-		h.db.Queries.UpdateAPIOrderStatusSubmitted(context.Background(), sqlc.UpdateAPIOrderStatusSubmittedParams{
+
+		// Record the provider order before charging; the hold sweep retries the charge
+		err = h.db.Queries.UpdateAPIOrderStatusSubmitted(context.Background(), sqlc.UpdateAPIOrderStatusSubmittedParams{
 			ProviderResp:    respJSON,
 			ProviderOrderID: pgtype.Text{String: providerOrderID, Valid: true},
 			Status:          "submitted",
 			ID:              newOrderID,
 		})
-			
+		if err != nil {
+			log.Printf("CRITICAL: API v2 failed to record provider order %q for Order #%d: %v", providerOrderID, newOrderID, err)
+		}
+		ptx, err := h.db.Pool.Begin(context.Background())
+		if err == nil {
+			defer ptx.Rollback(context.Background())
+			err = ledger.CaptureOrder(context.Background(), h.db.Queries.WithTx(ptx), newOrderID)
+			if err == nil {
+				err = ptx.Commit(context.Background())
+			}
+		}
+		if err != nil {
+			log.Printf("API v2 failed to charge Order #%d, the hold sweep will retry: %v", newOrderID, err)
+		}
+
 		json.NewEncoder(w).Encode(map[string]interface{}{"order": newOrderID})
 		return
 
+	case "refill":
+		orderID, err := strconv.Atoi(r.FormValue("order"))
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]string{"error": "Incorrect order ID"})
+			return
+		}
+
+		refillID, err := h.submitRefill(r.Context(), userID, orderID)
+		if err != nil {
+			var notEligible *refill.EligibilityError
+			if errors.Is(err, errOrderNotFound) {
+				json.NewEncoder(w).Encode(map[string]string{"error": "Incorrect order ID"})
+			} else if errors.As(err, &notEligible) {
+				json.NewEncoder(w).Encode(map[string]string{"error": notEligible.Reason})
+			} else {
+				json.NewEncoder(w).Encode(map[string]string{"error": "Failed to submit refill"})
+			}
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"refill": refillID})
+		return
+
+	case "refill_status":
+		refillID, err := strconv.Atoi(r.FormValue("refill"))
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]string{"error": "Incorrect refill ID"})
+			return
+		}
+
+		req, err := h.db.Queries.GetRefillRequestForUser(r.Context(), sqlc.GetRefillRequestForUserParams{
+			ID:     int32(refillID),
+			UserID: int32(userID),
+		})
+		if err != nil {
+			json.NewEncoder(w).Encode(map[string]string{"error": "Refill not found"})
+			return
+		}
+
+		statusMap := map[string]string{
+			"pending":     "Pending",
+			"in_progress": "In progress",
+			"completed":   "Completed",
+			"rejected":    "Rejected",
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"status": statusMap[refillDisplayStatus(req.RefillStatus, req.Status)],
+		})
+		return
+
 	default:
 		json.NewEncoder(w).Encode(map[string]string{"error": "Incorrect request"})
 		return
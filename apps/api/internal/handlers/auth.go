@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"strings"
@@ -18,6 +19,8 @@ import (
 	"golang.org/x/oauth2/google"
 
 	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/referral"
 )
 
 var jwtKey = []byte(os.Getenv("JWT_SECRET"))
@@ -56,6 +59,8 @@ type RegisterReq struct {
 	Username string `json:"username"`
 	Mobile   string `json:"mobile"`
 	Password string `json:"password"`
+	// ReferralCode is the code of the user who referred them, if any
+	ReferralCode string `json:"referralCode"`
 }
 
 type LoginReq struct {
@@ -105,12 +110,14 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 
 	// Insert user
 	// Assuming 'role' defaults to 'user' in DB or we set it explicitly
-	err = h.db.Queries.CreateUser(context.Background(), sqlc.CreateUserParams{
+	ip := clientIP(r)
+	userID, err := h.db.Queries.CreateUser(context.Background(), sqlc.CreateUserParams{
 		Name:         pgtype.Text{String: req.FullName, Valid: req.FullName != ""},
 		Email:        pgtype.Text{String: req.Email, Valid: true},
 		Username:     pgtype.Text{String: req.Username, Valid: true},
 		Mobile:       pgtype.Text{String: req.Mobile, Valid: req.Mobile != ""},
 		PasswordHash: pgtype.Text{String: string(hashedPassword), Valid: true},
+		SignupIp:     ip,
 	})
 
 	if err != nil {
@@ -119,6 +126,17 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A referral that cannot be recorded should not fail the signup
+	err = referral.Attribute(context.Background(), h.db.Queries, req.ReferralCode, referral.Signup{
+		UserID: userID,
+		IP:     ip,
+		Email:  req.Email,
+		Mobile: req.Mobile,
+	})
+	if err != nil {
+		log.Printf("Failed to record referral of user %d: %v", userID, err)
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]string{"status": "success", "message": "User registered successfully"})
 }
@@ -221,8 +239,8 @@ func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
 	log.Printf("🔍 [Me] Fetching user data for userID: %d", userID)
 
 	var u AdminUser // Reusing struct from users.go
-	var balanceCents int
-	var totalSpendCents int
+	var balanceCents int64
+	var totalSpendCents int64
 	var createdAtTime interface{}
 	var passwordHash string // Check if password is set
 
@@ -237,9 +255,9 @@ func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
 		u.Currency = uRow.Currency
 		createdAtTime = uRow.CreatedAt.Time
 		passwordHash = uRow.PasswordHash
-		balanceCents = int(uRow.Balance)
+		balanceCents = uRow.Balance
 		u.OrderCount = int(uRow.OrderCount)
-		totalSpendCents = int(uRow.TotalSpend)
+		totalSpendCents = uRow.TotalSpend
 	}
 
 	if err != nil {
@@ -250,10 +268,17 @@ func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("✅ [Me] Successfully fetched user %d (%s)", userID, u.Email)
 
-	u.Balance = float64(balanceCents) / 100.0
-	u.TotalSpend = float64(totalSpendCents) / 100.0
+	u.Balance = money.Paise(balanceCents).Major()
+	u.TotalSpend = money.Paise(totalSpendCents).Major()
 	u.CreatedAt = fmt.Sprintf("%v", createdAtTime)
 
+	// Funds reserved for orders not charged yet
+	funds, err := h.db.Queries.GetWalletFunds(context.Background(), int32(userID))
+	if err != nil {
+		log.Printf("Failed to fetch wallet funds for user %d: %v", userID, err)
+	}
+	heldBalance := money.Paise(funds.HeldCents).Major()
+
 	// Fetch detailed order stats (same as GetProfile)
 	var stats struct {
 		Active    int `json:"active"`
@@ -273,26 +298,47 @@ func (h *Handler) Me(w http.ResponseWriter, r *http.Request) {
 		// Don't fail the response, just zero stats
 	}
 
+	tf, err := h.db.Queries.GetTwoFactor(context.Background(), int32(userID))
+	twoFactorEnabled := err == nil && tf.EnabledAt.Valid
+
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"user": map[string]interface{}{
-			"id":          u.ID,
-			"name":        u.Name,
-			"username":    u.Username,
-			"email":       u.Email,
-			"mobile":      u.Mobile,
-			"role":        u.Role,
-			"currency":    u.Currency,
-			"balance":     u.Balance,
-			"totalSpend":  u.TotalSpend,
-			"orderCount":  u.OrderCount,
-			"stats":       stats,
-			"hasPassword": passwordHash != "",
+			"id":               u.ID,
+			"name":             u.Name,
+			"username":         u.Username,
+			"email":            u.Email,
+			"mobile":           u.Mobile,
+			"role":             u.Role,
+			"currency":         u.Currency,
+			"balance":          u.Balance,
+			"heldBalance":      heldBalance,
+			"availableBalance": u.Balance - heldBalance,
+			"totalSpend":       u.TotalSpend,
+			"orderCount":       u.OrderCount,
+			"stats":            stats,
+			"hasPassword":      passwordHash != "",
+			"twoFactorEnabled": twoFactorEnabled,
 		},
 	})
 }
 
-// GoogleLogin redirects to Google
+// referralCookie carries a referral code through the Google sign-in
+const referralCookie = "referral_code"
+
+// GoogleLogin redirects to Google. A ?ref= referral code is kept in a
+// cookie until the callback.
 func (h *Handler) GoogleLogin(w http.ResponseWriter, r *http.Request) {
+	if ref := r.URL.Query().Get("ref"); ref != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     referralCookie,
+			Value:    ref,
+			MaxAge:   int((15 * time.Minute).Seconds()),
+			HttpOnly: true,
+			Secure:   os.Getenv("APP_ENV") == "production",
+			SameSite: http.SameSiteLaxMode,
+			Path:     "/",
+		})
+	}
 	url := googleOauthConfig.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
 	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 }
@@ -362,12 +408,14 @@ func (h *Handler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 		baseName = strings.ReplaceAll(baseName, ".", "_")
 		username := fmt.Sprintf("%s_%d", baseName, time.Now().Unix()) // e.g. digxofficial_1700000000
 
+		ip := clientIP(r)
 		newUserRow, err := h.db.Queries.CreateGoogleUser(context.Background(), sqlc.CreateGoogleUserParams{
 			Name:      pgtype.Text{String: googleUser.Name, Valid: true},
 			Email:     pgtype.Text{String: googleUser.Email, Valid: true},
 			GoogleID:  pgtype.Text{String: googleUser.ID, Valid: true},
 			AvatarUrl: pgtype.Text{String: googleUser.Picture, Valid: true},
 			Username:  pgtype.Text{String: username, Valid: true},
+			SignupIp:  ip,
 		})
 		userID = int(newUserRow.ID)
 		role = newUserRow.Role
@@ -377,7 +425,19 @@ func (h *Handler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Registration failed", http.StatusInternalServerError)
 			return
 		}
+
+		if c, err := r.Cookie(referralCookie); err == nil {
+			err = referral.Attribute(context.Background(), h.db.Queries, c.Value, referral.Signup{
+				UserID: newUserRow.ID,
+				IP:     ip,
+				Email:  googleUser.Email,
+			})
+			if err != nil {
+				log.Printf("Failed to record referral of user %d: %v", newUserRow.ID, err)
+			}
+		}
 	}
+	http.SetCookie(w, &http.Cookie{Name: referralCookie, Path: "/", MaxAge: -1})
 
 	// Generate JWT
 	expirationTime := time.Now().Add(24 * time.Hour * 7)
@@ -730,3 +790,20 @@ func getEnvOrDefault(key, fallback string) string {
 	}
 	return fallback
 }
+
+// clientIP is the address a request came from, as reported by the proxy in
+// front of the API when there is one
+func clientIP(r *http.Request) string {
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		first, _, _ := strings.Cut(fwd, ",")
+		return strings.TrimSpace(first)
+	}
+	if ip := r.Header.Get("X-Real-IP"); ip != "" {
+		return strings.TrimSpace(ip)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -4,13 +4,19 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"pablosmm/backend/internal/config"
 	"pablosmm/backend/internal/db"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/ledger"
+	"pablosmm/backend/internal/service/links"
 	"pablosmm/backend/internal/service/metadata"
+	"pablosmm/backend/internal/service/promo"
+	"pablosmm/backend/internal/service/refill"
 	"pablosmm/backend/internal/service/smm"
 	"strconv"
 	"strings"
@@ -26,14 +32,18 @@ type Handler struct {
 	cfg      *config.Config
 	smm      *smm.ProviderService
 	metadata *metadata.Service
+	links    *links.Service
+	refill   *refill.Service
 }
 
-func New(database *db.DB, cfg *config.Config, smmSvc *smm.ProviderService, metaSvc *metadata.Service) *Handler {
+func New(database *db.DB, cfg *config.Config, smmSvc *smm.ProviderService, metaSvc *metadata.Service, linkSvc *links.Service, refillSvc *refill.Service) *Handler {
 	return &Handler{
 		db:       database,
 		cfg:      cfg,
 		smm:      smmSvc,
 		metadata: metaSvc,
+		links:    linkSvc,
+		refill:   refillSvc,
 	}
 }
 
@@ -101,7 +111,7 @@ func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
 		APIKey string `json:"apiKey"`
 	}
 	var wallet struct {
-		Balance int `json:"balance"`
+		Balance int64 `json:"balance"`
 	}
 
 	profile, err := h.db.Queries.GetUserProfile(context.Background(), pgtype.Text{String: email, Valid: email != ""})
@@ -115,7 +125,7 @@ func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	user.Email = profile.Email.String
 	user.Role = profile.Role
 	user.APIKey = profile.ApiKey
-	wallet.Balance = int(profile.Balance)
+	wallet.Balance = profile.Balance
 
 	// Fetch detailed order stats
 	var stats struct {
@@ -143,67 +153,63 @@ func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
 			"email":      user.Email,
 			"role":       user.Role,
 			"apiKey":     user.APIKey,
-			"balance":    float64(wallet.Balance) / 100.0,
-			"totalSpend": float64(totalSpendCents) / 100.0,
+			"balance":    money.Paise(wallet.Balance).Major(),
+			"totalSpend": money.Paise(totalSpendCents).Major(),
 			"stats":      stats,
 		},
 	})
 }
 
-func validateLink(platform, serviceType, link string) error {
-	link = strings.TrimSpace(link)
-	if link == "" {
-		return fmt.Errorf("link is required")
+// validateLink parses the link for the service's platform, checks it against
+// the link rules for the service and returns the canonical target the order
+// will be placed against
+func (h *Handler) validateLink(service *smm.NormalizedSmmService, link string) (*links.Target, error) {
+	target, err := h.links.Parse(service.Platform, link)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.checkLinkRule(service.Platform, service.ServiceType, service.Variant, target); err != nil {
+		return nil, err
 	}
+	return target, nil
+}
 
-	linkLower := strings.ToLower(link)
+// duplicateTargetError is returned for an order against a target the same
+// service is already running for
+type duplicateTargetError struct {
+	target *links.Target
+}
 
-	switch platform {
-	case "instagram":
-		if !strings.Contains(linkLower, "instagram.com") {
-			return fmt.Errorf("link must be an Instagram URL")
-		}
-	case "facebook":
-		if !strings.Contains(linkLower, "facebook.com") && !strings.Contains(linkLower, "fb.watch") {
-			return fmt.Errorf("link must be a Facebook URL")
-		}
-	case "youtube":
-		if !strings.Contains(linkLower, "youtube.com") && !strings.Contains(linkLower, "youtu.be") {
-			return fmt.Errorf("link must be a YouTube URL")
-		}
-	case "tiktok":
-		if !strings.Contains(linkLower, "tiktok.com") {
-			return fmt.Errorf("link must be a TikTok URL")
-		}
-	case "telegram":
-		if !strings.Contains(linkLower, "t.me") && !strings.Contains(linkLower, "telegram.me") {
-			return fmt.Errorf("link must be a Telegram URL (t.me/...)")
-		}
-	case "x", "twitter":
-		if !strings.Contains(linkLower, "twitter.com") && !strings.Contains(linkLower, "x.com") {
-			return fmt.Errorf("link must be an X (Twitter) URL")
-		}
-	case "linkedin":
-		if !strings.Contains(linkLower, "linkedin.com") {
-			return fmt.Errorf("link must be a LinkedIn URL")
-		}
-	case "spotify":
-		if !strings.Contains(linkLower, "spotify.com") {
-			return fmt.Errorf("link must be a Spotify URL")
-		}
-	case "twitch":
-		if !strings.Contains(linkLower, "twitch.tv") {
-			return fmt.Errorf("link must be a Twitch URL")
-		}
-	case "discord":
-		if !strings.Contains(linkLower, "discord.gg") && !strings.Contains(linkLower, "discord.com") {
-			return fmt.Errorf("link must be a Discord URL")
-		}
-	}
+func (e *duplicateTargetError) Error() string {
+	return fmt.Sprintf("an order for this service is already in progress for %s, please wait until it completes", e.target.Canonical)
+}
 
+// checkDuplicateTarget rejects a new order when the same service is already
+// running against the same target, however the link was written
+func checkDuplicateTarget(ctx context.Context, q *sqlc.Queries, serviceID string, target *links.Target) error {
+	count, err := q.CountActiveOrdersForTarget(ctx, sqlc.CountActiveOrdersForTargetParams{
+		ServiceID: serviceID,
+		TargetID:  pgtype.Text{String: target.Key(), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("check duplicate orders for %s: %w", target.Key(), err)
+	}
+	if count > 0 {
+		return &duplicateTargetError{target: target}
+	}
 	return nil
 }
 
+// claimTarget checks for a duplicate again inside the order's transaction,
+// holding a lock on the service and target until it commits, so concurrent
+// orders for the same target cannot both pass the check
+func claimTarget(ctx context.Context, qtx *sqlc.Queries, serviceID string, target *links.Target) error {
+	if err := qtx.LockOrderTarget(ctx, serviceID+"|"+target.Key()); err != nil {
+		return fmt.Errorf("lock target %s: %w", target.Key(), err)
+	}
+	return checkDuplicateTarget(ctx, qtx, serviceID, target)
+}
+
 func (h *Handler) GetSingleOrder(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(int)
 	orderIDStr := chi.URLParam(r, "id")
@@ -214,23 +220,23 @@ func (h *Handler) GetSingleOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var o struct {
-		ID          int     `json:"id"`
-		ServiceID   string  `json:"serviceId"`
-		DisplayID   string  `json:"displayId"`
-		DisplayName string  `json:"serviceName"`
-		Amount      float64 `json:"charge"`
-		Quantity    int     `json:"quantity"`
-		Status      string  `json:"status"`
-		Date        string  `json:"date"`
-		UpdatedAt   string  `json:"updatedAt"`
-		Link        string  `json:"link"`
-		StartCount  int     `json:"startCount"`
-		Remains     int     `json:"remains"`
-		ServiceType string  `json:"serviceType"`
-		Category    string  `json:"category"`
-		PendingCancel bool  `json:"pendingCancel"`
-		PendingRefill bool  `json:"pendingRefill"`
-		RefillsRemaining int `json:"refillsRemaining"`
+		ID               int     `json:"id"`
+		ServiceID        string  `json:"serviceId"`
+		DisplayID        string  `json:"displayId"`
+		DisplayName      string  `json:"serviceName"`
+		Amount           float64 `json:"charge"`
+		Quantity         int     `json:"quantity"`
+		Status           string  `json:"status"`
+		Date             string  `json:"date"`
+		UpdatedAt        string  `json:"updatedAt"`
+		Link             string  `json:"link"`
+		StartCount       int     `json:"startCount"`
+		Remains          int     `json:"remains"`
+		ServiceType      string  `json:"serviceType"`
+		Category         string  `json:"category"`
+		PendingCancel    bool    `json:"pendingCancel"`
+		PendingRefill    bool    `json:"pendingRefill"`
+		RefillsRemaining *int    `json:"refillsRemaining"`
 	}
 
 	orderRow, err := h.db.Queries.GetSingleOrder(context.Background(), sqlc.GetSingleOrderParams{
@@ -245,7 +251,7 @@ func (h *Handler) GetSingleOrder(w http.ResponseWriter, r *http.Request) {
 
 	o.ID = int(orderRow.ID)
 	o.ServiceID = orderRow.ServiceID
-	o.Amount = float64(orderRow.AmountCents) / 100.0
+	o.Amount = money.Paise(orderRow.AmountCents).Major()
 	o.Quantity = int(orderRow.Quantity)
 	o.Status = orderRow.Status
 	o.Date = orderRow.CreatedAt.Time.Format(time.RFC3339)
@@ -259,8 +265,11 @@ func (h *Handler) GetSingleOrder(w http.ResponseWriter, r *http.Request) {
 	o.Category = orderRow.Category
 
 	// Initialize new fields
-	o.RefillsRemaining = int(orderRow.RefillsRemaining)
-	
+	if orderRow.RefillsRemaining.Valid {
+		refills := int(orderRow.RefillsRemaining.Int32)
+		o.RefillsRemaining = &refills
+	}
+
 	// We'll fetch pending requests to check flags
 	pendingReqs, _ := h.db.Queries.GetPendingOrderRequestsByOrder(context.Background(), int32(orderID))
 	for _, req := range pendingReqs {
@@ -346,8 +355,48 @@ func (h *Handler) GetSingleOrder(w http.ResponseWriter, r *http.Request) {
 		o.Status = "active"
 	}
 
+	type orderEvent struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+		Date    string `json:"date"`
+	}
+	history := []orderEvent{}
+	if events, err := h.db.Queries.GetOrderEvents(context.Background(), int32(orderID)); err == nil {
+		for _, e := range events {
+			history = append(history, orderEvent{
+				Type:    e.EventType,
+				Message: e.Message,
+				Date:    e.CreatedAt.Time.Format(time.RFC3339),
+			})
+		}
+	}
+
+	type orderRefill struct {
+		ID        int32  `json:"id"`
+		RefillID  string `json:"refillId"`
+		Status    string `json:"status"`
+		Reason    string `json:"reason,omitempty"`
+		Date      string `json:"date"`
+		UpdatedAt string `json:"updatedAt"`
+	}
+	refills := []orderRefill{}
+	if rows, err := h.db.Queries.GetOrderRefills(context.Background(), int32(orderID)); err == nil {
+		for _, row := range rows {
+			refills = append(refills, orderRefill{
+				ID:        row.ID,
+				RefillID:  row.ProviderRefillID.String,
+				Status:    refillDisplayStatus(row.RefillStatus, row.Status),
+				Reason:    row.DecisionReason.String,
+				Date:      row.CreatedAt.Time.Format(time.RFC3339),
+				UpdatedAt: row.UpdatedAt.Time.Format(time.RFC3339),
+			})
+		}
+	}
+
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"order": o,
+		"order":   o,
+		"history": history,
+		"refills": refills,
 	})
 }
 
@@ -357,6 +406,7 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		SourceServiceID string `json:"sourceServiceId"`
 		Quantity        int    `json:"quantity"`
 		Link            string `json:"link"`
+		PromoCode       string `json:"promoCode"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
@@ -374,13 +424,14 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	balanceCents32, err := h.db.Queries.GetWalletBalance(context.Background(), int32(userID))
-	balanceCents := int(balanceCents32)
+	// Funds on hold for other orders are not available to this one
+	funds, err := h.db.Queries.GetWalletFunds(context.Background(), int32(userID))
+	available := money.Paise(funds.Balance - funds.HeldCents)
 
 	if err != nil {
 		// Create wallet if not exists (auto-repair) or return error
 		// For now, assume 0 balance if not found
-		balanceCents = 0
+		available = money.Paise(0)
 	}
 
 	// 2. Compute price
@@ -390,9 +441,13 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The service is found by its source ID when one is sent. Everything
+	// after this uses the selected service's own IDs, so a serviceId naming
+	// a different service cannot pick the promo or duplicate checks.
 	var selectedService *smm.NormalizedSmmService // Use pointer to smm package struct
 	for _, s := range services {
-		if s.ID == body.ServiceID || s.SourceServiceID == body.SourceServiceID {
+		if (body.SourceServiceID != "" && s.SourceServiceID == body.SourceServiceID) ||
+			(body.SourceServiceID == "" && s.ID == body.ServiceID) {
 			selectedService = &s
 			break
 		}
@@ -402,33 +457,73 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Service not found", http.StatusBadRequest)
 		return
 	}
+	if body.ServiceID != "" && body.ServiceID != selectedService.ID {
+		http.Error(w, "Service IDs do not match", http.StatusBadRequest)
+		return
+	}
 
 	// VALIDATE LINK
-	if err := validateLink(selectedService.Platform, selectedService.ServiceType, body.Link); err != nil {
+	target, err := h.validateLink(selectedService, body.Link)
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	err = checkDuplicateTarget(context.Background(), h.db.Queries, selectedService.ID, target)
+	var duplicate *duplicateTargetError
+	if errors.As(err, &duplicate) {
+		http.Error(w, duplicate.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to check for duplicate orders: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
 
 	// Calculate Cost
-	// RatePer1000 is in INR (from catalog). Wallet is in INR (Paisa).
-	rateINR := selectedService.RatePer1000
-	totalINR := (rateINR * float64(body.Quantity)) / 1000.0
-	amountCents := int(totalINR * 100) // Convert to Paisa
+	price, err := selectedService.Price(body.Quantity)
+	if err != nil {
+		http.Error(w, "Failed to price order", http.StatusBadRequest)
+		return
+	}
 
-	if amountCents <= 0 {
-		amountCents = 1 // Minimum 1 paisa to prevent free orders due to rounding
+	// Apply the promo code, if any; the order is charged the discounted price
+	charge := price
+	promoOrder := promo.Order{ServiceID: selectedService.ID, Platform: selectedService.Platform, Price: price}
+	var discount promo.Discount
+	if body.PromoCode != "" {
+		discount, err = promo.QuoteOrder(context.Background(), h.db.Queries, int32(userID), body.PromoCode, promoOrder)
+		var invalidCode *promo.InvalidError
+		if errors.As(err, &invalidCode) {
+			http.Error(w, invalidCode.Reason, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to apply promo code %q: %v", body.PromoCode, err)
+			http.Error(w, "Failed to apply promo code", http.StatusInternalServerError)
+			return
+		}
+		charge = discount.Charge()
 	}
 
-	if balanceCents < amountCents {
+	if available.Cmp(charge) < 0 {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusPaymentRequired)
 		json.NewEncoder(w).Encode(map[string]string{
-			"error": fmt.Sprintf("Insufficient balance. Required: ₹%.2f, Available: ₹%.2f", float64(amountCents)/100.0, float64(balanceCents)/100.0),
+			"error": fmt.Sprintf("Insufficient balance. Required: %s, Available: %s", charge, available),
 		})
 		return
 	}
 
-	// 3. Transactional update: Create order and debit wallet
+	// Reject private or missing targets before charging the user
+	ownStartCount, err := h.preflightTarget(selectedService, target)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// 3. Transactional update: Create order and hold its price. The hold
+	// fails if a concurrent order spent the balance since the check above.
 	tx, err := h.db.Pool.Begin(context.Background())
 	if err != nil {
 		http.Error(w, "Database error", http.StatusInternalServerError)
@@ -437,29 +532,76 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	defer tx.Rollback(context.Background())
 
 	qtx := h.db.Queries.WithTx(tx)
-	err = qtx.DebitWallet(context.Background(), sqlc.DebitWalletParams{
-		Balance: int32(amountCents),
-		UserID:  int32(userID),
-	})
+	err = claimTarget(context.Background(), qtx, selectedService.ID, target)
+	if errors.As(err, &duplicate) {
+		http.Error(w, duplicate.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Failed to debit wallet", http.StatusInternalServerError)
+		log.Printf("Failed to check for duplicate orders: %v", err)
+		http.Error(w, "Database error", http.StatusInternalServerError)
 		return
 	}
-
+	var promoCodeID pgtype.Int4
+	if discount.CodeID != 0 {
+		// Check the code again under its lock so concurrent orders cannot
+		// take it past its limits
+		discount, err = promo.LockOrder(context.Background(), qtx, int32(userID), discount, promoOrder)
+		var invalidCode *promo.InvalidError
+		if errors.As(err, &invalidCode) {
+			http.Error(w, invalidCode.Reason, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to lock promo code %s: %v", discount.Code, err)
+			http.Error(w, "Failed to apply promo code", http.StatusInternalServerError)
+			return
+		}
+		charge = discount.Charge()
+		promoCodeID = pgtype.Int4{Int32: discount.CodeID, Valid: true}
+	}
 	orderID, err := qtx.InsertOrder(context.Background(), sqlc.InsertOrderParams{
 		UserID:           int32(userID),
-		ServiceID:        body.ServiceID,
+		ServiceID:        selectedService.ID,
 		Quantity:         int32(body.Quantity),
-		AmountCents:      int32(amountCents),
+		AmountCents:      charge.Minor,
 		Status:           "pending",
 		Link:             pgtype.Text{String: body.Link, Valid: true},
-		RefillsRemaining: pgtype.Int4{Int32: int32(selectedService.RefillLimit), Valid: true},
+		RefillsRemaining: selectedService.OrderRefills(),
 		ProviderKey:      pgtype.Text{String: selectedService.Source, Valid: true},
+		CanonicalLink:    pgtype.Text{String: target.Canonical, Valid: true},
+		TargetKind:       pgtype.Text{String: target.Kind, Valid: true},
+		TargetID:         pgtype.Text{String: target.Key(), Valid: true},
+		OwnStartCount:    ownStartCount,
+		PromoCodeID:      promoCodeID,
+		DiscountCents:    discount.Amount.Minor,
 	})
 	if err != nil {
 		http.Error(w, "Failed to create order", http.StatusInternalServerError)
 		return
 	}
+	if promoCodeID.Valid {
+		if err := promo.RecordOrder(context.Background(), qtx, int32(userID), orderID, discount); err != nil {
+			log.Printf("Failed to record promo code for order: %v", err)
+			http.Error(w, "Failed to create order", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err = ledger.HoldOrder(context.Background(), qtx, int32(userID), orderID, charge)
+	if errors.Is(err, ledger.ErrInsufficientFunds) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusPaymentRequired)
+		json.NewEncoder(w).Encode(map[string]string{
+			"error": fmt.Sprintf("Insufficient balance. Required: %s", charge),
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to hold funds for order: %v", err)
+		http.Error(w, "Failed to reserve funds", http.StatusInternalServerError)
+		return
+	}
 
 	if err := tx.Commit(context.Background()); err != nil {
 		http.Error(w, "Failed to commit transaction", http.StatusInternalServerError)
@@ -469,7 +611,7 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	// 4. Update sales count - Moved to after provider success to consolidate DB updates
 
 	// 5. Forward to SMM Provider
-	resp, placeErr := h.smm.PlaceOrder(selectedService.Source, body.SourceServiceID, strconv.Itoa(body.Quantity), body.Link)
+	resp, placeErr := h.smm.PlaceOrder(selectedService.Source, selectedService.SourceServiceID, strconv.Itoa(body.Quantity), target.Canonical)
 
 	// Check for provider Error (API failure or Logic failure)
 	var providerError string
@@ -480,23 +622,26 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if providerError != "" {
-		log.Printf("Provider failed for Order #%d: %s. Initiating Refund.", orderID, providerError)
+		log.Printf("Provider failed for Order #%d: %s. Releasing held funds.", orderID, providerError)
 
 		// REFUND LOGIC
 		// We start a new transaction since the previous one passed
-			rtx, rerr := h.db.Pool.Begin(context.Background())
+		rtx, rerr := h.db.Pool.Begin(context.Background())
 		if rerr == nil {
 			defer rtx.Rollback(context.Background())
 			rqtx := h.db.Queries.WithTx(rtx)
-			// 1. Credit Wallet back
-			_ = rqtx.CreditWallet(context.Background(), sqlc.CreditWalletParams{
-				Balance: int32(amountCents),
-				UserID:  int32(userID),
-			})
+			// 1. Release the hold; nothing was charged
+			rerr = ledger.ReleaseOrder(context.Background(), rqtx, orderID)
 			// 2. Completely delete the order so it doesn't clutter history since it failed instantly
-			_ = rqtx.DeleteOrder(context.Background(), int32(orderID))
-
-			rtx.Commit(context.Background())
+			if rerr == nil {
+				rerr = rqtx.DeleteOrder(context.Background(), int32(orderID))
+			}
+			if rerr == nil {
+				rerr = rtx.Commit(context.Background())
+			}
+		}
+		if rerr != nil {
+			log.Printf("CRITICAL: Failed to release Order #%d after provider error: %v", orderID, rerr)
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -526,18 +671,35 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		providerOrderID = ""
 	}
 
-	h.db.Queries.UpdateOrderProvider(context.Background(), sqlc.UpdateOrderProviderParams{
+	// Record the provider order first so it is synced even if charging fails;
+	// holds left active are charged by the watchdog's hold sweep
+	ctx := context.Background()
+	err = h.db.Queries.UpdateOrderProvider(ctx, sqlc.UpdateOrderProviderParams{
 		ProviderResp:    respJSON,
 		ProviderOrderID: pgtype.Text{String: providerOrderID, Valid: true},
 		Status:          "submitted",
 		ID:              int32(orderID),
 	})
+	if err != nil {
+		log.Printf("CRITICAL: Failed to record provider order %q for Order #%d: %v", providerOrderID, orderID, err)
+	}
+	ptx, err := h.db.Pool.Begin(ctx)
+	if err == nil {
+		defer ptx.Rollback(ctx)
+		err = ledger.CaptureOrder(ctx, h.db.Queries.WithTx(ptx), orderID)
+		if err == nil {
+			err = ptx.Commit(ctx)
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to charge Order #%d, the hold sweep will retry: %v", orderID, err)
+	}
 
 	// Increment purchase count in service_overrides
 	if providerOrderID != "" {
-		err = h.db.Queries.IncrementServicePurchaseCount(context.Background(), body.SourceServiceID)
+		err = h.db.Queries.IncrementServicePurchaseCount(context.Background(), selectedService.SourceServiceID)
 		if err != nil {
-			log.Printf("Failed to increment purchase count for service %s: %v", body.SourceServiceID, err)
+			log.Printf("Failed to increment purchase count for service %s: %v", selectedService.SourceServiceID, err)
 		}
 	}
 
@@ -601,25 +763,109 @@ func (h *Handler) UpdateServiceOverride(w http.ResponseWriter, r *http.Request)
 	}
 
 	err := h.db.Queries.UpsertServiceOverride(context.Background(), sqlc.UpsertServiceOverrideParams{
-		SourceServiceID:     body.SourceServiceID,
-		DisplayName:         func() pgtype.Text { if body.DisplayName != nil { return pgtype.Text{String: *body.DisplayName, Valid: true} } else { return pgtype.Text{} } }(),
-		DisplayDescription:  func() pgtype.Text { if body.DisplayDescription != nil { return pgtype.Text{String: *body.DisplayDescription, Valid: true} } else { return pgtype.Text{} } }(),
-		RateMultiplier:      pgtype.Float8{Float64: body.RateMultiplier, Valid: true},
-		IsHidden:            pgtype.Bool{Bool: body.IsHidden, Valid: true},
-		Category:            func() pgtype.Text { if body.Category != nil { return pgtype.Text{String: *body.Category, Valid: true} } else { return pgtype.Text{} } }(),
-		Tags:                body.Tags,
-		ProviderCategory:    func() pgtype.Text { if body.ProviderCategory != nil { return pgtype.Text{String: *body.ProviderCategory, Valid: true} } else { return pgtype.Text{} } }(),
-		DisplayID:           pgtype.Text{String: body.DisplayID, Valid: true},
-		Refill:              func() pgtype.Bool { if body.Refill != nil { return pgtype.Bool{Bool: *body.Refill, Valid: true} } else { return pgtype.Bool{} } }(),
-		Cancel:              func() pgtype.Bool { if body.Cancel != nil { return pgtype.Bool{Bool: *body.Cancel, Valid: true} } else { return pgtype.Bool{} } }(),
-		Dripfeed:            func() pgtype.Bool { if body.Dripfeed != nil { return pgtype.Bool{Bool: *body.Dripfeed, Valid: true} } else { return pgtype.Bool{} } }(),
-		ServiceType:         func() pgtype.Text { if body.Type != nil { return pgtype.Text{String: *body.Type, Valid: true} } else { return pgtype.Text{} } }(),
-		Targeting:           func() pgtype.Text { if body.Targeting != nil { return pgtype.Text{String: *body.Targeting, Valid: true} } else { return pgtype.Text{} } }(),
-		Quality:             func() pgtype.Text { if body.Quality != nil { return pgtype.Text{String: *body.Quality, Valid: true} } else { return pgtype.Text{} } }(),
-		Stability:           func() pgtype.Text { if body.Stability != nil { return pgtype.Text{String: *body.Stability, Valid: true} } else { return pgtype.Text{} } }(),
-		RefillLimit:         func() pgtype.Int4 { if body.RefillLimit != nil { return pgtype.Int4{Int32: *body.RefillLimit, Valid: true} } else { return pgtype.Int4{Int32: 3, Valid: true} } }(),
-		CustomInputRequired: func() pgtype.Bool { if body.CustomInputRequired != nil { return pgtype.Bool{Bool: *body.CustomInputRequired, Valid: true} } else { return pgtype.Bool{} } }(),
-		CustomInputLabel:    func() pgtype.Text { if body.CustomInputLabel != nil { return pgtype.Text{String: *body.CustomInputLabel, Valid: true} } else { return pgtype.Text{} } }(),
+		SourceServiceID: body.SourceServiceID,
+		DisplayName: func() pgtype.Text {
+			if body.DisplayName != nil {
+				return pgtype.Text{String: *body.DisplayName, Valid: true}
+			} else {
+				return pgtype.Text{}
+			}
+		}(),
+		DisplayDescription: func() pgtype.Text {
+			if body.DisplayDescription != nil {
+				return pgtype.Text{String: *body.DisplayDescription, Valid: true}
+			} else {
+				return pgtype.Text{}
+			}
+		}(),
+		RateMultiplier: pgtype.Float8{Float64: body.RateMultiplier, Valid: true},
+		IsHidden:       pgtype.Bool{Bool: body.IsHidden, Valid: true},
+		Category: func() pgtype.Text {
+			if body.Category != nil {
+				return pgtype.Text{String: *body.Category, Valid: true}
+			} else {
+				return pgtype.Text{}
+			}
+		}(),
+		Tags: body.Tags,
+		ProviderCategory: func() pgtype.Text {
+			if body.ProviderCategory != nil {
+				return pgtype.Text{String: *body.ProviderCategory, Valid: true}
+			} else {
+				return pgtype.Text{}
+			}
+		}(),
+		DisplayID: pgtype.Text{String: body.DisplayID, Valid: true},
+		Refill: func() pgtype.Bool {
+			if body.Refill != nil {
+				return pgtype.Bool{Bool: *body.Refill, Valid: true}
+			} else {
+				return pgtype.Bool{}
+			}
+		}(),
+		Cancel: func() pgtype.Bool {
+			if body.Cancel != nil {
+				return pgtype.Bool{Bool: *body.Cancel, Valid: true}
+			} else {
+				return pgtype.Bool{}
+			}
+		}(),
+		Dripfeed: func() pgtype.Bool {
+			if body.Dripfeed != nil {
+				return pgtype.Bool{Bool: *body.Dripfeed, Valid: true}
+			} else {
+				return pgtype.Bool{}
+			}
+		}(),
+		ServiceType: func() pgtype.Text {
+			if body.Type != nil {
+				return pgtype.Text{String: *body.Type, Valid: true}
+			} else {
+				return pgtype.Text{}
+			}
+		}(),
+		Targeting: func() pgtype.Text {
+			if body.Targeting != nil {
+				return pgtype.Text{String: *body.Targeting, Valid: true}
+			} else {
+				return pgtype.Text{}
+			}
+		}(),
+		Quality: func() pgtype.Text {
+			if body.Quality != nil {
+				return pgtype.Text{String: *body.Quality, Valid: true}
+			} else {
+				return pgtype.Text{}
+			}
+		}(),
+		Stability: func() pgtype.Text {
+			if body.Stability != nil {
+				return pgtype.Text{String: *body.Stability, Valid: true}
+			} else {
+				return pgtype.Text{}
+			}
+		}(),
+		RefillLimit: func() pgtype.Int4 {
+			if body.RefillLimit != nil {
+				return pgtype.Int4{Int32: *body.RefillLimit, Valid: true}
+			} else {
+				return pgtype.Int4{Int32: 3, Valid: true}
+			}
+		}(),
+		CustomInputRequired: func() pgtype.Bool {
+			if body.CustomInputRequired != nil {
+				return pgtype.Bool{Bool: *body.CustomInputRequired, Valid: true}
+			} else {
+				return pgtype.Bool{}
+			}
+		}(),
+		CustomInputLabel: func() pgtype.Text {
+			if body.CustomInputLabel != nil {
+				return pgtype.Text{String: *body.CustomInputLabel, Valid: true}
+			} else {
+				return pgtype.Text{}
+			}
+		}(),
 	})
 
 	if err != nil {
@@ -714,9 +960,15 @@ func (h *Handler) BulkUpdateServiceOverrides(w http.ResponseWriter, r *http.Requ
 			Column14:        body.Targeting,
 			Column15:        body.Quality,
 			Column16:        body.Stability,
-			Column17:        func() pgtype.Int4 { if body.RefillLimit != nil { return pgtype.Int4{Int32: *body.RefillLimit, Valid: true} } else { return pgtype.Int4{Int32: 3, Valid: true} } }(),
-			Column18:        body.CustomInputRequired,
-			Column19:        body.CustomInputLabel,
+			Column17: func() pgtype.Int4 {
+				if body.RefillLimit != nil {
+					return pgtype.Int4{Int32: *body.RefillLimit, Valid: true}
+				} else {
+					return pgtype.Int4{Int32: 3, Valid: true}
+				}
+			}(),
+			Column18: body.CustomInputRequired,
+			Column19: body.CustomInputLabel,
 		})
 		if err != nil {
 			log.Printf("Bulk update failed for %s: %v", id, err)
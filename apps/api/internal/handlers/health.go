@@ -1,22 +1,60 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"net/http"
+	"time"
+
+	"pablosmm/backend/internal/service/jobs"
 )
 
-// HealthCheck is a simple endpoint to test connectivity
+// HealthCheck is a simple endpoint to test connectivity. It also reports
+// which instance last took each background job.
 func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	log.Printf("🏥 [HealthCheck] Request from: %s | Origin: %s | User-Agent: %s",
 		r.RemoteAddr,
 		r.Header.Get("Origin"),
 		r.Header.Get("User-Agent"))
 
+	type jobInfo struct {
+		Name       string `json:"name"`
+		Holder     string `json:"holder"`
+		Running    bool   `json:"running"`
+		StartedAt  string `json:"started_at"`
+		FinishedAt string `json:"finished_at,omitempty"`
+		ExpiresAt  string `json:"expires_at,omitempty"`
+	}
+
+	jobList := []jobInfo{}
+	if leases, err := h.db.Queries.ListJobLeases(context.Background()); err == nil {
+		for _, l := range leases {
+			j := jobInfo{
+				Name:      l.Name,
+				Holder:    l.Holder,
+				Running:   l.Running,
+				StartedAt: l.StartedAt.Time.Format(time.RFC3339),
+			}
+			if l.FinishedAt.Valid {
+				j.FinishedAt = l.FinishedAt.Time.Format(time.RFC3339)
+			}
+			if l.Running {
+				// A running lease past this belongs to an instance that died
+				j.ExpiresAt = l.ExpiresAt.Time.Format(time.RFC3339)
+			}
+			jobList = append(jobList, j)
+		}
+	} else {
+		log.Printf("[HealthCheck] Failed to list job leases: %v", err)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":  "ok",
-		"message": "Backend is reachable!",
-		"your_ip": r.RemoteAddr,
+		"status":   "ok",
+		"message":  "Backend is reachable!",
+		"your_ip":  r.RemoteAddr,
+		"instance": jobs.InstanceID(),
+		"jobs":     jobList,
 	})
 }
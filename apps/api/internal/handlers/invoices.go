@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/invoice"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// BillingProfileRes is what is printed as the customer on the user's
+// invoices
+type BillingProfileRes struct {
+	BillingName    string `json:"billing_name"`
+	GSTIN          string `json:"gstin"`
+	BillingAddress string `json:"billing_address"`
+	BillingState   string `json:"billing_state"`
+}
+
+// InvoiceRes is an invoice as listed to users and admins
+type InvoiceRes struct {
+	ID            int32   `json:"id"`
+	InvoiceNumber string  `json:"invoice_number"`
+	UserID        int32   `json:"user_id"`
+	TransactionID int32   `json:"transaction_id"`
+	PaymentMethod string  `json:"payment_method"`
+	CustomerName  string  `json:"customer_name"`
+	CustomerGSTIN string  `json:"customer_gstin,omitempty"`
+	PlaceOfSupply string  `json:"place_of_supply"`
+	GSTPct        float64 `json:"gst_pct"`
+	Taxable       float64 `json:"taxable"`
+	CGST          float64 `json:"cgst"`
+	SGST          float64 `json:"sgst"`
+	IGST          float64 `json:"igst"`
+	Total         float64 `json:"total"`
+	IssuedAt      string  `json:"issued_at"`
+}
+
+func newInvoiceRes(inv sqlc.Invoice) InvoiceRes {
+	return InvoiceRes{
+		ID:            inv.ID,
+		InvoiceNumber: inv.InvoiceNumber,
+		UserID:        inv.UserID,
+		TransactionID: inv.TransactionID,
+		PaymentMethod: inv.PaymentMethod,
+		CustomerName:  inv.CustomerName,
+		CustomerGSTIN: inv.CustomerGstin,
+		PlaceOfSupply: inv.PlaceOfSupply,
+		GSTPct:        inv.GstPct,
+		Taxable:       money.Paise(inv.TaxableCents).Major(),
+		CGST:          money.Paise(inv.CgstCents).Major(),
+		SGST:          money.Paise(inv.SgstCents).Major(),
+		IGST:          money.Paise(inv.IgstCents).Major(),
+		Total:         money.Paise(inv.TotalCents).Major(),
+		IssuedAt:      inv.IssuedAt.Time.Format(time.RFC3339),
+	}
+}
+
+// GetBillingProfile returns the user's billing details
+func (h *Handler) GetBillingProfile(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(int)
+	p, err := h.db.Queries.GetBillingProfile(context.Background(), int32(userID))
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BillingProfileRes{
+		BillingName:    p.BillingName,
+		GSTIN:          p.Gstin,
+		BillingAddress: p.BillingAddress,
+		BillingState:   p.BillingState,
+	})
+}
+
+// UpdateBillingProfile sets the user's billing details. With a GSTIN the
+// state is the one it was registered in; without one the state can be
+// given as its two digit GST code. Invoices already issued keep the details
+// they were issued with.
+func (h *Handler) UpdateBillingProfile(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(int)
+	var req BillingProfileRes
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.BillingName = strings.TrimSpace(req.BillingName)
+	req.GSTIN = invoice.NormalizeGSTIN(req.GSTIN)
+	req.BillingAddress = strings.TrimSpace(req.BillingAddress)
+	req.BillingState = strings.TrimSpace(req.BillingState)
+
+	if len(req.BillingName) > 200 || len(req.BillingAddress) > 500 {
+		http.Error(w, "Billing name or address is too long", http.StatusBadRequest)
+		return
+	}
+	if req.GSTIN != "" {
+		if !invoice.ValidGSTIN(req.GSTIN) {
+			http.Error(w, "Invalid GSTIN", http.StatusBadRequest)
+			return
+		}
+		req.BillingState = req.GSTIN[:2]
+	}
+	if _, ok := invoice.States[req.BillingState]; req.BillingState != "" && !ok {
+		http.Error(w, "Invalid billing state", http.StatusBadRequest)
+		return
+	}
+
+	err := h.db.Queries.UpdateBillingProfile(context.Background(), sqlc.UpdateBillingProfileParams{
+		ID:             int32(userID),
+		BillingName:    req.BillingName,
+		Gstin:          req.GSTIN,
+		BillingAddress: req.BillingAddress,
+		BillingState:   req.BillingState,
+	})
+	if err != nil {
+		log.Printf("Update billing profile failed for user %d: %v", userID, err)
+		http.Error(w, "Failed to update billing profile", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(req)
+}
+
+// GetInvoices lists the user's invoices, newest first, optionally for one
+// ?month=YYYY-MM
+func (h *Handler) GetInvoices(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(int)
+	h.writeInvoices(w, r, pgtype.Int4{Int32: int32(userID), Valid: true})
+}
+
+// GetInvoiceHTML returns one of the user's invoices as a printable page
+func (h *Handler) GetInvoiceHTML(w http.ResponseWriter, r *http.Request) {
+	if inv, ok := h.userInvoice(w, r); ok {
+		writeInvoiceHTML(w, inv)
+	}
+}
+
+// GetInvoicePDF downloads one of the user's invoices as a PDF
+func (h *Handler) GetInvoicePDF(w http.ResponseWriter, r *http.Request) {
+	if inv, ok := h.userInvoice(w, r); ok {
+		writeInvoicePDF(w, inv)
+	}
+}
+
+// GetInvoiceStatement bundles the user's invoices for ?month=YYYY-MM into
+// one document, a PDF or with ?format=html a printable page
+func (h *Handler) GetInvoiceStatement(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(int)
+	month := r.URL.Query().Get("month")
+	from, to, err := invoice.Month(month)
+	if err != nil {
+		http.Error(w, "Invalid month, expected YYYY-MM", http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "pdf" && format != "html" {
+		http.Error(w, "Invalid format", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	profile, err := h.db.Queries.GetBillingProfile(ctx, int32(userID))
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	invoices, err := h.db.Queries.ListInvoices(ctx, sqlc.ListInvoicesParams{
+		UserID:     pgtype.Int4{Int32: int32(userID), Valid: true},
+		IssuedFrom: pgtype.Timestamptz{Time: from, Valid: true},
+		IssuedTo:   pgtype.Timestamptz{Time: to, Valid: true},
+		Lim:        1000,
+	})
+	if err != nil {
+		log.Printf("Error fetching invoices for user %d: %v", userID, err)
+		http.Error(w, "Failed to fetch invoices", http.StatusInternalServerError)
+		return
+	}
+	// The statement reads in the order the invoices were issued
+	for i, j := 0, len(invoices)-1; i < j; i, j = i+1, j-1 {
+		invoices[i], invoices[j] = invoices[j], invoices[i]
+	}
+	name := profile.BillingName
+	if name == "" {
+		name = profile.Name
+	}
+	statement := invoice.Statement{Month: from, Name: name, Email: profile.Email, Invoices: invoices}
+
+	if format == "html" {
+		page, err := statement.HTML()
+		if err != nil {
+			log.Printf("Error rendering statement for user %d: %v", userID, err)
+			http.Error(w, "Failed to render statement", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="invoices-%s.pdf"`, month))
+	w.Write(statement.PDF())
+}
+
+// userInvoice loads the invoice in the URL, which must be the user's own
+func (h *Handler) userInvoice(w http.ResponseWriter, r *http.Request) (sqlc.Invoice, bool) {
+	userID := r.Context().Value("userID").(int)
+	inv, ok := h.invoiceFromURL(w, r)
+	if ok && inv.UserID != int32(userID) {
+		http.Error(w, "Invoice not found", http.StatusNotFound)
+		return inv, false
+	}
+	return inv, ok
+}
+
+func (h *Handler) invoiceFromURL(w http.ResponseWriter, r *http.Request) (sqlc.Invoice, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return sqlc.Invoice{}, false
+	}
+	inv, err := h.db.Queries.GetInvoice(context.Background(), int32(id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Invoice not found", http.StatusNotFound)
+		return inv, false
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return inv, false
+	}
+	return inv, true
+}
+
+func (h *Handler) writeInvoices(w http.ResponseWriter, r *http.Request, userID pgtype.Int4) {
+	arg := sqlc.ListInvoicesParams{UserID: userID, Lim: 500}
+	if month := r.URL.Query().Get("month"); month != "" {
+		from, to, err := invoice.Month(month)
+		if err != nil {
+			http.Error(w, "Invalid month, expected YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		arg.IssuedFrom = pgtype.Timestamptz{Time: from, Valid: true}
+		arg.IssuedTo = pgtype.Timestamptz{Time: to, Valid: true}
+	}
+	rows, err := h.db.Queries.ListInvoices(context.Background(), arg)
+	if err != nil {
+		log.Printf("Error fetching invoices: %v", err)
+		http.Error(w, "Failed to fetch invoices", http.StatusInternalServerError)
+		return
+	}
+	res := make([]InvoiceRes, 0, len(rows))
+	for _, inv := range rows {
+		res = append(res, newInvoiceRes(inv))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+func writeInvoiceHTML(w http.ResponseWriter, inv sqlc.Invoice) {
+	page, err := invoice.HTML(inv)
+	if err != nil {
+		log.Printf("Error rendering invoice #%d: %v", inv.ID, err)
+		http.Error(w, "Failed to render invoice", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+func writeInvoicePDF(w http.ResponseWriter, inv sqlc.Invoice) {
+	filename := strings.ReplaceAll(inv.InvoiceNumber, "/", "-") + ".pdf"
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Write(invoice.PDF(inv))
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -11,8 +12,14 @@ import (
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/ledger"
+	"pablosmm/backend/internal/service/refill"
+	"pablosmm/backend/internal/service/refund"
+	"pablosmm/backend/internal/service/smm"
 )
 
 func jsonError(w http.ResponseWriter, msg string, code int) {
@@ -47,16 +54,16 @@ func (h *Handler) GetOrders(w http.ResponseWriter, r *http.Request) {
 	}
 
 	type OrderRes struct {
-		ID          int     `json:"id"`
-		ServiceID   string  `json:"serviceId"`   // Original Source ID
-		DisplayID   string  `json:"displayId"`   // Custom Display ID
-		DisplayName string  `json:"serviceName"` // Custom Name
-		Amount      float64 `json:"charge"`
-		Quantity    int     `json:"quantity"`
-		Status      string  `json:"status"`
-		Date        string  `json:"date"`
-		Link        string  `json:"link"`
-		StartCount  int     `json:"startCount"`
+		ID            int     `json:"id"`
+		ServiceID     string  `json:"serviceId"`   // Original Source ID
+		DisplayID     string  `json:"displayId"`   // Custom Display ID
+		DisplayName   string  `json:"serviceName"` // Custom Name
+		Amount        float64 `json:"charge"`
+		Quantity      int     `json:"quantity"`
+		Status        string  `json:"status"`
+		Date          string  `json:"date"`
+		Link          string  `json:"link"`
+		StartCount    int     `json:"startCount"`
 		Remains       int     `json:"remains"`
 		ServiceType   string  `json:"serviceType"`
 		Category      string  `json:"category"`
@@ -90,7 +97,7 @@ func (h *Handler) GetOrders(w http.ResponseWriter, r *http.Request) {
 		var o OrderRes
 		o.ID = int(row.ID)
 		o.ServiceID = row.ServiceID
-		o.Amount = float64(row.AmountCents) / 100.0
+		o.Amount = money.Paise(row.AmountCents).Major()
 		o.Quantity = int(row.Quantity)
 		o.Status = row.Status
 		o.Date = row.CreatedAt.Time.Format(time.RFC3339)
@@ -186,7 +193,7 @@ func (h *Handler) CancelOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	status := orderRow.Status
-	amountCents := int(orderRow.AmountCents)
+	amountCents := orderRow.AmountCents
 	providerOrderID := orderRow.ProviderOrderID
 
 	if status == "canceled" || status == "completed" {
@@ -217,45 +224,56 @@ func (h *Handler) CancelOrder(w http.ResponseWriter, r *http.Request) {
 			jsonError(w, "Failed to submit cancellation request", http.StatusInternalServerError)
 			return
 		}
-		
+
 		tx.Commit(context.Background())
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"status":     "success",
-			"message":    "Cancellation request submitted. Our team will review it.",
+			"status":  "success",
+			"message": "Cancellation request submitted. Our team will review it.",
 		})
 		return
 	}
 
-	err = qtx.CancelOrder(context.Background(), int32(orderID))
+	order, err := qtx.GetOrderForApproval(context.Background(), int32(orderID))
 	if err != nil {
-		jsonError(w, "Failed to update order", http.StatusInternalServerError)
+		jsonError(w, "Order not found", http.StatusNotFound)
 		return
 	}
 
-	err = qtx.UpsertWalletBalance(context.Background(), sqlc.UpsertWalletBalanceParams{
-		UserID:  int32(userID),
-		Balance: int32(amountCents),
-	})
+	rule, err := refund.RuleFor(context.Background(), h.db.Queries, order.ServiceID)
 	if err != nil {
-		log.Printf("Refund failed: %v", err)
-		jsonError(w, "Refund process failed", http.StatusInternalServerError)
-		return
+		log.Printf("Failed to load refund rule for order #%d: %v", orderID, err)
+		rule = refund.DefaultRule()
 	}
+	result := refund.Calculate(rule, refund.Input{
+		Kind:          refund.KindCancel,
+		AmountCents:   amountCents,
+		Quantity:      int(order.Quantity),
+		Remains:       refund.UndeliveredQuantity(status, int(order.Quantity), int(order.Remains)),
+		RefundedCents: order.RefundedAmount,
+	})
 
-	newBalance, _ := qtx.GetWalletBalance(context.Background(), int32(userID))
-
-	refundAmount := float64(amountCents) / 100.0
-	err = qtx.InsertTransaction(context.Background(), sqlc.InsertTransactionParams{
-		UserID:      pgtype.Int4{Int32: int32(userID), Valid: true},
-		Amount:      func() pgtype.Numeric { n := pgtype.Numeric{}; n.Scan(fmt.Sprintf("%f", refundAmount)); return n }(),
-		Type:        "credit",
-		Description: pgtype.Text{String: fmt.Sprintf("Refund for Order #%d", orderID), Valid: true},
+	err = qtx.CancelOrderWithRefund(context.Background(), sqlc.CancelOrderWithRefundParams{
+		ID:             int32(orderID),
+		RefundedAmount: pgtype.Int8{Int64: result.Cents, Valid: true},
 	})
+	if err != nil {
+		jsonError(w, "Failed to update order", http.StatusInternalServerError)
+		return
+	}
 
+	err = refund.Apply(context.Background(), qtx, int32(orderID), int32(userID), refund.KindCancel, rule, result, fmt.Sprintf("Refund for Order #%d", orderID))
 	if err != nil {
-		jsonError(w, "Transaction log failed", http.StatusInternalServerError)
+		log.Printf("Refund failed: %v", err)
+		jsonError(w, "Refund process failed", http.StatusInternalServerError)
 		return
 	}
+	if err := ledger.SettleOrder(context.Background(), qtx, int32(orderID)); err != nil {
+		log.Printf("Settle failed: %v", err)
+		jsonError(w, "Refund process failed", http.StatusInternalServerError)
+		return
+	}
+
+	newBalance, _ := qtx.GetWalletBalance(context.Background(), int32(userID))
 
 	if err := tx.Commit(context.Background()); err != nil {
 		jsonError(w, "Commit failed", http.StatusInternalServerError)
@@ -265,7 +283,8 @@ func (h *Handler) CancelOrder(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status":     "success",
 		"message":    "Order canceled and refunded",
-		"newBalance": float64(newBalance) / 100.0,
+		"refunded":   money.Paise(result.Cents).Major(),
+		"newBalance": money.Paise(newBalance).Major(),
 	})
 }
 
@@ -275,78 +294,84 @@ func (h *Handler) RefillOrder(w http.ResponseWriter, r *http.Request) {
 	orderIDStr := chi.URLParam(r, "id")
 	orderID, _ := strconv.Atoi(orderIDStr)
 
-	tx, err := h.db.Pool.Begin(context.Background())
-	if err != nil {
-		jsonError(w, "Database error", http.StatusInternalServerError)
+	if _, err := h.submitRefill(r.Context(), userID, orderID); err != nil {
+		var notEligible *refill.EligibilityError
+		if errors.Is(err, errOrderNotFound) {
+			jsonError(w, "Order not found", http.StatusNotFound)
+		} else if errors.As(err, &notEligible) {
+			jsonError(w, notEligible.Reason, http.StatusBadRequest)
+		} else {
+			log.Printf("Failed to submit refill for order #%d: %v", orderID, err)
+			jsonError(w, "Failed to submit refill request", http.StatusInternalServerError)
+		}
 		return
 	}
-	defer tx.Rollback(context.Background())
 
-	qtx := h.db.Queries.WithTx(tx)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "success",
+		"message": "Refill request submitted. Our team will review it.",
+	})
+}
 
-	orderRow, err := qtx.GetOrderForCancel(context.Background(), sqlc.GetOrderForCancelParams{
-		ID:     int32(orderID),
-		UserID: int32(userID),
+var errOrderNotFound = errors.New("order not found")
+
+// submitRefill checks the service refill policy, asks the provider for a
+// refill and records the request. It returns the order request ID.
+func (h *Handler) submitRefill(ctx context.Context, userID, orderID int) (int32, error) {
+	order, requestID, err := h.refill.Reserve(ctx, int32(orderID), func(order sqlc.GetOrderForAutoRefillRow, _ smm.RefillPolicy) error {
+		if order.UserID != int32(userID) {
+			return errOrderNotFound
+		}
+		return nil
 	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, errOrderNotFound
+	}
 	if err != nil {
-		jsonError(w, "Order not found", http.StatusNotFound)
-		return
+		return 0, err
 	}
-	providerOrderID := orderRow.ProviderOrderID
 
-	pending, err := qtx.GetPendingOrderRequestsByOrder(context.Background(), int32(orderID))
-	if err == nil {
-		for _, req := range pending {
-			if req.RequestType == "refill" {
-				jsonError(w, "Refill request already pending", http.StatusBadRequest)
-				return
-			}
-		}
+	event := sqlc.InsertOrderEventParams{
+		EventType: "refill_requested",
+		Message:   "Refill requested by customer",
+	}
+	if order.ProviderOrderID == "" {
+		return requestID, h.refill.Unsent(ctx, order.ID, requestID, nil, event)
 	}
 
-	var providerRespJSON string
-	if providerOrderID != "" {
-		resp, err := h.smm.RefillOrder(orderRow.ProviderKey, providerOrderID)
-		if err == nil && resp != nil {
-			log.Printf("Provider refill response for #%s: %v", providerOrderID, resp)
-			b, _ := json.Marshal(resp)
-			providerRespJSON = string(b)
-			
-			// If provider rejected it, return the error directly and don't deduct refills
-			if errorMsg, ok := resp["error"].(string); ok {
-				jsonError(w, errorMsg, http.StatusBadRequest)
-				return
-			}
-		} else {
-			log.Printf("Provider refill failed/unsupported for #%s: %v", providerOrderID, err)
-			providerRespJSON = fmt.Sprintf(`{"error": "%v"}`, err)
+	// The order is no longer locked while the provider answers
+	resp, err := h.smm.RefillOrder(order.ProviderKey, order.ProviderOrderID)
+	if err != nil || resp == nil {
+		log.Printf("Provider refill failed/unsupported for #%s: %v", order.ProviderOrderID, err)
+		if err == nil {
+			err = fmt.Errorf("empty response")
 		}
+		return requestID, h.refill.Unsent(ctx, order.ID, requestID, err, event)
 	}
+	log.Printf("Provider refill response for #%s: %v", order.ProviderOrderID, resp)
 
-	_, err = qtx.CreateOrderRequest(context.Background(), sqlc.CreateOrderRequestParams{
-		OrderID:          int32(orderID),
-		UserID:           int32(userID),
-		RequestType:      "refill",
-		ProviderResponse: pgtype.Text{String: providerRespJSON, Valid: providerRespJSON != ""},
-	})
-	if err != nil {
-		log.Printf("Failed to create refill request: %v", err)
-		jsonError(w, "Failed to submit refill request", http.StatusInternalServerError)
-		return
-	}
-	
-	err = qtx.DecrementOrderRefills(context.Background(), int32(orderID))
-	if err != nil {
-		log.Printf("Failed to decrement refills: %v", err)
+	// If provider rejected it, return the error directly and give the refill back
+	if errorMsg, ok := resp["error"].(string); ok {
+		err := h.refill.Refused(ctx, order.ID, requestID, resp, errorMsg, sqlc.InsertOrderEventParams{
+			EventType: "refill_refused",
+			Message:   "Refill refused by the provider: " + errorMsg,
+		})
+		if err != nil {
+			return 0, err
+		}
+		return 0, &refill.EligibilityError{Reason: errorMsg}
 	}
+	return requestID, h.refill.Accepted(ctx, order.ID, requestID, resp, false, event)
+}
 
-	if err := tx.Commit(context.Background()); err != nil {
-		jsonError(w, "Commit failed", http.StatusInternalServerError)
-		return
+// refillDisplayStatus reports a refill as pending, in_progress, completed or
+// rejected. Refills without a provider refill ID fall back to the review status.
+func refillDisplayStatus(refillStatus, requestStatus pgtype.Text) string {
+	if refillStatus.Valid && refillStatus.String != "" {
+		return refillStatus.String
 	}
-
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":  "success",
-		"message": "Refill request submitted. Our team will review it.",
-	})
+	if requestStatus.String == "rejected" {
+		return "rejected"
+	}
+	return "pending"
 }
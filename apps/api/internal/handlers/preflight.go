@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"pablosmm/backend/internal/service/links"
+	"pablosmm/backend/internal/service/metadata"
+	"pablosmm/backend/internal/service/smm"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// platforms the metadata scraper understands well enough to trust
+var preflightPlatforms = map[string]bool{
+	"instagram": true,
+	"tiktok":    true,
+	"youtube":   true,
+}
+
+// preflightTarget checks that the target exists and is public before the
+// user is charged, and returns the counter we observed as our own start
+// count. It is enabled by the preflight_target_check setting and fails open
+// when the page cannot be scraped, so a flaky platform never blocks orders.
+func (h *Handler) preflightTarget(service *smm.NormalizedSmmService, target *links.Target) (pgtype.Int4, error) {
+	enabled, err := h.db.Queries.GetSetting(context.Background(), "preflight_target_check")
+	if err != nil || enabled != "true" {
+		return pgtype.Int4{}, nil
+	}
+	if !preflightPlatforms[target.Platform] {
+		return pgtype.Int4{}, nil
+	}
+
+	meta, err := h.metadata.Fetch(target.Canonical)
+	if err != nil {
+		if errors.Is(err, metadata.ErrNotFound) {
+			return pgtype.Int4{}, fmt.Errorf("the link could not be found, it may be deleted or mistyped. Please check the link and try again")
+		}
+		log.Printf("[Preflight] Skipping check for %s: %v", target.Canonical, err)
+		return pgtype.Int4{}, nil
+	}
+
+	if meta.IsPrivate {
+		return pgtype.Int4{}, fmt.Errorf("this account is private. Make it public before ordering, otherwise the order cannot be delivered")
+	}
+
+	if count, ok := meta.CountFor(service.ServiceType); ok {
+		return pgtype.Int4{Int32: int32(count), Valid: true}, nil
+	}
+	return pgtype.Int4{}, nil
+}
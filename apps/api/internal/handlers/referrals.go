@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/referral"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ReferralSummaryRes is the user's affiliate balance. Earned is every
+// available commission; Balance is what is left after withdrawals.
+type ReferralSummaryRes struct {
+	Referrals int64   `json:"referrals"`
+	Earned    float64 `json:"earned"`
+	Held      float64 `json:"held"`
+	Pending   float64 `json:"pending"`
+	Paid      float64 `json:"paid"`
+	Balance   float64 `json:"balance"`
+}
+
+// ReferralCommissionRes is a commission earned on a referred user's deposit
+// or order
+type ReferralCommissionRes struct {
+	ID         int32   `json:"id"`
+	SourceType string  `json:"source_type"`
+	SourceID   int32   `json:"source_id"`
+	Base       float64 `json:"base"`
+	RatePct    float64 `json:"rate_pct"`
+	Amount     float64 `json:"amount"`
+	Status     string  `json:"status"`
+	CreatedAt  string  `json:"created_at"`
+}
+
+// ReferralPayoutRes is a withdrawal from the affiliate balance
+type ReferralPayoutRes struct {
+	ID          int32   `json:"id"`
+	Amount      float64 `json:"amount"`
+	Method      string  `json:"method"`
+	Details     string  `json:"details,omitempty"`
+	Status      string  `json:"status"`
+	AdminNote   string  `json:"admin_note,omitempty"`
+	ProcessedAt string  `json:"processed_at,omitempty"`
+	CreatedAt   string  `json:"created_at"`
+}
+
+func newReferralPayoutRes(p sqlc.ListReferralPayoutsRow) ReferralPayoutRes {
+	res := ReferralPayoutRes{
+		ID:        p.ID,
+		Amount:    money.Paise(p.AmountCents).Major(),
+		Method:    p.Method,
+		Details:   p.Details,
+		Status:    p.Status,
+		AdminNote: p.AdminNote,
+		CreatedAt: p.CreatedAt.Time.Format(time.RFC3339),
+	}
+	if p.ProcessedAt.Valid {
+		res.ProcessedAt = p.ProcessedAt.Time.Format(time.RFC3339)
+	}
+	return res
+}
+
+// GetReferrals returns the user's referral code, the commission terms, their
+// affiliate balance and its recent commissions and withdrawals
+func (h *Handler) GetReferrals(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(int)
+	ctx := context.Background()
+	uid := pgtype.Int4{Int32: int32(userID), Valid: true}
+
+	code, err := h.db.Queries.GetReferralCode(ctx, int32(userID))
+	if err != nil {
+		log.Printf("Error fetching referral code for user %d: %v", userID, err)
+		http.Error(w, "Failed to fetch referrals", http.StatusInternalServerError)
+		return
+	}
+	summary, err := h.db.Queries.GetReferralSummary(ctx, int32(userID))
+	if err != nil {
+		log.Printf("Error fetching referral summary for user %d: %v", userID, err)
+		http.Error(w, "Failed to fetch referrals", http.StatusInternalServerError)
+		return
+	}
+	commissions, err := h.db.Queries.ListReferralCommissions(ctx, sqlc.ListReferralCommissionsParams{ReferrerID: uid, Lim: 100})
+	if err != nil {
+		log.Printf("Error fetching referral commissions for user %d: %v", userID, err)
+		http.Error(w, "Failed to fetch referrals", http.StatusInternalServerError)
+		return
+	}
+	payouts, err := h.db.Queries.ListReferralPayouts(ctx, sqlc.ListReferralPayoutsParams{UserID: uid, Lim: 100})
+	if err != nil {
+		log.Printf("Error fetching referral payouts for user %d: %v", userID, err)
+		http.Error(w, "Failed to fetch referrals", http.StatusInternalServerError)
+		return
+	}
+	cfg := referral.LoadConfig(ctx, h.db.Queries)
+
+	commissionRes := make([]ReferralCommissionRes, 0, len(commissions))
+	for _, c := range commissions {
+		commissionRes = append(commissionRes, ReferralCommissionRes{
+			ID:         c.ID,
+			SourceType: c.SourceType,
+			SourceID:   c.SourceID,
+			Base:       money.Paise(c.BaseCents).Major(),
+			RatePct:    c.RatePct,
+			Amount:     money.Paise(c.AmountCents).Major(),
+			Status:     c.Status,
+			CreatedAt:  c.CreatedAt.Time.Format(time.RFC3339),
+		})
+	}
+	payoutRes := make([]ReferralPayoutRes, 0, len(payouts))
+	for _, p := range payouts {
+		payoutRes = append(payoutRes, newReferralPayoutRes(p))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code": code,
+		"terms": map[string]interface{}{
+			"basis":      cfg.Basis,
+			"pct":        cfg.Pct,
+			"min_payout": money.Paise(cfg.MinPayoutCents).Major(),
+		},
+		"summary": ReferralSummaryRes{
+			Referrals: summary.Referrals,
+			Earned:    money.Paise(summary.EarnedCents).Major(),
+			Held:      money.Paise(summary.HeldCents).Major(),
+			Pending:   money.Paise(summary.PendingCents).Major(),
+			Paid:      money.Paise(summary.PaidCents).Major(),
+			Balance:   referral.Balance(summary).Major(),
+		},
+		"commissions": commissionRes,
+		"payouts":     payoutRes,
+	})
+}
+
+type ReferralWithdrawReq struct {
+	Amount float64 `json:"amount"`
+	// Details tell an admin where to send a payout, e.g. a UPI ID
+	Details string `json:"details"`
+}
+
+// ConvertReferralEarnings moves affiliate balance into the user's wallet
+func (h *Handler) ConvertReferralEarnings(w http.ResponseWriter, r *http.Request) {
+	h.withdrawReferralEarnings(w, r, referral.MethodWallet)
+}
+
+// RequestReferralPayout asks an admin to pay out affiliate balance
+func (h *Handler) RequestReferralPayout(w http.ResponseWriter, r *http.Request) {
+	h.withdrawReferralEarnings(w, r, referral.MethodPayout)
+}
+
+func (h *Handler) withdrawReferralEarnings(w http.ResponseWriter, r *http.Request, method string) {
+	userID := r.Context().Value("userID").(int)
+	var req ReferralWithdrawReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Details = strings.TrimSpace(req.Details)
+	if req.Amount <= 0 {
+		http.Error(w, "Invalid amount", http.StatusBadRequest)
+		return
+	}
+	if method == referral.MethodPayout && req.Details == "" {
+		http.Error(w, "Payout details are required", http.StatusBadRequest)
+		return
+	}
+	amount, err := money.FromMajor(req.Amount, money.Base, money.RoundNearest)
+	if err != nil {
+		http.Error(w, "Invalid amount", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	tx, err := h.db.Pool.Begin(ctx)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := h.db.Queries.WithTx(tx)
+
+	p, err := referral.Withdraw(ctx, qtx, int32(userID), amount, method, req.Details)
+	if errors.Is(err, referral.ErrBelowMinimum) {
+		cfg := referral.LoadConfig(ctx, h.db.Queries)
+		http.Error(w, "The minimum withdrawal is "+money.Paise(cfg.MinPayoutCents).String(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, referral.ErrInsufficientBalance) {
+		http.Error(w, "Insufficient affiliate balance", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to withdraw referral earnings for user %d: %v", userID, err)
+		http.Error(w, "Failed to withdraw earnings", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "Failed to withdraw earnings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ReferralPayoutRes{
+		ID:        p.ID,
+		Amount:    money.Paise(p.AmountCents).Major(),
+		Method:    p.Method,
+		Details:   p.Details,
+		Status:    p.Status,
+		CreatedAt: p.CreatedAt.Time.Format(time.RFC3339),
+	})
+}
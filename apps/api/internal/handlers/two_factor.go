@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/service/twofactor"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type TwoFactorCodeReq struct {
+	Code string `json:"code"`
+}
+
+// SetupTwoFactor creates a new authenticator app secret for the user. It is
+// only turned on once EnableTwoFactor gets a code from it.
+func (h *Handler) SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(int)
+	ctx := context.Background()
+
+	user, err := h.db.Queries.GetUserDataForMe(ctx, int32(userID))
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	secret, err := twofactor.NewSecret()
+	if err != nil {
+		http.Error(w, "Server error", http.StatusInternalServerError)
+		return
+	}
+	n, err := h.db.Queries.SetTwoFactorSecret(ctx, sqlc.SetTwoFactorSecretParams{
+		UserID: int32(userID),
+		Secret: secret,
+	})
+	if err != nil {
+		log.Printf("Failed to set up two-factor authentication for user %d: %v", userID, err)
+		http.Error(w, "Failed to set up two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "Two-factor authentication is already on", http.StatusConflict)
+		return
+	}
+
+	account := user.Email.String
+	if account == "" {
+		account = user.Username
+	}
+	json.NewEncoder(w).Encode(map[string]string{
+		"secret": secret,
+		"url":    twofactor.URL(account, secret),
+	})
+}
+
+// EnableTwoFactor turns two-factor authentication on once the user enters a
+// code from the secret SetupTwoFactor gave them
+func (h *Handler) EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(int)
+	var req TwoFactorCodeReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	tf, err := h.db.Queries.GetTwoFactor(ctx, int32(userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Set up two-factor authentication first", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if tf.EnabledAt.Valid {
+		http.Error(w, "Two-factor authentication is already on", http.StatusConflict)
+		return
+	}
+	step, ok := twofactor.Match(tf.Secret, req.Code, time.Now())
+	if !ok {
+		http.Error(w, "Incorrect code", http.StatusUnauthorized)
+		return
+	}
+	n, err := h.db.Queries.EnableTwoFactor(ctx, sqlc.EnableTwoFactorParams{
+		UserID:   int32(userID),
+		LastStep: step,
+	})
+	if err != nil {
+		log.Printf("Failed to enable two-factor authentication for user %d: %v", userID, err)
+		http.Error(w, "Failed to enable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "Incorrect code", http.StatusUnauthorized)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// DisableTwoFactor turns two-factor authentication off. It takes a current
+// code, so a stolen session alone cannot remove it.
+func (h *Handler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(int)
+	var req TwoFactorCodeReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	err := twofactor.Verify(ctx, h.db.Queries, int32(userID), req.Code)
+	if errors.Is(err, twofactor.ErrNotEnabled) {
+		http.Error(w, "Two-factor authentication is not on", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, twofactor.ErrInvalidCode) {
+		http.Error(w, "Incorrect code", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if err := h.db.Queries.DeleteTwoFactor(ctx, int32(userID)); err != nil {
+		http.Error(w, "Failed to disable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
@@ -12,6 +12,8 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/ledger"
 )
 
 type AdminUser struct {
@@ -32,6 +34,9 @@ type WalletUpdateReq struct {
 	Amount      float64 `json:"amount"` // Amount in main currency unit (e.g. USD)
 	Type        string  `json:"type"`   // "credit" or "debit"
 	Description string  `json:"description"`
+	// TopUp marks a credit as funds paid for outside the panel, so it is
+	// invoiced like a deposit
+	TopUp bool `json:"topUp"`
 }
 
 type UserUpdateReq struct {
@@ -82,9 +87,9 @@ func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
 		u.Role = row.Role
 		u.Currency = row.Currency
 		u.CreatedAt = fmt.Sprintf("%v", row.CreatedAt.Time)
-		u.Balance = float64(row.Balance) / 100.0
+		u.Balance = money.Paise(row.Balance).Major()
 		u.OrderCount = int(row.OrderCount)
-		u.TotalSpend = float64(row.TotalSpend) / 100.0
+		u.TotalSpend = money.Paise(row.TotalSpend).Major()
 		users = append(users, u)
 	}
 
@@ -110,7 +115,7 @@ func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "User not found", http.StatusNotFound)
 		return
 	}
-	
+
 	var u AdminUser
 	u.ID = int(uRow.ID)
 	u.Name = uRow.Name.String
@@ -120,9 +125,9 @@ func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
 	u.Role = uRow.Role
 	u.Currency = uRow.Currency
 	u.CreatedAt = fmt.Sprintf("%v", uRow.CreatedAt.Time)
-	u.Balance = float64(uRow.Balance) / 100.0
+	u.Balance = money.Paise(uRow.Balance).Major()
 	u.OrderCount = int(uRow.OrderCount)
-	u.TotalSpend = float64(uRow.TotalSpend) / 100.0
+	u.TotalSpend = money.Paise(uRow.TotalSpend).Major()
 
 	// Fetch recent orders (last 5)
 	type OrderSummary struct {
@@ -140,7 +145,7 @@ func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
 			orders = append(orders, OrderSummary{
 				ID:        int(row.ID),
 				ServiceID: row.ServiceID,
-				Amount:    float64(row.AmountCents) / 100.0,
+				Amount:    money.Paise(row.AmountCents).Major(),
 				Status:    row.Status,
 				CreatedAt: fmt.Sprintf("%v", row.CreatedAt.Time),
 			})
@@ -149,11 +154,14 @@ func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
 
 	// Fetch recent transactions (last 10)
 	type TransactionSummary struct {
-		ID          int     `json:"id"`
-		Amount      float64 `json:"amount"`
-		Type        string  `json:"type"`
-		Description string  `json:"description"`
-		CreatedAt   string  `json:"createdAt"`
+		ID            int     `json:"id"`
+		Amount        float64 `json:"amount"`
+		Type          string  `json:"type"`
+		Kind          string  `json:"kind"`
+		ReferenceType string  `json:"referenceType"`
+		ReferenceID   string  `json:"referenceId"`
+		Description   string  `json:"description"`
+		CreatedAt     string  `json:"createdAt"`
 	}
 
 	transactions := []TransactionSummary{}
@@ -167,17 +175,26 @@ func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
 				amt = val.Float64
 			}
 			transactions = append(transactions, TransactionSummary{
-				ID:          int(row.ID),
-				Amount:      amt,
-				Type:        row.Type,
-				Description: row.Description.String,
-				CreatedAt:   row.CreatedAt.Time.Format(time.RFC3339),
+				ID:            int(row.ID),
+				Amount:        amt,
+				Type:          row.Type,
+				Kind:          row.Kind,
+				ReferenceType: row.ReferenceType,
+				ReferenceID:   row.ReferenceID,
+				Description:   row.Description.String,
+				CreatedAt:     row.CreatedAt.Time.Format(time.RFC3339),
 			})
 		}
 	}
 
+	wallet, err := h.walletFunds(context.Background(), int32(id))
+	if err != nil {
+		log.Printf("Failed to fetch wallet funds for user %d: %v", id, err)
+	}
+
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"user":         u,
+		"wallet":       wallet,
 		"orders":       orders,
 		"transactions": transactions,
 	})
@@ -203,13 +220,21 @@ func (h *Handler) UpdateUserWallet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	amountCents := int(req.Amount * 100)
+	amount, err := money.FromMajor(req.Amount, money.Base, money.RoundNearest)
+	if err != nil {
+		http.Error(w, "Invalid amount", http.StatusBadRequest)
+		return
+	}
 	if req.Type == "debit" {
-		amountCents = -amountCents
+		amount = amount.Neg()
 	} else if req.Type != "credit" {
 		http.Error(w, "Invalid transaction type", http.StatusBadRequest)
 		return
 	}
+	if req.TopUp && req.Type != "credit" {
+		http.Error(w, "Only credits can be top-ups", http.StatusBadRequest)
+		return
+	}
 
 	// Update wallet logic (upsert wallet if not exists)
 	tx, err := h.db.Pool.Begin(context.Background())
@@ -221,35 +246,36 @@ func (h *Handler) UpdateUserWallet(w http.ResponseWriter, r *http.Request) {
 
 	qtx := h.db.Queries.WithTx(tx)
 
-	// Ensure wallet exists and update balance
-	err = qtx.UpsertWalletBalance(context.Background(), sqlc.UpsertWalletBalanceParams{
-		UserID:  int32(userID),
-		Balance: int32(amountCents),
-	})
+	// Ensure wallet exists, update balance and post the adjustment
+	adjustmentID, err := ledger.Adjust(context.Background(), qtx, int32(userID), amount, adminIDFromRequest(r), req.Description)
 	if err != nil {
 		log.Printf("Failed to update wallet: %v", err)
 		http.Error(w, "Failed to update balance", http.StatusInternalServerError)
 		return
 	}
-
-	newBalance, err := qtx.GetWalletBalance(context.Background(), int32(userID))
+	if req.TopUp {
+		if err := qtx.MarkWalletAdjustmentTopUp(context.Background(), adjustmentID); err != nil {
+			log.Printf("Failed to mark adjustment %d as a top-up: %v", adjustmentID, err)
+			http.Error(w, "Failed to update balance", http.StatusInternalServerError)
+			return
+		}
+	}
+	err = h.audit(context.Background(), qtx, r, "wallet.adjust", "wallet_adjustment", strconv.Itoa(int(adjustmentID)), map[string]interface{}{
+		"user_id":      userID,
+		"amount_cents": amount.Minor,
+		"description":  req.Description,
+		"top_up":       req.TopUp,
+	})
 	if err != nil {
-		log.Printf("Failed to get wallet balance: %v", err)
-		http.Error(w, "Failed to get new balance", http.StatusInternalServerError)
+		log.Printf("Failed to write audit log: %v", err)
+		http.Error(w, "Failed to update balance", http.StatusInternalServerError)
 		return
 	}
 
-	// Log Transaction
-	err = qtx.InsertTransaction(context.Background(), sqlc.InsertTransactionParams{
-		UserID:      pgtype.Int4{Int32: int32(userID), Valid: true},
-		Amount:      func() pgtype.Numeric { n := pgtype.Numeric{}; n.Scan(fmt.Sprintf("%f", req.Amount)); return n }(),
-		Type:        req.Type,
-		Description: pgtype.Text{String: req.Description, Valid: true},
-	})
-
+	newBalance, err := qtx.GetWalletBalance(context.Background(), int32(userID))
 	if err != nil {
-		log.Printf("Failed to log transaction: %v", err)
-		http.Error(w, "Transaction logging failed", http.StatusInternalServerError)
+		log.Printf("Failed to get wallet balance: %v", err)
+		http.Error(w, "Failed to get new balance", http.StatusInternalServerError)
 		return
 	}
 
@@ -260,7 +286,7 @@ func (h *Handler) UpdateUserWallet(w http.ResponseWriter, r *http.Request) {
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status":     "success",
-		"newBalance": float64(newBalance) / 100.0,
+		"newBalance": money.Paise(newBalance).Major(),
 	})
 }
 
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// BonusTierRes is a deposit bonus on offer
+type BonusTierRes struct {
+	Name             string   `json:"name"`
+	MinDeposit       float64  `json:"min_deposit"`
+	BonusPct         float64  `json:"bonus_pct"`
+	MaxBonus         *float64 `json:"max_bonus,omitempty"`
+	Methods          []string `json:"methods"`
+	EndsAt           string   `json:"ends_at,omitempty"`
+	Withdrawable     bool     `json:"withdrawable"`
+	ExpiresAfterDays *int32   `json:"expires_after_days,omitempty"`
+}
+
+// BonusRes is a bonus credited to the user's wallet
+type BonusRes struct {
+	ID           int32   `json:"id"`
+	Deposit      float64 `json:"deposit"`
+	Amount       float64 `json:"amount"`
+	Remaining    float64 `json:"remaining"`
+	Withdrawable bool    `json:"withdrawable"`
+	Status       string  `json:"status"`
+	Expired      float64 `json:"expired"`
+	ExpiresAt    string  `json:"expires_at,omitempty"`
+	CreatedAt    string  `json:"created_at"`
+}
+
+// GetWalletBonuses returns the deposit bonuses currently on offer and the
+// bonuses the user has received
+func (h *Handler) GetWalletBonuses(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(int)
+	ctx := context.Background()
+
+	tiers, err := h.db.Queries.ListCurrentDepositBonusTiers(ctx)
+	if err != nil {
+		log.Printf("Error fetching bonus tiers: %v", err)
+		http.Error(w, "Failed to fetch bonuses", http.StatusInternalServerError)
+		return
+	}
+	grants, err := h.db.Queries.ListBonusGrants(ctx, sqlc.ListBonusGrantsParams{
+		UserID: pgtype.Int4{Int32: int32(userID), Valid: true},
+		Lim:    100,
+	})
+	if err != nil {
+		log.Printf("Error fetching bonuses for user %d: %v", userID, err)
+		http.Error(w, "Failed to fetch bonuses", http.StatusInternalServerError)
+		return
+	}
+
+	offers := make([]BonusTierRes, 0, len(tiers))
+	for _, t := range tiers {
+		offer := BonusTierRes{
+			Name:         t.Name,
+			MinDeposit:   money.Paise(t.MinDepositCents).Major(),
+			BonusPct:     t.BonusPct,
+			Methods:      t.Methods,
+			Withdrawable: t.Withdrawable,
+		}
+		if t.MaxBonusCents.Valid {
+			maxBonus := money.Paise(t.MaxBonusCents.Int64).Major()
+			offer.MaxBonus = &maxBonus
+		}
+		if t.EndsAt.Valid {
+			offer.EndsAt = t.EndsAt.Time.Format(time.RFC3339)
+		}
+		if t.ExpiresAfterDays.Valid {
+			offer.ExpiresAfterDays = &t.ExpiresAfterDays.Int32
+		}
+		offers = append(offers, offer)
+	}
+
+	bonuses := make([]BonusRes, 0, len(grants))
+	for _, g := range grants {
+		b := BonusRes{
+			ID:           g.ID,
+			Deposit:      money.Paise(g.DepositCents).Major(),
+			Amount:       money.Paise(g.AmountCents).Major(),
+			Remaining:    money.Paise(g.RemainingCents).Major(),
+			Withdrawable: g.Withdrawable,
+			Status:       g.Status,
+			Expired:      money.Paise(g.ExpiredCents).Major(),
+			CreatedAt:    g.CreatedAt.Time.Format(time.RFC3339),
+		}
+		if g.ExpiresAt.Valid {
+			b.ExpiresAt = g.ExpiresAt.Time.Format(time.RFC3339)
+		}
+		bonuses = append(bonuses, b)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"offers":  offers,
+		"bonuses": bonuses,
+	})
+}
@@ -1,24 +1,37 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"crypto/md5"
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
-	"strconv"
 	"strings"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/bonus"
+	"pablosmm/backend/internal/service/ledger"
+	"pablosmm/backend/internal/service/promo"
 )
 
+// cryptomusRateSetting holds the INR price of one USD used to credit
+// Cryptomus payments
+const cryptomusRateSetting = "cryptomus_usd_inr_rate"
+
 type CryptomusCreatePaymentReq struct {
-	Amount float64 `json:"amount"`
+	// Amount is in USD, the currency Cryptomus charges in
+	Amount    float64 `json:"amount"`
+	PromoCode string  `json:"promo_code"`
 }
 
 type CryptomusWebhookReq struct {
@@ -47,16 +60,53 @@ func (h *Handler) CreateCryptomusPayment(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Invalid amount", http.StatusBadRequest)
 		return
 	}
+	payment, err := money.FromMajor(req.Amount, money.USD, money.RoundNearest)
+	if err != nil || !payment.IsPositive() {
+		http.Error(w, "Invalid amount", http.StatusBadRequest)
+		return
+	}
+	// The wallet is credited the INR value at today's rate, fixed now so the
+	// user knows what they will get
+	rate, err := h.db.Queries.GetSetting(r.Context(), cryptomusRateSetting)
+	if err != nil {
+		log.Printf("Failed to read the Cryptomus exchange rate: %v", err)
+		http.Error(w, "Crypto deposits are unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	credit, err := payment.Convert(money.Base, rate, money.RoundDown)
+	if err != nil {
+		log.Printf("Invalid Cryptomus exchange rate %q: %v", rate, err)
+		http.Error(w, "Crypto deposits are unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	// A promo code is checked now against the INR credit and paid out on
+	// the same stored amount when the payment completes
+	var promoCodeID pgtype.Int4
+	if req.PromoCode != "" {
+		code, err := promo.CheckDeposit(r.Context(), h.db.Queries, int32(userID), req.PromoCode, credit)
+		var invalidCode *promo.InvalidError
+		if errors.As(err, &invalidCode) {
+			http.Error(w, invalidCode.Reason, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to check promo code %q: %v", req.PromoCode, err)
+			http.Error(w, "Failed to apply promo code", http.StatusInternalServerError)
+			return
+		}
+		promoCodeID = pgtype.Int4{Int32: code.ID, Valid: true}
+	}
 
 	// Create internal order ID for tracking
 	// We use 'wallet_requests' table or just a unique string.
 	// Let's create a record in wallet_requests to link user.
-	amountNumeric := pgtype.Numeric{}
-	amountNumeric.Scan(fmt.Sprintf("%f", req.Amount))
-
 	requestID, err := h.db.Queries.CreateCryptomusWalletRequest(context.Background(), sqlc.CreateCryptomusWalletRequestParams{
-		UserID: pgtype.Int4{Int32: int32(userID), Valid: true},
-		Amount: amountNumeric,
+		UserID:      pgtype.Int4{Int32: int32(userID), Valid: true},
+		Amount:      credit.Numeric(),
+		PayAmount:   payment.Numeric(),
+		PayCurrency: pgtype.Text{String: string(payment.Currency), Valid: true},
+		PromoCodeID: promoCodeID,
 	})
 
 	if err != nil {
@@ -69,8 +119,8 @@ func (h *Handler) CreateCryptomusPayment(w http.ResponseWriter, r *http.Request)
 
 	// Call Cryptomus API
 	paymentData := map[string]interface{}{
-		"amount":              fmt.Sprintf("%.2f", req.Amount),
-		"currency":            "USD",
+		"amount":              payment.Decimal(),
+		"currency":            string(payment.Currency),
 		"order_id":            orderID,
 		"url_return":          h.cfg.SMMAPIURL + "/dashboard/wallet",             // Frontend URL fallback
 		"url_callback":        "https://api.pablosmm.com/api/webhooks/cryptomus", // Backend Webhook
@@ -131,7 +181,8 @@ func (h *Handler) CreateCryptomusPayment(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(result)
 }
 
-// CryptomusWebhook handles status updates
+// CryptomusWebhook handles status updates. Only signed webhooks are acted
+// on, and a paid request credits its stored amount, not the webhook's.
 func (h *Handler) CryptomusWebhook(w http.ResponseWriter, r *http.Request) {
 	// Read Body
 	bodyBytes, err := io.ReadAll(r.Body)
@@ -140,48 +191,17 @@ func (h *Handler) CryptomusWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Verify Signature
-	// sign = md5(base64(body) + key)
-	// But webhook sends JSON body. We need to verify signature sent in body['sign'] usually
-	// OR header. Cryptomus sends 'sign' in POST body usually for webhooks?
-	// Checking docs: "The signature is passed in the sign parameter of the POST request body."
-
 	var webhook CryptomusWebhookReq
 	if err := json.Unmarshal(bodyBytes, &webhook); err != nil {
 		log.Printf("Webhook parse error: %v", err)
 		http.Error(w, "Bad request", http.StatusBadRequest)
 		return
 	}
-
-	// Re-construct signature
-	// Exclude 'sign' from body? No, standard is:
-	// md5(base64(json_encode(data)) . apiKey)
-	// Implementation note: The data is the post body (without sign?).
-	// Actually for webhook, the 'sign' is PART of the body object in the documentation example?
-	// "sign": "..."
-	// Wait, if 'sign' is in the JSON, we need to remove it to calculate hash?
-	// Usually payload is: { "type":..., "valid":... } AND "sign".
-
-	// Let's try simple text matching logic if we trust the format, or map based.
-	var rawMap map[string]interface{}
-	json.Unmarshal(bodyBytes, &rawMap)
-	delete(rawMap, "sign")
-
-	// We need deterministic JSON not easy in Go.
-	// BUT Cryptomus might send signature related to what they sent.
-	// Alternative: The signature is generated from the received data excluding the sign parameter.
-	// However, usually we can't reproduce the exact JSON string because of key ordering.
-	// Most providers use a "sort keys" approach or raw string (if 'sign' is header).
-	// CRYPTOMUS WEBHOOOK: "sign" is in the body.
-	// "To verify... generate signature using API key and data... base64_encode(json_encode($data))."
-	// Ensure to use the SAME json structure.
-	// This is tricky in Go.
-	// workaround: Just check if status is "paid" or "paid_over".
-	// For now, let's skip rigorous signature check due to complexity of re-encoding JSON exactly,
-	// UNLESS we use the raw bytes and strip the "sign" field using regex?
-	// Or maybe just trust the ID for MVP if the user is trusted? No, security risk.
-
-	// Let's assume we implement at least checking order_id exists.
+	if !verifyCryptomusSign(bodyBytes, h.cfg.CryptomusAPIKey) {
+		log.Printf("Cryptomus webhook for %s rejected: bad signature", webhook.OrderId)
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
 
 	if webhook.Status == "paid" || webhook.Status == "paid_over" {
 		// Parse Order ID: WALLET-USERID-REQUESTID
@@ -202,52 +222,129 @@ func (h *Handler) CryptomusWebhook(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		tx, _ := h.db.Pool.Begin(context.Background())
+		tx, err := h.db.Pool.Begin(context.Background())
+		if err != nil {
+			http.Error(w, "DB Error", http.StatusInternalServerError)
+			return
+		}
 		defer tx.Rollback(context.Background())
 		qtx := h.db.Queries.WithTx(tx)
 
-		// Mark request approved
-		err = qtx.ApproveCryptomusWalletRequest(context.Background(), int32(reqID))
+		// Re-check under the row lock so concurrent webhook retries credit once
+		walletReq, err := qtx.GetWalletRequestForUpdateAdmin(context.Background(), int32(reqID))
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Webhook for unknown wallet request %s", webhook.OrderId)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
 		if err != nil {
-			log.Printf("Webhook update failed: %v", err)
+			log.Printf("Webhook request lookup failed: %v", err)
 			http.Error(w, "DB Error", http.StatusInternalServerError)
 			return
 		}
-
-		// Credit User
-		amountFloat, _ := strconv.ParseFloat(webhook.Amount, 64)
-		if amountFloat <= 0 {
-			// Fallback: use Amount from DB request
-			dbAmount, _ := qtx.GetWalletRequestAmount(context.Background(), int32(reqID))
-			val, _ := dbAmount.Float64Value()
-			amountFloat = val.Float64
+		if walletReq.UserID.Int32 != int32(userID) || walletReq.Method != "cryptomus" {
+			log.Printf("Webhook order %s does not match wallet request #%d", webhook.OrderId, reqID)
+			w.WriteHeader(http.StatusOK)
+			return
 		}
 
-		amountCents := int(amountFloat * 100)
-		err = qtx.UpsertWalletBalance(context.Background(), sqlc.UpsertWalletBalanceParams{
-			UserID:  int32(userID),
-			Balance: int32(amountCents),
-		})
+		// Mark request approved; only a pending request is credited
+		n, err := qtx.ApproveCryptomusWalletRequest(context.Background(), int32(reqID))
+		if err != nil {
+			log.Printf("Webhook update failed: %v", err)
+			http.Error(w, "DB Error", http.StatusInternalServerError)
+			return
+		}
+		if n != 1 {
+			if walletReq.Status.String != "approved" {
+				log.Printf("Webhook for %s wallet request #%d ignored", walletReq.Status.String, reqID)
+			}
+			w.WriteHeader(http.StatusOK)
+			return
+		}
 
+		// Credit the INR amount fixed when the payment was created; the
+		// webhook's amount is in USD
+		amount, err := money.FromNumeric(walletReq.Amount, money.Base, money.RoundNearest)
+		if err == nil && !amount.IsPositive() {
+			err = fmt.Errorf("amount %s", amount)
+		}
+		if err != nil {
+			log.Printf("Webhook amount invalid: %v", err)
+			http.Error(w, "Invalid amount", http.StatusBadRequest)
+			return
+		}
+		err = ledger.Deposit(context.Background(), qtx, int32(userID), amount, ledger.NewRef(ledger.RefWalletRequest, int32(reqID)), "Cryptomus Deposit")
 		if err != nil {
 			log.Printf("Webhook wallet credit failed: %v", err)
 			http.Error(w, "DB Error", http.StatusInternalServerError)
 			return
 		}
+		// Deposit bonuses are paid on the same stored credit
+		if _, err := bonus.Grant(context.Background(), qtx, int32(userID), int32(reqID), bonus.MethodCryptomus, amount); err != nil {
+			log.Printf("Webhook deposit bonus failed: %v", err)
+			http.Error(w, "DB Error", http.StatusInternalServerError)
+			return
+		}
+		if _, err := promo.RedeemDeposit(context.Background(), qtx, int32(userID), int32(reqID), amount); err != nil {
+			log.Printf("Webhook deposit promo code failed: %v", err)
+			http.Error(w, "DB Error", http.StatusInternalServerError)
+			return
+		}
 
-		// Log Transaction
-		qtx.InsertTransaction(context.Background(), sqlc.InsertTransactionParams{
-			UserID:      pgtype.Int4{Int32: int32(userID), Valid: true},
-			Amount:      func() pgtype.Numeric { n := pgtype.Numeric{}; n.Scan(fmt.Sprintf("%f", amountFloat)); return n }(),
-			Type:        "credit",
-			Description: pgtype.Text{String: "Cryptomus Deposit", Valid: true},
-		})
-
-		tx.Commit(context.Background())
+		if err := tx.Commit(context.Background()); err != nil {
+			log.Printf("Webhook commit failed: %v", err)
+			http.Error(w, "DB Error", http.StatusInternalServerError)
+			return
+		}
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
+// verifyCryptomusSign checks the sign member of a webhook body. Cryptomus
+// signs md5(base64(body without sign) + API key), so sign is cut out of the
+// raw bytes; decoding and encoding the JSON again would change key order and
+// escaping.
+func verifyCryptomusSign(body []byte, apiKey string) bool {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return false
+	}
+	var sign string
+	var cutStart, cutEnd int64 = -1, -1
+	for first := true; dec.More(); first = false {
+		start := dec.InputOffset()
+		key, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return false
+		}
+		if key != "sign" {
+			continue
+		}
+		if json.Unmarshal(value, &sign) != nil {
+			return false
+		}
+		cutStart, cutEnd = start, dec.InputOffset()
+		if first {
+			// Take the comma after the first member instead of the one before
+			rest := bytes.TrimLeft(body[cutEnd:], " \t\r\n")
+			if len(rest) > 0 && rest[0] == ',' {
+				cutEnd = int64(len(body)-len(rest)) + 1
+			}
+		}
+	}
+	if sign == "" || cutStart < 0 {
+		return false
+	}
+	signed := append(append([]byte{}, body[:cutStart]...), body[cutEnd:]...)
+	sum := md5.Sum([]byte(base64.StdEncoding.EncodeToString(signed) + apiKey))
+	return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(sign)) == 1
+}
+
 // strings import was missing in previous files, need to ensure imports
 var _ = strings.Split
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+)
+
+// WalletFundsRes splits a wallet into what can be spent and what is
+// reserved for orders that have not been charged yet. Withdrawable leaves
+// out bonus credit that can only be spent.
+type WalletFundsRes struct {
+	Balance      float64   `json:"balance"`
+	Held         float64   `json:"held"`
+	Available    float64   `json:"available"`
+	LockedBonus  float64   `json:"locked_bonus"`
+	Withdrawable float64   `json:"withdrawable"`
+	Holds        []HoldRes `json:"holds"`
+}
+
+type HoldRes struct {
+	ID            int32   `json:"id"`
+	Amount        float64 `json:"amount"`
+	ReferenceType string  `json:"reference_type"`
+	ReferenceID   string  `json:"reference_id"`
+	Description   string  `json:"description"`
+	CreatedAt     string  `json:"created_at"`
+}
+
+// GetWalletFunds returns the user's balance, the part of it on hold and
+// what is left to spend
+func (h *Handler) GetWalletFunds(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(int)
+	res, err := h.walletFunds(context.Background(), int32(userID))
+	if err != nil {
+		log.Printf("Error fetching wallet funds for user %d: %v", userID, err)
+		http.Error(w, "Failed to fetch wallet", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+func (h *Handler) walletFunds(ctx context.Context, userID int32) (WalletFundsRes, error) {
+	funds, err := h.db.Queries.GetWalletFunds(ctx, userID)
+	if err != nil {
+		return WalletFundsRes{}, err
+	}
+	holds, err := h.db.Queries.ListActiveWalletHolds(ctx, userID)
+	if err != nil {
+		return WalletFundsRes{}, err
+	}
+	locked, err := h.db.Queries.GetLockedBonusCents(ctx, userID)
+	if err != nil {
+		return WalletFundsRes{}, err
+	}
+	return newWalletFundsRes(funds, holds, locked), nil
+}
+
+func newWalletFundsRes(funds sqlc.GetWalletFundsRow, holds []sqlc.WalletHold, lockedBonusCents int64) WalletFundsRes {
+	available := funds.Balance - funds.HeldCents
+	res := WalletFundsRes{
+		Balance:      money.Paise(funds.Balance).Major(),
+		Held:         money.Paise(funds.HeldCents).Major(),
+		Available:    money.Paise(available).Major(),
+		LockedBonus:  money.Paise(lockedBonusCents).Major(),
+		Withdrawable: money.Paise(max(available-lockedBonusCents, 0)).Major(),
+		Holds:        make([]HoldRes, 0, len(holds)),
+	}
+	for _, hold := range holds {
+		res.Holds = append(res.Holds, HoldRes{
+			ID:            hold.ID,
+			Amount:        money.Paise(hold.AmountCents).Major(),
+			ReferenceType: hold.ReferenceType,
+			ReferenceID:   hold.ReferenceID,
+			Description:   hold.Description,
+			CreatedAt:     hold.CreatedAt.Time.Format(time.RFC3339),
+		})
+	}
+	return res
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -13,6 +14,11 @@ import (
 	"time"
 
 	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/bonus"
+	"pablosmm/backend/internal/service/ledger"
+	"pablosmm/backend/internal/service/promo"
+	"pablosmm/backend/internal/service/referral"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -22,6 +28,7 @@ type DepositReq struct {
 	Amount        float64 `json:"amount"`
 	Method        string  `json:"method"`
 	TransactionID string  `json:"transaction_id"`
+	PromoCode     string  `json:"promo_code"`
 }
 
 type WalletRequest struct {
@@ -102,6 +109,28 @@ func (h *Handler) RequestDeposit(w http.ResponseWriter, r *http.Request) {
 		txnID = pgtype.Text{String: req.TransactionID, Valid: true}
 	}
 
+	// A promo code is checked now and paid out when the deposit is approved
+	var promoCodeID pgtype.Int4
+	if req.PromoCode != "" {
+		deposit, err := money.FromMajor(req.Amount, money.Base, money.RoundNearest)
+		if err != nil {
+			http.Error(w, "Invalid amount", http.StatusBadRequest)
+			return
+		}
+		code, err := promo.CheckDeposit(ctx, h.db.Queries, int32(userID), req.PromoCode, deposit)
+		var invalidCode *promo.InvalidError
+		if errors.As(err, &invalidCode) {
+			http.Error(w, invalidCode.Reason, http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to check promo code %q: %v", req.PromoCode, err)
+			http.Error(w, "Failed to apply promo code", http.StatusInternalServerError)
+			return
+		}
+		promoCodeID = pgtype.Int4{Int32: code.ID, Valid: true}
+	}
+
 	// UniqueAmount is no longer used, we just pass NULL (invalid pgtype.Numeric)
 	var ua pgtype.Numeric
 
@@ -111,6 +140,7 @@ func (h *Handler) RequestDeposit(w http.ResponseWriter, r *http.Request) {
 		UniqueAmount:  ua,
 		Method:        req.Method,
 		TransactionID: txnID,
+		PromoCodeID:   promoCodeID,
 	})
 
 	if err != nil {
@@ -188,40 +218,10 @@ func (h *Handler) UpdateDepositUTR(w http.ResponseWriter, r *http.Request) {
 			if math.Abs(reqAmtFloat.Float64-notifAmtFloat.Float64) < 0.02 {
 				log.Printf("[UPI-NOTIFY] Late match! Request %d matched with UTR %s", req.RequestID, req.TransactionID)
 
-				tx, err := h.db.Pool.Begin(context.Background())
-				if err == nil {
-					defer tx.Rollback(context.Background())
-					qtx := h.db.Queries.WithTx(tx)
-
-					// Update request status
-					qtx.UpdateWalletRequestStatusAndTxn(context.Background(), sqlc.UpdateWalletRequestStatusAndTxnParams{
-						Status:        pgtype.Text{String: "approved", Valid: true},
-						TransactionID: pgtype.Text{String: req.TransactionID, Valid: true},
-						ID:            int32(req.RequestID),
-					})
-
-					// Credit wallet
-					amountCents := int(reqAmtFloat.Float64 * 100)
-					qtx.UpsertWalletBalance(context.Background(), sqlc.UpsertWalletBalanceParams{
-						UserID:  int32(userID),
-						Balance: int32(amountCents),
-					})
-
-					// Log transaction
-					qtx.InsertTransaction(context.Background(), sqlc.InsertTransactionParams{
-						UserID:      pgtype.Int4{Int32: int32(userID), Valid: true},
-						Amount:      func() pgtype.Numeric { n := pgtype.Numeric{}; n.Scan(fmt.Sprintf("%f", reqAmtFloat.Float64)); return n }(),
-						Type:        "credit",
-						Description: pgtype.Text{String: "UPI Deposit (Auto-Verified)", Valid: true},
-					})
-
-					// Update notification status
-					qtx.MarkUPINotificationMatched(context.Background(), sqlc.MarkUPINotificationMatchedParams{
-						MatchedRequestID: pgtype.Int4{Int32: int32(req.RequestID), Valid: true},
-						ID:               notification.ID,
-					})
-
-					tx.Commit(context.Background())
+				// On failure the UTR stays saved and the request waits for an admin
+				if err := h.approveLateUTRMatch(context.Background(), req.RequestID, userID, req.TransactionID, reqAmtFloat.Float64, notification.ID); err != nil {
+					log.Printf("[UPI-NOTIFY] Late match of request %d failed: %v", req.RequestID, err)
+				} else {
 					json.NewEncoder(w).Encode(map[string]string{"message": "Payment verified automatically!", "status": "approved"})
 					return
 				}
@@ -232,6 +232,58 @@ func (h *Handler) UpdateDepositUTR(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "UTR updated successfully", "status": "pending"})
 }
 
+// approveLateUTRMatch approves a request whose UTR had already arrived from
+// the notification listener. Every step must succeed or none is applied.
+func (h *Handler) approveLateUTRMatch(ctx context.Context, requestID, userID int, utr string, amount float64, notificationID int32) error {
+	tx, err := h.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := h.db.Queries.WithTx(tx)
+
+	status, err := qtx.GetWalletRequestStatusForUpdate(ctx, int32(requestID))
+	if err != nil {
+		return fmt.Errorf("lock request: %w", err)
+	}
+	if status.String != "pending" {
+		return fmt.Errorf("request is %s", status.String)
+	}
+
+	err = qtx.UpdateWalletRequestStatusAndTxn(ctx, sqlc.UpdateWalletRequestStatusAndTxnParams{
+		Status:        pgtype.Text{String: "approved", Valid: true},
+		TransactionID: pgtype.Text{String: utr, Valid: true},
+		ID:            int32(requestID),
+	})
+	if err != nil {
+		return fmt.Errorf("update request: %w", err)
+	}
+
+	credit, err := money.FromMajor(amount, money.Base, money.RoundNearest)
+	if err != nil {
+		return fmt.Errorf("amount: %w", err)
+	}
+	err = ledger.Deposit(ctx, qtx, int32(userID), credit, ledger.NewRef(ledger.RefWalletRequest, int32(requestID)), "UPI Deposit (Auto-Verified)")
+	if err != nil {
+		return err
+	}
+	if _, err := bonus.Grant(ctx, qtx, int32(userID), int32(requestID), bonus.MethodUPI, credit); err != nil {
+		return fmt.Errorf("deposit bonus: %w", err)
+	}
+	if _, err := promo.RedeemDeposit(ctx, qtx, int32(userID), int32(requestID), credit); err != nil {
+		return fmt.Errorf("deposit promo code: %w", err)
+	}
+
+	err = qtx.MarkUPINotificationMatched(ctx, sqlc.MarkUPINotificationMatchedParams{
+		MatchedRequestID: pgtype.Int4{Int32: int32(requestID), Valid: true},
+		ID:               notificationID,
+	})
+	if err != nil {
+		return fmt.Errorf("mark notification: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
 // UPINotification is the payload sent by the Android notification listener app
 type UPINotification struct {
 	Amount    float64 `json:"amount"`
@@ -356,29 +408,30 @@ func (h *Handler) AutoVerifyDeposit(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 5c. Credit user wallet (amount is the ORIGINAL requested amount, not unique amount)
-	amountCents := int(reqAmount.Float64 * 100)
-	err = qtx.UpsertWalletBalance(ctx, sqlc.UpsertWalletBalanceParams{
-		UserID:  int32(userID),
-		Balance: int32(amountCents),
-	})
+	credit, err := money.FromNumeric(matchRow.Amount, money.Base, money.RoundNearest)
 	if err != nil {
-		log.Printf("[UPI-NOTIFY] Failed to credit wallet: %v", err)
+		log.Printf("[UPI-NOTIFY] Invalid request amount: %v", err)
 		http.Error(w, "Failed to credit wallet", http.StatusInternalServerError)
 		return
 	}
-
-	// 5d. Log transaction
-	err = qtx.InsertTransaction(ctx, sqlc.InsertTransactionParams{
-		UserID:      pgtype.Int4{Int32: int32(userID), Valid: true},
-		Amount:      func() pgtype.Numeric { n := pgtype.Numeric{}; n.Scan(fmt.Sprintf("%f", reqAmount.Float64)); return n }(),
-		Type:        "credit",
-		Description: pgtype.Text{String: "UPI Deposit (Auto-Verified)", Valid: true},
-	})
+	err = ledger.Deposit(ctx, qtx, int32(userID), credit, ledger.NewRef(ledger.RefWalletRequest, int32(reqID)), "UPI Deposit (Auto-Verified)")
 	if err != nil {
-		log.Printf("[UPI-NOTIFY] Failed to log transaction: %v", err)
+		log.Printf("[UPI-NOTIFY] Failed to credit wallet: %v", err)
+		http.Error(w, "Failed to credit wallet", http.StatusInternalServerError)
+		return
+	}
+	if _, err := bonus.Grant(ctx, qtx, int32(userID), int32(reqID), bonus.MethodUPI, credit); err != nil {
+		log.Printf("[UPI-NOTIFY] Failed to credit deposit bonus: %v", err)
+		http.Error(w, "Failed to credit wallet", http.StatusInternalServerError)
+		return
+	}
+	if _, err := promo.RedeemDeposit(ctx, qtx, int32(userID), int32(reqID), credit); err != nil {
+		log.Printf("[UPI-NOTIFY] Failed to credit deposit promo code: %v", err)
+		http.Error(w, "Failed to credit wallet", http.StatusInternalServerError)
+		return
 	}
 
-	// 5e. Commit
+	// 5d. Commit
 	if err := tx.Commit(ctx); err != nil {
 		http.Error(w, "Commit failed", http.StatusInternalServerError)
 		return
@@ -451,12 +504,10 @@ func (h *Handler) ApproveWalletRequest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	userID := int(reqRow.UserID.Int32)
-	val, _ := reqRow.Amount.Float64Value()
-	amount := val.Float64
 	status := reqRow.Status
 
 	if status.String != "pending" {
-		log.Printf("Request %d not pending: %s", id, status)
+		log.Printf("Request %d not pending: %s", id, status.String)
 		http.Error(w, "Request already processed", http.StatusBadRequest)
 		return
 	}
@@ -473,28 +524,27 @@ func (h *Handler) ApproveWalletRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 3. Update User Balance (Upsert into wallets table)
-	amountCents := int(amount * 100)
-	err = qtx.UpsertWalletBalance(ctx, sqlc.UpsertWalletBalanceParams{
-		UserID:  int32(userID),
-		Balance: int32(amountCents),
-	})
-
+	// 3. Credit the wallet and post the deposit
+	credit, err := money.FromNumeric(reqRow.Amount, money.Base, money.RoundNearest)
 	if err != nil {
-		log.Printf("Error updating wallet balance: %v", err)
-		http.Error(w, "Failed to update balance", http.StatusInternalServerError)
+		log.Printf("Invalid amount on request %d: %v", id, err)
+		http.Error(w, "Failed to credit wallet", http.StatusInternalServerError)
 		return
 	}
-
-	// 4. Insert Transaction Log
-	err = qtx.InsertTransaction(ctx, sqlc.InsertTransactionParams{
-		UserID:      pgtype.Int4{Int32: int32(userID), Valid: true},
-		Amount:      func() pgtype.Numeric { n := pgtype.Numeric{}; n.Scan(fmt.Sprintf("%f", amount)); return n }(),
-		Type:        "credit",
-		Description: pgtype.Text{String: "Manual Deposit Approved", Valid: true},
-	})
+	err = ledger.Deposit(ctx, qtx, int32(userID), credit, ledger.NewRef(ledger.RefWalletRequest, int32(id)), "Manual Deposit Approved")
 	if err != nil {
-		http.Error(w, "Failed to log transaction", http.StatusInternalServerError)
+		log.Printf("Error crediting wallet: %v", err)
+		http.Error(w, "Failed to update balance", http.StatusInternalServerError)
+		return
+	}
+	if _, err := bonus.Grant(ctx, qtx, int32(userID), int32(id), reqRow.Method, credit); err != nil {
+		log.Printf("Error crediting deposit bonus: %v", err)
+		http.Error(w, "Failed to update balance", http.StatusInternalServerError)
+		return
+	}
+	if _, err := promo.RedeemDeposit(ctx, qtx, int32(userID), int32(id), credit); err != nil {
+		log.Printf("Error crediting deposit promo code: %v", err)
+		http.Error(w, "Failed to update balance", http.StatusInternalServerError)
 		return
 	}
 
@@ -522,6 +572,94 @@ func (h *Handler) RejectWalletRequest(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "Rejected successfully"})
 }
 
+type ReverseWalletRequestReq struct {
+	Reason string `json:"reason"`
+}
+
+// ReverseWalletRequest takes an approved deposit back out of the user's
+// wallet after the payment was refunded, charged back or found to be
+// fraudulent, and claws back the referral commission earned on it
+func (h *Handler) ReverseWalletRequest(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	var req ReverseWalletRequestReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		http.Error(w, "Reason is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	tx, err := h.db.Pool.Begin(ctx)
+	if err != nil {
+		http.Error(w, "DB error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.db.Queries.WithTx(tx)
+	reqRow, err := qtx.GetWalletRequestForUpdateAdmin(ctx, int32(id))
+	if err != nil {
+		http.Error(w, "Request not found", http.StatusNotFound)
+		return
+	}
+	if reqRow.Status.String != "approved" {
+		http.Error(w, "Only approved requests can be reversed", http.StatusBadRequest)
+		return
+	}
+	if _, err := qtx.ReverseWalletRequest(ctx, int32(id)); err != nil {
+		log.Printf("Error reversing request %d: %v", id, err)
+		http.Error(w, "Failed to update status", http.StatusInternalServerError)
+		return
+	}
+
+	userID := reqRow.UserID.Int32
+	amount, err := money.FromNumeric(reqRow.Amount, money.Base, money.RoundNearest)
+	if err != nil {
+		log.Printf("Invalid amount on request %d: %v", id, err)
+		http.Error(w, "Failed to reverse deposit", http.StatusInternalServerError)
+		return
+	}
+	err = ledger.ReverseDeposit(ctx, qtx, userID, amount, ledger.NewRef(ledger.RefWalletRequest, int32(id)), "Deposit reversed: "+req.Reason)
+	if errors.Is(err, ledger.ErrInsufficientFunds) {
+		http.Error(w, "The user's available balance does not cover the deposit", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Error reversing deposit %d: %v", id, err)
+		http.Error(w, "Failed to reverse deposit", http.StatusInternalServerError)
+		return
+	}
+	if err := referral.Clawback(ctx, qtx, referral.BasisDeposit, int32(id)); err != nil {
+		log.Printf("Error clawing back referral commission on deposit %d: %v", id, err)
+		http.Error(w, "Failed to reverse deposit", http.StatusInternalServerError)
+		return
+	}
+	err = h.audit(ctx, qtx, r, "wallet_request.reverse", "wallet_request", strconv.Itoa(id), map[string]interface{}{
+		"user_id":      userID,
+		"amount_cents": amount.Minor,
+		"reason":       req.Reason,
+	})
+	if err != nil {
+		log.Printf("Failed to write audit log: %v", err)
+		http.Error(w, "Failed to reverse deposit", http.StatusInternalServerError)
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "Commit failed", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"message": "Reversed successfully"})
+}
+
 // GetDepositStatus returns the current status of a wallet deposit request
 // Used by the frontend to poll for auto-verification
 func (h *Handler) GetDepositStatus(w http.ResponseWriter, r *http.Request) {
@@ -557,15 +695,7 @@ func (h *Handler) GetRecentTransactions(w http.ResponseWriter, r *http.Request)
 	userID := r.Context().Value("userID").(int)
 	uid := pgtype.Int4{Int32: int32(userID), Valid: true}
 
-	type txnResp struct {
-		ID          int32   `json:"id"`
-		Amount      float64 `json:"amount"`
-		Type        string  `json:"type"`
-		Description string  `json:"description"`
-		CreatedAt   string  `json:"created_at"`
-	}
-
-	var result []txnResp
+	var result []TransactionRes
 
 	if r.URL.Query().Get("all") == "true" {
 		rows, err := h.db.Queries.GetAllMoneyTransactions(context.Background(), uid)
@@ -574,25 +704,9 @@ func (h *Handler) GetRecentTransactions(w http.ResponseWriter, r *http.Request)
 			http.Error(w, "Failed to fetch transactions", http.StatusInternalServerError)
 			return
 		}
-		result = make([]txnResp, 0, len(rows))
+		result = make([]TransactionRes, 0, len(rows))
 		for _, row := range rows {
-			var amount float64
-			if row.Amount.Valid {
-				f, _ := row.Amount.Float64Value()
-				amount = f.Float64
-			}
-			desc := ""
-			if row.Description.Valid {
-				desc = row.Description.String
-			}
-			createdAt := ""
-			if row.CreatedAt.Valid {
-				createdAt = row.CreatedAt.Time.Format(time.RFC3339)
-			}
-			result = append(result, txnResp{
-				ID: row.ID, Amount: amount, Type: row.Type,
-				Description: desc, CreatedAt: createdAt,
-			})
+			result = append(result, newTransactionRes(row.ID, row.Amount, row.Type, row.Kind, row.ReferenceType, row.ReferenceID, row.Description, row.CreatedAt))
 		}
 	} else {
 		rows, err := h.db.Queries.GetRecentMoneyTransactions(context.Background(), uid)
@@ -601,25 +715,9 @@ func (h *Handler) GetRecentTransactions(w http.ResponseWriter, r *http.Request)
 			http.Error(w, "Failed to fetch transactions", http.StatusInternalServerError)
 			return
 		}
-		result = make([]txnResp, 0, len(rows))
+		result = make([]TransactionRes, 0, len(rows))
 		for _, row := range rows {
-			var amount float64
-			if row.Amount.Valid {
-				f, _ := row.Amount.Float64Value()
-				amount = f.Float64
-			}
-			desc := ""
-			if row.Description.Valid {
-				desc = row.Description.String
-			}
-			createdAt := ""
-			if row.CreatedAt.Valid {
-				createdAt = row.CreatedAt.Time.Format(time.RFC3339)
-			}
-			result = append(result, txnResp{
-				ID: row.ID, Amount: amount, Type: row.Type,
-				Description: desc, CreatedAt: createdAt,
-			})
+			result = append(result, newTransactionRes(row.ID, row.Amount, row.Type, row.Kind, row.ReferenceType, row.ReferenceID, row.Description, row.CreatedAt))
 		}
 	}
 
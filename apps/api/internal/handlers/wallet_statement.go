@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/service/ledger"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// TransactionRes is one row of a wallet statement
+type TransactionRes struct {
+	ID            int32   `json:"id"`
+	Amount        float64 `json:"amount"`
+	Type          string  `json:"type"`
+	Kind          string  `json:"kind"`
+	ReferenceType string  `json:"reference_type"`
+	ReferenceID   string  `json:"reference_id"`
+	Description   string  `json:"description"`
+	CreatedAt     string  `json:"created_at"`
+}
+
+// GetWalletStatement lists the user's own transactions, filtered by
+// ?kind=, ?reference_type= and ?reference_id=
+func (h *Handler) GetWalletStatement(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(int)
+	h.writeStatement(w, r, userID)
+}
+
+// GetUserStatementAdmin lists a user's transactions for admins, with the same
+// filters as the user's own statement
+func (h *Handler) GetUserStatementAdmin(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	h.writeStatement(w, r, userID)
+}
+
+func (h *Handler) writeStatement(w http.ResponseWriter, r *http.Request, userID int) {
+	q := r.URL.Query()
+	kind := q.Get("kind")
+	if kind != "" && !slices.Contains(ledger.TransactionKinds, kind) {
+		http.Error(w, "Invalid kind", http.StatusBadRequest)
+		return
+	}
+	refType := q.Get("reference_type")
+	if refType != "" && !slices.Contains(ledger.ReferenceTypes, refType) {
+		http.Error(w, "Invalid reference_type", http.StatusBadRequest)
+		return
+	}
+	refID := q.Get("reference_id")
+
+	page, _ := strconv.Atoi(q.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	uid := pgtype.Int4{Int32: int32(userID), Valid: true}
+	kindArg := pgtype.Text{String: kind, Valid: kind != ""}
+	refTypeArg := pgtype.Text{String: refType, Valid: refType != ""}
+	refIDArg := pgtype.Text{String: refID, Valid: refID != ""}
+
+	ctx := context.Background()
+	rows, err := h.db.Queries.GetWalletTransactions(ctx, sqlc.GetWalletTransactionsParams{
+		UserID:        uid,
+		Kind:          kindArg,
+		ReferenceType: refTypeArg,
+		ReferenceID:   refIDArg,
+		Lim:           int32(limit),
+		Off:           int32((page - 1) * limit),
+	})
+	if err != nil {
+		log.Printf("Error fetching statement for user %d: %v", userID, err)
+		http.Error(w, "Failed to fetch transactions", http.StatusInternalServerError)
+		return
+	}
+	total, err := h.db.Queries.CountWalletTransactions(ctx, sqlc.CountWalletTransactionsParams{
+		UserID:        uid,
+		Kind:          kindArg,
+		ReferenceType: refTypeArg,
+		ReferenceID:   refIDArg,
+	})
+	if err != nil {
+		log.Printf("Error counting statement for user %d: %v", userID, err)
+		http.Error(w, "Failed to fetch transactions", http.StatusInternalServerError)
+		return
+	}
+
+	res := make([]TransactionRes, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, newTransactionRes(row.ID, row.Amount, row.Type, row.Kind, row.ReferenceType, row.ReferenceID, row.Description, row.CreatedAt))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"transactions": res,
+		"total":        total,
+		"page":         page,
+		"limit":        limit,
+	})
+}
+
+func newTransactionRes(id int32, amount pgtype.Numeric, txnType, kind, refType, refID string, description pgtype.Text, createdAt pgtype.Timestamptz) TransactionRes {
+	res := TransactionRes{
+		ID:            id,
+		Type:          txnType,
+		Kind:          kind,
+		ReferenceType: refType,
+		ReferenceID:   refID,
+		Description:   description.String,
+	}
+	if amount.Valid {
+		f, _ := amount.Float64Value()
+		res.Amount = f.Float64
+	}
+	if createdAt.Valid {
+		res.CreatedAt = createdAt.Time.Format(time.RFC3339)
+	}
+	return res
+}
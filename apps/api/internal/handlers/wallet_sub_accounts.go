@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/service/transfer"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+// SubAccountRes is a link between an agency and one of its sub-accounts.
+// Role is the user's side of it: "owner" for the agency, "member" for the
+// sub-account.
+type SubAccountRes struct {
+	ID          int32  `json:"id"`
+	Role        string `json:"role"`
+	Counterpart string `json:"counterpart"`
+	Name        string `json:"name,omitempty"`
+	Status      string `json:"status"`
+	CreatedAt   string `json:"created_at"`
+}
+
+func subAccountRes(userID int32, l sqlc.ListSubAccountsRow) SubAccountRes {
+	res := SubAccountRes{
+		ID:          l.ID,
+		Role:        "owner",
+		Counterpart: l.Username,
+		Name:        l.Name,
+		Status:      l.Status,
+		CreatedAt:   l.CreatedAt.Time.Format(time.RFC3339),
+	}
+	if l.UserID == userID {
+		res.Role, res.Counterpart, res.Name = "member", l.OwnerUsername, ""
+	}
+	return res
+}
+
+// GetSubAccounts returns the user's sub-accounts and the agency they belong
+// to, invitations included
+func (h *Handler) GetSubAccounts(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(int)
+
+	rows, err := h.db.Queries.ListSubAccounts(context.Background(), int32(userID))
+	if err != nil {
+		log.Printf("Error fetching sub-accounts for user %d: %v", userID, err)
+		http.Error(w, "Failed to fetch sub-accounts", http.StatusInternalServerError)
+		return
+	}
+	links := make([]SubAccountRes, 0, len(rows))
+	for _, l := range rows {
+		links = append(links, subAccountRes(int32(userID), l))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(links)
+}
+
+type SubAccountInviteReq struct {
+	// Account is a username or email
+	Account string `json:"account"`
+}
+
+// InviteSubAccount invites an account to become one of the user's
+// sub-accounts; it can receive and send transfers once it accepts
+func (h *Handler) InviteSubAccount(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(int)
+	var req SubAccountInviteReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Account = strings.TrimSpace(req.Account)
+	if req.Account == "" {
+		http.Error(w, "Account is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	account, err := h.db.Queries.FindTransferRecipient(ctx, req.Account)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := h.db.Pool.Begin(ctx)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	link, err := transfer.Invite(ctx, h.db.Queries.WithTx(tx), int32(userID), account.ID)
+	var inv *transfer.InvalidError
+	if errors.As(err, &inv) {
+		http.Error(w, inv.Reason, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Sub-account invitation from user %d to user %d failed: %v", userID, account.ID, err)
+		http.Error(w, "Failed to invite account", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "Failed to invite account", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(SubAccountRes{
+		ID:          link.ID,
+		Role:        "owner",
+		Counterpart: account.Username,
+		Name:        account.Name,
+		Status:      link.Status,
+		CreatedAt:   link.CreatedAt.Time.Format(time.RFC3339),
+	})
+}
+
+// AcceptSubAccount accepts an agency's invitation to become its sub-account
+func (h *Handler) AcceptSubAccount(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(int)
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	tx, err := h.db.Pool.Begin(ctx)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	err = transfer.Accept(ctx, h.db.Queries.WithTx(tx), int32(id), int32(userID))
+	var inv *transfer.InvalidError
+	if errors.As(err, &inv) {
+		http.Error(w, inv.Reason, http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, transfer.ErrLinkNotFound) {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("User %d failed to accept sub-account link %d: %v", userID, id, err)
+		http.Error(w, "Failed to accept invitation", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "Failed to accept invitation", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
+
+// RemoveSubAccount ends a sub-account link or turns down an invitation;
+// either side may do it
+func (h *Handler) RemoveSubAccount(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(int)
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	n, err := h.db.Queries.DeleteSubAccount(context.Background(), sqlc.DeleteSubAccountParams{
+		ID:     int32(id),
+		UserID: int32(userID),
+	})
+	if err != nil {
+		log.Printf("User %d failed to remove sub-account link %d: %v", userID, id, err)
+		http.Error(w, "Failed to remove sub-account", http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "Sub-account not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
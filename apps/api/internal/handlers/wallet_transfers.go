@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/transfer"
+	"pablosmm/backend/internal/service/twofactor"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TransferRecipientRes is who a transfer would go to. Only what the sender
+// needs to recognise the account is shown.
+type TransferRecipientRes struct {
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
+
+// WalletTransferRes is a transfer sent or received by the user
+type WalletTransferRes struct {
+	ID          int32   `json:"id"`
+	Direction   string  `json:"direction"`
+	Counterpart string  `json:"counterpart"`
+	Amount      float64 `json:"amount"`
+	Note        string  `json:"note,omitempty"`
+	Status      string  `json:"status"`
+	CreatedAt   string  `json:"created_at"`
+}
+
+// LookupTransferRecipient finds the account a transfer to ?q= (a username
+// or email) would go to, so the user can check it before sending. Only
+// accounts of the user's agency are found.
+func (h *Handler) LookupTransferRecipient(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(int)
+	login := strings.TrimSpace(r.URL.Query().Get("q"))
+	if login == "" {
+		http.Error(w, "Missing recipient", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	recipient, err := h.db.Queries.FindTransferRecipient(ctx, login)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && recipient.ID == int32(userID)) {
+		http.Error(w, "Recipient not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	linked, err := h.db.Queries.InSameAgency(ctx, sqlc.InSameAgencyParams{AID: int32(userID), BID: recipient.ID})
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if !linked {
+		http.Error(w, "Recipient not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(TransferRecipientRes{Username: recipient.Username, Name: recipient.Name})
+}
+
+// GetWalletTransfers returns the user's transfer limits and their recent
+// transfers in both directions
+func (h *Handler) GetWalletTransfers(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(int)
+	ctx := context.Background()
+
+	rows, err := h.db.Queries.ListWalletTransfers(ctx, sqlc.ListWalletTransfersParams{
+		UserID: pgtype.Int4{Int32: int32(userID), Valid: true},
+		Lim:    100,
+	})
+	if err != nil {
+		log.Printf("Error fetching transfers for user %d: %v", userID, err)
+		http.Error(w, "Failed to fetch transfers", http.StatusInternalServerError)
+		return
+	}
+	sent, err := transfer.SentToday(ctx, h.db.Queries, int32(userID))
+	if err != nil {
+		log.Printf("Error fetching transfers for user %d: %v", userID, err)
+		http.Error(w, "Failed to fetch transfers", http.StatusInternalServerError)
+		return
+	}
+	cfg := transfer.LoadConfig(ctx, h.db.Queries)
+
+	transfers := make([]WalletTransferRes, 0, len(rows))
+	for _, t := range rows {
+		res := WalletTransferRes{
+			ID:          t.ID,
+			Direction:   "sent",
+			Counterpart: t.RecipientUsername,
+			Amount:      money.Paise(t.AmountCents).Major(),
+			Note:        t.Note,
+			Status:      t.Status,
+			CreatedAt:   t.CreatedAt.Time.Format(time.RFC3339),
+		}
+		if t.RecipientID == int32(userID) {
+			res.Direction, res.Counterpart = "received", t.SenderUsername
+		}
+		transfers = append(transfers, res)
+	}
+
+	limits := map[string]interface{}{
+		"min":         money.Paise(cfg.MinCents).Major(),
+		"daily_limit": money.Paise(cfg.DailyLimitCents).Major(),
+		"sent_today":  sent.Major(),
+		"remaining":   money.Max(money.Paise(cfg.DailyLimitCents).Sub(sent), money.Paise(0)).Major(),
+	}
+	if cfg.ApprovalCents > 0 {
+		limits["approval_from"] = money.Paise(cfg.ApprovalCents).Major()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"limits":    limits,
+		"transfers": transfers,
+	})
+}
+
+type WalletTransferReq struct {
+	// Recipient is a username or email
+	Recipient string  `json:"recipient"`
+	Amount    float64 `json:"amount"`
+	Note      string  `json:"note"`
+	// Password or Code, from the user's authenticator app, confirms the
+	// transfer
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// CreateWalletTransfer sends money from the user's wallet to another
+// account of their agency. Large transfers wait for admin approval with the
+// amount held.
+func (h *Handler) CreateWalletTransfer(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(int)
+	var req WalletTransferReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Recipient = strings.TrimSpace(req.Recipient)
+	req.Note = strings.TrimSpace(req.Note)
+	if req.Recipient == "" {
+		http.Error(w, "Recipient is required", http.StatusBadRequest)
+		return
+	}
+	if req.Amount <= 0 {
+		http.Error(w, "Invalid amount", http.StatusBadRequest)
+		return
+	}
+	if len(req.Note) > 200 {
+		http.Error(w, "Note must be at most 200 characters", http.StatusBadRequest)
+		return
+	}
+	amount, err := money.FromMajor(req.Amount, money.Base, money.RoundNearest)
+	if err != nil {
+		http.Error(w, "Invalid amount", http.StatusBadRequest)
+		return
+	}
+
+	ctx := context.Background()
+	if !h.confirmTransfer(w, ctx, int32(userID), req) {
+		return
+	}
+
+	recipient, err := h.db.Queries.FindTransferRecipient(ctx, req.Recipient)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Recipient not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := h.db.Pool.Begin(ctx)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback(ctx)
+	qtx := h.db.Queries.WithTx(tx)
+
+	t, err := transfer.Send(ctx, qtx, int32(userID), recipient.ID, amount, req.Note)
+	var inv *transfer.InvalidError
+	if errors.As(err, &inv) {
+		http.Error(w, inv.Reason, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Transfer from user %d to user %d failed: %v", userID, recipient.ID, err)
+		http.Error(w, "Transfer failed", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		http.Error(w, "Transfer failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(WalletTransferRes{
+		ID:          t.ID,
+		Direction:   "sent",
+		Counterpart: recipient.Username,
+		Amount:      money.Paise(t.AmountCents).Major(),
+		Note:        t.Note,
+		Status:      t.Status,
+		CreatedAt:   t.CreatedAt.Time.Format(time.RFC3339),
+	})
+}
+
+// confirmTransfer checks the two-factor code, or else the password, the
+// user confirmed a transfer with, writing the error response if it fails.
+// Accounts without a password, such as Google sign-ins, confirm with
+// two-factor authentication.
+func (h *Handler) confirmTransfer(w http.ResponseWriter, ctx context.Context, userID int32, req WalletTransferReq) bool {
+	if req.Code != "" {
+		err := twofactor.Verify(ctx, h.db.Queries, userID, req.Code)
+		switch {
+		case errors.Is(err, twofactor.ErrNotEnabled):
+			http.Error(w, "Two-factor authentication is not on for your account", http.StatusBadRequest)
+		case errors.Is(err, twofactor.ErrInvalidCode):
+			http.Error(w, "Incorrect code", http.StatusUnauthorized)
+		case err != nil:
+			http.Error(w, "Database error", http.StatusInternalServerError)
+		}
+		return err == nil
+	}
+
+	hash, err := h.db.Queries.GetPasswordHash(ctx, userID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return false
+	}
+	if hash == "" {
+		http.Error(w, "Turn on two-factor authentication or set a password to send transfers", http.StatusForbidden)
+		return false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(req.Password)) != nil {
+		http.Error(w, "Incorrect password", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,300 @@
+// Package money holds amounts as whole minor units (paise for INR) in an
+// int64, tagged with their currency. Conversions from decimal or float
+// inputs are exact up to the point of rounding, and every rounding names
+// its mode.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Currency is an ISO 4217 code
+type Currency string
+
+const (
+	INR Currency = "INR"
+	USD Currency = "USD"
+)
+
+// Base is the currency wallets, orders and the ledger are kept in
+const Base = INR
+
+var currencies = map[Currency]struct {
+	exponent int
+	symbol   string
+}{
+	INR: {2, "₹"},
+	USD: {2, "$"},
+}
+
+// Exponent is the number of minor-unit digits, 2 for paise and cents
+func (c Currency) Exponent() int {
+	if info, ok := currencies[c]; ok {
+		return info.exponent
+	}
+	return 2
+}
+
+func (c Currency) Symbol() string {
+	if info, ok := currencies[c]; ok {
+		return info.symbol
+	}
+	return string(c) + " "
+}
+
+func (c Currency) scale() *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(c.Exponent())), nil)
+}
+
+// Rounding says what happens to a fraction of a minor unit. The values are
+// the ones stored in refund rules.
+type Rounding string
+
+const (
+	// RoundDown truncates toward zero
+	RoundDown Rounding = "down"
+	// RoundUp rounds away from zero
+	RoundUp Rounding = "up"
+	// RoundNearest rounds to the nearest unit, halves away from zero
+	RoundNearest Rounding = "nearest"
+	// RoundHalfEven rounds to the nearest unit, halves to the even one
+	RoundHalfEven Rounding = "half_even"
+)
+
+var ErrOverflow = errors.New("money: amount out of range")
+
+// Money is an amount in minor units of its currency
+type Money struct {
+	Minor    int64
+	Currency Currency
+}
+
+func New(minor int64, c Currency) Money {
+	return Money{Minor: minor, Currency: c}
+}
+
+// Paise is an amount in the base currency
+func Paise(minor int64) Money {
+	return Money{Minor: minor, Currency: Base}
+}
+
+// FromMajor converts an amount in major units (rupees). The float is read as
+// the shortest decimal that represents it, so 0.29 is 29 paise and not
+// 28.999…, and only then rounded.
+func FromMajor(major float64, c Currency, mode Rounding) (Money, error) {
+	return Parse(strconv.FormatFloat(major, 'f', -1, 64), c, mode)
+}
+
+// Parse reads a decimal amount in major units, such as "1234.5"
+func Parse(s string, c Currency, mode Rounding) (Money, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return Money{}, fmt.Errorf("money: invalid amount %q", s)
+	}
+	r.Mul(r, new(big.Rat).SetInt(c.scale()))
+	minor, err := round(r, mode)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Minor: minor, Currency: c}, nil
+}
+
+// FromNumeric converts a NUMERIC column holding major units
+func FromNumeric(n pgtype.Numeric, c Currency, mode Rounding) (Money, error) {
+	if !n.Valid || n.Int == nil {
+		return Money{Currency: c}, nil
+	}
+	r := new(big.Rat).SetInt(n.Int)
+	exp := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(n.Exp))), nil)
+	if n.Exp >= 0 {
+		r.Mul(r, new(big.Rat).SetInt(exp))
+	} else {
+		r.Quo(r, new(big.Rat).SetInt(exp))
+	}
+	r.Mul(r, new(big.Rat).SetInt(c.scale()))
+	minor, err := round(r, mode)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Minor: minor, Currency: c}, nil
+}
+
+// PerThousand prices quantity units at a rate per 1000 units in major units,
+// as provider catalogs quote them
+func PerThousand(ratePer1000 float64, quantity int64, c Currency, mode Rounding) (Money, error) {
+	rate, ok := new(big.Rat).SetString(strconv.FormatFloat(ratePer1000, 'f', -1, 64))
+	if !ok {
+		return Money{}, fmt.Errorf("money: invalid rate %v", ratePer1000)
+	}
+	r := rate.Mul(rate, new(big.Rat).SetFrac(new(big.Int).Mul(big.NewInt(quantity), c.scale()), big.NewInt(1000)))
+	minor, err := round(r, mode)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Minor: minor, Currency: c}, nil
+}
+
+// Numeric is the amount in major units for a NUMERIC column
+func (m Money) Numeric() pgtype.Numeric {
+	return pgtype.Numeric{Int: big.NewInt(m.Minor), Exp: int32(-m.Currency.Exponent()), Valid: true}
+}
+
+// Major is the amount in major units for display and JSON; never compute
+// with it
+func (m Money) Major() float64 {
+	f, _ := new(big.Rat).SetFrac(big.NewInt(m.Minor), m.Currency.scale()).Float64()
+	return f
+}
+
+// Decimal formats the amount in major units without a symbol, e.g. "-12.05"
+func (m Money) Decimal() string {
+	return new(big.Rat).SetFrac(big.NewInt(m.Minor), m.Currency.scale()).FloatString(m.Currency.Exponent())
+}
+
+// String formats the amount with its symbol, e.g. "₹12.05" or "-₹3.00"
+func (m Money) String() string {
+	if m.Minor < 0 {
+		return "-" + m.Currency.Symbol() + m.Neg().Decimal()
+	}
+	return m.Currency.Symbol() + m.Decimal()
+}
+
+func (m Money) IsZero() bool     { return m.Minor == 0 }
+func (m Money) IsNegative() bool { return m.Minor < 0 }
+func (m Money) IsPositive() bool { return m.Minor > 0 }
+
+func (m Money) Neg() Money {
+	return Money{Minor: -m.Minor, Currency: m.Currency}
+}
+
+// Add and Sub panic when the currencies differ; mixing currencies is a bug,
+// not a runtime condition
+func (m Money) Add(o Money) Money {
+	m.mustMatch(o)
+	return Money{Minor: m.Minor + o.Minor, Currency: m.Currency}
+}
+
+func (m Money) Sub(o Money) Money {
+	m.mustMatch(o)
+	return Money{Minor: m.Minor - o.Minor, Currency: m.Currency}
+}
+
+// Cmp returns -1, 0 or 1 as m is less than, equal to or greater than o
+func (m Money) Cmp(o Money) int {
+	m.mustMatch(o)
+	switch {
+	case m.Minor < o.Minor:
+		return -1
+	case m.Minor > o.Minor:
+		return 1
+	}
+	return 0
+}
+
+func Min(a, b Money) Money {
+	if a.Cmp(b) <= 0 {
+		return a
+	}
+	return b
+}
+
+func Max(a, b Money) Money {
+	if a.Cmp(b) >= 0 {
+		return a
+	}
+	return b
+}
+
+// MulFrac is m × num / den, rounded once at the end
+func (m Money) MulFrac(num, den int64, mode Rounding) Money {
+	if den == 0 {
+		panic("money: division by zero")
+	}
+	r := new(big.Rat).SetFrac(new(big.Int).Mul(big.NewInt(m.Minor), big.NewInt(num)), big.NewInt(den))
+	minor, err := round(r, mode)
+	if err != nil {
+		panic(err)
+	}
+	return Money{Minor: minor, Currency: m.Currency}
+}
+
+// Percent is pct percent of m. The percentage is read as its shortest
+// decimal, like FromMajor.
+func (m Money) Percent(pct float64, mode Rounding) Money {
+	p, _ := new(big.Rat).SetString(strconv.FormatFloat(pct, 'f', -1, 64))
+	r := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Minor), p)
+	r.Quo(r, big.NewRat(100, 1))
+	minor, err := round(r, mode)
+	if err != nil {
+		panic(err)
+	}
+	return Money{Minor: minor, Currency: m.Currency}
+}
+
+// Convert changes m into another currency. rate is the price of one major
+// unit of m's currency in major units of to, as a decimal string such as
+// "83.25", so the conversion is exact up to the final rounding.
+func (m Money) Convert(to Currency, rate string, mode Rounding) (Money, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(rate))
+	if !ok || r.Sign() <= 0 {
+		return Money{}, fmt.Errorf("money: invalid rate %q", rate)
+	}
+	r.Mul(r, new(big.Rat).SetFrac(big.NewInt(m.Minor), m.Currency.scale()))
+	r.Mul(r, new(big.Rat).SetInt(to.scale()))
+	minor, err := round(r, mode)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Minor: minor, Currency: to}, nil
+}
+
+func (m Money) mustMatch(o Money) {
+	if m.Currency != o.Currency {
+		panic(fmt.Sprintf("money: %s and %s amounts mixed", m.Currency, o.Currency))
+	}
+}
+
+// Round rounds an exact fraction of minor units to a whole one
+func Round(r *big.Rat, mode Rounding) (int64, error) {
+	return round(r, mode)
+}
+
+func round(r *big.Rat, mode Rounding) (int64, error) {
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if rem.Sign() != 0 {
+		// q was truncated toward zero; away moves it one unit outward
+		away := false
+		switch mode {
+		case RoundUp:
+			away = true
+		case RoundNearest, RoundHalfEven:
+			twice := new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2))
+			switch twice.Cmp(r.Denom()) {
+			case 1:
+				away = true
+			case 0:
+				away = mode == RoundNearest || q.Bit(0) == 1
+			}
+		}
+		if away {
+			q.Add(q, big.NewInt(int64(r.Sign())))
+		}
+	}
+	if !q.IsInt64() {
+		return 0, ErrOverflow
+	}
+	return q.Int64(), nil
+}
+
+func abs(n int32) int32 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
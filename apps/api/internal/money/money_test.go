@@ -0,0 +1,223 @@
+package money
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestRound(t *testing.T) {
+	cases := []struct {
+		num, den int64
+		mode     Rounding
+		want     int64
+	}{
+		{5, 2, RoundDown, 2},
+		{5, 2, RoundUp, 3},
+		{5, 2, RoundNearest, 3},
+		{5, 2, RoundHalfEven, 2},
+		{7, 2, RoundHalfEven, 4},
+		{-5, 2, RoundDown, -2},
+		{-5, 2, RoundUp, -3},
+		{-5, 2, RoundNearest, -3},
+		{-5, 2, RoundHalfEven, -2},
+		{-7, 2, RoundHalfEven, -4},
+		{7, 3, RoundDown, 2},
+		{7, 3, RoundUp, 3},
+		{7, 3, RoundNearest, 2},
+		{8, 3, RoundNearest, 3},
+		{8, 3, RoundHalfEven, 3},
+		{6, 3, RoundUp, 2},
+		{0, 1, RoundUp, 0},
+	}
+	for _, c := range cases {
+		got, err := Round(big.NewRat(c.num, c.den), c.mode)
+		if err != nil {
+			t.Fatalf("Round(%d/%d, %s): %v", c.num, c.den, c.mode, err)
+		}
+		if got != c.want {
+			t.Errorf("Round(%d/%d, %s) = %d, want %d", c.num, c.den, c.mode, got, c.want)
+		}
+	}
+}
+
+func TestRoundOverflow(t *testing.T) {
+	r := new(big.Rat).SetInt(new(big.Int).Lsh(big.NewInt(1), 64))
+	if _, err := Round(r, RoundDown); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Round(2^64) error = %v, want ErrOverflow", err)
+	}
+}
+
+func TestFromMajor(t *testing.T) {
+	cases := []struct {
+		major float64
+		mode  Rounding
+		want  int64
+	}{
+		// 0.29 * 100 is 28.999999999999996 in float64
+		{0.29, RoundDown, 29},
+		{1.005, RoundDown, 100},
+		{1.005, RoundNearest, 101},
+		{1.005, RoundHalfEven, 100},
+		{1.015, RoundHalfEven, 102},
+		{-0.125, RoundNearest, -13},
+		{21474836.48, RoundDown, 2147483648},
+		{0, RoundUp, 0},
+	}
+	for _, c := range cases {
+		got, err := FromMajor(c.major, INR, c.mode)
+		if err != nil {
+			t.Fatalf("FromMajor(%v): %v", c.major, err)
+		}
+		if got.Minor != c.want || got.Currency != INR {
+			t.Errorf("FromMajor(%v, %s) = %+v, want %d INR", c.major, c.mode, got, c.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	got, err := Parse(" 1234.5 ", INR, RoundDown)
+	if err != nil || got.Minor != 123450 {
+		t.Errorf("Parse(1234.5) = %+v, %v", got, err)
+	}
+	got, err = Parse("0.004", USD, RoundUp)
+	if err != nil || got.Minor != 1 || got.Currency != USD {
+		t.Errorf("Parse(0.004, up) = %+v, %v", got, err)
+	}
+	if _, err := Parse("12,50", INR, RoundDown); err == nil {
+		t.Error("Parse(12,50) succeeded")
+	}
+}
+
+func TestPerThousand(t *testing.T) {
+	cases := []struct {
+		rate float64
+		qty  int64
+		mode Rounding
+		want int64
+	}{
+		{29, 10, RoundDown, 29},
+		{0.29, 1000, RoundDown, 29},
+		{12.5, 3, RoundDown, 3},
+		{12.5, 3, RoundUp, 4},
+		{12.5, 3, RoundNearest, 4},
+		{15, 1, RoundNearest, 2},
+		{15, 1, RoundHalfEven, 2},
+		{25, 1, RoundHalfEven, 2},
+	}
+	for _, c := range cases {
+		got, err := PerThousand(c.rate, c.qty, INR, c.mode)
+		if err != nil {
+			t.Fatalf("PerThousand(%v, %d): %v", c.rate, c.qty, err)
+		}
+		if got.Minor != c.want {
+			t.Errorf("PerThousand(%v, %d, %s) = %d, want %d", c.rate, c.qty, c.mode, got.Minor, c.want)
+		}
+	}
+}
+
+func TestNumeric(t *testing.T) {
+	n := pgtype.Numeric{}
+	if err := n.Scan("150.255"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := FromNumeric(n, INR, RoundNearest)
+	if err != nil || got.Minor != 15026 {
+		t.Errorf("FromNumeric(150.255) = %+v, %v", got, err)
+	}
+	got, err = FromNumeric(pgtype.Numeric{Int: big.NewInt(2), Exp: 3, Valid: true}, INR, RoundDown)
+	if err != nil || got.Minor != 200000 {
+		t.Errorf("FromNumeric(2e3) = %+v, %v", got, err)
+	}
+	got, _ = FromNumeric(pgtype.Numeric{}, INR, RoundDown)
+	if !got.IsZero() {
+		t.Errorf("FromNumeric(NULL) = %+v, want zero", got)
+	}
+
+	back, err := FromNumeric(Paise(-1205).Numeric(), INR, RoundDown)
+	if err != nil || back.Minor != -1205 {
+		t.Errorf("Numeric round trip = %+v, %v", back, err)
+	}
+}
+
+func TestMulFracAndPercent(t *testing.T) {
+	m := Paise(1000)
+	if got := m.MulFrac(1, 3, RoundDown); got.Minor != 333 {
+		t.Errorf("1000 x 1/3 down = %d", got.Minor)
+	}
+	if got := m.MulFrac(2, 3, RoundNearest); got.Minor != 667 {
+		t.Errorf("1000 x 2/3 nearest = %d", got.Minor)
+	}
+	if got := Paise(999).Percent(2.5, RoundDown); got.Minor != 24 {
+		t.Errorf("2.5%% of 999 down = %d", got.Minor)
+	}
+	if got := Paise(999).Percent(2.5, RoundUp); got.Minor != 25 {
+		t.Errorf("2.5%% of 999 up = %d", got.Minor)
+	}
+	// 0.1 is not exact in float64; the shortest decimal is used
+	if got := Paise(1000).Percent(0.1, RoundDown); got.Minor != 1 {
+		t.Errorf("0.1%% of 1000 down = %d", got.Minor)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	got, err := New(1050, USD).Convert(INR, "83.25", RoundDown)
+	if err != nil || got != Paise(87412) {
+		t.Errorf("$10.50 at 83.25 = %+v, %v", got, err)
+	}
+	got, err = New(1050, USD).Convert(INR, "83.25", RoundUp)
+	if err != nil || got != Paise(87413) {
+		t.Errorf("$10.50 at 83.25 up = %+v, %v", got, err)
+	}
+	for _, rate := range []string{"", "0", "-1", "abc"} {
+		if _, err := New(100, USD).Convert(INR, rate, RoundDown); err == nil {
+			t.Errorf("rate %q accepted", rate)
+		}
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	cases := []struct {
+		m       Money
+		str     string
+		decimal string
+	}{
+		{Paise(1205), "₹12.05", "12.05"},
+		{Paise(-300), "-₹3.00", "-3.00"},
+		{Paise(7), "₹0.07", "0.07"},
+		{New(123456789, USD), "$1234567.89", "1234567.89"},
+	}
+	for _, c := range cases {
+		if got := c.m.String(); got != c.str {
+			t.Errorf("String(%+v) = %q, want %q", c.m, got, c.str)
+		}
+		if got := c.m.Decimal(); got != c.decimal {
+			t.Errorf("Decimal(%+v) = %q, want %q", c.m, got, c.decimal)
+		}
+	}
+	if got := Paise(1205).Major(); got != 12.05 {
+		t.Errorf("Major = %v", got)
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	a, b := Paise(500), Paise(200)
+	if got := a.Sub(b); got.Minor != 300 {
+		t.Errorf("Sub = %d", got.Minor)
+	}
+	if got := a.Add(b.Neg()); got.Minor != 300 {
+		t.Errorf("Add(Neg) = %d", got.Minor)
+	}
+	if Min(a, b) != b || Max(a, b) != a || a.Cmp(b) != 1 {
+		t.Error("comparison helpers disagree")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("mixing currencies did not panic")
+		}
+	}()
+	a.Add(New(1, USD))
+}
@@ -6,4 +6,8 @@ const (
 	DefaultName     = "TOPSMM"
 	DefaultAPIURL   = "https://topsmm.in/api/v2"
 	DefaultCurrency = "INR"
+
+	// DefaultMaxConcurrency is how many status calls may run against a
+	// provider at once unless configured otherwise
+	DefaultMaxConcurrency = 2
 )
@@ -8,7 +8,9 @@ import (
 	"pablosmm/backend/internal/config"
 	"pablosmm/backend/internal/db"
 	"pablosmm/backend/internal/handlers"
+	"pablosmm/backend/internal/service/links"
 	"pablosmm/backend/internal/service/metadata"
+	"pablosmm/backend/internal/service/refill"
 	"pablosmm/backend/internal/service/smm"
 
 	"github.com/go-chi/chi/v5"
@@ -16,9 +18,9 @@ import (
 	"github.com/go-chi/cors"
 )
 
-func New(cfg *config.Config, database *db.DB, smmSvc *smm.ProviderService) *http.Server {
-	metaSvc := metadata.New()
-	h := handlers.New(database, cfg, smmSvc, metaSvc)
+func New(cfg *config.Config, database *db.DB, smmSvc *smm.ProviderService, metaSvc *metadata.Service, refillSvc *refill.Service) *http.Server {
+	linkSvc := links.New()
+	h := handlers.New(database, cfg, smmSvc, metaSvc, linkSvc, refillSvc)
 	h.EnsureDefaultAdminUser()
 
 	r := chi.NewRouter()
@@ -71,14 +73,36 @@ func New(cfg *config.Config, database *db.DB, smmSvc *smm.ProviderService) *http
 			r.Get("/wallet/deposit/status", h.GetDepositStatus)
 			r.Post("/wallet/cryptomus/create", h.CreateCryptomusPayment)
 			r.Get("/wallet/transactions/recent", h.GetRecentTransactions)
+			r.Get("/wallet/transactions", h.GetWalletStatement)
+			r.Get("/wallet/funds", h.GetWalletFunds)
+			r.Get("/wallet/bonuses", h.GetWalletBonuses)
+			r.Get("/wallet/transfers", h.GetWalletTransfers)
+			r.Get("/wallet/transfers/recipient", h.LookupTransferRecipient)
+			r.Post("/wallet/transfers", h.CreateWalletTransfer)
+			r.Get("/wallet/sub-accounts", h.GetSubAccounts)
+			r.Post("/wallet/sub-accounts", h.InviteSubAccount)
+			r.Post("/wallet/sub-accounts/{id}/accept", h.AcceptSubAccount)
+			r.Delete("/wallet/sub-accounts/{id}", h.RemoveSubAccount)
+			r.Get("/referrals", h.GetReferrals)
+			r.Post("/referrals/convert", h.ConvertReferralEarnings)
+			r.Post("/referrals/payouts", h.RequestReferralPayout)
+			r.Get("/invoices", h.GetInvoices)
+			r.Get("/invoices/statement", h.GetInvoiceStatement)
+			r.Get("/invoices/{id}/html", h.GetInvoiceHTML)
+			r.Get("/invoices/{id}/pdf", h.GetInvoicePDF)
 			r.Get("/orders", h.GetOrders)
 			r.Post("/orders/{id}/cancel", h.CancelOrder)
 			r.Post("/orders/{id}/refill", h.RefillOrder)
 			r.Post("/orders", h.CreateOrder)
 			r.Get("/orders/{id}", h.GetSingleOrder)
 			r.Post("/auth/change-password", h.ChangePassword)
+			r.Post("/auth/2fa/setup", h.SetupTwoFactor)
+			r.Post("/auth/2fa/enable", h.EnableTwoFactor)
+			r.Post("/auth/2fa/disable", h.DisableTwoFactor)
 			r.Put("/profile", h.UpdateProfile)
 			r.Post("/profile/api-key", h.GenerateAPIKey)
+			r.Get("/profile/billing", h.GetBillingProfile)
+			r.Put("/profile/billing", h.UpdateBillingProfile)
 		})
 
 		r.Get("/services", h.GetServices)
@@ -102,6 +126,16 @@ func New(cfg *config.Config, database *db.DB, smmSvc *smm.ProviderService) *http
 			r.Post("/admin/catalog", h.CreateCatalogServiceAdmin)
 			r.Put("/admin/catalog/{id}", h.UpdateCatalogServiceAdmin)
 			r.Delete("/admin/catalog/{id}", h.DeleteCatalogServiceAdmin)
+			r.Get("/admin/catalog/refill-policies", h.ListRefillPoliciesAdmin)
+			r.Put("/admin/catalog/{id}/refill-policy", h.UpsertRefillPolicyAdmin)
+			r.Put("/admin/catalog/{id}/refund-rule", h.UpsertServiceRefundRuleAdmin)
+			r.Delete("/admin/catalog/{id}/refund-rule", h.DeleteServiceRefundRuleAdmin)
+			r.Get("/admin/refund-rules", h.ListRefundRulesAdmin)
+			r.Put("/admin/refund-rules/global", h.UpsertGlobalRefundRuleAdmin)
+			r.Get("/admin/status-mappings", h.ListStatusMappingsAdmin)
+			r.Put("/admin/status-mappings", h.UpsertStatusMappingAdmin)
+			r.Delete("/admin/status-mappings/{id}", h.DeleteStatusMappingAdmin)
+			r.Get("/admin/status-mappings/unmapped", h.GetUnmappedStatusesAdmin)
 			r.Get("/admin/provider-services", h.GetRawProviderServices)
 
 			r.Post("/admin/services/curate", h.CurateServicesAdmin)
@@ -115,19 +149,68 @@ func New(cfg *config.Config, database *db.DB, smmSvc *smm.ProviderService) *http
 			r.Get("/admin/users", h.GetUsers)
 			r.Get("/admin/users/{id}", h.GetUser)
 			r.Post("/admin/users/{id}/wallet", h.UpdateUserWallet)
+			r.Get("/admin/users/{id}/transactions", h.GetUserStatementAdmin)
 			r.Patch("/admin/users/{id}", h.UpdateUser)
 
 			r.Get("/admin/orders", h.GetAdminOrders)
 			r.Post("/admin/orders/{id}/refund", h.RefundOrder)
+			r.Get("/admin/orders/{id}/refunds", h.GetOrderRefundsAdmin)
 			r.Patch("/admin/orders/{id}/refills", h.UpdateOrderRefills)
+			r.Post("/admin/orders/{id}/resume-sync", h.ResumeOrderSync)
+			r.Get("/admin/orders/{id}/delivery-checks", h.GetOrderDeliveryChecks)
+			r.Get("/admin/delivery-checks", h.GetDeliveryDashboard)
+			r.Get("/admin/delivery-stats", h.GetDeliveryStatsAdmin)
+			r.Get("/admin/ledger/check", h.GetLedgerCheck)
+			r.Get("/admin/reconcile/runs", h.ListReconcileRunsAdmin)
+			r.Post("/admin/reconcile/run", h.RunReconcileAdmin)
+			r.Get("/admin/reconcile/discrepancies", h.ListReconcileDiscrepanciesAdmin)
+			r.Get("/admin/reconcile/discrepancies/{id}", h.GetReconcileDiscrepancyAdmin)
+			r.Post("/admin/reconcile/discrepancies/{id}/fix", h.FixReconcileDiscrepancyAdmin)
+			r.Post("/admin/reconcile/discrepancies/{id}/ignore", h.IgnoreReconcileDiscrepancyAdmin)
 
 			r.Get("/admin/order-requests", h.GetAdminOrderRequests)
 			r.Post("/admin/order-requests/{id}/approve", h.ApproveOrderRequest)
 			r.Post("/admin/order-requests/{id}/reject", h.RejectOrderRequest)
+			r.Get("/admin/audit-log", h.GetAdminAuditLog)
+			r.Get("/admin/escalations", h.GetOrderEscalations)
+			r.Post("/admin/escalations/{id}/resolve", h.ResolveOrderEscalation)
 
 			r.Get("/admin/wallet-requests", h.ListWalletRequests)
 			r.Post("/admin/wallet-requests/{id}/approve", h.ApproveWalletRequest)
 			r.Post("/admin/wallet-requests/{id}/reject", h.RejectWalletRequest)
+			r.Post("/admin/wallet-requests/{id}/reverse", h.ReverseWalletRequest)
+			r.Get("/admin/wallet-transfers", h.ListWalletTransfersAdmin)
+			r.Post("/admin/wallet-transfers/{id}/approve", h.ApproveWalletTransferAdmin)
+			r.Post("/admin/wallet-transfers/{id}/reject", h.RejectWalletTransferAdmin)
+
+			r.Get("/admin/bonus-tiers", h.ListDepositBonusTiersAdmin)
+			r.Post("/admin/bonus-tiers", h.CreateDepositBonusTierAdmin)
+			r.Put("/admin/bonus-tiers/{id}", h.UpdateDepositBonusTierAdmin)
+			r.Delete("/admin/bonus-tiers/{id}", h.DeleteDepositBonusTierAdmin)
+			r.Get("/admin/bonuses", h.ListBonusGrantsAdmin)
+
+			r.Get("/admin/promo-codes", h.ListPromoCodesAdmin)
+			r.Post("/admin/promo-codes", h.CreatePromoCodeAdmin)
+			r.Get("/admin/promo-codes/analytics", h.GetPromoCodeAnalyticsAdmin)
+			r.Get("/admin/promo-codes/redemptions", h.ListPromoRedemptionsAdmin)
+			r.Put("/admin/promo-codes/{id}", h.UpdatePromoCodeAdmin)
+			r.Delete("/admin/promo-codes/{id}", h.DeletePromoCodeAdmin)
+
+			r.Get("/admin/referrals", h.ListReferralsAdmin)
+			r.Get("/admin/referrals/report", h.GetReferralReportAdmin)
+			r.Put("/admin/referrals/{id}/status", h.SetReferralStatusAdmin)
+			r.Get("/admin/referral-payouts", h.ListReferralPayoutsAdmin)
+			r.Post("/admin/referral-payouts/{id}/approve", h.ApproveReferralPayoutAdmin)
+			r.Post("/admin/referral-payouts/{id}/reject", h.RejectReferralPayoutAdmin)
+			r.Get("/admin/invoices", h.ListInvoicesAdmin)
+			r.Get("/admin/invoices/failures", h.ListInvoiceFailuresAdmin)
+			r.Get("/admin/invoices/{id}/html", h.GetInvoiceHTMLAdmin)
+			r.Get("/admin/invoices/{id}/pdf", h.GetInvoicePDFAdmin)
+
+			r.Get("/admin/link-rules", h.ListLinkRulesAdmin)
+			r.Post("/admin/link-rules", h.CreateLinkRuleAdmin)
+			r.Put("/admin/link-rules/{id}", h.UpdateLinkRuleAdmin)
+			r.Delete("/admin/link-rules/{id}", h.DeleteLinkRuleAdmin)
 
 			r.Get("/admin/settings", h.GetSettings)
 			r.Post("/admin/settings", h.UpdateSettings)
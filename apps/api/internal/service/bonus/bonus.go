@@ -0,0 +1,180 @@
+// Package bonus credits promotional money on deposits according to the
+// deposit bonus tiers, and takes back what is left of a bonus once it
+// expires.
+package bonus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"pablosmm/backend/internal/db"
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/jobs"
+	"pablosmm/backend/internal/service/ledger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const JobName = "bonus_expiry"
+
+// Payment methods a tier can be limited to. Deposit methods are compared in
+// lowercase, so "UPI" requests match MethodUPI.
+const (
+	MethodUPI       = "upi"
+	MethodCryptomus = "cryptomus"
+)
+
+var Methods = []string{MethodUPI, MethodCryptomus}
+
+func NormalizeMethod(method string) string {
+	return strings.ToLower(strings.TrimSpace(method))
+}
+
+// Amount is what a tier pays on a deposit: its percentage, rounded down and
+// capped at the tier's maximum
+func Amount(tier sqlc.DepositBonusTier, deposit money.Money) money.Money {
+	bonus := deposit.Percent(tier.BonusPct, money.RoundDown)
+	if tier.MaxBonusCents.Valid && bonus.Minor > tier.MaxBonusCents.Int64 {
+		bonus = money.New(tier.MaxBonusCents.Int64, deposit.Currency)
+	}
+	return bonus
+}
+
+// Grant credits the bonus an approved deposit earns, if any, and returns
+// it. q must be bound to the transaction approving the deposit so both
+// commit together; a deposit can only earn one bonus.
+func Grant(ctx context.Context, q *sqlc.Queries, userID, requestID int32, method string, deposit money.Money) (money.Money, error) {
+	none := money.New(0, deposit.Currency)
+	tier, err := q.GetDepositBonusTierFor(ctx, sqlc.GetDepositBonusTierForParams{
+		DepositCents: deposit.Minor,
+		Method:       NormalizeMethod(method),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return none, nil
+	}
+	if err != nil {
+		return none, fmt.Errorf("bonus tier: %w", err)
+	}
+	amount := Amount(tier, deposit)
+	if !amount.IsPositive() {
+		return none, nil
+	}
+
+	var expiresAt pgtype.Timestamptz
+	if tier.ExpiresAfterDays.Valid {
+		expiresAt = pgtype.Timestamptz{Time: time.Now().AddDate(0, 0, int(tier.ExpiresAfterDays.Int32)), Valid: true}
+	}
+	description := fmt.Sprintf("%g%% bonus on deposit #%d", tier.BonusPct, requestID)
+	if tier.Name != "" {
+		description = fmt.Sprintf("%s: %s", tier.Name, description)
+	}
+	err = Credit(ctx, q, sqlc.InsertBonusGrantParams{
+		UserID:          userID,
+		TierID:          pgtype.Int4{Int32: tier.ID, Valid: true},
+		WalletRequestID: pgtype.Int4{Int32: requestID, Valid: true},
+		DepositCents:    deposit.Minor,
+		AmountCents:     amount.Minor,
+		Withdrawable:    tier.Withdrawable,
+		ExpiresAt:       expiresAt,
+	}, description)
+	if err != nil {
+		return none, err
+	}
+	return amount, nil
+}
+
+// Credit records a bonus grant and pays it into the wallet. Tier bonuses
+// and deposit promo codes both go through it, so their credit is locked and
+// expired the same way.
+func Credit(ctx context.Context, q *sqlc.Queries, arg sqlc.InsertBonusGrantParams, description string) error {
+	grantID, err := q.InsertBonusGrant(ctx, arg)
+	if err != nil {
+		return fmt.Errorf("record bonus: %w", err)
+	}
+	return ledger.CreditBonus(ctx, q, arg.UserID, money.Paise(arg.AmountCents), ledger.NewRef(ledger.RefBonus, grantID), description)
+}
+
+// Withdrawable is the part of the available balance that is not locked up
+// in what is left of non-withdrawable bonuses. Bonus credit is spent last.
+func Withdrawable(ctx context.Context, q *sqlc.Queries, userID int32) (money.Money, error) {
+	funds, err := q.GetWalletFunds(ctx, userID)
+	if err != nil {
+		return money.Money{}, err
+	}
+	locked, err := q.GetLockedBonusCents(ctx, userID)
+	if err != nil {
+		return money.Money{}, err
+	}
+	return money.Paise(max(funds.Balance-funds.HeldCents-locked, 0)), nil
+}
+
+// Expirer takes back what is left of bonuses past their expiry
+type Expirer struct {
+	db *db.DB
+}
+
+func New(database *db.DB) *Expirer {
+	return &Expirer{db: database}
+}
+
+func (e *Expirer) Start(ctx context.Context, runner *jobs.Runner) {
+	runner.Every(ctx, JobName, time.Hour, e.ExpireDue)
+}
+
+// ExpireDue expires every bonus past its expiry, each in its own transaction
+func (e *Expirer) ExpireDue(ctx context.Context) {
+	due, err := e.db.Queries.ListDueBonusGrants(ctx, 500)
+	if err != nil {
+		log.Printf("[Bonus] Failed to list expiring bonuses: %v", err)
+		return
+	}
+	for _, g := range due {
+		if err := e.expire(ctx, g.ID); err != nil {
+			log.Printf("[Bonus] Failed to expire bonus #%d: %v", g.ID, err)
+		}
+	}
+}
+
+// expire debits whatever is left of a bonus. Debits reduce its remainder
+// once deposited money runs out; funds held for pending orders are not
+// taken back.
+func (e *Expirer) expire(ctx context.Context, grantID int32) error {
+	tx, err := e.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := e.db.Queries.WithTx(tx)
+
+	g, err := qtx.GetBonusGrantForUpdate(ctx, grantID)
+	if err != nil {
+		return err
+	}
+	if g.Status != "active" {
+		return nil
+	}
+	var available int64
+	funds, err := qtx.GetWalletFundsForUpdate(ctx, g.UserID)
+	switch {
+	case err == nil:
+		available = max(funds.Balance-funds.HeldCents, 0)
+	case !errors.Is(err, pgx.ErrNoRows):
+		return fmt.Errorf("lock wallet: %w", err)
+	}
+
+	left := min(g.RemainingCents, available)
+	ref := ledger.NewRef(ledger.RefBonus, g.ID)
+	if err := ledger.ReverseBonus(ctx, qtx, g.UserID, money.Paise(left), ref, fmt.Sprintf("Bonus #%d expired", g.ID)); err != nil {
+		return err
+	}
+	if _, err := qtx.ExpireBonusGrant(ctx, sqlc.ExpireBonusGrantParams{ID: g.ID, ExpiredCents: left}); err != nil {
+		return fmt.Errorf("mark expired: %w", err)
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,291 @@
+// Package invoice issues GST tax invoices for wallet top-ups and renders
+// them, and monthly statements of them, as HTML and PDF.
+package invoice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"pablosmm/backend/internal/db"
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/jobs"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const JobName = "invoice_issue"
+
+// India is the zone invoice dates and financial years are reckoned in
+var India = time.FixedZone("IST", 5*60*60+30*60)
+
+var gstinPattern = regexp.MustCompile(`^[0-9]{2}[A-Z]{5}[0-9]{4}[A-Z][1-9A-Z]Z[0-9A-Z]$`)
+
+// NormalizeGSTIN is how GSTINs are stored and validated
+func NormalizeGSTIN(gstin string) string {
+	return strings.ToUpper(strings.TrimSpace(gstin))
+}
+
+// ValidGSTIN checks a GSTIN's format and that it starts with a known state
+// code
+func ValidGSTIN(gstin string) bool {
+	_, ok := States[gstin[:min(2, len(gstin))]]
+	return ok && gstinPattern.MatchString(gstin)
+}
+
+// States are the GST state codes
+var States = map[string]string{
+	"01": "Jammu and Kashmir", "02": "Himachal Pradesh", "03": "Punjab", "04": "Chandigarh",
+	"05": "Uttarakhand", "06": "Haryana", "07": "Delhi", "08": "Rajasthan",
+	"09": "Uttar Pradesh", "10": "Bihar", "11": "Sikkim", "12": "Arunachal Pradesh",
+	"13": "Nagaland", "14": "Manipur", "15": "Mizoram", "16": "Tripura",
+	"17": "Meghalaya", "18": "Assam", "19": "West Bengal", "20": "Jharkhand",
+	"21": "Odisha", "22": "Chhattisgarh", "23": "Madhya Pradesh", "24": "Gujarat",
+	"26": "Dadra and Nagar Haveli and Daman and Diu", "27": "Maharashtra", "29": "Karnataka",
+	"30": "Goa", "31": "Lakshadweep", "32": "Kerala", "33": "Tamil Nadu",
+	"34": "Puducherry", "35": "Andaman and Nicobar Islands", "36": "Telangana",
+	"37": "Andhra Pradesh", "38": "Ladakh", "97": "Other Territory",
+}
+
+// StateName is a state code with its name, as printed on invoices
+func StateName(code string) string {
+	if name, ok := States[code]; ok {
+		return fmt.Sprintf("%s (%s)", name, code)
+	}
+	return code
+}
+
+type Config struct {
+	Prefix        string
+	GSTPct        float64
+	SACCode       string
+	SellerName    string
+	SellerGSTIN   string
+	SellerAddress string
+	SellerState   string
+	// Since is when invoicing started; earlier top-ups are not invoiced
+	Since time.Time
+}
+
+// LoadConfig reads the invoice settings, falling back to the seeded
+// defaults
+func LoadConfig(ctx context.Context, q *sqlc.Queries) Config {
+	cfg := Config{Prefix: "INV", GSTPct: 18}
+	if v, err := q.GetSetting(ctx, "invoice_prefix"); err == nil && strings.TrimSpace(v) != "" {
+		cfg.Prefix = strings.TrimSpace(v)
+	}
+	if v, err := q.GetSetting(ctx, "invoice_gst_pct"); err == nil {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 && f <= 100 {
+			cfg.GSTPct = f
+		}
+	}
+	if v, err := q.GetSetting(ctx, "invoice_sac_code"); err == nil {
+		cfg.SACCode = strings.TrimSpace(v)
+	}
+	if v, err := q.GetSetting(ctx, "invoice_seller_name"); err == nil {
+		cfg.SellerName = strings.TrimSpace(v)
+	}
+	if v, err := q.GetSetting(ctx, "invoice_seller_gstin"); err == nil {
+		cfg.SellerGSTIN = NormalizeGSTIN(v)
+	}
+	if v, err := q.GetSetting(ctx, "invoice_seller_address"); err == nil {
+		cfg.SellerAddress = strings.TrimSpace(v)
+	}
+	if v, err := q.GetSetting(ctx, "invoice_seller_state"); err == nil {
+		cfg.SellerState = strings.TrimSpace(v)
+	}
+	if cfg.SellerState == "" && len(cfg.SellerGSTIN) >= 2 {
+		cfg.SellerState = cfg.SellerGSTIN[:2]
+	}
+	if v, err := q.GetSetting(ctx, "invoices_since"); err == nil {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			cfg.Since = t
+		}
+	}
+	return cfg
+}
+
+// Tax is a GST inclusive amount split into its taxable value and tax.
+// Supplies within the seller's state pay CGST and SGST, others IGST.
+type Tax struct {
+	Taxable money.Money
+	CGST    money.Money
+	SGST    money.Money
+	IGST    money.Money
+}
+
+// Split works out the GST included in total at pct percent. The taxable
+// value is rounded to the nearest paisa and the tax is the rest, so the
+// parts always add up to the total; an odd paisa of intra-state tax goes
+// to SGST.
+func Split(total money.Money, pct float64, intraState bool) Tax {
+	bps := int64(math.Round(pct * 100))
+	taxable := total.MulFrac(10000, 10000+bps, money.RoundNearest)
+	gst := total.Sub(taxable)
+	zero := money.New(0, total.Currency)
+	if !intraState {
+		return Tax{Taxable: taxable, CGST: zero, SGST: zero, IGST: gst}
+	}
+	cgst := money.New(gst.Minor/2, total.Currency)
+	return Tax{Taxable: taxable, CGST: cgst, SGST: gst.Sub(cgst), IGST: zero}
+}
+
+// FinancialYear is the Indian financial year (April to March) t falls in,
+// e.g. "2026-27"
+func FinancialYear(t time.Time) string {
+	t = t.In(India)
+	start := t.Year()
+	if t.Month() < time.April {
+		start--
+	}
+	return fmt.Sprintf("%d-%02d", start, (start+1)%100)
+}
+
+// Month parses a "YYYY-MM" month and returns when it starts and ends in
+// Indian time
+func Month(s string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation("2006-01", s, India)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// Number formats an invoice number, e.g. "INV/2026-27/00042"
+func Number(prefix, financialYear string, n int32) string {
+	return fmt.Sprintf("%s/%s/%05d", prefix, financialYear, n)
+}
+
+// PaymentMethod is how a top-up is described on its invoice
+func PaymentMethod(kind, method string) string {
+	switch {
+	case kind == "adjustment":
+		return "Admin credit"
+	case strings.EqualFold(method, "upi"):
+		return "UPI"
+	case strings.EqualFold(method, "cryptomus"):
+		return "Cryptomus"
+	case method == "":
+		return "Deposit"
+	}
+	return method
+}
+
+// Issuer invoices top-ups as they are credited
+type Issuer struct {
+	db *db.DB
+}
+
+func New(database *db.DB) *Issuer {
+	return &Issuer{db: database}
+}
+
+func (s *Issuer) Start(ctx context.Context, runner *jobs.Runner) {
+	runner.Every(ctx, JobName, 5*time.Minute, s.IssueDue)
+}
+
+// IssueDue issues an invoice for every top-up since invoicing started that
+// does not have one, oldest first so numbers follow the order of payments.
+// A top-up that cannot be invoiced is recorded in invoice_failures for
+// admins and retried next run; it does not hold up the ones after it.
+func (s *Issuer) IssueDue(ctx context.Context) {
+	cfg := LoadConfig(ctx, s.db.Queries)
+	if cfg.Since.IsZero() {
+		return
+	}
+	due, err := s.db.Queries.ListUninvoicedTopUps(ctx, sqlc.ListUninvoicedTopUpsParams{
+		Since: pgtype.Timestamptz{Time: cfg.Since, Valid: true},
+		Lim:   500,
+	})
+	if err != nil {
+		log.Printf("[Invoice] Failed to list top-ups: %v", err)
+		return
+	}
+	for _, t := range due {
+		if err := s.issue(ctx, cfg, t); err != nil {
+			log.Printf("[Invoice] Failed to invoice transaction #%d: %v", t.ID, err)
+			err = s.db.Queries.RecordInvoiceFailure(ctx, sqlc.RecordInvoiceFailureParams{
+				TransactionID: t.ID,
+				UserID:        t.UserID,
+				Error:         err.Error(),
+			})
+			if err != nil {
+				log.Printf("[Invoice] Failed to record failure for transaction #%d: %v", t.ID, err)
+			}
+		}
+	}
+}
+
+func (s *Issuer) issue(ctx context.Context, cfg Config, t sqlc.ListUninvoicedTopUpsRow) error {
+	if t.AmountCents <= 0 {
+		return errors.New("top-up is not positive")
+	}
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := s.db.Queries.WithTx(tx)
+
+	customer, err := qtx.GetBillingProfile(ctx, t.UserID)
+	if err != nil {
+		return fmt.Errorf("billing profile: %w", err)
+	}
+	name := customer.BillingName
+	if name == "" {
+		name = customer.Name
+	}
+	// Place of supply is the customer's state, or the seller's for
+	// customers who have not given one
+	placeOfSupply := customer.BillingState
+	if placeOfSupply == "" && len(customer.Gstin) >= 2 {
+		placeOfSupply = customer.Gstin[:2]
+	}
+	if placeOfSupply == "" {
+		placeOfSupply = cfg.SellerState
+	}
+	tax := Split(money.Paise(t.AmountCents), cfg.GSTPct, cfg.SellerState != "" && placeOfSupply == cfg.SellerState)
+
+	fy := FinancialYear(t.CreatedAt.Time)
+	n, err := qtx.NextInvoiceNumber(ctx, fy)
+	if err != nil {
+		return fmt.Errorf("invoice number: %w", err)
+	}
+	_, err = qtx.InsertInvoice(ctx, sqlc.InsertInvoiceParams{
+		InvoiceNumber:   Number(cfg.Prefix, fy, n),
+		UserID:          t.UserID,
+		TransactionID:   t.ID,
+		PaymentMethod:   PaymentMethod(t.Kind, t.Method),
+		CustomerName:    name,
+		CustomerEmail:   customer.Email,
+		CustomerGstin:   customer.Gstin,
+		CustomerAddress: customer.BillingAddress,
+		PlaceOfSupply:   placeOfSupply,
+		SellerName:      cfg.SellerName,
+		SellerGstin:     cfg.SellerGSTIN,
+		SellerAddress:   cfg.SellerAddress,
+		SellerState:     cfg.SellerState,
+		SacCode:         cfg.SACCode,
+		GstPct:          cfg.GSTPct,
+		TotalCents:      t.AmountCents,
+		TaxableCents:    tax.Taxable.Minor,
+		CgstCents:       tax.CGST.Minor,
+		SgstCents:       tax.SGST.Minor,
+		IgstCents:       tax.IGST.Minor,
+		IssuedAt:        t.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("record invoice: %w", err)
+	}
+	if err := qtx.DeleteInvoiceFailure(ctx, t.ID); err != nil {
+		return fmt.Errorf("clear failure: %w", err)
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,169 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A4 in points, and the margin text is kept within
+const (
+	pageWidth  = 595.0
+	pageHeight = 842.0
+	margin     = 50.0
+)
+
+// The standard fonts every PDF reader has, so nothing is embedded
+const (
+	fontRegular = "F1"
+	fontBold    = "F2"
+	// fontMono is used for amounts: its fixed width lets them be right
+	// aligned without font metrics
+	fontMono = "F3"
+)
+
+var fontNames = []struct{ key, base string }{
+	{fontRegular, "Helvetica"},
+	{fontBold, "Helvetica-Bold"},
+	{fontMono, "Courier"},
+}
+
+// pdf lays out text top to bottom on A4 pages, starting a new page when
+// one fills up
+type pdf struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+func newPDF() *pdf {
+	d := &pdf{}
+	d.newPage()
+	return d
+}
+
+func (d *pdf) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pageHeight - margin
+}
+
+func (d *pdf) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// next moves down by height, first starting a new page if it would not fit
+func (d *pdf) next(height float64) {
+	if d.y-height < margin {
+		d.newPage()
+	}
+	d.y -= height
+}
+
+// text writes s at x on the current line
+func (d *pdf) text(x, size float64, font, s string) {
+	fmt.Fprintf(d.page(), "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, d.y, pdfString(s))
+}
+
+// amount writes s in the fixed width font ending at right
+func (d *pdf) amount(right, size float64, s string) {
+	width := 0.6 * size * float64(len([]rune(s)))
+	d.text(right-width, size, fontMono, s)
+}
+
+// rule draws a line across the page just below the current line
+func (d *pdf) rule() {
+	fmt.Fprintf(d.page(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", margin, d.y-4, pageWidth-margin, d.y-4)
+}
+
+// wrap writes s as lines of at most width characters
+func (d *pdf) wrap(x, size float64, font, s string, width int) {
+	for _, line := range wrapText(s, width) {
+		d.next(size + 3)
+		d.text(x, size, font, line)
+	}
+}
+
+// bytes assembles the document: the catalog, the page tree, the fonts and
+// each page with its content stream, followed by the cross reference table
+func (d *pdf) bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n")
+	firstPage := 3 + len(fontNames)
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	fonts := make([]string, len(fontNames))
+	for i, f := range fontNames {
+		fonts[i] = fmt.Sprintf("/%s %d 0 R", f.key, 3+i)
+	}
+
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	for _, f := range fontNames {
+		obj(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", f.base))
+	}
+	for i, p := range d.pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << %s >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, strings.Join(fonts, " "), firstPage+2*i+1))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.Len(), p.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// pdfString escapes s for a PDF string literal. The standard fonts only
+// cover Latin-1, so anything else is replaced.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n' || r == '\r' || r == '\t':
+			b.WriteByte(' ')
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// wrapText breaks s into lines of at most width characters at spaces,
+// keeping the line breaks already in it
+func wrapText(s string, width int) []string {
+	var lines []string
+	for _, para := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			if line != "" && len([]rune(line))+1+len([]rune(word)) > width {
+				lines = append(lines, line)
+				line = ""
+			}
+			if line != "" {
+				line += " "
+			}
+			line += word
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,334 @@
+package invoice
+
+import (
+	"bytes"
+	"html/template"
+	"strconv"
+	"time"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+)
+
+// party is the seller or the customer as printed on an invoice
+type party struct {
+	Name    string
+	Email   string
+	Address string
+	GSTIN   string
+	State   string
+}
+
+type taxLine struct {
+	Label  string
+	Amount money.Money
+}
+
+// view is an invoice laid out for printing, shared by the HTML and PDF
+// renderings so both always show the same thing
+type view struct {
+	Number        string
+	Date          string
+	PaymentMethod string
+	Description   string
+	SAC           string
+	PlaceOfSupply string
+	Seller        party
+	Customer      party
+	Taxable       money.Money
+	Taxes         []taxLine
+	Total         money.Money
+}
+
+func newView(inv sqlc.Invoice) view {
+	v := view{
+		Number:        inv.InvoiceNumber,
+		Date:          inv.IssuedAt.Time.In(India).Format("02 Jan 2006"),
+		PaymentMethod: inv.PaymentMethod,
+		Description:   "Wallet top-up (" + inv.PaymentMethod + ")",
+		SAC:           inv.SacCode,
+		PlaceOfSupply: StateName(inv.PlaceOfSupply),
+		Seller: party{
+			Name:    inv.SellerName,
+			Address: inv.SellerAddress,
+			GSTIN:   inv.SellerGstin,
+			State:   StateName(inv.SellerState),
+		},
+		Customer: party{
+			Name:    inv.CustomerName,
+			Email:   inv.CustomerEmail,
+			Address: inv.CustomerAddress,
+			GSTIN:   inv.CustomerGstin,
+		},
+		Taxable: money.Paise(inv.TaxableCents),
+		Total:   money.Paise(inv.TotalCents),
+	}
+	if inv.IgstCents != 0 || inv.CgstCents+inv.SgstCents == 0 {
+		v.Taxes = []taxLine{{"IGST @ " + pct(inv.GstPct), money.Paise(inv.IgstCents)}}
+	} else {
+		v.Taxes = []taxLine{
+			{"CGST @ " + pct(inv.GstPct/2), money.Paise(inv.CgstCents)},
+			{"SGST @ " + pct(inv.GstPct/2), money.Paise(inv.SgstCents)},
+		}
+	}
+	return v
+}
+
+func pct(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64) + "%"
+}
+
+// inr is an amount for the PDF, whose fonts have no rupee sign
+func inr(m money.Money) string {
+	return "Rs. " + m.Decimal()
+}
+
+// Statement is a customer's invoices for one calendar month
+type Statement struct {
+	Month    time.Time
+	Name     string
+	Email    string
+	Invoices []sqlc.Invoice
+}
+
+type statementTotals struct {
+	Taxable money.Money
+	Tax     money.Money
+	Total   money.Money
+}
+
+func (s Statement) totals() statementTotals {
+	t := statementTotals{Taxable: money.Paise(0), Tax: money.Paise(0), Total: money.Paise(0)}
+	for _, inv := range s.Invoices {
+		t.Taxable = t.Taxable.Add(money.Paise(inv.TaxableCents))
+		t.Tax = t.Tax.Add(money.Paise(inv.CgstCents + inv.SgstCents + inv.IgstCents))
+		t.Total = t.Total.Add(money.Paise(inv.TotalCents))
+	}
+	return t
+}
+
+// PDF renders a single invoice
+func PDF(inv sqlc.Invoice) []byte {
+	d := newPDF()
+	writeInvoicePDF(d, newView(inv))
+	return d.bytes()
+}
+
+// PDF renders the statement: a summary page listing the month's invoices,
+// then each invoice on its own page
+func (s Statement) PDF() []byte {
+	d := newPDF()
+	right := pageWidth - margin
+
+	d.next(20)
+	d.text(margin, 18, fontBold, "Invoice statement")
+	d.next(18)
+	d.text(margin, 11, fontRegular, s.Month.Format("January 2006"))
+	d.next(16)
+	d.text(margin, 10, fontRegular, s.Name+" <"+s.Email+">")
+
+	d.next(28)
+	d.text(margin, 9, fontBold, "Invoice")
+	d.text(margin+150, 9, fontBold, "Date")
+	d.text(margin+230, 9, fontBold, "Method")
+	d.amount(right-130, 9, "Taxable")
+	d.amount(right-65, 9, "GST")
+	d.amount(right, 9, "Total")
+	d.rule()
+	d.next(4)
+	for _, inv := range s.Invoices {
+		d.next(14)
+		d.text(margin, 9, fontRegular, inv.InvoiceNumber)
+		d.text(margin+150, 9, fontRegular, inv.IssuedAt.Time.In(India).Format("02 Jan 2006"))
+		d.text(margin+230, 9, fontRegular, inv.PaymentMethod)
+		d.amount(right-130, 9, money.Paise(inv.TaxableCents).Decimal())
+		d.amount(right-65, 9, money.Paise(inv.CgstCents+inv.SgstCents+inv.IgstCents).Decimal())
+		d.amount(right, 9, money.Paise(inv.TotalCents).Decimal())
+	}
+	if len(s.Invoices) == 0 {
+		d.next(14)
+		d.text(margin, 9, fontRegular, "No invoices were issued this month.")
+	}
+	t := s.totals()
+	d.rule()
+	d.next(18)
+	d.text(margin, 9, fontBold, "Total (INR)")
+	d.amount(right-130, 9, t.Taxable.Decimal())
+	d.amount(right-65, 9, t.Tax.Decimal())
+	d.amount(right, 9, t.Total.Decimal())
+
+	for _, inv := range s.Invoices {
+		d.newPage()
+		writeInvoicePDF(d, newView(inv))
+	}
+	return d.bytes()
+}
+
+func writeInvoicePDF(d *pdf, v view) {
+	right := pageWidth - margin
+	mid := pageWidth / 2
+
+	d.next(20)
+	d.text(margin, 18, fontBold, "Tax Invoice")
+	d.next(22)
+	d.text(margin, 11, fontBold, v.Seller.Name)
+	d.wrap(margin, 9, fontRegular, v.Seller.Address, 90)
+	if v.Seller.GSTIN != "" {
+		d.next(12)
+		d.text(margin, 9, fontRegular, "GSTIN: "+v.Seller.GSTIN)
+	}
+	if v.Seller.State != "" {
+		d.next(12)
+		d.text(margin, 9, fontRegular, "State: "+v.Seller.State)
+	}
+
+	d.next(24)
+	d.text(margin, 9, fontBold, "Invoice No.")
+	d.text(margin+80, 9, fontRegular, v.Number)
+	d.text(mid, 9, fontBold, "Date")
+	d.text(mid+80, 9, fontRegular, v.Date)
+	d.next(12)
+	d.text(margin, 9, fontBold, "Payment")
+	d.text(margin+80, 9, fontRegular, v.PaymentMethod)
+	d.text(mid, 9, fontBold, "Place of supply")
+	d.text(mid+80, 9, fontRegular, v.PlaceOfSupply)
+
+	d.next(24)
+	d.text(margin, 9, fontBold, "Bill to")
+	d.next(13)
+	d.text(margin, 10, fontRegular, v.Customer.Name)
+	if v.Customer.Email != "" {
+		d.next(12)
+		d.text(margin, 9, fontRegular, v.Customer.Email)
+	}
+	d.wrap(margin, 9, fontRegular, v.Customer.Address, 90)
+	if v.Customer.GSTIN != "" {
+		d.next(12)
+		d.text(margin, 9, fontRegular, "GSTIN: "+v.Customer.GSTIN)
+	}
+
+	d.next(28)
+	d.text(margin, 9, fontBold, "Description")
+	d.text(margin+300, 9, fontBold, "SAC")
+	d.amount(right, 9, "Amount")
+	d.rule()
+	d.next(18)
+	d.text(margin, 9, fontRegular, v.Description)
+	d.text(margin+300, 9, fontRegular, v.SAC)
+	d.amount(right, 9, inr(v.Taxable))
+	d.rule()
+
+	d.next(18)
+	d.text(mid, 9, fontRegular, "Taxable value")
+	d.amount(right, 9, inr(v.Taxable))
+	for _, t := range v.Taxes {
+		d.next(13)
+		d.text(mid, 9, fontRegular, t.Label)
+		d.amount(right, 9, inr(t.Amount))
+	}
+	d.rule()
+	d.next(18)
+	d.text(mid, 10, fontBold, "Total")
+	d.amount(right, 10, inr(v.Total))
+
+	d.next(30)
+	d.text(margin, 8, fontRegular, "Amounts are in Indian rupees and include GST. This is a computer generated invoice and needs no signature.")
+}
+
+var invoiceTmpl = template.Must(template.New("invoice").Parse(`
+{{define "style"}}<style>
+body{font-family:Helvetica,Arial,sans-serif;font-size:13px;color:#222;max-width:760px;margin:24px auto}
+h1{font-size:22px;margin:0 0 12px}
+table{width:100%;border-collapse:collapse;margin:12px 0}
+th,td{padding:6px 4px;text-align:left;vertical-align:top}
+th{border-bottom:1px solid #999}
+.num{text-align:right;font-variant-numeric:tabular-nums}
+.total td{border-top:1px solid #999;font-weight:bold}
+.addr{white-space:pre-line}
+.note{font-size:11px;color:#666}
+section.invoice{page-break-after:always}
+section.invoice:last-child{page-break-after:auto}
+</style>{{end}}
+
+{{define "body"}}<section class="invoice">
+<h1>Tax Invoice</h1>
+<p><strong>{{.Seller.Name}}</strong><br>
+<span class="addr">{{.Seller.Address}}</span>
+{{if .Seller.GSTIN}}<br>GSTIN: {{.Seller.GSTIN}}{{end}}
+{{if .Seller.State}}<br>State: {{.Seller.State}}{{end}}</p>
+<table>
+<tr><th>Invoice No.</th><td>{{.Number}}</td><th>Date</th><td>{{.Date}}</td></tr>
+<tr><th>Payment</th><td>{{.PaymentMethod}}</td><th>Place of supply</th><td>{{.PlaceOfSupply}}</td></tr>
+</table>
+<p><strong>Bill to</strong><br>
+{{.Customer.Name}}
+{{if .Customer.Email}}<br>{{.Customer.Email}}{{end}}
+{{if .Customer.Address}}<br><span class="addr">{{.Customer.Address}}</span>{{end}}
+{{if .Customer.GSTIN}}<br>GSTIN: {{.Customer.GSTIN}}{{end}}</p>
+<table>
+<tr><th>Description</th><th>SAC</th><th class="num">Amount</th></tr>
+<tr><td>{{.Description}}</td><td>{{.SAC}}</td><td class="num">{{.Taxable}}</td></tr>
+<tr><td></td><td>Taxable value</td><td class="num">{{.Taxable}}</td></tr>
+{{range .Taxes}}<tr><td></td><td>{{.Label}}</td><td class="num">{{.Amount}}</td></tr>
+{{end}}<tr class="total"><td></td><td>Total</td><td class="num">{{.Total}}</td></tr>
+</table>
+<p class="note">Amounts are in Indian rupees and include GST. This is a computer generated invoice and needs no signature.</p>
+</section>{{end}}
+
+{{define "invoice"}}<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Invoice {{.Number}}</title>{{template "style"}}</head>
+<body>{{template "body" .}}</body></html>{{end}}
+
+{{define "statement"}}<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Invoice statement {{.Month}}</title>{{template "style"}}</head>
+<body>
+<section class="invoice">
+<h1>Invoice statement</h1>
+<p>{{.Month}}<br>{{.Name}} &lt;{{.Email}}&gt;</p>
+<table>
+<tr><th>Invoice</th><th>Date</th><th>Method</th><th class="num">Taxable</th><th class="num">GST</th><th class="num">Total</th></tr>
+{{range .Rows}}<tr><td>{{.Number}}</td><td>{{.Date}}</td><td>{{.PaymentMethod}}</td><td class="num">{{.Taxable}}</td><td class="num">{{.Tax}}</td><td class="num">{{.Total}}</td></tr>
+{{else}}<tr><td colspan="6">No invoices were issued this month.</td></tr>
+{{end}}<tr class="total"><td colspan="3">Total</td><td class="num">{{.Totals.Taxable}}</td><td class="num">{{.Totals.Tax}}</td><td class="num">{{.Totals.Total}}</td></tr>
+</table>
+</section>
+{{range .Invoices}}{{template "body" .}}
+{{end}}</body></html>{{end}}
+`))
+
+// HTML renders a single invoice as a standalone page
+func HTML(inv sqlc.Invoice) ([]byte, error) {
+	var buf bytes.Buffer
+	err := invoiceTmpl.ExecuteTemplate(&buf, "invoice", newView(inv))
+	return buf.Bytes(), err
+}
+
+// HTML renders the statement as one page with the summary followed by each
+// invoice, which print on pages of their own
+func (s Statement) HTML() ([]byte, error) {
+	type row struct {
+		view
+		Tax money.Money
+	}
+	data := struct {
+		Month    string
+		Name     string
+		Email    string
+		Rows     []row
+		Totals   statementTotals
+		Invoices []view
+	}{
+		Month:  s.Month.Format("January 2006"),
+		Name:   s.Name,
+		Email:  s.Email,
+		Totals: s.totals(),
+	}
+	for _, inv := range s.Invoices {
+		v := newView(inv)
+		data.Rows = append(data.Rows, row{view: v, Tax: money.Paise(inv.CgstCents + inv.SgstCents + inv.IgstCents)})
+		data.Invoices = append(data.Invoices, v)
+	}
+	var buf bytes.Buffer
+	err := invoiceTmpl.ExecuteTemplate(&buf, "statement", data)
+	return buf.Bytes(), err
+}
@@ -0,0 +1,151 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"pablosmm/backend/internal/db"
+	"pablosmm/backend/internal/db/sqlc"
+)
+
+var (
+	instanceOnce sync.Once
+	instanceID   string
+)
+
+// InstanceID names this server process in job leases, as host:pid
+func InstanceID() string {
+	instanceOnce.Do(func() {
+		host, err := os.Hostname()
+		if err != nil || host == "" {
+			host = "unknown"
+		}
+		instanceID = fmt.Sprintf("%s:%d", host, os.Getpid())
+	})
+	return instanceID
+}
+
+// Runner runs periodic jobs so that, however many API instances are up,
+// each run of a job happens on exactly one of them. Every run claims the
+// job's lease row in a short transaction and renews it while the job runs,
+// so no connection is held for the length of the run. Instances that find
+// the lease taken skip that run, as do instances whose tick comes after
+// another instance already ran the job this interval. A lease left by a
+// crashed instance runs out after leaseTTL.
+type Runner struct {
+	db *db.DB
+}
+
+// leaseTTL is how long a claimed lease lasts without being renewed; a
+// running job renews it every third of that
+const leaseTTL = 2 * time.Minute
+
+func New(database *db.DB) *Runner {
+	return &Runner{db: database}
+}
+
+// Every runs fn once immediately and then on every tick until ctx is done
+func (r *Runner) Every(ctx context.Context, name string, interval time.Duration, fn func(context.Context)) {
+	ticker := time.NewTicker(interval)
+	// Run once immediately
+	go r.run(ctx, name, interval, fn)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.run(ctx, name, interval, fn)
+			}
+		}
+	}()
+}
+
+// RunOnce runs fn if no other instance is running the job. It reports
+// whether fn ran.
+func (r *Runner) RunOnce(ctx context.Context, name string, fn func(context.Context)) bool {
+	return r.run(ctx, name, 0, fn)
+}
+
+// run runs fn under the job's lease. With an interval it also skips the run
+// when the job last started within that interval, less a tenth for the
+// instances' tickers drifting apart.
+func (r *Runner) run(ctx context.Context, name string, interval time.Duration, fn func(context.Context)) bool {
+	holder := InstanceID()
+	claimed, err := r.claim(ctx, name, holder, interval-interval/10)
+	if err != nil {
+		log.Printf("[Jobs] %s: failed to claim lease: %v", name, err)
+		return false
+	}
+	if !claimed {
+		return false
+	}
+
+	done := make(chan struct{})
+	go r.renew(name, holder, done)
+	fn(ctx)
+	close(done)
+
+	// Finish even when ctx is done, or the lease would only free up once it
+	// runs out
+	if err := r.db.Queries.FinishJobLease(context.Background(), sqlc.FinishJobLeaseParams{Name: name, Holder: holder}); err != nil {
+		log.Printf("[Jobs] %s: failed to record finish: %v", name, err)
+	}
+	return true
+}
+
+// claim takes the job's lease for holder. The transaction-scoped advisory
+// lock keeps instances whose ticks coincide from queueing on the lease row;
+// it is gone as soon as the claim commits.
+func (r *Runner) claim(ctx context.Context, name, holder string, minGap time.Duration) (bool, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.db.Queries.WithTx(tx)
+
+	locked, err := qtx.TryJobXactLock(ctx, name)
+	if err != nil || !locked {
+		return false, err
+	}
+	n, err := qtx.ClaimJobLease(ctx, sqlc.ClaimJobLeaseParams{
+		Name:          name,
+		Holder:        holder,
+		TtlSeconds:    int32(leaseTTL / time.Second),
+		MinGapSeconds: int32(minGap / time.Second),
+	})
+	if err != nil || n == 0 {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// renew keeps the lease from running out until done is closed
+func (r *Runner) renew(name, holder string, done <-chan struct{}) {
+	ticker := time.NewTicker(leaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			n, err := r.db.Queries.RenewJobLease(context.Background(), sqlc.RenewJobLeaseParams{
+				TtlSeconds: int32(leaseTTL / time.Second),
+				Name:       name,
+				Holder:     holder,
+			})
+			if err != nil {
+				log.Printf("[Jobs] %s: failed to renew lease: %v", name, err)
+			} else if n == 0 {
+				log.Printf("[Jobs] %s: lease was lost while running", name)
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrInsufficientFunds is returned when the available balance (balance less
+// active holds) does not cover a debit or hold
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+// HoldOrder reserves the price of a new order. The funds stay in the wallet
+// but cannot be spent until CaptureOrder charges them or ReleaseOrder frees
+// them; the check and the reservation are one statement, so concurrent
+// orders cannot overspend.
+func HoldOrder(ctx context.Context, q *sqlc.Queries, userID, orderID int32, amount money.Money) error {
+	return hold(ctx, q, userID, amount, NewRef(RefOrder, orderID), fmt.Sprintf("Order #%d", orderID))
+}
+
+// HoldTransfer reserves the amount of a transfer waiting for approval until
+// Transfer sends it or ReleaseTransfer frees it
+func HoldTransfer(ctx context.Context, q *sqlc.Queries, senderID, transferID int32, amount money.Money) error {
+	return hold(ctx, q, senderID, amount, NewRef(RefWalletTransfer, transferID), fmt.Sprintf("Transfer #%d", transferID))
+}
+
+func hold(ctx context.Context, q *sqlc.Queries, userID int32, amount money.Money, ref Ref, description string) error {
+	n, err := q.ReserveWalletFunds(ctx, sqlc.ReserveWalletFundsParams{
+		HeldCents: amount.Minor,
+		UserID:    userID,
+	})
+	if err != nil {
+		return fmt.Errorf("reserve funds: %w", err)
+	}
+	if n == 0 {
+		return ErrInsufficientFunds
+	}
+	_, err = q.InsertWalletHold(ctx, sqlc.InsertWalletHoldParams{
+		UserID:        userID,
+		AmountCents:   amount.Minor,
+		ReferenceType: ref.Type,
+		ReferenceID:   ref.ID,
+		Description:   description,
+	})
+	if err != nil {
+		return fmt.Errorf("record hold: %w", err)
+	}
+	return nil
+}
+
+// CaptureOrder charges an order's active hold to the wallet. It is a no-op
+// when the order has no active hold, so refund and settlement paths can
+// call it unconditionally.
+func CaptureOrder(ctx context.Context, q *sqlc.Queries, orderID int32) error {
+	hold, ok, err := activeHold(ctx, q, NewRef(RefOrder, orderID), "captured")
+	if err != nil || !ok {
+		return err
+	}
+	err = q.CaptureWalletFunds(ctx, sqlc.CaptureWalletFundsParams{
+		HeldCents: hold.AmountCents,
+		UserID:    hold.UserID,
+	})
+	if err != nil {
+		return fmt.Errorf("capture funds: %w", err)
+	}
+	if err := spendBonuses(ctx, q, hold.UserID); err != nil {
+		return err
+	}
+	return postCharge(ctx, q, hold.UserID, orderID, hold.AmountCents)
+}
+
+// ReleaseOrder frees an order's active hold without charging anything
+func ReleaseOrder(ctx context.Context, q *sqlc.Queries, orderID int32) error {
+	return release(ctx, q, NewRef(RefOrder, orderID))
+}
+
+// ReleaseTransfer frees the hold of a transfer that will not be sent
+func ReleaseTransfer(ctx context.Context, q *sqlc.Queries, transferID int32) error {
+	return release(ctx, q, NewRef(RefWalletTransfer, transferID))
+}
+
+func release(ctx context.Context, q *sqlc.Queries, ref Ref) error {
+	hold, ok, err := activeHold(ctx, q, ref, "released")
+	if err != nil || !ok {
+		return err
+	}
+	err = q.ReleaseWalletFunds(ctx, sqlc.ReleaseWalletFundsParams{
+		HeldCents: hold.AmountCents,
+		UserID:    hold.UserID,
+	})
+	if err != nil {
+		return fmt.Errorf("release funds: %w", err)
+	}
+	return nil
+}
+
+// activeHold locks the active hold for ref and marks it with its final
+// status
+func activeHold(ctx context.Context, q *sqlc.Queries, ref Ref, status string) (sqlc.WalletHold, bool, error) {
+	hold, err := q.GetActiveWalletHoldForUpdate(ctx, sqlc.GetActiveWalletHoldForUpdateParams{
+		ReferenceType: ref.Type,
+		ReferenceID:   ref.ID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return hold, false, nil
+	}
+	if err != nil {
+		return hold, false, fmt.Errorf("read hold: %w", err)
+	}
+	if _, err := q.ResolveWalletHold(ctx, sqlc.ResolveWalletHoldParams{ID: hold.ID, Status: status}); err != nil {
+		return hold, false, fmt.Errorf("resolve hold: %w", err)
+	}
+	return hold, true, nil
+}
@@ -0,0 +1,493 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// System accounts, seeded by the ledger migration
+const (
+	AccountDepositsClearing = "deposits_clearing"
+	AccountProviderPayable  = "provider_payable"
+	AccountRevenue          = "revenue"
+	AccountAdjustments      = "adjustments"
+	AccountOpeningBalances  = "opening_balances"
+	AccountPromotions       = "promotions"
+
+	// Seeded by the referrals migration
+	AccountReferralCommissions = "referral_commissions"
+	AccountAffiliatePayable    = "affiliate_payable"
+)
+
+// Journal kinds. The ones that move a wallet double as the kind of the
+// matching row in the user's transaction history.
+const (
+	KindDeposit        = "deposit"
+	KindOrderCharge    = "order_charge"
+	KindOrderRefund    = "order_refund"
+	KindOrderSettle    = "order_settle"
+	KindAdjustment     = "adjustment"
+	KindBonus          = "bonus"
+	KindReferral       = "referral"
+	KindTransfer       = "transfer"
+	KindOpeningBalance = "opening_balance"
+)
+
+// Reference types linking a journal or transaction to the row that caused it
+const (
+	RefOrder           = "order"
+	RefWalletRequest   = "wallet_request"
+	RefRefund          = "refund"
+	RefAdminAdjustment = "admin_adjustment"
+	RefBonus           = "bonus"
+	RefReferral        = "referral"
+	RefWalletTransfer  = "wallet_transfer"
+
+	// RefReferralCommission only appears on journals; wallet credits from
+	// the affiliate balance reference the referral payout instead
+	RefReferralCommission = "referral_commission"
+)
+
+// TransactionKinds are the kinds a transaction row can have, and
+// ReferenceTypes what it can point at; both mirror the table's checks
+var (
+	TransactionKinds = []string{KindDeposit, KindOrderCharge, KindOrderRefund, KindAdjustment, KindBonus, KindReferral, KindTransfer}
+	ReferenceTypes   = []string{RefOrder, RefWalletRequest, RefRefund, RefAdminAdjustment, RefBonus, RefReferral, RefWalletTransfer}
+)
+
+// Ref points at the row behind a money movement
+type Ref struct {
+	Type string
+	ID   string
+}
+
+func NewRef(refType string, id int32) Ref {
+	return Ref{Type: refType, ID: strconv.Itoa(int(id))}
+}
+
+// Entry is one leg of a journal. Debits are positive and credits negative,
+// in paise.
+type Entry struct {
+	Account     string
+	UserID      int32
+	AmountCents int64
+}
+
+// Journal is a set of entries that must sum to zero
+type Journal struct {
+	Kind          string
+	Description   string
+	ReferenceType string
+	ReferenceID   string
+	Entries       []Entry
+}
+
+// Wallet is the entry against a user's wallet account
+func Wallet(userID int32, amountCents int64) Entry {
+	return Entry{Account: WalletAccount(userID), UserID: userID, AmountCents: amountCents}
+}
+
+// System is the entry against one of the system accounts
+func System(code string, amountCents int64) Entry {
+	return Entry{Account: code, AmountCents: amountCents}
+}
+
+func WalletAccount(userID int32) string {
+	return fmt.Sprintf("wallet:%d", userID)
+}
+
+// Post writes a balanced journal. q must be bound to the caller's
+// transaction so the journal commits together with the balance change;
+// the database also rejects unbalanced journals at commit.
+func Post(ctx context.Context, q *sqlc.Queries, j Journal) (int32, error) {
+	var sum int64
+	for _, e := range j.Entries {
+		sum += e.AmountCents
+	}
+	if sum != 0 || len(j.Entries) < 2 {
+		return 0, fmt.Errorf("ledger: %s journal is not balanced", j.Kind)
+	}
+
+	journalID, err := q.InsertLedgerJournal(ctx, sqlc.InsertLedgerJournalParams{
+		Kind:          j.Kind,
+		Description:   j.Description,
+		ReferenceType: j.ReferenceType,
+		ReferenceID:   j.ReferenceID,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ledger: insert journal: %w", err)
+	}
+	for _, e := range j.Entries {
+		if e.AmountCents == 0 {
+			continue
+		}
+		accountID, err := ensureAccount(ctx, q, e)
+		if err != nil {
+			return 0, err
+		}
+		err = q.InsertLedgerEntry(ctx, sqlc.InsertLedgerEntryParams{
+			JournalID:   journalID,
+			AccountID:   accountID,
+			AmountCents: e.AmountCents,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("ledger: insert entry: %w", err)
+		}
+	}
+	return journalID, nil
+}
+
+func ensureAccount(ctx context.Context, q *sqlc.Queries, e Entry) (int32, error) {
+	params := sqlc.EnsureLedgerAccountParams{Code: e.Account}
+	if e.UserID != 0 {
+		params.Kind = "liability"
+		params.Name = fmt.Sprintf("Wallet of user %d", e.UserID)
+		params.UserID = pgtype.Int4{Int32: e.UserID, Valid: true}
+	} else {
+		// System accounts are seeded; this only runs for a typo'd code
+		params.Kind = "expense"
+		params.Name = e.Account
+	}
+	id, err := q.EnsureLedgerAccount(ctx, params)
+	if err != nil {
+		return 0, fmt.Errorf("ledger: account %s: %w", e.Account, err)
+	}
+	return id, nil
+}
+
+// Deposit credits a wallet with money received through a payment method and
+// logs it in the user's transaction history
+func Deposit(ctx context.Context, q *sqlc.Queries, userID int32, amount money.Money, ref Ref, description string) error {
+	if !amount.IsPositive() {
+		return fmt.Errorf("ledger: deposit must be positive")
+	}
+	amountCents := amount.Minor
+	err := q.UpsertWalletBalance(ctx, sqlc.UpsertWalletBalanceParams{
+		UserID:  userID,
+		Balance: amountCents,
+	})
+	if err != nil {
+		return fmt.Errorf("credit wallet: %w", err)
+	}
+	if err := logTransaction(ctx, q, userID, amountCents, KindDeposit, ref, description); err != nil {
+		return err
+	}
+	_, err = Post(ctx, q, Journal{
+		Kind:          KindDeposit,
+		Description:   description,
+		ReferenceType: ref.Type,
+		ReferenceID:   ref.ID,
+		Entries: []Entry{
+			System(AccountDepositsClearing, amountCents),
+			Wallet(userID, -amountCents),
+		},
+	})
+	return err
+}
+
+// ReverseDeposit takes a deposit back out of a wallet when the payment is
+// refunded or charged back. It fails with ErrInsufficientFunds when the
+// available balance does not cover it.
+func ReverseDeposit(ctx context.Context, q *sqlc.Queries, userID int32, amount money.Money, ref Ref, description string) error {
+	if !amount.IsPositive() {
+		return fmt.Errorf("ledger: reversal must be positive")
+	}
+	amountCents := amount.Minor
+	n, err := q.DebitWallet(ctx, sqlc.DebitWalletParams{
+		Balance: amountCents,
+		UserID:  userID,
+	})
+	if err != nil {
+		return fmt.Errorf("debit wallet: %w", err)
+	}
+	if n == 0 {
+		return ErrInsufficientFunds
+	}
+	if err := spendBonuses(ctx, q, userID); err != nil {
+		return err
+	}
+	if err := logTransaction(ctx, q, userID, -amountCents, KindDeposit, ref, description); err != nil {
+		return err
+	}
+	_, err = Post(ctx, q, Journal{
+		Kind:          KindDeposit,
+		Description:   description,
+		ReferenceType: ref.Type,
+		ReferenceID:   ref.ID,
+		Entries: []Entry{
+			Wallet(userID, amountCents),
+			System(AccountDepositsClearing, -amountCents),
+		},
+	})
+	return err
+}
+
+// Adjust applies a manual admin credit (positive) or debit (negative). It
+// records the adjustment with the admin who made it, references it from the
+// transaction and journal, and returns its ID.
+func Adjust(ctx context.Context, q *sqlc.Queries, userID int32, amount money.Money, adminID pgtype.Int4, description string) (int32, error) {
+	if amount.IsZero() {
+		return 0, nil
+	}
+	amountCents := amount.Minor
+	adjustmentID, err := q.InsertWalletAdjustment(ctx, sqlc.InsertWalletAdjustmentParams{
+		UserID:      userID,
+		AdminID:     adminID,
+		AmountCents: amountCents,
+		Description: description,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("record adjustment: %w", err)
+	}
+	err = q.UpsertWalletBalance(ctx, sqlc.UpsertWalletBalanceParams{
+		UserID:  userID,
+		Balance: amountCents,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("update wallet: %w", err)
+	}
+	if amountCents < 0 {
+		if err := spendBonuses(ctx, q, userID); err != nil {
+			return 0, err
+		}
+	}
+	ref := NewRef(RefAdminAdjustment, adjustmentID)
+	if err := logTransaction(ctx, q, userID, amountCents, KindAdjustment, ref, description); err != nil {
+		return 0, err
+	}
+	_, err = Post(ctx, q, Journal{
+		Kind:          KindAdjustment,
+		Description:   description,
+		ReferenceType: ref.Type,
+		ReferenceID:   ref.ID,
+		Entries: []Entry{
+			System(AccountAdjustments, amountCents),
+			Wallet(userID, -amountCents),
+		},
+	})
+	return adjustmentID, err
+}
+
+// CreditBonus credits promotional money to a wallet, paid for by the
+// promotions account
+func CreditBonus(ctx context.Context, q *sqlc.Queries, userID int32, amount money.Money, ref Ref, description string) error {
+	if !amount.IsPositive() {
+		return fmt.Errorf("ledger: bonus must be positive")
+	}
+	amountCents := amount.Minor
+	err := q.UpsertWalletBalance(ctx, sqlc.UpsertWalletBalanceParams{
+		UserID:  userID,
+		Balance: amountCents,
+	})
+	if err != nil {
+		return fmt.Errorf("credit wallet: %w", err)
+	}
+	if err := logTransaction(ctx, q, userID, amountCents, KindBonus, ref, description); err != nil {
+		return err
+	}
+	_, err = Post(ctx, q, Journal{
+		Kind:          KindBonus,
+		Description:   description,
+		ReferenceType: ref.Type,
+		ReferenceID:   ref.ID,
+		Entries: []Entry{
+			System(AccountPromotions, amountCents),
+			Wallet(userID, -amountCents),
+		},
+	})
+	return err
+}
+
+// ReverseBonus takes promotional money back from a wallet, for instance when
+// a bonus expires. It fails with ErrInsufficientFunds when the available
+// balance does not cover it.
+func ReverseBonus(ctx context.Context, q *sqlc.Queries, userID int32, amount money.Money, ref Ref, description string) error {
+	if !amount.IsPositive() {
+		return nil
+	}
+	amountCents := amount.Minor
+	n, err := q.DebitWallet(ctx, sqlc.DebitWalletParams{
+		Balance: amountCents,
+		UserID:  userID,
+	})
+	if err != nil {
+		return fmt.Errorf("debit wallet: %w", err)
+	}
+	if n == 0 {
+		return ErrInsufficientFunds
+	}
+	if err := logTransaction(ctx, q, userID, -amountCents, KindBonus, ref, description); err != nil {
+		return err
+	}
+	_, err = Post(ctx, q, Journal{
+		Kind:          KindBonus,
+		Description:   description,
+		ReferenceType: ref.Type,
+		ReferenceID:   ref.ID,
+		Entries: []Entry{
+			Wallet(userID, amountCents),
+			System(AccountPromotions, -amountCents),
+		},
+	})
+	return err
+}
+
+// spendBonuses brings the unspent part of a wallet's bonuses down to what
+// its balance still covers, after the wallet is debited for spending
+func spendBonuses(ctx context.Context, q *sqlc.Queries, userID int32) error {
+	if err := q.SpendBonusGrants(ctx, userID); err != nil {
+		return fmt.Errorf("spend bonuses: %w", err)
+	}
+	return nil
+}
+
+// ChargeOrder debits the wallet for an order straight away, failing with
+// ErrInsufficientFunds when the available balance does not cover it. The
+// charge is held in provider_payable until the order settles.
+func ChargeOrder(ctx context.Context, q *sqlc.Queries, userID, orderID int32, amount money.Money) error {
+	n, err := q.DebitWallet(ctx, sqlc.DebitWalletParams{
+		Balance: amount.Minor,
+		UserID:  userID,
+	})
+	if err != nil {
+		return fmt.Errorf("debit wallet: %w", err)
+	}
+	if n == 0 {
+		return ErrInsufficientFunds
+	}
+	if err := spendBonuses(ctx, q, userID); err != nil {
+		return err
+	}
+	return postCharge(ctx, q, userID, orderID, amount.Minor)
+}
+
+// postCharge records an order charge once the wallet has been debited
+func postCharge(ctx context.Context, q *sqlc.Queries, userID, orderID int32, amountCents int64) error {
+	ref := NewRef(RefOrder, orderID)
+	description := fmt.Sprintf("Charge for Order #%d", orderID)
+	if err := logTransaction(ctx, q, userID, -amountCents, KindOrderCharge, ref, description); err != nil {
+		return err
+	}
+	_, err := Post(ctx, q, Journal{
+		Kind:          KindOrderCharge,
+		Description:   description,
+		ReferenceType: ref.Type,
+		ReferenceID:   ref.ID,
+		Entries: []Entry{
+			Wallet(userID, amountCents),
+			System(AccountProviderPayable, -amountCents),
+		},
+	})
+	return err
+}
+
+// RefundOrder credits the wallet for an order. The refund comes out of the
+// order's held charge first and out of revenue for whatever was already
+// settled. txn is what the user's transaction row references: the
+// order_refunds row when there is one, otherwise the order. An order still
+// on hold is charged first, so the refund always follows a charge.
+func RefundOrder(ctx context.Context, q *sqlc.Queries, userID, orderID int32, amount money.Money, txn Ref, description string) error {
+	if !amount.IsPositive() {
+		return nil
+	}
+	amountCents := amount.Minor
+	if err := CaptureOrder(ctx, q, orderID); err != nil {
+		return err
+	}
+	order := NewRef(RefOrder, orderID)
+	held, err := q.GetOrderHeldCents(ctx, order.ID)
+	if err != nil {
+		return fmt.Errorf("read held charge: %w", err)
+	}
+	fromPayable := min(max(held, 0), amountCents)
+
+	err = q.UpsertWalletBalance(ctx, sqlc.UpsertWalletBalanceParams{
+		UserID:  userID,
+		Balance: amountCents,
+	})
+	if err != nil {
+		return fmt.Errorf("credit wallet: %w", err)
+	}
+	if err := logTransaction(ctx, q, userID, amountCents, KindOrderRefund, txn, description); err != nil {
+		return err
+	}
+	_, err = Post(ctx, q, Journal{
+		Kind:          KindOrderRefund,
+		Description:   description,
+		ReferenceType: order.Type,
+		ReferenceID:   order.ID,
+		Entries: []Entry{
+			System(AccountProviderPayable, fromPayable),
+			System(AccountRevenue, amountCents-fromPayable),
+			Wallet(userID, -amountCents),
+		},
+	})
+	return err
+}
+
+// SettleOrder recognises whatever is still held for a finished order as
+// revenue, charging it first if it is still on hold. It is a no-op when
+// nothing is held.
+func SettleOrder(ctx context.Context, q *sqlc.Queries, orderID int32) error {
+	if err := CaptureOrder(ctx, q, orderID); err != nil {
+		return err
+	}
+	ref := NewRef(RefOrder, orderID)
+	held, err := q.GetOrderHeldCents(ctx, ref.ID)
+	if err != nil {
+		return fmt.Errorf("read held charge: %w", err)
+	}
+	if held <= 0 {
+		return nil
+	}
+	_, err = Post(ctx, q, Journal{
+		Kind:          KindOrderSettle,
+		Description:   fmt.Sprintf("Order #%d settled", orderID),
+		ReferenceType: ref.Type,
+		ReferenceID:   ref.ID,
+		Entries: []Entry{
+			System(AccountProviderPayable, held),
+			System(AccountRevenue, -held),
+		},
+	})
+	return err
+}
+
+// NormalBalance turns a debit-positive sum into the balance as the account
+// kind is usually read: assets and expenses grow with debits, the rest with
+// credits
+func NormalBalance(kind string, debitBalance int64) int64 {
+	switch kind {
+	case "asset", "expense":
+		return debitBalance
+	}
+	return -debitBalance
+}
+
+// logTransaction writes the user-facing transaction row. Positive amounts
+// are credits and negative ones debits.
+func logTransaction(ctx context.Context, q *sqlc.Queries, userID int32, amountCents int64, kind string, ref Ref, description string) error {
+	txnType := "credit"
+	if amountCents < 0 {
+		txnType, amountCents = "debit", -amountCents
+	}
+	err := q.InsertTransaction(ctx, sqlc.InsertTransactionParams{
+		UserID:        pgtype.Int4{Int32: userID, Valid: true},
+		Amount:        money.Paise(amountCents).Numeric(),
+		Type:          txnType,
+		Kind:          kind,
+		Description:   pgtype.Text{String: description, Valid: true},
+		ReferenceType: ref.Type,
+		ReferenceID:   ref.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("log transaction: %w", err)
+	}
+	return nil
+}
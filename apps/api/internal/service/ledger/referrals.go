@@ -0,0 +1,100 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+)
+
+// AccrueCommission expenses a referral commission and owes it to the
+// affiliate until it is converted or paid out
+func AccrueCommission(ctx context.Context, q *sqlc.Queries, commissionID int32, amount money.Money, description string) error {
+	if !amount.IsPositive() {
+		return nil
+	}
+	ref := NewRef(RefReferralCommission, commissionID)
+	_, err := Post(ctx, q, Journal{
+		Kind:          KindReferral,
+		Description:   description,
+		ReferenceType: ref.Type,
+		ReferenceID:   ref.ID,
+		Entries: []Entry{
+			System(AccountReferralCommissions, amount.Minor),
+			System(AccountAffiliatePayable, -amount.Minor),
+		},
+	})
+	return err
+}
+
+// ReverseCommission claws back an accrued referral commission, for instance
+// when the deposit it was earned on is reversed. The affiliate balance goes
+// down by the amount, below zero if it was already withdrawn.
+func ReverseCommission(ctx context.Context, q *sqlc.Queries, commissionID int32, amount money.Money, description string) error {
+	if !amount.IsPositive() {
+		return nil
+	}
+	ref := NewRef(RefReferralCommission, commissionID)
+	_, err := Post(ctx, q, Journal{
+		Kind:          KindReferral,
+		Description:   description,
+		ReferenceType: ref.Type,
+		ReferenceID:   ref.ID,
+		Entries: []Entry{
+			System(AccountAffiliatePayable, amount.Minor),
+			System(AccountReferralCommissions, -amount.Minor),
+		},
+	})
+	return err
+}
+
+// ConvertCommission moves affiliate balance into the affiliate's wallet and
+// logs it in their transaction history
+func ConvertCommission(ctx context.Context, q *sqlc.Queries, userID, payoutID int32, amount money.Money, description string) error {
+	if !amount.IsPositive() {
+		return fmt.Errorf("ledger: conversion must be positive")
+	}
+	amountCents := amount.Minor
+	err := q.UpsertWalletBalance(ctx, sqlc.UpsertWalletBalanceParams{
+		UserID:  userID,
+		Balance: amountCents,
+	})
+	if err != nil {
+		return fmt.Errorf("credit wallet: %w", err)
+	}
+	ref := NewRef(RefReferral, payoutID)
+	if err := logTransaction(ctx, q, userID, amountCents, KindReferral, ref, description); err != nil {
+		return err
+	}
+	_, err = Post(ctx, q, Journal{
+		Kind:          KindReferral,
+		Description:   description,
+		ReferenceType: ref.Type,
+		ReferenceID:   ref.ID,
+		Entries: []Entry{
+			System(AccountAffiliatePayable, amountCents),
+			Wallet(userID, -amountCents),
+		},
+	})
+	return err
+}
+
+// PayCommission records affiliate balance paid out outside the platform
+func PayCommission(ctx context.Context, q *sqlc.Queries, payoutID int32, amount money.Money, description string) error {
+	if !amount.IsPositive() {
+		return fmt.Errorf("ledger: payout must be positive")
+	}
+	ref := NewRef(RefReferral, payoutID)
+	_, err := Post(ctx, q, Journal{
+		Kind:          KindReferral,
+		Description:   description,
+		ReferenceType: ref.Type,
+		ReferenceID:   ref.ID,
+		Entries: []Entry{
+			System(AccountAffiliatePayable, amount.Minor),
+			System(AccountDepositsClearing, -amount.Minor),
+		},
+	})
+	return err
+}
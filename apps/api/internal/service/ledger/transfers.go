@@ -0,0 +1,75 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+)
+
+// Transfer moves money from one wallet to another. A transfer that was held
+// while it waited for approval is sent from its hold; otherwise the sender
+// is debited, failing with ErrInsufficientFunds when the available balance
+// does not cover it. Both sides are logged against the transfer and posted
+// as one journal.
+func Transfer(ctx context.Context, q *sqlc.Queries, senderID, recipientID, transferID int32, amount money.Money) error {
+	if !amount.IsPositive() {
+		return fmt.Errorf("ledger: transfer must be positive")
+	}
+	amountCents := amount.Minor
+	ref := NewRef(RefWalletTransfer, transferID)
+
+	hold, held, err := activeHold(ctx, q, ref, "captured")
+	if err != nil {
+		return err
+	}
+	if held {
+		err = q.CaptureWalletFunds(ctx, sqlc.CaptureWalletFundsParams{
+			HeldCents: hold.AmountCents,
+			UserID:    hold.UserID,
+		})
+		if err != nil {
+			return fmt.Errorf("capture funds: %w", err)
+		}
+	} else {
+		n, err := q.DebitWallet(ctx, sqlc.DebitWalletParams{
+			Balance: amountCents,
+			UserID:  senderID,
+		})
+		if err != nil {
+			return fmt.Errorf("debit wallet: %w", err)
+		}
+		if n == 0 {
+			return ErrInsufficientFunds
+		}
+	}
+	if err := spendBonuses(ctx, q, senderID); err != nil {
+		return err
+	}
+	err = q.UpsertWalletBalance(ctx, sqlc.UpsertWalletBalanceParams{
+		UserID:  recipientID,
+		Balance: amountCents,
+	})
+	if err != nil {
+		return fmt.Errorf("credit wallet: %w", err)
+	}
+
+	if err := logTransaction(ctx, q, senderID, -amountCents, KindTransfer, ref, fmt.Sprintf("Transfer #%d sent", transferID)); err != nil {
+		return err
+	}
+	if err := logTransaction(ctx, q, recipientID, amountCents, KindTransfer, ref, fmt.Sprintf("Transfer #%d received", transferID)); err != nil {
+		return err
+	}
+	_, err = Post(ctx, q, Journal{
+		Kind:          KindTransfer,
+		Description:   fmt.Sprintf("Transfer #%d from user %d to user %d", transferID, senderID, recipientID),
+		ReferenceType: ref.Type,
+		ReferenceID:   ref.ID,
+		Entries: []Entry{
+			Wallet(senderID, amountCents),
+			Wallet(recipientID, -amountCents),
+		},
+	})
+	return err
+}
@@ -0,0 +1,536 @@
+// Package links parses social media URLs into canonical targets so that
+// orders can be validated, deduplicated and reported on the real profile,
+// post or video they point at instead of the raw string the user pasted.
+package links
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Target kinds extracted from a link
+const (
+	KindProfile = "profile"
+	KindPost    = "post"
+	KindReel    = "reel"
+	KindVideo   = "video"
+	KindShort   = "short"
+	KindChannel = "channel"
+	KindStory   = "story"
+	KindInvite  = "invite"
+	KindOther   = "other"
+)
+
+// Target is the normalized form of a user supplied link
+type Target struct {
+	Platform  string `json:"platform"`
+	Kind      string `json:"kind"`
+	ID        string `json:"id"`
+	Canonical string `json:"canonical"`
+}
+
+// Key identifies the target independently of how the link was written
+func (t *Target) Key() string {
+	return t.Platform + ":" + t.Kind + ":" + t.ID
+}
+
+type Service struct {
+	client *http.Client
+}
+
+func New() *Service {
+	return &Service{
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+var (
+	instagramReserved = map[string]bool{
+		"explore": true, "accounts": true, "direct": true, "about": true, "developer": true,
+		"legal": true, "web": true, "emails": true, "challenge": true,
+	}
+	xReserved = map[string]bool{
+		"home": true, "search": true, "explore": true, "settings": true, "intent": true,
+		"notifications": true, "messages": true, "compose": true, "share": true, "hashtag": true,
+	}
+	facebookReserved = map[string]bool{
+		"login": true, "help": true, "settings": true, "marketplace": true, "gaming": true,
+		"events": true, "hashtag": true, "sharer": true, "dialog": true,
+	}
+
+	usernameRx     = regexp.MustCompile(`^[A-Za-z0-9._]{1,30}$`)
+	youtubeIDRx    = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	numericRx      = regexp.MustCompile(`^[0-9]+$`)
+	telegramNameRx = regexp.MustCompile(`^[A-Za-z0-9_]{4,32}$`)
+	tiktokVideoRx  = regexp.MustCompile(`^/v/([0-9]+)(\.html)?$`)
+)
+
+// Parse normalizes a link for the given platform. Short links (youtu.be is
+// handled offline; fb.watch, vm.tiktok.com and friends are followed over
+// HTTP) are resolved before extraction.
+func (s *Service) Parse(platform, raw string) (*Target, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("link is required")
+	}
+
+	u, err := parseURL(raw)
+	if err != nil {
+		return nil, fmt.Errorf("link is not a valid URL")
+	}
+
+	if s.isShortLink(u) {
+		resolved, err := s.resolve(u.String())
+		switch {
+		case err == nil:
+			u = resolved
+		case tiktokVideoRx.MatchString(u.Path):
+			// The video ID is in the link itself, so it can be used as is
+			log.Printf("[Links] Failed to resolve TikTok share link %s, using it as given: %v", raw, err)
+		default:
+			log.Printf("[Links] Failed to resolve short link %s: %v", raw, err)
+			return nil, fmt.Errorf("could not open the short link, please paste the full URL")
+		}
+	}
+
+	platform = PlatformKey(platform)
+	switch platform {
+	case "instagram":
+		return parseInstagram(u)
+	case "facebook":
+		return parseFacebook(u)
+	case "youtube":
+		return parseYouTube(u)
+	case "tiktok":
+		return parseTikTok(u)
+	case "telegram":
+		return parseTelegram(u)
+	case "x":
+		return parseX(u)
+	case "linkedin":
+		return parseGeneric("linkedin", u, "LinkedIn", "linkedin.com")
+	case "spotify":
+		return parseGeneric("spotify", u, "Spotify", "spotify.com")
+	case "twitch":
+		return parseGeneric("twitch", u, "Twitch", "twitch.tv")
+	case "discord":
+		return parseGeneric("discord", u, "Discord", "discord.gg", "discord.com")
+	}
+
+	return &Target{
+		Platform:  platform,
+		Kind:      KindOther,
+		ID:        strings.ToLower(u.Host + strings.TrimSuffix(u.Path, "/")),
+		Canonical: stripTracking(u).String(),
+	}, nil
+}
+
+// PlatformKey is the name a platform goes by in targets and link rules.
+// X still appears as Twitter in some catalogs, so both map to "x".
+func PlatformKey(platform string) string {
+	platform = strings.ToLower(strings.TrimSpace(platform))
+	if platform == "twitter" {
+		return "x"
+	}
+	return platform
+}
+
+func parseURL(raw string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("missing host")
+	}
+	u.Scheme = "https"
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	return u, nil
+}
+
+func (s *Service) isShortLink(u *url.URL) bool {
+	switch u.Host {
+	case "fb.watch", "vm.tiktok.com", "vt.tiktok.com", "t.co":
+		return true
+	case "tiktok.com", "www.tiktok.com", "m.tiktok.com":
+		// /v/<id>.html share links have no username; they redirect to the
+		// full @user/video/<id> link
+		return strings.HasPrefix(u.Path, "/t/") || strings.HasPrefix(u.Path, "/v/")
+	case "facebook.com", "www.facebook.com", "m.facebook.com", "web.facebook.com":
+		return strings.HasPrefix(u.Path, "/share/")
+	}
+	return false
+}
+
+// resolve follows redirects and returns the final URL without reading the body
+func (s *Service) resolve(shortURL string) (*url.URL, error) {
+	req, err := http.NewRequest("GET", shortURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	final := resp.Request.URL
+	if final == nil || final.String() == shortURL {
+		return nil, fmt.Errorf("short link did not redirect")
+	}
+	return parseURL(final.String())
+}
+
+func pathParts(u *url.URL) []string {
+	var parts []string
+	for _, p := range strings.Split(u.Path, "/") {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return parts
+}
+
+func hostIs(u *url.URL, domains ...string) bool {
+	for _, d := range domains {
+		if u.Host == d || strings.HasSuffix(u.Host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// trackingParams are dropped from links that keep their query string
+var trackingParams = []string{"igshid", "igsh", "si", "fbclid", "gclid", "feature", "_t", "_r", "is_from_webapp", "sender_device", "ref", "ref_src", "mibextid", "s", "t"}
+
+func stripTracking(u *url.URL) *url.URL {
+	clean := *u
+	q := clean.Query()
+	for key := range q {
+		if strings.HasPrefix(strings.ToLower(key), "utm_") {
+			q.Del(key)
+		}
+	}
+	for _, key := range trackingParams {
+		q.Del(key)
+	}
+	clean.RawQuery = q.Encode()
+	return &clean
+}
+
+func parseInstagram(u *url.URL) (*Target, error) {
+	if !hostIs(u, "instagram.com", "instagr.am") {
+		return nil, fmt.Errorf("link must be an Instagram URL")
+	}
+	parts := pathParts(u)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("Instagram link must point to a profile, post or reel")
+	}
+
+	t := &Target{Platform: "instagram"}
+	switch strings.ToLower(parts[0]) {
+	case "p":
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("Instagram post link is incomplete")
+		}
+		t.Kind, t.ID = KindPost, parts[1]
+		t.Canonical = "https://www.instagram.com/p/" + parts[1] + "/"
+	case "reel", "reels":
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("Instagram reel link is incomplete")
+		}
+		t.Kind, t.ID = KindReel, parts[1]
+		t.Canonical = "https://www.instagram.com/reel/" + parts[1] + "/"
+	case "tv":
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("Instagram video link is incomplete")
+		}
+		t.Kind, t.ID = KindVideo, parts[1]
+		t.Canonical = "https://www.instagram.com/tv/" + parts[1] + "/"
+	case "stories":
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("Instagram story link is incomplete")
+		}
+		t.Kind = KindStory
+		if strings.ToLower(parts[1]) == "highlights" {
+			t.ID = "highlights/" + parts[2]
+		} else {
+			t.ID = strings.ToLower(parts[1]) + "/" + parts[2]
+		}
+		t.Canonical = "https://www.instagram.com/stories/" + t.ID + "/"
+	default:
+		username := strings.ToLower(strings.TrimPrefix(parts[0], "@"))
+		if instagramReserved[username] || !usernameRx.MatchString(username) {
+			return nil, fmt.Errorf("Instagram link must point to a profile, post or reel")
+		}
+		// instagram.com/username/p/CODE is also a valid post link
+		if len(parts) >= 3 && (parts[1] == "p" || parts[1] == "reel") {
+			return parseInstagram(&url.URL{Scheme: "https", Host: "www.instagram.com", Path: "/" + strings.Join(parts[1:], "/")})
+		}
+		t.Kind, t.ID = KindProfile, username
+		t.Canonical = "https://www.instagram.com/" + username + "/"
+	}
+	return t, nil
+}
+
+func parseFacebook(u *url.URL) (*Target, error) {
+	if !hostIs(u, "facebook.com", "fb.com") {
+		return nil, fmt.Errorf("link must be a Facebook URL")
+	}
+	parts := pathParts(u)
+	q := u.Query()
+	t := &Target{Platform: "facebook"}
+
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("Facebook link must point to a page, profile, post or video")
+	}
+
+	first := strings.ToLower(parts[0])
+	switch {
+	case first == "profile.php" && q.Get("id") != "":
+		t.Kind, t.ID = KindProfile, q.Get("id")
+		t.Canonical = "https://www.facebook.com/profile.php?id=" + t.ID
+	case first == "watch" && q.Get("v") != "":
+		t.Kind, t.ID = KindVideo, q.Get("v")
+		t.Canonical = "https://www.facebook.com/watch/?v=" + t.ID
+	case (first == "reel" || first == "reels") && len(parts) >= 2:
+		t.Kind, t.ID = KindReel, parts[1]
+		t.Canonical = "https://www.facebook.com/reel/" + t.ID
+	case first == "story.php" || first == "permalink.php":
+		// The post ID is only unique together with the ID of the page or
+		// profile it was posted on
+		if q.Get("story_fbid") == "" || q.Get("id") == "" {
+			return nil, fmt.Errorf("Facebook post link is incomplete, please copy the full link of the post")
+		}
+		t.Kind, t.ID = KindPost, q.Get("id")+"_"+q.Get("story_fbid")
+		t.Canonical = "https://www.facebook.com/permalink.php?story_fbid=" + q.Get("story_fbid") + "&id=" + q.Get("id")
+	case first == "stories" && len(parts) >= 2:
+		t.Kind, t.ID = KindStory, strings.Join(parts[1:], "/")
+		t.Canonical = "https://www.facebook.com/stories/" + t.ID
+	case first == "groups" && len(parts) >= 2:
+		if len(parts) >= 4 && (parts[2] == "posts" || parts[2] == "permalink") {
+			t.Kind, t.ID = KindPost, parts[3]
+			t.Canonical = "https://www.facebook.com/groups/" + parts[1] + "/posts/" + parts[3]
+		} else {
+			t.Kind, t.ID = KindChannel, strings.ToLower(parts[1])
+			t.Canonical = "https://www.facebook.com/groups/" + parts[1]
+		}
+	case facebookReserved[first]:
+		return nil, fmt.Errorf("Facebook link must point to a page, profile, post or video")
+	default:
+		page := parts[0]
+		if len(parts) >= 3 {
+			switch strings.ToLower(parts[1]) {
+			case "posts", "photos":
+				t.Kind, t.ID = KindPost, parts[len(parts)-1]
+				t.Canonical = "https://www.facebook.com/" + page + "/posts/" + t.ID
+				return t, nil
+			case "videos":
+				t.Kind, t.ID = KindVideo, parts[len(parts)-1]
+				t.Canonical = "https://www.facebook.com/" + page + "/videos/" + t.ID
+				return t, nil
+			}
+		}
+		t.Kind, t.ID = KindProfile, strings.ToLower(page)
+		t.Canonical = "https://www.facebook.com/" + page
+	}
+	return t, nil
+}
+
+func parseYouTube(u *url.URL) (*Target, error) {
+	t := &Target{Platform: "youtube"}
+
+	if u.Host == "youtu.be" {
+		parts := pathParts(u)
+		if len(parts) == 0 || !youtubeIDRx.MatchString(parts[0]) {
+			return nil, fmt.Errorf("YouTube short link is incomplete")
+		}
+		t.Kind, t.ID = KindVideo, parts[0]
+		t.Canonical = "https://www.youtube.com/watch?v=" + t.ID
+		return t, nil
+	}
+
+	if !hostIs(u, "youtube.com") {
+		return nil, fmt.Errorf("link must be a YouTube URL")
+	}
+	parts := pathParts(u)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("YouTube link must point to a channel or video")
+	}
+
+	first := parts[0]
+	switch {
+	case first == "watch":
+		v := u.Query().Get("v")
+		if !youtubeIDRx.MatchString(v) {
+			return nil, fmt.Errorf("YouTube video link is incomplete")
+		}
+		t.Kind, t.ID = KindVideo, v
+		t.Canonical = "https://www.youtube.com/watch?v=" + v
+	case (first == "shorts" || first == "live" || first == "embed") && len(parts) >= 2:
+		if !youtubeIDRx.MatchString(parts[1]) {
+			return nil, fmt.Errorf("YouTube video link is incomplete")
+		}
+		t.ID = parts[1]
+		if first == "shorts" {
+			t.Kind = KindShort
+			t.Canonical = "https://www.youtube.com/shorts/" + t.ID
+		} else {
+			t.Kind = KindVideo
+			t.Canonical = "https://www.youtube.com/watch?v=" + t.ID
+		}
+	case first == "post" && len(parts) >= 2:
+		t.Kind, t.ID = KindPost, parts[1]
+		t.Canonical = "https://www.youtube.com/post/" + t.ID
+	case first == "channel" && len(parts) >= 2:
+		t.Kind, t.ID = KindChannel, parts[1]
+		t.Canonical = "https://www.youtube.com/channel/" + t.ID
+	case (first == "c" || first == "user") && len(parts) >= 2:
+		t.Kind, t.ID = KindChannel, strings.ToLower(parts[1])
+		t.Canonical = "https://www.youtube.com/" + first + "/" + parts[1]
+	case strings.HasPrefix(first, "@"):
+		if len(parts) >= 2 && parts[1] == "community" && u.Query().Get("lb") != "" {
+			t.Kind, t.ID = KindPost, u.Query().Get("lb")
+			t.Canonical = "https://www.youtube.com/post/" + t.ID
+			return t, nil
+		}
+		t.Kind, t.ID = KindChannel, strings.ToLower(first)
+		t.Canonical = "https://www.youtube.com/" + first
+	default:
+		return nil, fmt.Errorf("YouTube link must point to a channel or video")
+	}
+	return t, nil
+}
+
+func parseTikTok(u *url.URL) (*Target, error) {
+	if !hostIs(u, "tiktok.com") {
+		return nil, fmt.Errorf("link must be a TikTok URL")
+	}
+	t := &Target{Platform: "tiktok"}
+
+	if m := tiktokVideoRx.FindStringSubmatch(u.Path); m != nil {
+		// A share link that could not be followed to the full link. There
+		// is no video URL without the username that panels accept, so the
+		// link is passed on as given.
+		t.Kind, t.ID = KindVideo, m[1]
+		t.Canonical = stripTracking(u).String()
+		return t, nil
+	}
+
+	parts := pathParts(u)
+	if len(parts) == 0 || !strings.HasPrefix(parts[0], "@") {
+		return nil, fmt.Errorf("TikTok link must point to a profile or video")
+	}
+	username := strings.ToLower(parts[0])
+
+	if len(parts) >= 3 && numericRx.MatchString(parts[2]) {
+		switch parts[1] {
+		case "video":
+			t.Kind = KindVideo
+		case "photo":
+			t.Kind = KindPost
+		default:
+			return nil, fmt.Errorf("TikTok link must point to a profile or video")
+		}
+		t.ID = parts[2]
+		t.Canonical = "https://www.tiktok.com/" + username + "/" + parts[1] + "/" + t.ID
+		return t, nil
+	}
+
+	t.Kind, t.ID = KindProfile, username
+	t.Canonical = "https://www.tiktok.com/" + username
+	return t, nil
+}
+
+func parseTelegram(u *url.URL) (*Target, error) {
+	if !hostIs(u, "t.me", "telegram.me", "telegram.dog") {
+		return nil, fmt.Errorf("link must be a Telegram URL (t.me/...)")
+	}
+	parts := pathParts(u)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("Telegram link must point to a channel, group or post")
+	}
+	if parts[0] == "s" && len(parts) >= 2 {
+		parts = parts[1:]
+	}
+
+	t := &Target{Platform: "telegram"}
+	first := parts[0]
+	switch {
+	case strings.HasPrefix(first, "+") || first == "joinchat":
+		t.Kind = KindInvite
+		t.ID = strings.TrimPrefix(first, "+")
+		if first == "joinchat" && len(parts) >= 2 {
+			t.ID = parts[1]
+		}
+		t.Canonical = "https://t.me/+" + t.ID
+	case first == "c" && len(parts) >= 3:
+		// t.me/c/<internal id>/<post> only works for members of private chats
+		t.Kind, t.ID = KindPost, parts[1]+"/"+parts[2]
+		t.Canonical = "https://t.me/c/" + t.ID
+	case telegramNameRx.MatchString(first):
+		name := strings.ToLower(first)
+		if len(parts) >= 2 && numericRx.MatchString(parts[1]) {
+			t.Kind, t.ID = KindPost, name+"/"+parts[1]
+			t.Canonical = "https://t.me/" + t.ID
+		} else {
+			t.Kind, t.ID = KindChannel, name
+			t.Canonical = "https://t.me/" + name
+		}
+	default:
+		return nil, fmt.Errorf("Telegram link must point to a channel, group or post")
+	}
+	return t, nil
+}
+
+func parseX(u *url.URL) (*Target, error) {
+	if !hostIs(u, "x.com", "twitter.com") {
+		return nil, fmt.Errorf("link must be an X (Twitter) URL")
+	}
+	parts := pathParts(u)
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("X link must point to a profile or post")
+	}
+	t := &Target{Platform: "x"}
+
+	if len(parts) >= 3 && (parts[1] == "status" || parts[1] == "statuses") && numericRx.MatchString(parts[2]) {
+		t.Kind, t.ID = KindPost, parts[2]
+		t.Canonical = "https://x.com/i/status/" + t.ID
+		return t, nil
+	}
+
+	username := strings.ToLower(strings.TrimPrefix(parts[0], "@"))
+	if xReserved[username] || username == "i" || !usernameRx.MatchString(username) {
+		return nil, fmt.Errorf("X link must point to a profile or post")
+	}
+	t.Kind, t.ID = KindProfile, username
+	t.Canonical = "https://x.com/" + username
+	return t, nil
+}
+
+func parseGeneric(platform string, u *url.URL, label string, domains ...string) (*Target, error) {
+	if !hostIs(u, domains...) {
+		return nil, fmt.Errorf("link must be a %s URL", label)
+	}
+	clean := stripTracking(u)
+	clean.Path = strings.TrimSuffix(clean.Path, "/")
+	return &Target{
+		Platform:  platform,
+		Kind:      KindOther,
+		ID:        strings.ToLower(clean.Host + clean.Path),
+		Canonical: clean.String(),
+	}, nil
+}
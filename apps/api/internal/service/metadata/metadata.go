@@ -2,6 +2,7 @@ package metadata
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html"
 	"io"
@@ -25,6 +26,29 @@ type Metadata struct {
 	Likes       int    `json:"likes"`
 	Views       int    `json:"views"`
 	Comments    int    `json:"comments"`
+	IsPrivate   bool   `json:"isPrivate"`
+}
+
+// ErrNotFound is returned when the page does not exist or the content was removed
+var ErrNotFound = errors.New("page not found")
+
+// CountFor returns the public counter that a service type grows, e.g. the
+// follower count for followers services. ok is false when the page did not
+// expose that counter.
+func (m *Metadata) CountFor(serviceType string) (count int, ok bool) {
+	switch strings.ToLower(serviceType) {
+	case "followers", "subscribers", "members":
+		count = m.Followers
+	case "likes":
+		count = m.Likes
+	case "views":
+		count = m.Views
+	case "comments":
+		count = m.Comments
+	default:
+		return 0, false
+	}
+	return count, count > 0
 }
 
 type Service struct {
@@ -113,8 +137,8 @@ func (s *Service) Fetch(targetURL string) (*Metadata, error) {
 		}
 
 		// If 404 or 429, we might want to return partial data or error
-		if resp.StatusCode == 404 {
-			return nil, fmt.Errorf("page not found")
+		if resp.StatusCode == 404 || resp.StatusCode == 410 {
+			return nil, ErrNotFound
 		}
 	}
 
@@ -149,6 +173,11 @@ func (s *Service) Fetch(targetURL string) (*Metadata, error) {
 		if oEmbedMeta.Image != "" { m.Image = oEmbedMeta.Image }
 	}
 
+	if isMissingPage(targetURL, m.Title, htmlContent) {
+		return nil, ErrNotFound
+	}
+	m.IsPrivate = isPrivatePage(targetURL, htmlContent)
+
 	// PLATFORM SPECIFIC PARSING
 	if strings.Contains(targetURL, "instagram.com") {
 		// IG Parsing
@@ -219,6 +248,40 @@ func (s *Service) saveCache(url string, m *Metadata) {
 	s.mu.Unlock()
 }
 
+// isMissingPage detects removed content on platforms that answer 200 with an
+// error page instead of a 404
+func isMissingPage(targetURL, title, htmlContent string) bool {
+	switch {
+	case strings.Contains(targetURL, "instagram.com"):
+		return strings.Contains(strings.ToLower(title), "page not found") ||
+			strings.Contains(htmlContent, "Sorry, this page isn't available")
+	case strings.Contains(targetURL, "tiktok.com"):
+		return strings.Contains(htmlContent, `"statusCode":10204`) ||
+			strings.Contains(htmlContent, `"statusCode":10202`) ||
+			strings.Contains(htmlContent, "Couldn't find this account")
+	case strings.Contains(targetURL, "youtube.com") || strings.Contains(targetURL, "youtu.be"):
+		return strings.Contains(htmlContent, `"playabilityStatus":{"status":"ERROR"`) ||
+			strings.Contains(htmlContent, "This channel does not exist")
+	}
+	return false
+}
+
+func isPrivatePage(targetURL, htmlContent string) bool {
+	switch {
+	case strings.Contains(targetURL, "instagram.com"):
+		return strings.Contains(htmlContent, `"is_private":true`) ||
+			strings.Contains(htmlContent, "This account is private") ||
+			strings.Contains(htmlContent, "This Account is Private")
+	case strings.Contains(targetURL, "tiktok.com"):
+		return strings.Contains(htmlContent, `"privateAccount":true`) ||
+			strings.Contains(htmlContent, "This account is private")
+	case strings.Contains(targetURL, "youtube.com") || strings.Contains(targetURL, "youtu.be"):
+		return strings.Contains(htmlContent, `"status":"LOGIN_REQUIRED"`) &&
+			strings.Contains(htmlContent, "This video is private")
+	}
+	return false
+}
+
 func parseInstagramStats(desc string) (followers, following, posts, likes, comments, views int) {
 	fRe := regexp.MustCompile(`(?i)([\d,.]+)([KMB]?)\s*Followers`)
 	fingRe := regexp.MustCompile(`(?i)([\d,.]+)([KMB]?)\s*Following`)
@@ -0,0 +1,257 @@
+// Package promo applies promo codes: a discount on an order, or extra
+// credit on a deposit once it is approved.
+package promo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/bonus"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// What a code applies to
+const (
+	AppliesToOrder   = "order"
+	AppliesToDeposit = "deposit"
+)
+
+// How a code's value is worked out
+const (
+	ValuePercent = "percent"
+	ValueFixed   = "fixed"
+)
+
+// InvalidError is returned when a code cannot be used. Its message is meant
+// for the user.
+type InvalidError struct {
+	Reason string
+}
+
+func (e *InvalidError) Error() string {
+	return e.Reason
+}
+
+func invalid(format string, args ...any) error {
+	return &InvalidError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// NormalizeCode is how codes are stored and looked up
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// NormalizePlatform is how platform restrictions are stored and compared
+func NormalizePlatform(platform string) string {
+	return strings.ToLower(strings.TrimSpace(platform))
+}
+
+// Order is what an order code is checked against
+type Order struct {
+	// ServiceID is the catalog service ordered
+	ServiceID string
+	Platform  string
+	Price     money.Money
+}
+
+// Discount is an order code applied to a price
+type Discount struct {
+	CodeID int32
+	Code   string
+	Price  money.Money
+	Amount money.Money
+}
+
+// Charge is the price after the discount
+func (d Discount) Charge() money.Money {
+	return d.Price.Sub(d.Amount)
+}
+
+// Value is what a code is worth on an order price or deposit: its
+// percentage rounded down, or its fixed amount, capped at its maximum
+func Value(pc sqlc.PromoCode, base money.Money) money.Money {
+	value := money.New(pc.ValueCents, base.Currency)
+	if pc.ValueType == ValuePercent {
+		value = base.Percent(pc.ValuePct, money.RoundDown)
+	}
+	if pc.MaxValueCents.Valid && value.Minor > pc.MaxValueCents.Int64 {
+		value = money.New(pc.MaxValueCents.Int64, base.Currency)
+	}
+	return value
+}
+
+// QuoteOrder works out the discount a code gives on an order without using
+// the code up, so the order can be priced before its transaction starts
+func QuoteOrder(ctx context.Context, q *sqlc.Queries, userID int32, code string, o Order) (Discount, error) {
+	pc, err := q.GetPromoCodeByCode(ctx, NormalizeCode(code))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Discount{}, invalid("Promo code %s does not exist", NormalizeCode(code))
+	}
+	if err != nil {
+		return Discount{}, err
+	}
+	return quoteOrder(ctx, q, pc, userID, o)
+}
+
+// LockOrder locks a code and checks it again. q must be bound to the
+// transaction creating the order, which has to pass the result to
+// RecordOrder; holding the lock until commit keeps concurrent orders from
+// using a code past its limits.
+func LockOrder(ctx context.Context, q *sqlc.Queries, userID int32, d Discount, o Order) (Discount, error) {
+	pc, err := q.GetPromoCodeForUpdate(ctx, d.CodeID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Discount{}, invalid("Promo code %s does not exist", d.Code)
+	}
+	if err != nil {
+		return Discount{}, err
+	}
+	return quoteOrder(ctx, q, pc, userID, o)
+}
+
+// RecordOrder counts a use of the code by an order
+func RecordOrder(ctx context.Context, q *sqlc.Queries, userID, orderID int32, d Discount) error {
+	_, err := q.InsertPromoRedemption(ctx, sqlc.InsertPromoRedemptionParams{
+		PromoCodeID: d.CodeID,
+		UserID:      userID,
+		OrderID:     pgtype.Int4{Int32: orderID, Valid: true},
+		BaseCents:   d.Price.Minor,
+		ValueCents:  d.Amount.Minor,
+	})
+	if err != nil {
+		return fmt.Errorf("record promo code: %w", err)
+	}
+	return nil
+}
+
+func quoteOrder(ctx context.Context, q *sqlc.Queries, pc sqlc.PromoCode, userID int32, o Order) (Discount, error) {
+	if err := check(ctx, q, pc, userID, AppliesToOrder, o.Price); err != nil {
+		return Discount{}, err
+	}
+	if len(pc.Platforms) > 0 && !slices.Contains(pc.Platforms, NormalizePlatform(o.Platform)) {
+		return Discount{}, invalid("Promo code %s does not apply to this service", pc.Code)
+	}
+	if len(pc.CatalogIds) > 0 {
+		id, err := strconv.Atoi(o.ServiceID)
+		if err != nil || !slices.Contains(pc.CatalogIds, int32(id)) {
+			return Discount{}, invalid("Promo code %s does not apply to this service", pc.Code)
+		}
+	}
+
+	// An order always costs something, so the discount stops a paisa short
+	// of the price
+	amount := money.Min(Value(pc, o.Price), o.Price.Sub(money.New(1, o.Price.Currency)))
+	if !amount.IsPositive() {
+		return Discount{}, invalid("Promo code %s gives no discount on this order", pc.Code)
+	}
+	return Discount{CodeID: pc.ID, Code: pc.Code, Price: o.Price, Amount: amount}, nil
+}
+
+// CheckDeposit checks that a code can be used on a deposit about to be
+// requested and returns it. The credit is only paid by RedeemDeposit, once
+// the deposit is approved.
+func CheckDeposit(ctx context.Context, q *sqlc.Queries, userID int32, code string, deposit money.Money) (sqlc.PromoCode, error) {
+	pc, err := q.GetPromoCodeByCode(ctx, NormalizeCode(code))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return pc, invalid("Promo code %s does not exist", NormalizeCode(code))
+	}
+	if err != nil {
+		return pc, err
+	}
+	return pc, check(ctx, q, pc, userID, AppliesToDeposit, deposit)
+}
+
+// RedeemDeposit pays the credit of the code entered with an approved
+// deposit, if any, and returns it. q must be bound to the transaction
+// approving the deposit. A code that can no longer be used by then, for
+// instance because it expired while the deposit was pending, pays nothing
+// and does not hold up the deposit.
+func RedeemDeposit(ctx context.Context, q *sqlc.Queries, userID, requestID int32, deposit money.Money) (money.Money, error) {
+	none := money.New(0, deposit.Currency)
+	codeID, err := q.GetWalletRequestPromoCode(ctx, requestID)
+	if err != nil {
+		return none, fmt.Errorf("deposit promo code: %w", err)
+	}
+	if !codeID.Valid {
+		return none, nil
+	}
+	pc, err := q.GetPromoCodeForUpdate(ctx, codeID.Int32)
+	if err != nil {
+		return none, fmt.Errorf("lock promo code: %w", err)
+	}
+	var inv *InvalidError
+	if err := check(ctx, q, pc, userID, AppliesToDeposit, deposit); errors.As(err, &inv) {
+		log.Printf("[Promo] Not applying %s to deposit #%d: %s", pc.Code, requestID, inv.Reason)
+		return none, nil
+	} else if err != nil {
+		return none, err
+	}
+
+	credit := Value(pc, deposit)
+	if !credit.IsPositive() {
+		return none, nil
+	}
+	_, err = q.InsertPromoRedemption(ctx, sqlc.InsertPromoRedemptionParams{
+		PromoCodeID:     pc.ID,
+		UserID:          userID,
+		WalletRequestID: pgtype.Int4{Int32: requestID, Valid: true},
+		BaseCents:       deposit.Minor,
+		ValueCents:      credit.Minor,
+	})
+	if err != nil {
+		return none, fmt.Errorf("record promo code: %w", err)
+	}
+	err = bonus.Credit(ctx, q, sqlc.InsertBonusGrantParams{
+		UserID:          userID,
+		PromoCodeID:     pgtype.Int4{Int32: pc.ID, Valid: true},
+		WalletRequestID: pgtype.Int4{Int32: requestID, Valid: true},
+		DepositCents:    deposit.Minor,
+		AmountCents:     credit.Minor,
+	}, fmt.Sprintf("Promo code %s on deposit #%d", pc.Code, requestID))
+	if err != nil {
+		return none, err
+	}
+	return credit, nil
+}
+
+// check applies the rules every code has: active and within its window,
+// used for the right thing, above its minimum and within its usage limits
+func check(ctx context.Context, q *sqlc.Queries, pc sqlc.PromoCode, userID int32, appliesTo string, base money.Money) error {
+	now := time.Now()
+	if !pc.IsActive || (pc.StartsAt.Valid && now.Before(pc.StartsAt.Time)) {
+		return invalid("Promo code %s is not active", pc.Code)
+	}
+	if pc.ExpiresAt.Valid && !now.Before(pc.ExpiresAt.Time) {
+		return invalid("Promo code %s has expired", pc.Code)
+	}
+	if pc.AppliesTo != appliesTo {
+		return invalid("Promo code %s can only be used on a %s", pc.Code, pc.AppliesTo)
+	}
+	if base.Minor < pc.MinAmountCents {
+		return invalid("Promo code %s needs a %s of at least %s", pc.Code, appliesTo, money.New(pc.MinAmountCents, base.Currency))
+	}
+
+	if !pc.MaxUses.Valid && !pc.MaxUsesPerUser.Valid {
+		return nil
+	}
+	used, err := q.CountPromoRedemptions(ctx, sqlc.CountPromoRedemptionsParams{UserID: userID, PromoCodeID: pc.ID})
+	if err != nil {
+		return fmt.Errorf("count promo code uses: %w", err)
+	}
+	if pc.MaxUses.Valid && used.Total >= int64(pc.MaxUses.Int32) {
+		return invalid("Promo code %s has been fully redeemed", pc.Code)
+	}
+	if pc.MaxUsesPerUser.Valid && used.ByUser >= int64(pc.MaxUsesPerUser.Int32) {
+		return invalid("You have already used promo code %s", pc.Code)
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"pablosmm/backend/internal/db"
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/jobs"
+	"pablosmm/backend/internal/service/ledger"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// JobName is the advisory lock shared by the scheduled job, the admin
+// endpoint and the CLI, so only one run happens at a time
+const JobName = "wallet_reconcile"
+
+// Discrepancy kinds
+const (
+	KindBalance = "balance_mismatch"
+	KindRefund  = "refund_credit_mismatch"
+	KindDeposit = "deposit_credit_mismatch"
+	KindOpening = "opening_balance_mismatch"
+)
+
+// What started a run
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+	TriggerCLI      = "cli"
+)
+
+var (
+	ErrNotFixable = errors.New("only balance mismatches can be fixed automatically")
+	ErrNotOpen    = errors.New("discrepancy is no longer open")
+	ErrStale      = errors.New("wallet changed since the run; run reconciliation again")
+)
+
+// Config is read from global_settings on every run
+type Config struct {
+	AutoFix         bool
+	AutoFixMaxCents int64
+	DepositsSince   time.Time
+}
+
+// Report summarises one run
+type Report struct {
+	RunID          int32 `json:"runId"`
+	CheckedWallets int   `json:"checkedWallets"`
+	Discrepancies  int   `json:"discrepancies"`
+	Fixed          int   `json:"fixed"`
+}
+
+// Reconciler recomputes every wallet from its ledger entries and compares it
+// with wallets.balance. As the ledger opened with those balances, it also
+// checks them against the transactions from before the ledger. It checks
+// too that refunded orders and approved deposit requests have the credits
+// they should. Only cached balances are
+// ever corrected automatically; the rest is left for an admin.
+type Reconciler struct {
+	db *db.DB
+}
+
+func New(database *db.DB) *Reconciler {
+	return &Reconciler{db: database}
+}
+
+func (r *Reconciler) Start(ctx context.Context, runner *jobs.Runner) {
+	runner.Every(ctx, JobName, 6*time.Hour, func(ctx context.Context) {
+		report, err := r.Run(ctx, TriggerSchedule, r.LoadConfig(ctx).AutoFix)
+		if err != nil {
+			log.Printf("[Reconcile] Run failed: %v", err)
+			return
+		}
+		if report.Discrepancies > 0 {
+			log.Printf("[Reconcile] Run #%d found %d discrepancies, fixed %d", report.RunID, report.Discrepancies, report.Fixed)
+		}
+	})
+}
+
+// LoadConfig reads the reconciliation settings, falling back to the seeded
+// defaults
+func (r *Reconciler) LoadConfig(ctx context.Context) Config {
+	cfg := Config{AutoFixMaxCents: 10000}
+	if v, err := r.db.Queries.GetSetting(ctx, "reconcile_autofix"); err == nil {
+		cfg.AutoFix = v == "true"
+	}
+	if v, err := r.db.Queries.GetSetting(ctx, "reconcile_autofix_max_cents"); err == nil {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			cfg.AutoFixMaxCents = n
+		}
+	}
+	if v, err := r.db.Queries.GetSetting(ctx, "reconcile_deposits_since"); err == nil {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			cfg.DepositsSince = t
+		}
+	}
+	return cfg
+}
+
+// Run records a run, supersedes the previous run's open discrepancies and
+// records the new ones. With autoFix, balance mismatches within
+// reconcile_autofix_max_cents are corrected.
+func (r *Reconciler) Run(ctx context.Context, trigger string, autoFix bool) (Report, error) {
+	cfg := r.LoadConfig(ctx)
+
+	runID, err := r.db.Queries.InsertReconcileRun(ctx, trigger)
+	if err != nil {
+		return Report{}, fmt.Errorf("start run: %w", err)
+	}
+	report := Report{RunID: runID}
+
+	runErr := r.check(ctx, cfg, autoFix, &report)
+
+	finish := sqlc.FinishReconcileRunParams{
+		ID:               runID,
+		Status:           "completed",
+		CheckedWallets:   int32(report.CheckedWallets),
+		DiscrepancyCount: int32(report.Discrepancies),
+		FixedCount:       int32(report.Fixed),
+	}
+	if runErr != nil {
+		finish.Status = "failed"
+		finish.Error = pgtype.Text{String: runErr.Error(), Valid: true}
+	}
+	// Record the outcome even when ctx was canceled mid-run
+	if err := r.db.Queries.FinishReconcileRun(context.Background(), finish); err != nil {
+		log.Printf("[Reconcile] Failed to finish run #%d: %v", runID, err)
+	}
+	return report, runErr
+}
+
+func (r *Reconciler) check(ctx context.Context, cfg Config, autoFix bool, report *Report) error {
+	q := r.db.Queries
+	if _, err := q.SupersedeOpenDiscrepancies(ctx); err != nil {
+		return fmt.Errorf("supersede: %w", err)
+	}
+
+	wallets, err := q.CountWallets(ctx)
+	if err != nil {
+		return fmt.Errorf("count wallets: %w", err)
+	}
+	report.CheckedWallets = int(wallets)
+
+	balances, err := q.GetWalletLedgerMismatches(ctx)
+	if err != nil {
+		return fmt.Errorf("balances: %w", err)
+	}
+	for _, b := range balances {
+		id, err := r.record(ctx, report, sqlc.InsertReconcileDiscrepancyParams{
+			Kind:          KindBalance,
+			UserID:        pgtype.Int4{Int32: b.UserID, Valid: true},
+			ExpectedCents: b.LedgerBalance,
+			ActualCents:   b.CachedBalance,
+			Message: fmt.Sprintf("Wallet balance is %s but ledger entries add up to %s",
+				money.Paise(b.CachedBalance), money.Paise(b.LedgerBalance)),
+		})
+		if err != nil {
+			return err
+		}
+		diff := b.CachedBalance - b.LedgerBalance
+		if !autoFix || diff > cfg.AutoFixMaxCents || -diff > cfg.AutoFixMaxCents {
+			continue
+		}
+		note := fmt.Sprintf("Auto-fixed by reconciliation run #%d", report.RunID)
+		if err := r.fixBalance(ctx, id, b.UserID, b.LedgerBalance, b.CachedBalance, pgtype.Int4{}, note); err != nil {
+			log.Printf("[Reconcile] Auto-fix of user %d failed: %v", b.UserID, err)
+			continue
+		}
+		report.Fixed++
+	}
+
+	openings, err := q.GetOpeningBalanceMismatches(ctx)
+	if err != nil {
+		return fmt.Errorf("opening balances: %w", err)
+	}
+	for _, o := range openings {
+		_, err := r.record(ctx, report, sqlc.InsertReconcileDiscrepancyParams{
+			Kind:          KindOpening,
+			UserID:        pgtype.Int4{Int32: o.UserID, Valid: true},
+			ExpectedCents: o.ExpectedCents,
+			ActualCents:   o.OpeningCents,
+			Message: fmt.Sprintf("Wallet opened in the ledger with %s but its earlier transactions and orders add up to %s",
+				money.Paise(o.OpeningCents), money.Paise(o.ExpectedCents)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	refunds, err := q.GetRefundCreditMismatches(ctx)
+	if err != nil {
+		return fmt.Errorf("refunds: %w", err)
+	}
+	for _, o := range refunds {
+		_, err := r.record(ctx, report, sqlc.InsertReconcileDiscrepancyParams{
+			Kind:          KindRefund,
+			UserID:        pgtype.Int4{Int32: o.UserID, Valid: true},
+			ReferenceType: ledger.RefOrder,
+			ReferenceID:   strconv.Itoa(int(o.ID)),
+			ExpectedCents: o.RefundedCents,
+			ActualCents:   o.CreditedCents,
+			Message: fmt.Sprintf("Order #%d is marked refunded %s but its refund credits add up to %s",
+				o.ID, money.Paise(o.RefundedCents), money.Paise(o.CreditedCents)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	deposits, err := q.GetDepositCreditMismatches(ctx, pgtype.Timestamptz{Time: cfg.DepositsSince, Valid: true})
+	if err != nil {
+		return fmt.Errorf("deposits: %w", err)
+	}
+	for _, d := range deposits {
+		expected := int64(0)
+		msg := fmt.Sprintf("Request #%d is %s but has %d deposit credits", d.ID, d.Status, d.CreditCount)
+		switch d.Status {
+		case "approved":
+			expected = d.AmountCents
+			msg = fmt.Sprintf("Approved request #%d has %d deposit credits instead of one", d.ID, d.CreditCount)
+		case "reversed":
+			msg = fmt.Sprintf("Reversed request #%d has %d deposit credits and was not debited back in full", d.ID, d.CreditCount)
+		}
+		_, err := r.record(ctx, report, sqlc.InsertReconcileDiscrepancyParams{
+			Kind:          KindDeposit,
+			UserID:        pgtype.Int4{Int32: d.UserID, Valid: d.UserID != 0},
+			ReferenceType: ledger.RefWalletRequest,
+			ReferenceID:   strconv.Itoa(int(d.ID)),
+			ExpectedCents: expected,
+			ActualCents:   d.CreditedCents,
+			Message:       msg,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) record(ctx context.Context, report *Report, arg sqlc.InsertReconcileDiscrepancyParams) (int32, error) {
+	arg.RunID = report.RunID
+	id, err := r.db.Queries.InsertReconcileDiscrepancy(ctx, arg)
+	if err != nil {
+		return 0, fmt.Errorf("record discrepancy: %w", err)
+	}
+	report.Discrepancies++
+	return id, nil
+}
+
+// Fix corrects the cached balance behind an open balance mismatch on an
+// admin's request. Unlike the automatic fix it is not capped, but it still
+// refuses when the wallet or its ledger moved since the run.
+func (r *Reconciler) Fix(ctx context.Context, id int32, adminID pgtype.Int4, note string) error {
+	d, err := r.db.Queries.GetReconcileDiscrepancy(ctx, id)
+	if err != nil {
+		return err
+	}
+	if d.Status != "open" {
+		return ErrNotOpen
+	}
+	if d.Kind != KindBalance || !d.UserID.Valid {
+		return ErrNotFixable
+	}
+	return r.fixBalance(ctx, d.ID, d.UserID.Int32, d.ExpectedCents, d.ActualCents, adminID, note)
+}
+
+// fixBalance sets wallets.balance to the ledger balance, provided both are
+// still what the run saw
+func (r *Reconciler) fixBalance(ctx context.Context, id, userID int32, ledgerBalance, observed int64, resolvedBy pgtype.Int4, note string) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := r.db.Queries.WithTx(tx)
+
+	current, err := qtx.GetWalletLedgerBalance(ctx, pgtype.Int4{Int32: userID, Valid: true})
+	if err != nil {
+		return fmt.Errorf("ledger balance: %w", err)
+	}
+	if current != ledgerBalance {
+		return ErrStale
+	}
+	n, err := qtx.FixWalletBalance(ctx, sqlc.FixWalletBalanceParams{
+		Balance:  ledgerBalance,
+		UserID:   userID,
+		Observed: observed,
+	})
+	if err != nil {
+		return fmt.Errorf("fix wallet: %w", err)
+	}
+	if n == 0 {
+		return ErrStale
+	}
+	n, err = qtx.ResolveReconcileDiscrepancy(ctx, sqlc.ResolveReconcileDiscrepancyParams{
+		ID:             id,
+		Status:         "fixed",
+		ResolutionNote: pgtype.Text{String: note, Valid: note != ""},
+		ResolvedBy:     resolvedBy,
+	})
+	if err != nil {
+		return fmt.Errorf("resolve: %w", err)
+	}
+	if n == 0 {
+		return ErrNotOpen
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,402 @@
+// Package referral attributes signups to the users who referred them, earns
+// referrers a commission on what referred users deposit or spend, and pays
+// out the affiliate balance.
+package referral
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"pablosmm/backend/internal/db"
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/jobs"
+	"pablosmm/backend/internal/service/ledger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const JobName = "referral_commissions"
+
+// What commission is earned on
+const (
+	BasisDeposit = "deposit"
+	BasisOrder   = "order"
+)
+
+// Referral states. Held referrals are waiting for an admin to rule out a
+// self-referral; blocked ones earn nothing.
+const (
+	StatusActive  = "active"
+	StatusHeld    = "held"
+	StatusBlocked = "blocked"
+)
+
+// Commission states
+const (
+	CommissionAvailable = "available"
+	CommissionHeld      = "held"
+	CommissionRejected  = "rejected"
+	CommissionReversed  = "reversed"
+)
+
+// How the affiliate balance is withdrawn, and the states of a withdrawal
+const (
+	MethodWallet = "wallet"
+	MethodPayout = "payout"
+
+	PayoutPending  = "pending"
+	PayoutPaid     = "paid"
+	PayoutRejected = "rejected"
+)
+
+// Signs that a referred user may be the referrer
+const (
+	FlagSameIP       = "same_ip"
+	FlagSharedIP     = "shared_ip"
+	FlagSameMobile   = "same_mobile"
+	FlagSimilarEmail = "similar_email"
+)
+
+// ErrBelowMinimum is returned when a withdrawal is under the minimum payout
+var ErrBelowMinimum = errors.New("amount is below the minimum payout")
+
+// ErrInsufficientBalance is returned when a withdrawal is more than the
+// affiliate balance
+var ErrInsufficientBalance = errors.New("insufficient affiliate balance")
+
+type Config struct {
+	Basis          string
+	Pct            float64
+	MinPayoutCents int64
+}
+
+// LoadConfig reads the referral settings, falling back to the seeded
+// defaults
+func LoadConfig(ctx context.Context, q *sqlc.Queries) Config {
+	cfg := Config{Basis: BasisDeposit, Pct: 5, MinPayoutCents: 50000}
+	if v, err := q.GetSetting(ctx, "referral_commission_basis"); err == nil && v == BasisOrder {
+		cfg.Basis = BasisOrder
+	}
+	if v, err := q.GetSetting(ctx, "referral_commission_pct"); err == nil {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 && f <= 100 {
+			cfg.Pct = f
+		}
+	}
+	if v, err := q.GetSetting(ctx, "referral_min_payout_cents"); err == nil {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			cfg.MinPayoutCents = n
+		}
+	}
+	return cfg
+}
+
+// Signup is a new user as far as attribution is concerned
+type Signup struct {
+	UserID int32
+	IP     string
+	Email  string
+	Mobile string
+}
+
+// Attribute records that a new user signed up with a referral code. Unknown
+// codes and a user's own code are ignored, since a bad code should not stop
+// a signup. Signups that look like the referrer's own are held for review.
+func Attribute(ctx context.Context, q *sqlc.Queries, code string, s Signup) error {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return nil
+	}
+	referrer, err := q.GetReferrerByCode(ctx, pgtype.Text{String: code, Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("look up referral code: %w", err)
+	}
+	if referrer.ID == s.UserID {
+		return nil
+	}
+
+	var flags []string
+	if s.IP != "" {
+		if s.IP == referrer.SignupIp {
+			flags = append(flags, FlagSameIP)
+		}
+		n, err := q.CountReferralsFromIP(ctx, sqlc.CountReferralsFromIPParams{ReferrerID: referrer.ID, SignupIp: s.IP})
+		if err != nil {
+			return fmt.Errorf("count referrals from ip: %w", err)
+		}
+		if n > 0 {
+			flags = append(flags, FlagSharedIP)
+		}
+	}
+	if s.Mobile != "" && s.Mobile == referrer.Mobile {
+		flags = append(flags, FlagSameMobile)
+	}
+	if s.Email != "" && canonicalEmail(s.Email) == canonicalEmail(referrer.Email) {
+		flags = append(flags, FlagSimilarEmail)
+	}
+
+	status := StatusActive
+	if len(flags) > 0 {
+		status = StatusHeld
+		log.Printf("[Referral] Holding referral of user %d by user %d: %s", s.UserID, referrer.ID, strings.Join(flags, ", "))
+	}
+	err = q.InsertReferral(ctx, sqlc.InsertReferralParams{
+		ReferredID: s.UserID,
+		ReferrerID: referrer.ID,
+		SignupIp:   s.IP,
+		Flags:      append([]string{}, flags...),
+		Status:     status,
+	})
+	if err != nil {
+		return fmt.Errorf("record referral: %w", err)
+	}
+	return nil
+}
+
+// canonicalEmail folds the aliases mail providers commonly allow: case,
+// +tags, and dots in Gmail addresses
+func canonicalEmail(email string) string {
+	local, domain, ok := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+	if !ok {
+		return local
+	}
+	local, _, _ = strings.Cut(local, "+")
+	if domain == "googlemail.com" {
+		domain = "gmail.com"
+	}
+	if domain == "gmail.com" {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	return local + "@" + domain
+}
+
+// Balance is what a referrer can withdraw: available commissions less
+// withdrawals that are pending or paid
+func Balance(s sqlc.GetReferralSummaryRow) money.Money {
+	return money.Paise(s.EarnedCents - s.PendingCents - s.PaidCents)
+}
+
+// Withdraw takes an amount off the affiliate balance, either straight into
+// the wallet or as a payout request for an admin. q must be bound to a
+// transaction; the user row is locked so concurrent withdrawals cannot
+// overdraw the balance.
+func Withdraw(ctx context.Context, q *sqlc.Queries, userID int32, amount money.Money, method, details string) (sqlc.ReferralPayout, error) {
+	cfg := LoadConfig(ctx, q)
+	if amount.Minor < cfg.MinPayoutCents {
+		return sqlc.ReferralPayout{}, ErrBelowMinimum
+	}
+	if err := q.LockUserForUpdate(ctx, userID); err != nil {
+		return sqlc.ReferralPayout{}, fmt.Errorf("lock user: %w", err)
+	}
+	summary, err := q.GetReferralSummary(ctx, userID)
+	if err != nil {
+		return sqlc.ReferralPayout{}, fmt.Errorf("affiliate balance: %w", err)
+	}
+	if amount.Cmp(Balance(summary)) > 0 {
+		return sqlc.ReferralPayout{}, ErrInsufficientBalance
+	}
+
+	status := PayoutPending
+	if method == MethodWallet {
+		status = PayoutPaid
+	}
+	p, err := q.InsertReferralPayout(ctx, sqlc.InsertReferralPayoutParams{
+		UserID:      userID,
+		AmountCents: amount.Minor,
+		Method:      method,
+		Details:     details,
+		Status:      status,
+	})
+	if err != nil {
+		return p, fmt.Errorf("record withdrawal: %w", err)
+	}
+	if method == MethodWallet {
+		err = ledger.ConvertCommission(ctx, q, userID, p.ID, amount, fmt.Sprintf("Referral earnings #%d", p.ID))
+	}
+	return p, err
+}
+
+// Review activates or blocks a referral. Activating releases its held
+// commissions into the referrer's balance; blocking rejects them. q must be
+// bound to a transaction.
+func Review(ctx context.Context, q *sqlc.Queries, referredID int32, status string, adminID int32) error {
+	r, err := q.GetReferralForUpdate(ctx, referredID)
+	if err != nil {
+		return err
+	}
+	if r.Status == status {
+		return nil
+	}
+	err = q.UpdateReferralStatus(ctx, sqlc.UpdateReferralStatusParams{
+		ReferredID: referredID,
+		Status:     status,
+		ReviewedBy: pgtype.Int4{Int32: adminID, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("update referral: %w", err)
+	}
+
+	held, err := q.ListHeldReferralCommissions(ctx, referredID)
+	if err != nil {
+		return fmt.Errorf("held commissions: %w", err)
+	}
+	for _, c := range held {
+		next := CommissionAvailable
+		if status == StatusBlocked {
+			next = CommissionRejected
+		}
+		if err := q.SetReferralCommissionStatus(ctx, sqlc.SetReferralCommissionStatusParams{ID: c.ID, Status: next}); err != nil {
+			return fmt.Errorf("update commission: %w", err)
+		}
+		if next == CommissionAvailable {
+			if err := ledger.AccrueCommission(ctx, q, c.ID, money.Paise(c.AmountCents), commissionDescription(c.SourceType, c.SourceID)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Clawback reverses the commission earned on a deposit or order that was
+// refunded or reversed. Held commissions are simply dropped; available ones
+// come back off the affiliate balance. q must be bound to a transaction.
+func Clawback(ctx context.Context, q *sqlc.Queries, sourceType string, sourceID int32) error {
+	c, err := q.ReverseReferralCommission(ctx, sqlc.ReverseReferralCommissionParams{SourceType: sourceType, SourceID: sourceID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("reverse commission: %w", err)
+	}
+	if c.PreviousStatus != CommissionAvailable {
+		return nil
+	}
+	description := fmt.Sprintf("Referral commission on %s #%d reversed", sourceType, sourceID)
+	return ledger.ReverseCommission(ctx, q, c.ID, money.Paise(c.AmountCents), description)
+}
+
+func commissionDescription(sourceType string, sourceID int32) string {
+	return fmt.Sprintf("Referral commission on %s #%d", sourceType, sourceID)
+}
+
+// Accruer earns referrers their commission on new deposits or orders of
+// the users they referred
+type Accruer struct {
+	db *db.DB
+}
+
+func New(database *db.DB) *Accruer {
+	return &Accruer{db: database}
+}
+
+func (a *Accruer) Start(ctx context.Context, runner *jobs.Runner) {
+	runner.Every(ctx, JobName, 15*time.Minute, a.Accrue)
+}
+
+type source struct {
+	ReferrerID     int32
+	ReferredID     int32
+	ReferralStatus string
+	SourceID       int32
+	BaseCents      int64
+}
+
+// Accrue records a commission for every deposit or finished order, per the
+// configured basis, that does not have one yet
+func (a *Accruer) Accrue(ctx context.Context) {
+	cfg := LoadConfig(ctx, a.db.Queries)
+
+	var sources []source
+	if cfg.Basis == BasisOrder {
+		rows, err := a.db.Queries.ListReferralOrderSources(ctx, 500)
+		if err != nil {
+			log.Printf("[Referral] Failed to list referred orders: %v", err)
+			return
+		}
+		for _, r := range rows {
+			sources = append(sources, source(r))
+		}
+	} else {
+		rows, err := a.db.Queries.ListReferralDepositSources(ctx, 500)
+		if err != nil {
+			log.Printf("[Referral] Failed to list referred deposits: %v", err)
+			return
+		}
+		for _, r := range rows {
+			sources = append(sources, source(r))
+		}
+	}
+
+	for _, s := range sources {
+		if err := a.accrue(ctx, cfg, s); err != nil {
+			log.Printf("[Referral] Failed to accrue commission on %s #%d: %v", cfg.Basis, s.SourceID, err)
+		}
+	}
+}
+
+func (a *Accruer) accrue(ctx context.Context, cfg Config, s source) error {
+	tx, err := a.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := a.db.Queries.WithTx(tx)
+
+	// The status is read again under lock so a commission cannot be held
+	// after a review has released the referral's held ones
+	r, err := qtx.GetReferralForUpdate(ctx, s.ReferredID)
+	if err != nil {
+		return fmt.Errorf("lock referral: %w", err)
+	}
+	if cfg.Basis == BasisDeposit {
+		// A deposit reversed since it was listed earns nothing; the
+		// reversal locks the request too, so it cannot slip in after this
+		w, err := qtx.GetWalletRequestForUpdateAdmin(ctx, s.SourceID)
+		if err != nil {
+			return fmt.Errorf("lock deposit: %w", err)
+		}
+		if w.Status.String != "approved" {
+			return nil
+		}
+	}
+	amount := money.Paise(max(s.BaseCents, 0)).Percent(cfg.Pct, money.RoundDown)
+	status := CommissionAvailable
+	switch r.Status {
+	case StatusHeld:
+		status = CommissionHeld
+	case StatusBlocked:
+		status = CommissionRejected
+	}
+	id, err := qtx.InsertReferralCommission(ctx, sqlc.InsertReferralCommissionParams{
+		ReferrerID:  s.ReferrerID,
+		ReferredID:  s.ReferredID,
+		SourceType:  cfg.Basis,
+		SourceID:    s.SourceID,
+		BaseCents:   s.BaseCents,
+		RatePct:     cfg.Pct,
+		AmountCents: amount.Minor,
+		Status:      status,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Accrued by a concurrent run
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("record commission: %w", err)
+	}
+	if status == CommissionAvailable {
+		if err := ledger.AccrueCommission(ctx, qtx, id, amount, commissionDescription(cfg.Basis, s.SourceID)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,93 @@
+package refill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/service/smm"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// EligibilityError explains to the customer why an order cannot be refilled
+type EligibilityError struct {
+	Reason string
+}
+
+func (e *EligibilityError) Error() string {
+	return e.Reason
+}
+
+func ineligible(format string, args ...interface{}) error {
+	return &EligibilityError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// Policy returns the refill policy of a catalog service, or the default
+// policy when none is configured
+func (s *Service) Policy(ctx context.Context, q *sqlc.Queries, serviceID string) (smm.RefillPolicy, error) {
+	catalogID, err := strconv.Atoi(serviceID)
+	if err != nil {
+		return smm.DefaultRefillPolicy(), nil
+	}
+	row, err := q.GetCatalogRefillPolicy(ctx, int32(catalogID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return smm.DefaultRefillPolicy(), nil
+	} else if err != nil {
+		return smm.RefillPolicy{}, err
+	}
+	return smm.NewRefillPolicy(row), nil
+}
+
+// CheckEligibility enforces the service refill policy and the provider
+// cooldown for an order locked with GetOrderForAutoRefill
+func (s *Service) CheckEligibility(ctx context.Context, q *sqlc.Queries, order sqlc.GetOrderForAutoRefillRow, policy smm.RefillPolicy) error {
+	allowed := false
+	for _, status := range policy.AllowedStatuses {
+		if status == order.Status {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return ineligible("Orders with status %q cannot be refilled", order.Status)
+	}
+
+	// refills_remaining is set from the service's refill limit when the
+	// order is placed; NULL means unlimited
+	if order.RefillsRemaining.Valid && order.RefillsRemaining.Int32 <= 0 {
+		return ineligible("No refills remaining for this order")
+	}
+
+	if !order.CompletedAt.Valid {
+		return ineligible("Refills are available once the order has completed")
+	}
+	if policy.PeriodDays > 0 && time.Since(order.CompletedAt.Time) > time.Duration(policy.PeriodDays)*24*time.Hour {
+		return ineligible("The %d day refill period for this order has ended", policy.PeriodDays)
+	}
+
+	if order.LastRefillAt.Valid {
+		wait := time.Duration(policy.MinHoursBetween) * time.Hour
+		if cooldown := s.ProviderCooldown(ctx, order.ProviderKey); cooldown > wait {
+			wait = cooldown
+		}
+		if next := order.LastRefillAt.Time.Add(wait); time.Now().Before(next) {
+			return ineligible("Next refill available in %s", time.Until(next).Round(time.Minute))
+		}
+	}
+
+	pending, err := q.GetPendingOrderRequestsByOrder(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+	for _, req := range pending {
+		if req.RequestType == "refill" {
+			return ineligible("Refill request already pending")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package refill
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/service/jobs"
+	"pablosmm/backend/internal/service/smm"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	pollBatchSize  = 200
+	multiRefillMax = 100
+)
+
+// Start polls open provider refills every 10 minutes
+func (s *Service) Start(ctx context.Context, runner *jobs.Runner) {
+	runner.Every(ctx, "refill_poll", 10*time.Minute, s.PollRefills)
+}
+
+// PollRefills asks providers for the status of every pending or in-progress
+// refill and records the transitions
+func (s *Service) PollRefills(ctx context.Context) {
+	rows, err := s.db.Queries.GetRefillsForPolling(ctx, pollBatchSize)
+	if err != nil {
+		log.Printf("[Refill] Failed to fetch refills for polling: %v", err)
+		return
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	byProvider := make(map[string][]sqlc.GetRefillsForPollingRow)
+	for _, row := range rows {
+		byProvider[row.ProviderKey] = append(byProvider[row.ProviderKey], row)
+	}
+
+	updated := 0
+	for providerKey, refills := range byProvider {
+		statuses := s.fetchStatuses(ctx, providerKey, refills)
+		for _, row := range refills {
+			data, ok := statuses[row.ProviderRefillID].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if errMsg, ok := data["error"].(string); ok {
+				log.Printf("[Refill] Provider error for refill %s (order #%d): %s", row.ProviderRefillID, row.OrderID, errMsg)
+				continue
+			}
+			status, _ := data["status"].(string)
+			if status == "" {
+				continue
+			}
+			changed, err := s.applyStatus(ctx, row, smm.NormalizeRefillStatus(status))
+			if err != nil {
+				log.Printf("[Refill] Failed to update refill %s (order #%d): %v", row.ProviderRefillID, row.OrderID, err)
+				continue
+			}
+			if changed {
+				updated++
+			}
+		}
+	}
+
+	log.Printf("[Refill] Polled %d refills, %d changed", len(rows), updated)
+}
+
+// fetchStatuses returns provider responses keyed by refill ID, batching the
+// request when the provider supports multi refill status
+func (s *Service) fetchStatuses(ctx context.Context, providerKey string, refills []sqlc.GetRefillsForPollingRow) map[string]interface{} {
+	statuses := make(map[string]interface{})
+
+	provider, err := s.db.Queries.GetSmmProviderByKey(ctx, providerKey)
+	if err == nil && provider.SupportsMultiRefill {
+		for i := 0; i < len(refills); i += multiRefillMax {
+			end := i + multiRefillMax
+			if end > len(refills) {
+				end = len(refills)
+			}
+			ids := make([]string, 0, end-i)
+			for _, row := range refills[i:end] {
+				ids = append(ids, row.ProviderRefillID)
+			}
+			resp, err := s.smm.MultiRefillStatus(providerKey, ids)
+			if err != nil {
+				log.Printf("[Refill] Multi refill status failed for %s: %v", providerKey, err)
+				continue
+			}
+			for id, data := range resp {
+				statuses[id] = data
+			}
+		}
+		return statuses
+	}
+
+	for _, row := range refills {
+		resp, err := s.smm.RefillStatus(providerKey, row.ProviderRefillID)
+		if err != nil {
+			log.Printf("[Refill] Refill status failed for %s/%s: %v", providerKey, row.ProviderRefillID, err)
+			continue
+		}
+		statuses[row.ProviderRefillID] = resp
+	}
+	return statuses
+}
+
+// applyStatus stores the new refill status. A rejected refill gives the
+// order its refill credit back.
+func (s *Service) applyStatus(ctx context.Context, row sqlc.GetRefillsForPollingRow, status string) (bool, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.db.Queries.WithTx(tx)
+
+	affected, err := qtx.UpdateRefillStatus(ctx, sqlc.UpdateRefillStatusParams{
+		ID:           row.ID,
+		RefillStatus: pgtype.Text{String: status, Valid: true},
+	})
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	switch status {
+	case "completed":
+		err = qtx.InsertOrderEvent(ctx, sqlc.InsertOrderEventParams{
+			OrderID:   row.OrderID,
+			EventType: "refill_completed",
+			Message:   fmt.Sprintf("Refill %s completed by the provider", row.ProviderRefillID),
+		})
+	case "rejected":
+		if err = qtx.IncrementOrderRefills(ctx, row.OrderID); err != nil {
+			return false, err
+		}
+		err = qtx.InsertOrderEvent(ctx, sqlc.InsertOrderEventParams{
+			OrderID:   row.OrderID,
+			EventType: "refill_rejected",
+			Message:   fmt.Sprintf("Refill %s was rejected by the provider, the refill has been credited back", row.ProviderRefillID),
+		})
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
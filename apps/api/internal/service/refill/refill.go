@@ -0,0 +1,243 @@
+package refill
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"pablosmm/backend/internal/db"
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/service/smm"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DefaultCooldown applies when the provider has no cooldown configured,
+// most panels reject a second refill within 24 hours
+const DefaultCooldown = 24 * time.Hour
+
+// What the provider made of a refill request sent to it. A request that
+// was accepted must not be sent again.
+const (
+	OutcomeAccepted = "accepted"
+	OutcomeRefused  = "refused"
+	// OutcomeFailed means the call did not go through, so the provider
+	// may never have seen the request
+	OutcomeFailed = "failed"
+)
+
+// Service triggers provider refills for orders whose delivery dropped
+type Service struct {
+	db  *db.DB
+	smm *smm.ProviderService
+}
+
+func New(database *db.DB, smmSvc *smm.ProviderService) *Service {
+	return &Service{db: database, smm: smmSvc}
+}
+
+// ProviderCooldown returns the minimum time between two refills of the same
+// order on a provider
+func (s *Service) ProviderCooldown(ctx context.Context, providerKey string) time.Duration {
+	p, err := s.db.Queries.GetSmmProviderByKey(ctx, providerKey)
+	if err != nil || p.RefillCooldownHours <= 0 {
+		return DefaultCooldown
+	}
+	return time.Duration(p.RefillCooldownHours) * time.Hour
+}
+
+// errSkip stops a reservation without an error for the caller
+var errSkip = errors.New("refill not wanted")
+
+// AutoRefill requests a provider refill when the catalog service's policy
+// allows it for the observed drop. It reports whether a refill was sent.
+func (s *Service) AutoRefill(ctx context.Context, orderID int32, dropPct float64) (bool, error) {
+	order, requestID, err := s.Reserve(ctx, orderID, func(order sqlc.GetOrderForAutoRefillRow, policy smm.RefillPolicy) error {
+		if !policy.AutoRefill || dropPct < policy.AutoRefillDrop || order.ProviderOrderID == "" {
+			return errSkip
+		}
+		return nil
+	})
+	var notEligible *EligibilityError
+	if errors.Is(err, errSkip) || errors.As(err, &notEligible) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	resp, refillErr := s.smm.RefillOrder(order.ProviderKey, order.ProviderOrderID)
+	if refillErr == nil {
+		if errorMsg, ok := resp["error"].(string); ok {
+			refillErr = fmt.Errorf("%s", errorMsg)
+		}
+	}
+	if refillErr != nil {
+		err := s.Refused(ctx, orderID, requestID, resp, refillErr.Error(), sqlc.InsertOrderEventParams{
+			EventType: "auto_refill_failed",
+			Message:   fmt.Sprintf("Automatic refill after %.1f%% drop was refused by the provider: %v", dropPct, refillErr),
+		})
+		if err != nil {
+			return false, err
+		}
+		return false, refillErr
+	}
+
+	err = s.Accepted(ctx, orderID, requestID, resp, true, sqlc.InsertOrderEventParams{
+		EventType: "auto_refill",
+		Message:   fmt.Sprintf("Automatic refill requested after a %.1f%% drop was detected", dropPct),
+	})
+	if err != nil {
+		return false, err
+	}
+	log.Printf("[Refill] Auto refill sent for order #%d (drop %.1f%%)", orderID, dropPct)
+	return true, nil
+}
+
+// Reserve locks an order, checks it can be refilled and records a pending
+// refill request that takes one of its refills. check runs first with the
+// order and its policy and can stop the reservation. The pending request
+// keeps other refills of the order out, so the provider is asked after the
+// row lock is released; Accepted or Refused records its answer.
+func (s *Service) Reserve(ctx context.Context, orderID int32, check func(sqlc.GetOrderForAutoRefillRow, smm.RefillPolicy) error) (sqlc.GetOrderForAutoRefillRow, int32, error) {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return sqlc.GetOrderForAutoRefillRow{}, 0, err
+	}
+	defer tx.Rollback(ctx)
+	qtx := s.db.Queries.WithTx(tx)
+
+	order, err := qtx.GetOrderForAutoRefill(ctx, orderID)
+	if err != nil {
+		return order, 0, err
+	}
+	policy, err := s.Policy(ctx, qtx, order.ServiceID)
+	if err != nil {
+		return order, 0, err
+	}
+	if check != nil {
+		if err := check(order, policy); err != nil {
+			return order, 0, err
+		}
+	}
+	if err := s.CheckEligibility(ctx, qtx, order, policy); err != nil {
+		return order, 0, err
+	}
+
+	req, err := qtx.CreateOrderRequest(ctx, sqlc.CreateOrderRequestParams{
+		OrderID:     orderID,
+		UserID:      order.UserID,
+		RequestType: "refill",
+	})
+	if err != nil {
+		return order, 0, fmt.Errorf("create refill request: %w", err)
+	}
+	if err := qtx.DecrementOrderRefills(ctx, orderID); err != nil {
+		return order, 0, err
+	}
+	return order, req.ID, tx.Commit(ctx)
+}
+
+// Accepted records that the provider took on a reserved refill, with its
+// refill ID when the panel returned one. approve closes the request for
+// refills that need no review; otherwise it stays pending for an admin.
+func (s *Service) Accepted(ctx context.Context, orderID, requestID int32, resp map[string]interface{}, approve bool, event sqlc.InsertOrderEventParams) error {
+	return s.record(ctx, func(q *sqlc.Queries) error {
+		if err := setResponse(ctx, q, requestID, resp, OutcomeAccepted); err != nil {
+			return err
+		}
+		if refillID := smm.IDString(resp["refill"]); refillID != "" {
+			err := q.SetOrderRequestRefill(ctx, sqlc.SetOrderRequestRefillParams{
+				ID:               requestID,
+				ProviderRefillID: pgtype.Text{String: refillID, Valid: true},
+				RefillStatus:     pgtype.Text{String: "pending", Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if approve {
+			err := q.UpdateOrderRequestStatus(ctx, sqlc.UpdateOrderRequestStatusParams{
+				ID:     requestID,
+				Status: pgtype.Text{String: "approved", Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if err := q.MarkOrderRefilled(ctx, orderID); err != nil {
+			return err
+		}
+		event.OrderID = orderID
+		return q.InsertOrderEvent(ctx, event)
+	})
+}
+
+// Refused records that the provider turned a reserved refill down: the
+// request is rejected with the provider's reason and the order gets its
+// refill back
+func (s *Service) Refused(ctx context.Context, orderID, requestID int32, resp map[string]interface{}, reason string, event sqlc.InsertOrderEventParams) error {
+	return s.record(ctx, func(q *sqlc.Queries) error {
+		if err := setResponse(ctx, q, requestID, resp, OutcomeRefused); err != nil {
+			return err
+		}
+		n, err := q.DecideOrderRequest(ctx, sqlc.DecideOrderRequestParams{
+			ID:             requestID,
+			Status:         pgtype.Text{String: "rejected", Valid: true},
+			DecisionReason: pgtype.Text{String: reason, Valid: reason != ""},
+		})
+		if err != nil {
+			return err
+		}
+		if n == 1 {
+			if err := q.IncrementOrderRefills(ctx, orderID); err != nil {
+				return err
+			}
+		}
+		event.OrderID = orderID
+		return q.InsertOrderEvent(ctx, event)
+	})
+}
+
+// Unsent records a reserved refill that was not sent to the provider, with
+// the error when asking failed. The request stays pending for an admin.
+func (s *Service) Unsent(ctx context.Context, orderID, requestID int32, sendErr error, event sqlc.InsertOrderEventParams) error {
+	return s.record(ctx, func(q *sqlc.Queries) error {
+		if sendErr != nil {
+			resp := map[string]interface{}{"error": sendErr.Error()}
+			if err := setResponse(ctx, q, requestID, resp, OutcomeFailed); err != nil {
+				return err
+			}
+		}
+		event.OrderID = orderID
+		return q.InsertOrderEvent(ctx, event)
+	})
+}
+
+func (s *Service) record(ctx context.Context, fn func(q *sqlc.Queries) error) error {
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := fn(s.db.Queries.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// setResponse stores the provider's answer to a request and what it came to
+func setResponse(ctx context.Context, q *sqlc.Queries, requestID int32, resp map[string]interface{}, outcome string) error {
+	text := pgtype.Text{}
+	if resp != nil {
+		b, _ := json.Marshal(resp)
+		text = pgtype.Text{String: string(b), Valid: true}
+	}
+	return q.SetOrderRequestResponse(ctx, sqlc.SetOrderRequestResponseParams{
+		ID:               requestID,
+		ProviderResponse: text,
+		ProviderOutcome:  pgtype.Text{String: outcome, Valid: true},
+	})
+}
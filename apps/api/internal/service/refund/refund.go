@@ -0,0 +1,229 @@
+package refund
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/ledger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Kinds of refund, each with its own formula
+const (
+	// KindCancel refunds the undelivered share of an order canceled on request, less the cancellation fee
+	KindCancel = "cancel"
+	// KindProviderCancel refunds everything not yet refunded when the provider cancels or fails the order
+	KindProviderCancel = "provider_cancel"
+	// KindPartial refunds the undelivered share of an order the provider finished as partial
+	KindPartial = "partial"
+	// KindAdmin refunds an amount chosen by an admin, or everything not yet refunded
+	KindAdmin = "admin"
+	// KindStuck refunds the undelivered share of an order the watchdog gave up on, without the cancellation fee
+	KindStuck = "stuck"
+)
+
+// Rounding modes for fractional cents, as stored in refund rules
+const (
+	RoundDown    = string(money.RoundDown)
+	RoundUp      = string(money.RoundUp)
+	RoundNearest = string(money.RoundNearest)
+)
+
+// Partial refund formulas
+const (
+	PartialProportional         = "proportional"
+	PartialProportionalAfterFee = "proportional_after_fee"
+	PartialNone                 = "none"
+)
+
+// Rule holds the refund settings of a catalog service, or the global defaults
+type Rule struct {
+	ID                    int32
+	CancelFeePct          float64
+	MinNonRefundableCents int64
+	Rounding              string
+	PartialFormula        string
+}
+
+// DefaultRule matches the historic behaviour: full proportional refunds, rounded down
+func DefaultRule() Rule {
+	return Rule{Rounding: RoundDown, PartialFormula: PartialProportional}
+}
+
+// Input describes the order being refunded
+type Input struct {
+	Kind           string
+	AmountCents    int64
+	Quantity       int
+	Remains        int
+	RefundedCents  int64
+	RequestedCents int64 // KindAdmin only, 0 refunds everything left
+}
+
+// Result is the amount to refund and a readable account of how it was reached
+type Result struct {
+	Cents   int64
+	Formula string
+}
+
+// RuleFor returns the refund rule of a catalog service, falling back to the
+// global rule and then to DefaultRule
+func RuleFor(ctx context.Context, q *sqlc.Queries, serviceID string) (Rule, error) {
+	catalogID := pgtype.Int4{}
+	if id, err := strconv.Atoi(serviceID); err == nil {
+		catalogID = pgtype.Int4{Int32: int32(id), Valid: true}
+	}
+
+	row, err := q.GetRefundRuleForService(ctx, catalogID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return DefaultRule(), nil
+	} else if err != nil {
+		return Rule{}, err
+	}
+	return NewRule(row), nil
+}
+
+// NewRule converts a stored refund rule
+func NewRule(row sqlc.RefundRule) Rule {
+	return Rule{
+		ID:                    row.ID,
+		CancelFeePct:          row.CancelFeePct,
+		MinNonRefundableCents: row.MinNonRefundableCents,
+		Rounding:              row.Rounding,
+		PartialFormula:        row.PartialFormula,
+	}
+}
+
+// Calculate applies the rule to an order. Shares and fees are worked out
+// exactly and rounded once, at the end. The result never exceeds what is
+// left to refund on the order.
+func Calculate(rule Rule, in Input) Result {
+	refundable := in.AmountCents - in.RefundedCents
+	if refundable <= 0 {
+		return Result{Formula: "nothing left to refund"}
+	}
+
+	var (
+		amount  *big.Rat
+		formula string
+	)
+	applyFee := false
+	applyMin := false
+
+	switch in.Kind {
+	case KindAdmin:
+		if in.RequestedCents > 0 && in.RequestedCents < refundable {
+			amount = new(big.Rat).SetInt64(in.RequestedCents)
+			formula = fmt.Sprintf("requested %s", cents(amount))
+		} else {
+			amount = new(big.Rat).SetInt64(refundable)
+			formula = fmt.Sprintf("remaining %s", cents(amount))
+		}
+
+	case KindProviderCancel:
+		amount = new(big.Rat).SetInt64(refundable)
+		formula = fmt.Sprintf("remaining %s", cents(amount))
+
+	case KindCancel, KindPartial, KindStuck:
+		if in.Kind == KindPartial && rule.PartialFormula == PartialNone {
+			return Result{Formula: "partial refunds disabled"}
+		}
+		if in.Quantity <= 0 {
+			return Result{Formula: "no quantity"}
+		}
+		remains := in.Remains
+		if remains > in.Quantity {
+			remains = in.Quantity
+		}
+		if remains < 0 {
+			remains = 0
+		}
+		share := new(big.Int).Mul(big.NewInt(in.AmountCents), big.NewInt(int64(remains)))
+		amount = new(big.Rat).SetFrac(share, big.NewInt(int64(in.Quantity)))
+		formula = fmt.Sprintf("%s x %d/%d = %s", money.Paise(in.AmountCents).Decimal(), remains, in.Quantity, cents(amount))
+		applyFee = in.Kind == KindCancel || rule.PartialFormula == PartialProportionalAfterFee
+		applyMin = true
+
+	default:
+		return Result{Formula: fmt.Sprintf("unknown refund kind %q", in.Kind)}
+	}
+
+	if applyFee && rule.CancelFeePct > 0 {
+		pct, _ := new(big.Rat).SetString(strconv.FormatFloat(rule.CancelFeePct, 'f', -1, 64))
+		fee := new(big.Rat).Mul(amount, pct)
+		fee.Quo(fee, big.NewRat(100, 1))
+		amount.Sub(amount, fee)
+		formula += fmt.Sprintf(", fee %.2f%% = -%s", rule.CancelFeePct, cents(fee))
+	}
+
+	if applyMin && rule.MinNonRefundableCents > 0 {
+		limit := max(in.AmountCents-rule.MinNonRefundableCents-in.RefundedCents, 0)
+		if amount.Cmp(new(big.Rat).SetInt64(limit)) > 0 {
+			amount.SetInt64(limit)
+			formula += fmt.Sprintf(", capped at %s to keep %s non-refundable", money.Paise(limit).Decimal(), money.Paise(rule.MinNonRefundableCents).Decimal())
+		}
+	}
+
+	mode := roundingName(rule.Rounding)
+	result, err := money.Round(amount, money.Rounding(mode))
+	if err != nil {
+		return Result{Formula: err.Error()}
+	}
+	result = min(max(result, 0), refundable)
+	formula += fmt.Sprintf(", rounded %s = %s", mode, money.Paise(result).Decimal())
+
+	return Result{Cents: result, Formula: formula}
+}
+
+// UndeliveredQuantity is the quantity to refund on a canceled order. Orders
+// the provider has not started report no remains, so the full quantity counts.
+func UndeliveredQuantity(status string, quantity, remains int) int {
+	if remains <= 0 {
+		switch status {
+		case "pending", "submitted", "processing", "failed":
+			return quantity
+		}
+	}
+	return remains
+}
+
+// Apply credits the wallet through the ledger and records the refund
+// with its formula. Callers update the order's refunded_amount.
+func Apply(ctx context.Context, q *sqlc.Queries, orderID, userID int32, kind string, rule Rule, result Result, description string) error {
+	if result.Cents <= 0 {
+		return nil
+	}
+
+	row, err := q.InsertOrderRefund(ctx, sqlc.InsertOrderRefundParams{
+		OrderID:     orderID,
+		UserID:      userID,
+		Kind:        kind,
+		AmountCents: result.Cents,
+		Formula:     result.Formula,
+		RuleID:      pgtype.Int4{Int32: rule.ID, Valid: rule.ID != 0},
+	})
+	if err != nil {
+		return fmt.Errorf("record refund: %w", err)
+	}
+	return ledger.RefundOrder(ctx, q, userID, orderID, money.Paise(result.Cents), ledger.NewRef(ledger.RefRefund, row.ID), description)
+}
+
+func roundingName(mode string) string {
+	switch mode {
+	case RoundUp, RoundNearest:
+		return mode
+	}
+	return RoundDown
+}
+
+// cents formats an exact amount of paise in rupees
+func cents(v *big.Rat) string {
+	return new(big.Rat).Quo(v, big.NewRat(100, 1)).FloatString(2)
+}
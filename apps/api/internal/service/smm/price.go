@@ -0,0 +1,14 @@
+package smm
+
+import "pablosmm/backend/internal/money"
+
+// Price is what quantity units of the service cost in the base currency.
+// RatePer1000 is in rupees; a fraction of a paisa is rounded up, and every
+// order costs at least one paisa.
+func (s NormalizedSmmService) Price(quantity int) (money.Money, error) {
+	price, err := money.PerThousand(s.RatePer1000, int64(quantity), money.Base, money.RoundUp)
+	if err != nil {
+		return money.Money{}, err
+	}
+	return money.Max(price, money.Paise(1)), nil
+}
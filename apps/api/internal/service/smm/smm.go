@@ -4,11 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/jackc/pgx/v5/pgtype"
 	"log"
 	"net/http"
 	"net/url"
 	"pablosmm/backend/internal/config"
 	"pablosmm/backend/internal/db"
+	"pablosmm/backend/internal/db/sqlc"
 	"pablosmm/backend/internal/provider"
 	"regexp"
 	"strconv"
@@ -80,6 +82,60 @@ type NormalizedSmmService struct {
 	ProposedRefillTag            string      `json:"proposedRefillTag,omitempty"`
 	ProposedQuality              string      `json:"proposedQuality,omitempty"`
 	ProposedCancel               *bool       `json:"proposedCancel,omitempty"`
+	RefillPolicy                 *RefillPolicy `json:"refillPolicy,omitempty"`
+	DeliveryStats                *DeliveryStats `json:"deliveryStats,omitempty"`
+}
+
+// OrderRefills is the refills_remaining a new order on the service starts
+// with. Services whose refill policy has no limit give NULL, unlimited.
+func (n NormalizedSmmService) OrderRefills() pgtype.Int4 {
+	if !n.Refill {
+		return pgtype.Int4{Int32: 0, Valid: true}
+	}
+	if n.RefillPolicy != nil && n.RefillPolicy.MaxRefills == nil {
+		return pgtype.Int4{}
+	}
+	return pgtype.Int4{Int32: int32(n.RefillLimit), Valid: true}
+}
+
+// RefillPolicy describes when orders on a catalog service may be refilled
+type RefillPolicy struct {
+	PeriodDays      int      `json:"periodDays"`
+	MinHoursBetween int      `json:"minHoursBetween"`
+	AllowedStatuses []string `json:"allowedStatuses"`
+	// MaxRefills is nil when orders may be refilled any number of times
+	MaxRefills      *int     `json:"maxRefills"`
+	AutoRefill      bool     `json:"autoRefill"`
+	AutoRefillDrop  float64  `json:"autoRefillDropPct,omitempty"`
+}
+
+var defaultMaxRefills = 3
+
+// DefaultRefillPolicy applies to refillable services without a configured policy
+func DefaultRefillPolicy() RefillPolicy {
+	return RefillPolicy{
+		PeriodDays:      30,
+		MinHoursBetween: 24,
+		AllowedStatuses: []string{"completed", "partial"},
+		MaxRefills:      &defaultMaxRefills,
+	}
+}
+
+// NewRefillPolicy converts a stored catalog refill policy
+func NewRefillPolicy(row sqlc.CatalogRefillPolicy) RefillPolicy {
+	var maxRefills *int
+	if row.MaxRefills.Valid {
+		n := int(row.MaxRefills.Int32)
+		maxRefills = &n
+	}
+	return RefillPolicy{
+		PeriodDays:      int(row.RefillPeriodDays),
+		MinHoursBetween: int(row.MinHoursBetween),
+		AllowedStatuses: row.AllowedStatuses,
+		MaxRefills:      maxRefills,
+		AutoRefill:      row.AutoRefillEnabled,
+		AutoRefillDrop:  row.AutoRefillDropPct,
+	}
 }
 
 type ProviderService struct {
@@ -272,6 +328,31 @@ func (s *ProviderService) FetchServices() ([]NormalizedSmmService, error) {
 		log.Printf("ERROR: Query pablo_catalog failed: %v", err)
 	}
 
+	refillPolicies := make(map[int32]sqlc.CatalogRefillPolicy)
+	if policies, err := s.db.Queries.ListCatalogRefillPolicies(context.Background()); err == nil {
+		for _, p := range policies {
+			refillPolicies[p.CatalogID] = p
+		}
+	} else {
+		log.Printf("ERROR: Query catalog_refill_policies failed: %v", err)
+	}
+
+	// Services without a refill policy keep the refill limit set in their
+	// admin override
+	overrides := make(map[string]sqlc.GetAllServiceOverridesRow)
+	if rows, err := s.db.Queries.GetAllServiceOverrides(context.Background()); err == nil {
+		for _, o := range rows {
+			overrides[o.SourceServiceID] = o
+		}
+	} else {
+		log.Printf("ERROR: Query service_overrides failed: %v", err)
+	}
+
+	deliveryStats, err := s.ServiceDeliveryStats(context.Background(), DeliveryStatsWindowDays)
+	if err != nil {
+		log.Printf("ERROR: Query delivery stats failed: %v", err)
+	}
+
 	normalized := make([]NormalizedSmmService, 0)
 	for _, catSvc := range catalog {
 		providerKey := ""
@@ -366,6 +447,26 @@ func (s *ProviderService) FetchServices() ([]NormalizedSmmService, error) {
 			ProposedCancel:               nil,
 		}
 
+		if refill {
+			policy := DefaultRefillPolicy()
+			if row, ok := refillPolicies[catSvc.ID]; ok {
+				policy = NewRefillPolicy(row)
+			} else {
+				for _, key := range []string{n.ID, providerKey + ":" + providerServiceID, providerServiceID} {
+					if o, ok := overrides[key]; ok && o.RefillLimit.Valid {
+						limit := int(o.RefillLimit.Int32)
+						policy.MaxRefills = &limit
+						break
+					}
+				}
+			}
+			n.RefillPolicy = &policy
+			n.RefillLimit = 0
+			if policy.MaxRefills != nil {
+				n.RefillLimit = *policy.MaxRefills
+			}
+		}
+
 		if hasLive {
 			avgTime := int(toNumber(raw.AverageTime))
 			if avgTime > 0 {
@@ -373,6 +474,10 @@ func (s *ProviderService) FetchServices() ([]NormalizedSmmService, error) {
 			}
 		}
 
+		if stats, ok := deliveryStats[n.ID]; ok && stats.SampleSize >= MinDeliverySample {
+			n.DeliveryStats = &stats
+		}
+
 		normalized = append(normalized, n)
 	}
 
@@ -567,6 +672,98 @@ func (s *ProviderService) RefillOrder(providerKey, orderID string) (map[string]i
 	return result, nil
 }
 
+// RefillStatus fetches the status of a single provider refill
+func (s *ProviderService) RefillStatus(providerKey, refillID string) (map[string]interface{}, error) {
+	apiURL, apiKey, err := s.getProviderCreds(providerKey)
+	if err != nil {
+		return nil, err
+	}
+
+	formData := url.Values{}
+	formData.Set("key", apiKey)
+	formData.Set("action", "refill_status")
+	formData.Set("refill", refillID)
+
+	resp, err := http.PostForm(apiURL, formData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch refill status: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode refill status response: %v", err)
+	}
+
+	return result, nil
+}
+
+// MultiRefillStatus fetches several refill statuses in one call, keyed by
+// refill ID. Only panels that support the "refills" parameter accept it.
+func (s *ProviderService) MultiRefillStatus(providerKey string, refillIDs []string) (map[string]interface{}, error) {
+	apiURL, apiKey, err := s.getProviderCreds(providerKey)
+	if err != nil {
+		return nil, err
+	}
+
+	formData := url.Values{}
+	formData.Set("key", apiKey)
+	formData.Set("action", "refill_status")
+	formData.Set("refills", strings.Join(refillIDs, ","))
+
+	resp, err := http.PostForm(apiURL, formData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch refill statuses: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var items []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		return nil, fmt.Errorf("failed to decode refill statuses response: %v", err)
+	}
+
+	result := make(map[string]interface{})
+	for _, item := range items {
+		id := IDString(item["refill"])
+		if id == "" {
+			continue
+		}
+		if status, ok := item["status"].(string); ok {
+			result[id] = map[string]interface{}{"status": status}
+		} else if status, ok := item["status"].(map[string]interface{}); ok {
+			result[id] = status
+		}
+	}
+
+	return result, nil
+}
+
+// IDString converts an ID from a provider response, which panels return
+// either as a string or a number, into a string
+func IDString(v interface{}) string {
+	switch id := v.(type) {
+	case string:
+		return id
+	case float64:
+		return fmt.Sprintf("%.0f", id)
+	}
+	return ""
+}
+
+// NormalizeRefillStatus maps a panel refill status onto
+// pending, in_progress, completed or rejected
+func NormalizeRefillStatus(status string) string {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "completed", "complete", "success":
+		return "completed"
+	case "in progress", "in_progress", "processing", "active":
+		return "in_progress"
+	case "rejected", "canceled", "cancelled", "error", "fail", "failed":
+		return "rejected"
+	}
+	return "pending"
+}
+
 func (s *ProviderService) GetOrderStatus(providerKey string, orderIDs []string) (map[string]interface{}, error) {
 	apiURL, apiKey, err := s.getProviderCreds(providerKey)
 	if err != nil {
@@ -0,0 +1,72 @@
+package smm
+
+import (
+	"context"
+	"math"
+)
+
+// DeliveryStatsWindowDays is how far back order history is considered
+const DeliveryStatsWindowDays = 90
+
+// MinDeliverySample is the number of finished orders a service needs before
+// its statistics are shown to customers
+const MinDeliverySample = 5
+
+// DeliveryStats summarises how orders actually went, measured from our own
+// order history rather than the provider's advertised average_time
+type DeliveryStats struct {
+	SampleSize    int     `json:"sampleSize"`
+	MedianMinutes *int    `json:"medianMinutes"`
+	P90Minutes    *int    `json:"p90Minutes"`
+	PartialRate   float64 `json:"partialRate"`
+	CancelRate    float64 `json:"cancelRate"`
+}
+
+// NewDeliveryStats builds the stats from the aggregate counts. Completion
+// times are only set when at least one order finished with a timestamp.
+func NewDeliveryStats(finished, timed, partial, canceled int64, median, p90 float64) DeliveryStats {
+	stats := DeliveryStats{SampleSize: int(finished)}
+	if finished > 0 {
+		stats.PartialRate = roundRate(float64(partial) / float64(finished))
+		stats.CancelRate = roundRate(float64(canceled) / float64(finished))
+	}
+	if timed > 0 {
+		m := int(math.Round(median))
+		p := int(math.Round(p90))
+		stats.MedianMinutes = &m
+		stats.P90Minutes = &p
+	}
+	return stats
+}
+
+// ServiceDeliveryStats returns the stats of every catalog service with
+// finished orders in the window, keyed by catalog ID
+func (s *ProviderService) ServiceDeliveryStats(ctx context.Context, days int) (map[string]DeliveryStats, error) {
+	rows, err := s.db.Queries.GetServiceDeliveryStats(ctx, int32(days))
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]DeliveryStats, len(rows))
+	for _, row := range rows {
+		res[row.ServiceID] = NewDeliveryStats(row.Finished, row.Timed, row.PartialCount, row.CanceledCount, row.MedianMinutes, row.P90Minutes)
+	}
+	return res, nil
+}
+
+// ProviderDeliveryStats returns the stats of every provider with finished
+// orders in the window, keyed by provider key
+func (s *ProviderService) ProviderDeliveryStats(ctx context.Context, days int) (map[string]DeliveryStats, error) {
+	rows, err := s.db.Queries.GetProviderDeliveryStats(ctx, int32(days))
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]DeliveryStats, len(rows))
+	for _, row := range rows {
+		res[row.ProviderKey] = NewDeliveryStats(row.Finished, row.Timed, row.PartialCount, row.CanceledCount, row.MedianMinutes, row.P90Minutes)
+	}
+	return res, nil
+}
+
+func roundRate(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
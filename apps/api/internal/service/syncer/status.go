@@ -0,0 +1,80 @@
+package syncer
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// LocalStatuses are the order statuses a provider status may map to
+var LocalStatuses = []string{"pending", "processing", "active", "completed", "partial", "canceled", "failed"}
+
+// defaultStatuses mirrors the mappings seeded for every provider. It is
+// only used until the table has been loaded.
+var defaultStatuses = map[string]string{
+	"completed":           "completed",
+	"complete":            "completed",
+	"pending":             "pending",
+	"processing":          "processing",
+	"inprogress":          "active",
+	"in progress":         "active",
+	"active":              "active",
+	"canceled":            "canceled",
+	"cancelled":           "canceled",
+	"refunded":            "canceled",
+	"partial":             "partial",
+	"partially completed": "partial",
+	"failed":              "failed",
+	"fail":                "failed",
+	"error":               "failed",
+}
+
+// statusMap holds raw -> local status mappings per provider key. The empty
+// key holds the mappings shared by every provider.
+type statusMap map[string]map[string]string
+
+// lookup maps a raw provider status, preferring the provider's own mapping
+func (m statusMap) lookup(providerKey, raw string) (string, bool) {
+	raw = NormalizeRawStatus(raw)
+	if local, ok := m[providerKey][raw]; ok {
+		return local, true
+	}
+	if local, ok := m[""][raw]; ok {
+		return local, true
+	}
+	return "", false
+}
+
+func (s *OrderSyncer) statusMap() statusMap {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.statuses == nil {
+		return statusMap{"": defaultStatuses}
+	}
+	return s.statuses
+}
+
+// refreshStatusMap reloads provider_status_mappings. On failure the last
+// loaded mappings stay in use.
+func (s *OrderSyncer) refreshStatusMap(ctx context.Context) {
+	rows, err := s.db.Queries.ListProviderStatusMappings(ctx)
+	if err != nil {
+		log.Printf("Failed to load status mappings: %v", err)
+		return
+	}
+	m := statusMap{}
+	for _, row := range rows {
+		if m[row.ProviderKey] == nil {
+			m[row.ProviderKey] = make(map[string]string)
+		}
+		m[row.ProviderKey][NormalizeRawStatus(row.RawStatus)] = row.LocalStatus
+	}
+	s.mu.Lock()
+	s.statuses = m
+	s.mu.Unlock()
+}
+
+// NormalizeRawStatus is the form raw statuses are stored and matched in
+func NormalizeRawStatus(raw string) string {
+	return strings.ToLower(strings.TrimSpace(raw))
+}
@@ -6,173 +6,403 @@ import (
 	"log"
 	"pablosmm/backend/internal/db"
 	"pablosmm/backend/internal/provider"
+	"pablosmm/backend/internal/service/jobs"
+	"pablosmm/backend/internal/service/ledger"
+	"pablosmm/backend/internal/service/refund"
 	"pablosmm/backend/internal/service/smm"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
 	"pablosmm/backend/internal/db/sqlc"
 )
 
+const (
+	// syncBatchLimit caps how many due orders are synced per run
+	syncBatchLimit = 1000
+	// statusChunkSize is how many order IDs are sent in one status call
+	statusChunkSize = 100
+)
+
 type OrderSyncer struct {
 	db  *db.DB
 	smm *smm.ProviderService
+
+	mu       sync.RWMutex
+	statuses statusMap
+}
+
+// syncOrder is the local side of an order being synced
+type syncOrder struct {
+	ID        int32
+	CreatedAt time.Time
 }
 
 func New(database *db.DB, smmSvc *smm.ProviderService) *OrderSyncer {
 	return &OrderSyncer{db: database, smm: smmSvc}
 }
 
-func (s *OrderSyncer) Start(ctx context.Context) {
-	ticker := time.NewTicker(2 * time.Minute)
-	// Run once immediately
-	go s.SyncOrders(ctx)
-
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				s.SyncOrders(ctx)
-			}
-		}
-	}()
+func (s *OrderSyncer) Start(ctx context.Context, runner *jobs.Runner) {
+	runner.Every(ctx, "order_sync", 2*time.Minute, s.SyncOrders)
 }
 
 func (s *OrderSyncer) SyncOrders(ctx context.Context) {
 	log.Println("Starting Order Sync...")
+	s.refreshStatusMap(ctx)
 
-	// 1. Fetch pending/processing orders + recently canceled/failed to catch provider corrections
-	rows, err := s.db.Queries.GetOrdersForSync(ctx)
+	// 1. Fetch orders that are due for a status check
+	rows, err := s.db.Queries.GetOrdersForSync(ctx, syncBatchLimit)
 	if err != nil {
 		log.Printf("Sync fetch error: %v", err)
 		return
 	}
 
-		// provider_key -> []orderIDs
-	providerGroups := make(map[string][]string)
-	orderMap := make(map[string]int) // providerID -> localID
-
+	// provider_key -> provider order ID -> local order. Provider order IDs
+	// are only unique within one provider, so they are never mixed.
+	providerGroups := make(map[string]map[string]syncOrder)
 	for _, row := range rows {
 		providerKey := row.ProviderKey
 		if providerKey == "" {
 			providerKey = provider.DefaultKey
 		}
-		providerGroups[providerKey] = append(providerGroups[providerKey], row.ProviderOrderID.String)
-		orderMap[row.ProviderOrderID.String] = int(row.ID)
+		if providerGroups[providerKey] == nil {
+			providerGroups[providerKey] = make(map[string]syncOrder)
+		}
+		providerGroups[providerKey][row.ProviderOrderID] = syncOrder{ID: row.ID, CreatedAt: row.CreatedAt.Time}
 	}
 
-	if len(orderMap) == 0 {
+	if len(rows) == 0 {
 		log.Println("No orders found to sync.")
 		return
 	}
-	log.Printf("Syncing %d orders across %d providers", len(orderMap), len(providerGroups))
+	log.Printf("Syncing %d orders across %d providers", len(rows), len(providerGroups))
 
-	statusData := make(map[string]interface{})
+	// 2. Fetch from providers in chunks, never running more status calls
+	// against one provider than it allows
+	limits := s.providerConcurrency(ctx)
+	var wg sync.WaitGroup
+	for providerKey, orders := range providerGroups {
+		limit := limits[providerKey]
+		if limit < 1 {
+			limit = provider.DefaultMaxConcurrency
+		}
+		sem := make(chan struct{}, limit)
 
-	// 2. Fetch from Providers
-	for providerKey, orderIDs := range providerGroups {
-		providerStatus, err := s.smm.GetOrderStatus(providerKey, orderIDs)
-		if err != nil {
-			log.Printf("Sync provider error for %s: %v", providerKey, err)
-			continue
+		ids := make([]string, 0, len(orders))
+		for id := range orders {
+			ids = append(ids, id)
 		}
-		log.Printf("Provider %s returned status for %d orders", providerKey, len(providerStatus))
-		
-		for k, v := range providerStatus {
-			statusData[k] = v
+		for start := 0; start < len(ids); start += statusChunkSize {
+			end := start + statusChunkSize
+			if end > len(ids) {
+				end = len(ids)
+			}
+			wg.Add(1)
+			go func(providerKey string, chunk []string, orders map[string]syncOrder) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				s.syncChunk(ctx, providerKey, chunk, orders)
+			}(providerKey, ids[start:end], orders)
 		}
 	}
+	wg.Wait()
+	log.Println("Order Sync Complete")
+}
+
+// syncChunk fetches one provider status call worth of orders and applies
+// the results
+func (s *OrderSyncer) syncChunk(ctx context.Context, providerKey string, ids []string, orders map[string]syncOrder) {
+	providerStatus, err := s.smm.GetOrderStatus(providerKey, ids)
+	if err != nil {
+		log.Printf("Sync provider error for %s: %v", providerKey, err)
+		for _, id := range ids {
+			s.scheduleNext(ctx, orders[id], "")
+		}
+		return
+	}
+	log.Printf("Provider %s returned status for %d of %d orders", providerKey, len(providerStatus), len(ids))
 
 	// 3. Update DB
-	for pID, localID := range orderMap {
-		if raw, ok := statusData[pID]; ok {
-			if data, ok := raw.(map[string]interface{}); ok {
-				// Get status string robustly
-				var pStatus string
-				if s, ok := data["status"].(string); ok {
-					pStatus = s
-				} else {
-					pStatus = fmt.Sprintf("%v", data["status"])
-				}
-
-				// Get remains and start_count robustly
-				remains := parseInterfaceInt(data["remains"])
-				startCount := parseInterfaceInt(data["start_count"])
-
-				if pStatus != "" {
-					localStatus := mapProviderStatus(pStatus)
-
-					// CRITICAL: Do NOT overwrite refunded or canceled orders
-					// These are terminal states set manually by admins
-					// Fetch the order data so we can calculate refunds if topsmm canceled it
-					orderRow, err := s.db.Queries.GetOrderForSyncUpdate(ctx, int32(localID))
-					if err != nil {
-						log.Printf("Failed to read order %d for refund sync: %v", localID, err)
-						continue
-					}
-
-					amountCents := int(orderRow.AmountCents)
-					uID := int(orderRow.UserID)
-					quantity := int(orderRow.Quantity)
-					currentStatus := orderRow.Status
-
-					// Proceed if the DB status is not already terminal
-					if currentStatus != "refunded" && currentStatus != "canceled" {
-						tx, txErr := s.db.Pool.Begin(ctx)
-						if txErr == nil {
-							qtx := s.db.Queries.WithTx(tx)
-							refundCents := 0
-							if localStatus == "canceled" {
-								refundCents = amountCents // 100% refund
-							} else if localStatus == "partial" && quantity > 0 && remains > 0 {
-								// Safe integer math: fraction of amount to refund
-								refundCents = (amountCents * remains) / quantity
-							}
-
-							if refundCents > 0 {
-								// Refund Wallet
-								qtx.CreditWallet(ctx, sqlc.CreditWalletParams{
-									Balance: int32(refundCents),
-									UserID:  int32(uID),
-								})
-
-								// Log Transaction
-								qtx.InsertTransaction(ctx, sqlc.InsertTransactionParams{
-									UserID:      pgtype.Int4{Int32: int32(uID), Valid: true},
-									Amount:      func() pgtype.Numeric { n := pgtype.Numeric{}; n.Scan(fmt.Sprintf("%f", float64(refundCents)/100.0)); return n }(),
-									Type:        "credit",
-									Description: pgtype.Text{String: fmt.Sprintf("Auto-Refund for provider status '%s' Order #%d", localStatus, localID), Valid: true},
-								})
-
-								// Update Order with Refund Amount
-								qtx.UpdateOrderSyncWithRefund(ctx, sqlc.UpdateOrderSyncWithRefundParams{
-									Status:         localStatus,
-									Remains:        pgtype.Int4{Int32: int32(remains), Valid: true},
-									StartCount:     pgtype.Int4{Int32: int32(startCount), Valid: true},
-									RefundedAmount: pgtype.Int4{Int32: int32(refundCents), Valid: true},
-									ID:             int32(localID),
-								})
-							} else {
-								// Just update the status normally
-								qtx.UpdateOrderSyncNoRefund(ctx, sqlc.UpdateOrderSyncNoRefundParams{
-									Status:     localStatus,
-									Remains:    pgtype.Int4{Int32: int32(remains), Valid: true},
-									StartCount: pgtype.Int4{Int32: int32(startCount), Valid: true},
-									ID:         int32(localID),
-								})
-							}
-
-							tx.Commit(ctx)
-						}
-					}
-				}
-			}
+	for _, id := range ids {
+		order := orders[id]
+		status := ""
+		data, ok := providerStatus[id].(map[string]interface{})
+		if !ok {
+			status = s.recordError(ctx, order.ID, "order missing from provider status response")
+		} else if errMsg, ok := data["error"].(string); ok {
+			log.Printf("Sync error for order %d (%s:%s): %s", order.ID, providerKey, id, errMsg)
+			status = s.recordError(ctx, order.ID, errMsg)
+		} else {
+			status = s.applyStatus(ctx, providerKey, int(order.ID), data)
 		}
+		s.scheduleNext(ctx, order, status)
 	}
-	log.Println("Order Sync Complete")
+}
+
+// scheduleNext sets when the order is checked again. Terminal orders are no
+// longer polled.
+func (s *OrderSyncer) scheduleNext(ctx context.Context, order syncOrder, status string) {
+	next := pgtype.Timestamptz{}
+	if !isTerminalStatus(status) {
+		next = pgtype.Timestamptz{Time: time.Now().Add(pollInterval(time.Since(order.CreatedAt))), Valid: true}
+	}
+	err := s.db.Queries.ScheduleOrderSync(ctx, sqlc.ScheduleOrderSyncParams{
+		ID:         order.ID,
+		NextSyncAt: next,
+	})
+	if err != nil {
+		log.Printf("Failed to schedule sync for order %d: %v", order.ID, err)
+	}
+}
+
+// recordError counts a per-order provider error. After sync_error_threshold
+// consecutive errors the order is moved to needs_attention for an admin, and
+// that status is returned.
+func (s *OrderSyncer) recordError(ctx context.Context, orderID int32, msg string) string {
+	count, err := s.db.Queries.RecordOrderSyncError(ctx, sqlc.RecordOrderSyncErrorParams{
+		ID:            orderID,
+		LastSyncError: pgtype.Text{String: msg, Valid: true},
+	})
+	if err != nil {
+		log.Printf("Failed to record sync error for order %d: %v", orderID, err)
+		return ""
+	}
+	if int(count) < s.errorThreshold(ctx) {
+		return ""
+	}
+
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		log.Printf("Failed to flag order %d: %v", orderID, err)
+		return ""
+	}
+	defer tx.Rollback(ctx)
+	qtx := s.db.Queries.WithTx(tx)
+
+	n, err := qtx.MarkOrderNeedsAttention(ctx, orderID)
+	if err != nil || n == 0 {
+		return ""
+	}
+	qtx.InsertOrderEvent(ctx, sqlc.InsertOrderEventParams{
+		OrderID:   orderID,
+		EventType: "needs_attention",
+		Message:   fmt.Sprintf("Status sync failed %d times in a row: %s", count, msg),
+	})
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Failed to flag order %d: %v", orderID, err)
+		return ""
+	}
+	log.Printf("Order %d needs attention after %d sync errors: %s", orderID, count, msg)
+	return "needs_attention"
+}
+
+// errorThreshold reads sync_error_threshold, defaulting to 5
+func (s *OrderSyncer) errorThreshold(ctx context.Context) int {
+	if v, err := s.db.Queries.GetSetting(ctx, "sync_error_threshold"); err == nil {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 5
+}
+
+// providerConcurrency returns the configured max_concurrency per provider
+func (s *OrderSyncer) providerConcurrency(ctx context.Context) map[string]int {
+	limits := make(map[string]int)
+	providers, err := s.db.Queries.GetActiveSmmProviders(ctx)
+	if err != nil {
+		log.Printf("Failed to load provider limits: %v", err)
+		return limits
+	}
+	for _, p := range providers {
+		limits[p.Key] = int(p.MaxConcurrency)
+	}
+	return limits
+}
+
+// pollInterval is how long to wait before checking an order of the given
+// age again. Young orders move quickly, old ones rarely change.
+func pollInterval(age time.Duration) time.Duration {
+	switch {
+	case age < time.Hour:
+		return 2 * time.Minute
+	case age < 6*time.Hour:
+		return 5 * time.Minute
+	case age < 24*time.Hour:
+		return 15 * time.Minute
+	case age < 72*time.Hour:
+		return time.Hour
+	default:
+		return 6 * time.Hour
+	}
+}
+
+func isTerminalStatus(status string) bool {
+	switch status {
+	case "completed", "partial", "canceled", "refunded", "failed", "needs_attention":
+		return true
+	}
+	return false
+}
+
+// RecheckOrder fetches the provider status of a single order and applies it
+// like a regular sync. It returns the resulting local status, or "" when the
+// provider had nothing for the order.
+func (s *OrderSyncer) RecheckOrder(ctx context.Context, orderID int32, providerKey, providerOrderID string) (string, error) {
+	if providerKey == "" {
+		providerKey = provider.DefaultKey
+	}
+	providerStatus, err := s.smm.GetOrderStatus(providerKey, []string{providerOrderID})
+	if err != nil {
+		return "", err
+	}
+	data, ok := providerStatus[providerOrderID].(map[string]interface{})
+	if !ok {
+		return "", nil
+	}
+	if errMsg, ok := data["error"].(string); ok {
+		s.recordError(ctx, orderID, errMsg)
+		return "", fmt.Errorf("%s", errMsg)
+	}
+	return s.applyStatus(ctx, providerKey, int(orderID), data), nil
+}
+
+// applyStatus stores a provider status on the order, refunding canceled and
+// partial orders, and returns the local status it mapped to. Statuses no
+// mapping covers are reported and the order keeps its current status.
+func (s *OrderSyncer) applyStatus(ctx context.Context, providerKey string, localID int, data map[string]interface{}) string {
+	// Get status string robustly
+	var pStatus string
+	if s, ok := data["status"].(string); ok {
+		pStatus = strings.TrimSpace(s)
+	} else if data["status"] != nil {
+		pStatus = fmt.Sprintf("%v", data["status"])
+	}
+	if pStatus == "" {
+		// A reply without a status is as broken as an explicit error
+		return s.recordError(ctx, int32(localID), "provider returned no status")
+	}
+
+	// Get remains and start_count robustly
+	remains := parseInterfaceInt(data["remains"])
+	startCount := parseInterfaceInt(data["start_count"])
+
+	localStatus, mapped := s.statusMap().lookup(providerKey, pStatus)
+
+	// The order row is locked for the whole update so that two instances,
+	// or the syncer and the watchdog, can never refund the same order twice
+	tx, err := s.db.Pool.Begin(ctx)
+	if err != nil {
+		log.Printf("Failed to start sync update for order %d: %v", localID, err)
+		return ""
+	}
+	defer tx.Rollback(ctx)
+	qtx := s.db.Queries.WithTx(tx)
+
+	// Fetch the order data so we can calculate refunds if the provider canceled it
+	orderRow, err := qtx.GetOrderForSyncUpdate(ctx, int32(localID))
+	if err != nil {
+		log.Printf("Failed to read order %d for refund sync: %v", localID, err)
+		return ""
+	}
+
+	amountCents := orderRow.AmountCents
+	uID := int(orderRow.UserID)
+	quantity := int(orderRow.Quantity)
+	currentStatus := orderRow.Status
+
+	// CRITICAL: Do NOT overwrite refunded or canceled orders
+	// These are terminal states set manually by admins
+	if currentStatus == "refunded" || currentStatus == "canceled" {
+		return ""
+	}
+
+	if !mapped {
+		log.Printf("ALERT: Unmapped status %q from provider %s for order %d, keeping %q", pStatus, providerKey, localID, currentStatus)
+		err := s.db.Queries.RecordUnmappedStatus(ctx, sqlc.RecordUnmappedStatusParams{
+			ProviderKey: providerKey,
+			RawStatus:   NormalizeRawStatus(pStatus),
+			LastOrderID: pgtype.Int4{Int32: int32(localID), Valid: true},
+		})
+		if err != nil {
+			log.Printf("Failed to record unmapped status: %v", err)
+		}
+		localStatus = currentStatus
+	}
+
+	refundCents := int64(0)
+	kind := ""
+	// A failed order is as final as a canceled one and was not delivered,
+	// so it is refunded the same way rather than settled as revenue
+	if localStatus == "canceled" || localStatus == "failed" {
+		kind = refund.KindProviderCancel
+	} else if localStatus == "partial" && quantity > 0 && remains > 0 {
+		kind = refund.KindPartial
+	}
+
+	if kind != "" {
+		rule, ruleErr := refund.RuleFor(ctx, s.db.Queries, orderRow.ServiceID)
+		if ruleErr != nil {
+			log.Printf("Failed to load refund rule for order %d: %v", localID, ruleErr)
+			rule = refund.DefaultRule()
+		}
+		result := refund.Calculate(rule, refund.Input{
+			Kind:          kind,
+			AmountCents:   amountCents,
+			Quantity:      quantity,
+			Remains:       remains,
+			RefundedCents: orderRow.RefundedAmount,
+		})
+		desc := fmt.Sprintf("Auto-Refund for provider status '%s' Order #%d", localStatus, localID)
+		if err := refund.Apply(ctx, qtx, int32(localID), int32(uID), kind, rule, result, desc); err != nil {
+			log.Printf("Refund failed for order %d: %v", localID, err)
+			return ""
+		}
+		refundCents = result.Cents
+	}
+
+	if refundCents > 0 {
+		// Update Order with Refund Amount
+		err = qtx.UpdateOrderSyncWithRefund(ctx, sqlc.UpdateOrderSyncWithRefundParams{
+			Status:         localStatus,
+			Remains:        pgtype.Int4{Int32: int32(remains), Valid: true},
+			StartCount:     pgtype.Int4{Int32: int32(startCount), Valid: true},
+			RefundedAmount: pgtype.Int8{Int64: refundCents, Valid: true},
+			ID:             int32(localID),
+			ProviderStatus: pgtype.Text{String: pStatus, Valid: true},
+		})
+	} else {
+		// Just update the status normally
+		err = qtx.UpdateOrderSyncNoRefund(ctx, sqlc.UpdateOrderSyncNoRefundParams{
+			Status:         localStatus,
+			Remains:        pgtype.Int4{Int32: int32(remains), Valid: true},
+			StartCount:     pgtype.Int4{Int32: int32(startCount), Valid: true},
+			ID:             int32(localID),
+			ProviderStatus: pgtype.Text{String: pStatus, Valid: true},
+		})
+	}
+	if err != nil {
+		log.Printf("Failed to update order %d: %v", localID, err)
+		return ""
+	}
+
+	// A finished order's remaining charge is earned
+	switch localStatus {
+	case "completed", "partial", "canceled", "failed":
+		if err := ledger.SettleOrder(ctx, qtx, int32(localID)); err != nil {
+			log.Printf("Failed to settle order %d: %v", localID, err)
+			return ""
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Failed to commit sync update for order %d: %v", localID, err)
+		return ""
+	}
+	return localStatus
 }
 
 func parseInterfaceInt(v interface{}) int {
@@ -199,28 +429,6 @@ func parseInterfaceInt(v interface{}) int {
 	return int(f)
 }
 
-func mapProviderStatus(s string) string {
-	s = strings.ToLower(s)
-	switch s {
-	case "completed", "complete":
-		return "completed"
-	case "pending":
-		return "pending"
-	case "processing":
-		return "processing"
-	case "inprogress", "in progress", "active":
-		return "active"
-	case "canceled", "cancelled":
-		return "canceled"
-	case "partial", "partially completed":
-		return "partial"
-	case "failed", "fail":
-		return "failed"
-	default:
-		return "active"
-	}
-}
-
 func jsonString(v interface{}) string {
 	if s, ok := v.(string); ok {
 		return s
@@ -0,0 +1,67 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"pablosmm/backend/internal/db/sqlc"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrLinkNotFound is returned when a user acts on a sub-account link that
+// is not theirs or not in a state they can act on
+var ErrLinkNotFound = errors.New("sub-account link not found")
+
+// Invite asks an account to become one of an agency's sub-accounts. The
+// link takes effect once that account accepts. q must be bound to a
+// transaction.
+func Invite(ctx context.Context, q *sqlc.Queries, ownerID, userID int32) (sqlc.SubAccount, error) {
+	var link sqlc.SubAccount
+	if ownerID == userID {
+		return link, invalid("You cannot add your own account")
+	}
+	if _, err := q.GetSubAccountByUser(ctx, ownerID); err == nil {
+		return link, invalid("A sub-account cannot have sub-accounts of its own")
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return link, fmt.Errorf("agency of owner: %w", err)
+	}
+	if _, err := q.GetSubAccountByUser(ctx, userID); err == nil {
+		return link, invalid("That account is already linked to an agency or has a pending invitation")
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return link, fmt.Errorf("agency of account: %w", err)
+	}
+	owned, err := q.CountOwnedSubAccounts(ctx, userID)
+	if err != nil {
+		return link, fmt.Errorf("sub-accounts of account: %w", err)
+	}
+	if owned > 0 {
+		return link, invalid("That account has sub-accounts of its own")
+	}
+	link, err = q.InsertSubAccount(ctx, sqlc.InsertSubAccountParams{OwnerID: ownerID, UserID: userID})
+	if err != nil {
+		return link, fmt.Errorf("record link: %w", err)
+	}
+	return link, nil
+}
+
+// Accept makes an invitation to an agency active. An account that has
+// sub-accounts of its own cannot join an agency.
+func Accept(ctx context.Context, q *sqlc.Queries, linkID, userID int32) error {
+	owned, err := q.CountOwnedSubAccounts(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("sub-accounts of account: %w", err)
+	}
+	if owned > 0 {
+		return invalid("Remove your own sub-accounts before joining an agency")
+	}
+	n, err := q.AcceptSubAccount(ctx, sqlc.AcceptSubAccountParams{ID: linkID, UserID: userID})
+	if err != nil {
+		return fmt.Errorf("accept link: %w", err)
+	}
+	if n == 0 {
+		return ErrLinkNotFound
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+// Package transfer moves money between the wallets of an agency and its
+// sub-accounts, within the daily limit and, above a threshold, only once an
+// admin approves.
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/money"
+	"pablosmm/backend/internal/service/bonus"
+	"pablosmm/backend/internal/service/ledger"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Transfer states
+const (
+	StatusPending   = "pending"
+	StatusCompleted = "completed"
+	StatusRejected  = "rejected"
+)
+
+// ErrNotPending is returned when deciding on a transfer that has already
+// been decided
+var ErrNotPending = errors.New("transfer is not pending")
+
+// InvalidError is returned when a transfer is not allowed. Its message is
+// meant for the user.
+type InvalidError struct {
+	Reason string
+}
+
+func (e *InvalidError) Error() string {
+	return e.Reason
+}
+
+func invalid(format string, args ...any) error {
+	return &InvalidError{Reason: fmt.Sprintf(format, args...)}
+}
+
+type Config struct {
+	MinCents        int64
+	DailyLimitCents int64
+	// ApprovalCents is the amount from which a transfer waits for an admin;
+	// 0 sends every transfer straight away
+	ApprovalCents int64
+}
+
+// LoadConfig reads the transfer settings, falling back to the seeded
+// defaults
+func LoadConfig(ctx context.Context, q *sqlc.Queries) Config {
+	cfg := Config{MinCents: 100, DailyLimitCents: 2500000, ApprovalCents: 1000000}
+	if v, err := q.GetSetting(ctx, "transfer_min_cents"); err == nil {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 {
+			cfg.MinCents = n
+		}
+	}
+	if v, err := q.GetSetting(ctx, "transfer_daily_limit_cents"); err == nil {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			cfg.DailyLimitCents = n
+		}
+	}
+	if v, err := q.GetSetting(ctx, "transfer_approval_cents"); err == nil {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			cfg.ApprovalCents = n
+		}
+	}
+	return cfg
+}
+
+// SentToday is what a user has sent, or is waiting to send, in the last 24
+// hours
+func SentToday(ctx context.Context, q *sqlc.Queries, userID int32) (money.Money, error) {
+	sent, err := q.GetTransferredSince(ctx, sqlc.GetTransferredSinceParams{
+		SenderID: userID,
+		Since:    pgtype.Timestamptz{Time: time.Now().Add(-24 * time.Hour), Valid: true},
+	})
+	if err != nil {
+		return money.Money{}, fmt.Errorf("transferred today: %w", err)
+	}
+	return money.Paise(sent), nil
+}
+
+// Send transfers an amount from one user to another in the same agency.
+// Transfers from the approval threshold up are recorded as pending with the amount held;
+// smaller ones complete at once. q must be bound to a transaction: the
+// sender's wallet is locked so concurrent transfers cannot get past the
+// daily limit.
+func Send(ctx context.Context, q *sqlc.Queries, senderID, recipientID int32, amount money.Money, note string) (sqlc.WalletTransfer, error) {
+	var t sqlc.WalletTransfer
+	if senderID == recipientID {
+		return t, invalid("You cannot transfer to your own account")
+	}
+	linked, err := q.InSameAgency(ctx, sqlc.InSameAgencyParams{AID: senderID, BID: recipientID})
+	if err != nil {
+		return t, fmt.Errorf("agency link: %w", err)
+	}
+	if !linked {
+		return t, invalid("You can only transfer between your agency's accounts")
+	}
+	cfg := LoadConfig(ctx, q)
+	if amount.Minor < cfg.MinCents {
+		return t, invalid("The minimum transfer is %s", money.Paise(cfg.MinCents))
+	}
+
+	if _, err := q.GetWalletFundsForUpdate(ctx, senderID); errors.Is(err, pgx.ErrNoRows) {
+		return t, invalid("Insufficient balance")
+	} else if err != nil {
+		return t, fmt.Errorf("lock wallet: %w", err)
+	}
+	sent, err := SentToday(ctx, q, senderID)
+	if err != nil {
+		return t, err
+	}
+	if left := money.Paise(cfg.DailyLimitCents).Sub(sent); amount.Cmp(left) > 0 {
+		return t, invalid("This exceeds your daily transfer limit; you can send up to %s more today", money.Max(left, money.Paise(0)))
+	}
+	// Bonus credit that cannot be withdrawn cannot be given away either
+	withdrawable, err := bonus.Withdrawable(ctx, q, senderID)
+	if err != nil {
+		return t, fmt.Errorf("withdrawable balance: %w", err)
+	}
+	if amount.Cmp(withdrawable) > 0 {
+		return t, invalid("Insufficient balance; %s can be transferred", withdrawable)
+	}
+
+	status := StatusCompleted
+	if cfg.ApprovalCents > 0 && amount.Minor >= cfg.ApprovalCents {
+		status = StatusPending
+	}
+	t, err = q.InsertWalletTransfer(ctx, sqlc.InsertWalletTransferParams{
+		SenderID:    senderID,
+		RecipientID: recipientID,
+		AmountCents: amount.Minor,
+		Note:        note,
+		Status:      status,
+	})
+	if err != nil {
+		return t, fmt.Errorf("record transfer: %w", err)
+	}
+	if status == StatusPending {
+		err = ledger.HoldTransfer(ctx, q, senderID, t.ID, amount)
+	} else {
+		err = ledger.Transfer(ctx, q, senderID, recipientID, t.ID, amount)
+	}
+	if errors.Is(err, ledger.ErrInsufficientFunds) {
+		return t, invalid("Insufficient balance")
+	}
+	return t, err
+}
+
+// Decide approves a pending transfer, sending it from its hold, or rejects
+// it, releasing the hold. q must be bound to a transaction.
+func Decide(ctx context.Context, q *sqlc.Queries, transferID int32, approve bool, adminID pgtype.Int4, adminNote string) (sqlc.WalletTransfer, error) {
+	t, err := q.GetWalletTransferForUpdate(ctx, transferID)
+	if err != nil {
+		return t, err
+	}
+	status := StatusRejected
+	if approve {
+		status = StatusCompleted
+	}
+	n, err := q.UpdateWalletTransferStatus(ctx, sqlc.UpdateWalletTransferStatusParams{
+		ID:          t.ID,
+		Status:      status,
+		AdminNote:   adminNote,
+		ProcessedBy: adminID,
+	})
+	if err != nil {
+		return t, fmt.Errorf("update transfer: %w", err)
+	}
+	if n == 0 {
+		return t, ErrNotPending
+	}
+	t.Status = status
+	if approve {
+		return t, ledger.Transfer(ctx, q, t.SenderID, t.RecipientID, t.ID, money.Paise(t.AmountCents))
+	}
+	return t, ledger.ReleaseTransfer(ctx, q, t.ID)
+}
@@ -0,0 +1,113 @@
+// Package twofactor implements authenticator app codes (TOTP, RFC 6238):
+// six digits from HMAC-SHA1 over 30-second time steps.
+package twofactor
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"pablosmm/backend/internal/db/sqlc"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	Issuer = "PabloSMM"
+	period = 30
+	// skew is how many steps either side of now a code is accepted for, to
+	// allow for clock drift on the user's device
+	skew = 1
+)
+
+var (
+	// ErrNotEnabled is returned when checking a code for a user who has not
+	// turned two-factor authentication on
+	ErrNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrInvalidCode is returned for a wrong, expired or already used code
+	ErrInvalidCode = errors.New("invalid two-factor code")
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// NewSecret returns a random secret in the base32 form authenticator apps
+// take
+func NewSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// URL is the otpauth:// link an authenticator app reads from a QR code
+func URL(account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", Issuer)
+	return "otpauth://totp/" + url.PathEscape(Issuer+":"+account) + "?" + v.Encode()
+}
+
+func code(key []byte, step int64) string {
+	mac := hmac.New(sha1.New, key)
+	binary.Write(mac, binary.BigEndian, step)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	n := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", n%1000000)
+}
+
+// Match returns the time step a code is valid for at t
+func Match(secret, input string, t time.Time) (int64, bool) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+	input = strings.ReplaceAll(strings.TrimSpace(input), " ", "")
+	if len(input) != 6 {
+		return 0, false
+	}
+	now := t.Unix() / period
+	for step := now - skew; step <= now+skew; step++ {
+		if subtle.ConstantTimeCompare([]byte(code(key, step)), []byte(input)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// Verify checks a code from the user's authenticator app and uses it up, so
+// the same code cannot confirm a second action
+func Verify(ctx context.Context, q *sqlc.Queries, userID int32, input string) error {
+	tf, err := q.GetTwoFactor(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotEnabled
+	}
+	if err != nil {
+		return err
+	}
+	if !tf.EnabledAt.Valid {
+		return ErrNotEnabled
+	}
+	step, ok := Match(tf.Secret, input, time.Now())
+	if !ok {
+		return ErrInvalidCode
+	}
+	n, err := q.UseTwoFactorStep(ctx, sqlc.UseTwoFactorStepParams{UserID: userID, LastStep: step})
+	if err != nil {
+		return fmt.Errorf("use code: %w", err)
+	}
+	if n == 0 {
+		return ErrInvalidCode
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package verifier
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"pablosmm/backend/internal/db"
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/service/jobs"
+	"pablosmm/backend/internal/service/metadata"
+	"pablosmm/backend/internal/service/refill"
+	"pablosmm/backend/internal/service/smm"
+)
+
+// batchSize caps how many targets are scraped per run so we stay well under
+// the platforms' rate limits
+const batchSize = 50
+
+// DeliveryVerifier re-reads public counters for completed orders on refill
+// services and records how much of the delivery has dropped since. Drops
+// are handed to the refill service, which decides on an automatic refill.
+type DeliveryVerifier struct {
+	db       *db.DB
+	smm      *smm.ProviderService
+	metadata *metadata.Service
+	refill   *refill.Service
+}
+
+func New(database *db.DB, smmSvc *smm.ProviderService, metaSvc *metadata.Service, refillSvc *refill.Service) *DeliveryVerifier {
+	return &DeliveryVerifier{db: database, smm: smmSvc, metadata: metaSvc, refill: refillSvc}
+}
+
+func (v *DeliveryVerifier) Start(ctx context.Context, runner *jobs.Runner) {
+	runner.Every(ctx, "delivery_verifier", 1*time.Hour, v.VerifyOrders)
+}
+
+func (v *DeliveryVerifier) VerifyOrders(ctx context.Context) {
+	services, err := v.smm.FetchServices()
+	if err != nil {
+		log.Printf("[Verifier] Failed to load services: %v", err)
+		return
+	}
+	serviceMap := make(map[string]smm.NormalizedSmmService)
+	var refillable []string
+	for _, s := range services {
+		serviceMap[s.ID] = s
+		if s.Refill {
+			refillable = append(refillable, s.ID)
+		}
+	}
+	if len(refillable) == 0 {
+		return
+	}
+
+	rows, err := v.db.Queries.GetOrdersForVerification(ctx, sqlc.GetOrdersForVerificationParams{
+		ServiceIds: refillable,
+		Lim:        batchSize,
+	})
+	if err != nil {
+		log.Printf("[Verifier] Fetch error: %v", err)
+		return
+	}
+
+	checked := 0
+	for _, row := range rows {
+		check, err := v.verifyOrder(ctx, row, serviceMap[row.ServiceID])
+		// Every attempt counts, so orders that cannot be measured wait their
+		// turn instead of filling each batch
+		if err := v.db.Queries.MarkDeliveryChecked(ctx, row.ID); err != nil {
+			log.Printf("[Verifier] Order #%d: %v", row.ID, err)
+		}
+		if err != nil {
+			log.Printf("[Verifier] Order #%d: %v", row.ID, err)
+			continue
+		}
+		if check != nil {
+			checked++
+			if check.DropPct > 0 {
+				if _, err := v.refill.AutoRefill(ctx, row.ID, check.DropPct); err != nil {
+					log.Printf("[Verifier] Auto refill for order #%d failed: %v", row.ID, err)
+				}
+			}
+		}
+
+		// Be gentle with the platforms we scrape
+		time.Sleep(2 * time.Second)
+	}
+	if len(rows) > 0 {
+		log.Printf("[Verifier] Checked delivery for %d of %d orders", checked, len(rows))
+	}
+}
+
+// verifyOrder records one delivery check. It returns nil without an error
+// when the target does not expose the counter the service grows.
+func (v *DeliveryVerifier) verifyOrder(ctx context.Context, row sqlc.GetOrdersForVerificationRow, svc smm.NormalizedSmmService) (*sqlc.OrderDeliveryCheck, error) {
+	delivered := int(row.Quantity - row.Remains)
+
+	meta, err := v.metadata.Fetch(row.CanonicalLink)
+	if err != nil {
+		return nil, err
+	}
+	observed, ok := meta.CountFor(svc.ServiceType)
+	if !ok {
+		return nil, nil
+	}
+
+	expected := int(row.StartCount) + delivered
+	check, err := v.db.Queries.InsertDeliveryCheck(ctx, sqlc.InsertDeliveryCheckParams{
+		OrderID:       row.ID,
+		StartCount:    row.StartCount,
+		Delivered:     int32(delivered),
+		ExpectedCount: int32(expected),
+		ObservedCount: int32(observed),
+		DropPct:       DropPercent(expected, observed, delivered),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &check, nil
+}
+
+// DropPercent is the share of the delivered quantity that is no longer
+// visible on the target, clamped to 0-100. Organic growth reads as 0.
+func DropPercent(expected, observed, delivered int) float64 {
+	if delivered <= 0 || observed >= expected {
+		return 0
+	}
+	pct := float64(expected-observed) / float64(delivered) * 100
+	if pct > 100 {
+		pct = 100
+	}
+	return pct
+}
@@ -0,0 +1,89 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/service/ledger"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// holdSweepMinutes is how long an order's hold may stay active before the
+// sweep settles it; placing an order takes seconds
+const holdSweepMinutes = 15
+
+// SweepHolds settles order holds left active because placing the order was
+// interrupted after the provider was asked, or charging it failed. Orders
+// with a provider order id are charged. Orders without one may still have
+// reached the provider, so they are charged too and escalated for an admin
+// to check. Holds of orders that are gone or closed are released.
+func (w *Watchdog) SweepHolds(ctx context.Context) {
+	orderIDs, err := w.db.Queries.ListStaleOrderHolds(ctx, sqlc.ListStaleOrderHoldsParams{
+		MinAgeMinutes: holdSweepMinutes,
+		Lim:           batchSize,
+	})
+	if err != nil {
+		log.Printf("[Watchdog] Fetch stale holds error: %v", err)
+		return
+	}
+	for _, orderID := range orderIDs {
+		if err := w.sweepHold(ctx, orderID); err != nil {
+			log.Printf("[Watchdog] Hold of order #%d: %v", orderID, err)
+		}
+	}
+	if len(orderIDs) > 0 {
+		log.Printf("[Watchdog] Swept %d stale order holds", len(orderIDs))
+	}
+}
+
+func (w *Watchdog) sweepHold(ctx context.Context, orderID int32) error {
+	tx, err := w.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := w.db.Queries.WithTx(tx)
+
+	order, err := qtx.GetOrderForApproval(ctx, orderID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if err := ledger.ReleaseOrder(ctx, qtx, orderID); err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("lock order: %w", err)
+	}
+
+	switch {
+	case order.ProviderOrderID != "":
+		if err := ledger.CaptureOrder(ctx, qtx, orderID); err != nil {
+			return err
+		}
+		err = qtx.InsertOrderEvent(ctx, sqlc.InsertOrderEventParams{
+			OrderID:   orderID,
+			EventType: "hold_captured",
+			Message:   "Charged the funds held for the order, which the provider accepted",
+		})
+		if err != nil {
+			return fmt.Errorf("log event: %w", err)
+		}
+	case order.Status == "pending":
+		if err := ledger.CaptureOrder(ctx, qtx, orderID); err != nil {
+			return err
+		}
+		reason := "Order was never recorded as placed with the provider; the held funds were charged in case the provider has it"
+		if err := escalateTx(ctx, qtx, orderID, reason); err != nil {
+			return err
+		}
+	default:
+		if err := ledger.ReleaseOrder(ctx, qtx, orderID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,343 @@
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"pablosmm/backend/internal/db"
+	"pablosmm/backend/internal/db/sqlc"
+	"pablosmm/backend/internal/service/jobs"
+	"pablosmm/backend/internal/service/ledger"
+	"pablosmm/backend/internal/service/refund"
+	"pablosmm/backend/internal/service/smm"
+	"pablosmm/backend/internal/service/syncer"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// batchSize caps how many overdue orders are handled per run
+const batchSize = 100
+
+// Stages an overdue order moves through, one per run, kept on the order.
+// Orders the provider finishes in the meantime drop out of the watchdog on
+// their own.
+const (
+	StageRechecked       = "rechecked"
+	StageCancelRequested = "cancel_requested"
+	StageRefunded        = "refunded"
+	StageEscalated       = "escalated"
+)
+
+// Final actions once the provider neither moved nor canceled the order
+const (
+	ActionRefund   = "refund"
+	ActionEscalate = "escalate"
+)
+
+// Config is read from global_settings on every run
+type Config struct {
+	SLAFactor         float64
+	MinSLAMinutes     int
+	DefaultSLAMinutes int
+	FinalAction       string
+}
+
+// Watchdog looks for orders the provider has not finished within their
+// service's SLA, whether or not it has started them. It re-checks them with
+// the provider, then asks the provider to cancel, and finally refunds or
+// escalates them to the admin queue. Orders the provider would not cancel
+// are always escalated, as the provider may still deliver them.
+type Watchdog struct {
+	db     *db.DB
+	smm    *smm.ProviderService
+	syncer *syncer.OrderSyncer
+}
+
+func New(database *db.DB, smmSvc *smm.ProviderService, syncerSvc *syncer.OrderSyncer) *Watchdog {
+	return &Watchdog{db: database, smm: smmSvc, syncer: syncerSvc}
+}
+
+func (w *Watchdog) Start(ctx context.Context, runner *jobs.Runner) {
+	runner.Every(ctx, "order_watchdog", 15*time.Minute, w.CheckOrders)
+	runner.Every(ctx, "order_hold_sweep", 10*time.Minute, w.SweepHolds)
+}
+
+// LoadConfig reads the watchdog settings, falling back to the seeded defaults
+func (w *Watchdog) LoadConfig(ctx context.Context) Config {
+	cfg := Config{
+		SLAFactor:         3,
+		MinSLAMinutes:     60,
+		DefaultSLAMinutes: 1440,
+		FinalAction:       ActionEscalate,
+	}
+	if v, err := w.db.Queries.GetSetting(ctx, "watchdog_sla_factor"); err == nil {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f > 0 {
+			cfg.SLAFactor = f
+		}
+	}
+	if v, err := w.db.Queries.GetSetting(ctx, "watchdog_min_sla_minutes"); err == nil {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.MinSLAMinutes = n
+		}
+	}
+	if v, err := w.db.Queries.GetSetting(ctx, "watchdog_default_sla_minutes"); err == nil {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.DefaultSLAMinutes = n
+		}
+	}
+	if v, err := w.db.Queries.GetSetting(ctx, "watchdog_final_action"); err == nil && v == ActionRefund {
+		cfg.FinalAction = ActionRefund
+	}
+	return cfg
+}
+
+// SLA is how long an order of the service may stay unfinished
+func (c Config) SLA(svc *smm.NormalizedSmmService) time.Duration {
+	minutes := c.DefaultSLAMinutes
+	if svc != nil && svc.AverageTime != nil && *svc.AverageTime > 0 {
+		minutes = int(float64(*svc.AverageTime) * c.SLAFactor)
+	}
+	if minutes < c.MinSLAMinutes {
+		minutes = c.MinSLAMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+func (w *Watchdog) CheckOrders(ctx context.Context) {
+	cfg := w.LoadConfig(ctx)
+
+	rows, err := w.db.Queries.GetWatchdogCandidates(ctx, sqlc.GetWatchdogCandidatesParams{
+		MinAgeMinutes: int32(cfg.MinSLAMinutes),
+		Limit:         batchSize,
+	})
+	if err != nil {
+		log.Printf("[Watchdog] Fetch error: %v", err)
+		return
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	services, err := w.smm.FetchServices()
+	if err != nil {
+		// Without the catalog every order falls back to the default SLA
+		log.Printf("[Watchdog] Failed to load services: %v", err)
+	}
+	serviceMap := make(map[string]*smm.NormalizedSmmService)
+	for i := range services {
+		serviceMap[services[i].ID] = &services[i]
+	}
+
+	handled := 0
+	var skipped []int32
+	for _, row := range rows {
+		sla := cfg.SLA(serviceMap[row.ServiceID])
+		if !row.CreatedAt.Valid || time.Since(row.CreatedAt.Time) < sla {
+			skipped = append(skipped, row.ID)
+			continue
+		}
+		if err := w.advance(ctx, cfg, row, sla); err != nil {
+			log.Printf("[Watchdog] Order #%d: %v", row.ID, err)
+			skipped = append(skipped, row.ID)
+			continue
+		}
+		handled++
+	}
+	if len(skipped) > 0 {
+		if err := w.db.Queries.TouchOrderWatchdogCheck(ctx, skipped); err != nil {
+			log.Printf("[Watchdog] Failed to mark skipped orders checked: %v", err)
+		}
+	}
+	if handled > 0 {
+		log.Printf("[Watchdog] Handled %d overdue orders", handled)
+	}
+}
+
+// advance moves an overdue order one step down the ladder
+func (w *Watchdog) advance(ctx context.Context, cfg Config, row sqlc.GetWatchdogCandidatesRow, sla time.Duration) error {
+	overdue := fmt.Sprintf("%s past its %s SLA", time.Since(row.CreatedAt.Time).Round(time.Minute), sla)
+
+	// Without a provider order id there is nothing to ask the provider about
+	if row.ProviderOrderID == "" {
+		return w.finalAction(ctx, cfg, row, fmt.Sprintf("Order has no provider order id, %s", overdue))
+	}
+
+	switch row.WatchdogStage {
+	case "":
+		status, err := w.syncer.RecheckOrder(ctx, row.ID, row.ProviderKey, row.ProviderOrderID)
+		if err != nil {
+			log.Printf("[Watchdog] Re-check of order #%d failed: %v", row.ID, err)
+		}
+		if status == "" || unfinished(status) {
+			return w.setStage(ctx, row.ID, StageRechecked, "watchdog_recheck",
+				fmt.Sprintf("Order is %s; provider re-check did not finish it", overdue))
+		}
+		// The provider finished the order, nothing left to do
+		return nil
+
+	case StageRechecked:
+		resp, err := w.smm.CancelOrder(row.ProviderKey, row.ProviderOrderID)
+		if err == nil {
+			if errorMsg, ok := resp["error"].(string); ok {
+				err = fmt.Errorf("%s", errorMsg)
+			}
+		}
+		if err != nil {
+			// The order is still live with the provider, so refunding it
+			// here could hand it out for free
+			return w.escalate(ctx, row.ID, fmt.Sprintf("Provider refused cancellation (%v), %s", err, overdue))
+		}
+		// The syncer refunds the order once the provider reports it canceled
+		return w.setStage(ctx, row.ID, StageCancelRequested, "watchdog_cancel",
+			fmt.Sprintf("Asked the provider to cancel; order is %s", overdue))
+
+	default:
+		return w.finalAction(ctx, cfg, row, fmt.Sprintf("Provider did not cancel the order, %s", overdue))
+	}
+}
+
+// unfinished reports whether an order is still open with the provider. An
+// order that started but stopped moving is as stuck as one never started,
+// so in-progress orders stay in the watchdog until the last stage.
+func unfinished(status string) bool {
+	switch status {
+	case "pending", "submitted", "processing", "active", "in_progress":
+		return true
+	}
+	return false
+}
+
+func (w *Watchdog) setStage(ctx context.Context, orderID int32, stage, eventType, message string) error {
+	tx, err := w.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := w.db.Queries.WithTx(tx)
+
+	err = qtx.SetOrderWatchdogStage(ctx, sqlc.SetOrderWatchdogStageParams{
+		ID:            orderID,
+		WatchdogStage: pgtype.Text{String: stage, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("set stage: %w", err)
+	}
+	err = qtx.InsertOrderEvent(ctx, sqlc.InsertOrderEventParams{
+		OrderID:   orderID,
+		EventType: eventType,
+		Message:   message,
+	})
+	if err != nil {
+		return fmt.Errorf("log event: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// finalAction refunds the order or hands it to the admin escalation queue,
+// depending on watchdog_final_action
+func (w *Watchdog) finalAction(ctx context.Context, cfg Config, row sqlc.GetWatchdogCandidatesRow, reason string) error {
+	if cfg.FinalAction != ActionRefund {
+		return w.escalate(ctx, row.ID, reason)
+	}
+
+	rule, err := refund.RuleFor(ctx, w.db.Queries, row.ServiceID)
+	if err != nil {
+		log.Printf("[Watchdog] Failed to load refund rule for order #%d: %v", row.ID, err)
+		rule = refund.DefaultRule()
+	}
+
+	tx, err := w.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	qtx := w.db.Queries.WithTx(tx)
+
+	order, err := qtx.GetOrderForApproval(ctx, row.ID)
+	if err != nil {
+		return fmt.Errorf("lock order: %w", err)
+	}
+	if !unfinished(order.Status) {
+		// Something else settled the order since the candidates were read
+		return nil
+	}
+
+	result := refund.Calculate(rule, refund.Input{
+		Kind:          refund.KindStuck,
+		AmountCents:   order.AmountCents,
+		Quantity:      int(order.Quantity),
+		Remains:       refund.UndeliveredQuantity(order.Status, int(order.Quantity), int(order.Remains)),
+		RefundedCents: order.RefundedAmount,
+	})
+	desc := fmt.Sprintf("Auto-Refund for stuck Order #%d", order.ID)
+	if err := refund.Apply(ctx, qtx, order.ID, order.UserID, refund.KindStuck, rule, result, desc); err != nil {
+		return err
+	}
+	err = qtx.CancelOrderWithRefund(ctx, sqlc.CancelOrderWithRefundParams{
+		ID:             order.ID,
+		RefundedAmount: pgtype.Int8{Int64: result.Cents, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("cancel order: %w", err)
+	}
+	if err := ledger.SettleOrder(ctx, qtx, order.ID); err != nil {
+		return fmt.Errorf("settle order: %w", err)
+	}
+	err = qtx.SetOrderWatchdogStage(ctx, sqlc.SetOrderWatchdogStageParams{
+		ID:            order.ID,
+		WatchdogStage: pgtype.Text{String: StageRefunded, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("set stage: %w", err)
+	}
+	err = qtx.InsertOrderEvent(ctx, sqlc.InsertOrderEventParams{
+		OrderID:   order.ID,
+		EventType: "watchdog_refund",
+		Message:   fmt.Sprintf("%s. Canceled and refunded %s", reason, result.Formula),
+	})
+	if err != nil {
+		return fmt.Errorf("log event: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+func (w *Watchdog) escalate(ctx context.Context, orderID int32, reason string) error {
+	tx, err := w.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := escalateTx(ctx, w.db.Queries.WithTx(tx), orderID, reason); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func escalateTx(ctx context.Context, qtx *sqlc.Queries, orderID int32, reason string) error {
+	err := qtx.InsertOrderEscalation(ctx, sqlc.InsertOrderEscalationParams{
+		OrderID: orderID,
+		Reason:  reason,
+	})
+	if err != nil {
+		return fmt.Errorf("escalate: %w", err)
+	}
+	err = qtx.SetOrderWatchdogStage(ctx, sqlc.SetOrderWatchdogStageParams{
+		ID:            orderID,
+		WatchdogStage: pgtype.Text{String: StageEscalated, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("set stage: %w", err)
+	}
+	err = qtx.InsertOrderEvent(ctx, sqlc.InsertOrderEventParams{
+		OrderID:   orderID,
+		EventType: "watchdog_escalated",
+		Message:   reason + ". Escalated to an admin",
+	})
+	if err != nil {
+		return fmt.Errorf("log event: %w", err)
+	}
+	return nil
+}
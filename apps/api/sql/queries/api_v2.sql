@@ -2,7 +2,7 @@
 UPDATE users SET api_key = $1 WHERE id = $2;
 
 -- name: GetUserByAPIKey :one
-SELECT u.id, COALESCE(w.balance, 0)::int as balance, COALESCE(u.currency, 'INR')::text as currency 
+SELECT u.id, COALESCE(w.balance - w.held_cents, 0)::bigint as balance, COALESCE(u.currency, 'INR')::text as currency 
 FROM users u 
 LEFT JOIN wallets w ON u.id = w.user_id 
 WHERE u.api_key = $1 AND u.api_key_enabled = TRUE;
@@ -12,8 +12,8 @@ SELECT amount_cents, COALESCE(start_count, 0)::int as start_count, status, COALE
 FROM orders WHERE id = $1 AND user_id = $2;
 
 -- name: InsertAPIOrder :one
-INSERT INTO orders (user_id, service_id, quantity, amount_cents, status, created_at, link) 
-VALUES ($1, $2, $3, $4, $5, NOW(), $6) RETURNING id;
+INSERT INTO orders (user_id, service_id, quantity, amount_cents, status, created_at, link, provider_key, canonical_link, target_kind, target_id, own_start_count, refills_remaining) 
+VALUES ($1, $2, $3, $4, $5, NOW(), $6, $7, $8, $9, $10, $11, $12) RETURNING id;
 
 -- name: UpdateAPIOrderStatusFailed :exec
 UPDATE orders SET status = 'failed' WHERE id = $1;
@@ -0,0 +1,13 @@
+-- name: InsertAdminAudit :exec
+INSERT INTO admin_audit_log (admin_id, action, entity_type, entity_id, details)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: ListAdminAudit :many
+SELECT a.id, a.admin_id, a.action, a.entity_type, a.entity_id, a.details, a.created_at,
+       COALESCE(u.email, '')::text as admin_email
+FROM admin_audit_log a
+LEFT JOIN users u ON a.admin_id = u.id
+WHERE (sqlc.narg('entity_type')::text IS NULL OR a.entity_type = sqlc.narg('entity_type'))
+  AND (sqlc.narg('entity_id')::text IS NULL OR a.entity_id = sqlc.narg('entity_id'))
+ORDER BY a.created_at DESC
+LIMIT $1;
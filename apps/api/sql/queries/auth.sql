@@ -1,9 +1,10 @@
 -- name: CheckUserExists :one
 SELECT EXISTS(SELECT 1 FROM users WHERE email=$1 OR username=$2);
 
--- name: CreateUser :exec
-INSERT INTO users (name, email, username, mobile, password_hash, role)
-VALUES ($1, $2, $3, $4, $5, 'user');
+-- name: CreateUser :one
+INSERT INTO users (name, email, username, mobile, password_hash, role, signup_ip)
+VALUES ($1, $2, $3, $4, $5, 'user', $6)
+RETURNING id;
 
 -- name: GetUserForLogin :one
 SELECT id, password_hash, role 
@@ -21,14 +22,14 @@ SELECT
     COALESCE(u.currency, 'USD')::text as currency,
     u.created_at, 
     COALESCE(u.password_hash, '')::text as password_hash,
-    COALESCE(w.balance, 0)::int as balance,
+    COALESCE(w.balance, 0)::bigint as balance,
     (SELECT COUNT(*)::int FROM orders o WHERE o.user_id = u.id) as order_count,
     (SELECT COALESCE(SUM(
         CASE 
             WHEN o.status IN ('failed', 'canceled', 'refunded') THEN 0 
             ELSE o.amount_cents 
         END
-    ), 0)::int FROM orders o WHERE o.user_id = u.id) as total_spend
+    ), 0)::bigint FROM orders o WHERE o.user_id = u.id) as total_spend
 FROM users u
 LEFT JOIN wallets w ON u.id = w.user_id
 WHERE u.id = $1;
@@ -48,8 +49,8 @@ SELECT id, role FROM users WHERE email=$1 OR google_id=$2;
 UPDATE users SET google_id=$1, avatar_url=$2 WHERE id=$3;
 
 -- name: CreateGoogleUser :one
-INSERT INTO users (name, email, google_id, avatar_url, role, username)
-VALUES ($1, $2, $3, $4, 'user', $5)
+INSERT INTO users (name, email, google_id, avatar_url, role, username, signup_ip)
+VALUES ($1, $2, $3, $4, 'user', $5, $6)
 RETURNING id, role;
 
 -- name: GetPasswordHash :one
@@ -0,0 +1,85 @@
+-- name: ListDepositBonusTiers :many
+SELECT * FROM deposit_bonus_tiers
+ORDER BY min_deposit_cents, id;
+
+-- name: ListCurrentDepositBonusTiers :many
+-- Tiers a deposit made now could earn
+SELECT * FROM deposit_bonus_tiers
+WHERE is_active = TRUE
+AND (starts_at IS NULL OR starts_at <= NOW())
+AND (ends_at IS NULL OR ends_at > NOW())
+ORDER BY min_deposit_cents, id;
+
+-- name: GetDepositBonusTierFor :one
+-- The highest current tier a deposit reaches with its payment method
+SELECT * FROM deposit_bonus_tiers
+WHERE is_active = TRUE
+AND (starts_at IS NULL OR starts_at <= NOW())
+AND (ends_at IS NULL OR ends_at > NOW())
+AND min_deposit_cents <= sqlc.arg(deposit_cents)
+AND (cardinality(methods) = 0 OR sqlc.arg(method)::text = ANY(methods))
+ORDER BY min_deposit_cents DESC, bonus_pct DESC, id
+LIMIT 1;
+
+-- name: CreateDepositBonusTier :one
+INSERT INTO deposit_bonus_tiers (name, min_deposit_cents, bonus_pct, max_bonus_cents, methods, starts_at, ends_at, withdrawable, expires_after_days, is_active)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: UpdateDepositBonusTier :one
+UPDATE deposit_bonus_tiers
+SET name = $2, min_deposit_cents = $3, bonus_pct = $4, max_bonus_cents = $5, methods = $6,
+    starts_at = $7, ends_at = $8, withdrawable = $9, expires_after_days = $10, is_active = $11
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteDepositBonusTier :execrows
+DELETE FROM deposit_bonus_tiers WHERE id = $1;
+
+-- name: InsertBonusGrant :one
+INSERT INTO bonus_grants (user_id, tier_id, promo_code_id, wallet_request_id, deposit_cents, amount_cents, remaining_cents, withdrawable, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $6, $7, $8)
+RETURNING id;
+
+-- name: ListBonusGrants :many
+SELECT * FROM bonus_grants
+WHERE (sqlc.narg(user_id)::int IS NULL OR user_id = sqlc.narg(user_id))
+AND (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(lim);
+
+-- name: ListDueBonusGrants :many
+SELECT * FROM bonus_grants
+WHERE status = 'active' AND expires_at <= NOW()
+ORDER BY expires_at
+LIMIT $1;
+
+-- name: GetBonusGrantForUpdate :one
+SELECT * FROM bonus_grants WHERE id = $1 FOR UPDATE;
+
+-- name: ExpireBonusGrant :execrows
+UPDATE bonus_grants SET status = 'expired', expired_cents = $2, remaining_cents = 0, expired_at = NOW()
+WHERE id = $1 AND status = 'active';
+
+-- name: GetLockedBonusCents :one
+-- Unspent active bonus credit that may be spent but not withdrawn
+SELECT COALESCE(SUM(remaining_cents), 0)::bigint as locked FROM bonus_grants
+WHERE user_id = $1 AND status = 'active' AND withdrawable = FALSE;
+
+-- name: SpendBonusGrants :exec
+-- Cuts the unspent part of a wallet's active bonuses down to what its
+-- balance still covers. Bonus credit is spent after deposited money, and
+-- the bonus expiring soonest is spent first.
+UPDATE bonus_grants g
+SET remaining_cents = GREATEST(s.balance - s.kept_after, 0)
+FROM (
+    SELECT b.id, COALESCE(w.balance, 0) AS balance,
+        COALESCE(SUM(b.remaining_cents) OVER (
+            ORDER BY b.expires_at DESC NULLS FIRST, b.id DESC
+            ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING
+        ), 0) AS kept_after
+    FROM bonus_grants b
+    LEFT JOIN wallets w ON w.user_id = b.user_id
+    WHERE b.user_id = $1 AND b.status = 'active'
+) s
+WHERE g.id = s.id AND g.remaining_cents > GREATEST(s.balance - s.kept_after, 0);
@@ -0,0 +1,51 @@
+-- name: GetOrdersForVerification :many
+-- Completed orders on refillable services with a delivery to measure, least
+-- recently attempted first
+SELECT
+	o.id,
+	o.service_id,
+	o.quantity,
+	COALESCE(o.remains, 0)::int as remains,
+	COALESCE(o.own_start_count, o.start_count, 0)::int as start_count,
+	COALESCE(o.canonical_link, '')::text as canonical_link
+FROM orders o
+WHERE o.status IN ('completed', 'partial')
+AND o.service_id = ANY(sqlc.arg(service_ids)::text[])
+AND o.canonical_link IS NOT NULL
+AND o.completed_at > NOW() - INTERVAL '60 days'
+AND COALESCE(o.own_start_count, o.start_count, 0) > 0
+AND o.quantity > COALESCE(o.remains, 0)
+AND (o.delivery_checked_at IS NULL OR o.delivery_checked_at < NOW() - INTERVAL '12 hours')
+ORDER BY o.delivery_checked_at ASC NULLS FIRST, o.completed_at DESC
+LIMIT sqlc.arg(lim);
+
+-- name: MarkDeliveryChecked :exec
+UPDATE orders SET delivery_checked_at = NOW() WHERE id = $1;
+
+-- name: InsertDeliveryCheck :one
+INSERT INTO order_delivery_checks (order_id, start_count, delivered, expected_count, observed_count, drop_pct)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetDeliveryChecksByOrder :many
+SELECT * FROM order_delivery_checks
+WHERE order_id = $1
+ORDER BY checked_at DESC;
+
+-- name: GetDeliveryDashboard :many
+SELECT DISTINCT ON (c.order_id)
+	c.order_id,
+	o.service_id,
+	o.status,
+	COALESCE(o.canonical_link, '')::text as canonical_link,
+	u.email,
+	c.expected_count,
+	c.observed_count,
+	c.drop_pct,
+	c.checked_at,
+	(SELECT COUNT(*) FROM order_delivery_checks c2 WHERE c2.order_id = c.order_id) as check_count
+FROM order_delivery_checks c
+JOIN orders o ON o.id = c.order_id
+JOIN users u ON u.id = o.user_id
+WHERE c.checked_at > NOW() - INTERVAL '60 days'
+ORDER BY c.order_id, c.checked_at DESC;
@@ -0,0 +1,33 @@
+-- name: GetServiceDeliveryStats :many
+-- Orders are only timed when completed_at is after created_at; completion
+-- times backfilled from the order's own timestamps read as 0 minutes
+SELECT
+	service_id,
+	COUNT(*) as finished,
+	COUNT(*) FILTER (WHERE status IN ('completed', 'partial') AND completed_at > created_at) as timed,
+	COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM completed_at - created_at) / 60)
+		FILTER (WHERE status IN ('completed', 'partial') AND completed_at > created_at), 0)::float8 as median_minutes,
+	COALESCE(percentile_cont(0.9) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM completed_at - created_at) / 60)
+		FILTER (WHERE status IN ('completed', 'partial') AND completed_at > created_at), 0)::float8 as p90_minutes,
+	COUNT(*) FILTER (WHERE status = 'partial') as partial_count,
+	COUNT(*) FILTER (WHERE status IN ('canceled', 'refunded')) as canceled_count
+FROM orders
+WHERE created_at > NOW() - (sqlc.arg(days)::int * INTERVAL '1 day')
+  AND status IN ('completed', 'partial', 'canceled', 'refunded')
+GROUP BY service_id;
+
+-- name: GetProviderDeliveryStats :many
+SELECT
+	COALESCE(provider_key, '')::text as provider_key,
+	COUNT(*) as finished,
+	COUNT(*) FILTER (WHERE status IN ('completed', 'partial') AND completed_at > created_at) as timed,
+	COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM completed_at - created_at) / 60)
+		FILTER (WHERE status IN ('completed', 'partial') AND completed_at > created_at), 0)::float8 as median_minutes,
+	COALESCE(percentile_cont(0.9) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM completed_at - created_at) / 60)
+		FILTER (WHERE status IN ('completed', 'partial') AND completed_at > created_at), 0)::float8 as p90_minutes,
+	COUNT(*) FILTER (WHERE status = 'partial') as partial_count,
+	COUNT(*) FILTER (WHERE status IN ('canceled', 'refunded')) as canceled_count
+FROM orders
+WHERE created_at > NOW() - (sqlc.arg(days)::int * INTERVAL '1 day')
+  AND status IN ('completed', 'partial', 'canceled', 'refunded')
+GROUP BY COALESCE(provider_key, '');
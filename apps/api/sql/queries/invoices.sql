@@ -0,0 +1,63 @@
+-- name: GetBillingProfile :one
+SELECT COALESCE(name, '')::text as name, COALESCE(email, '')::text as email, billing_name, gstin, billing_address, billing_state
+FROM users WHERE id = $1;
+
+-- name: UpdateBillingProfile :exec
+UPDATE users SET billing_name = $2, gstin = $3, billing_address = $4, billing_state = $5
+WHERE id = $1;
+
+-- name: ListUninvoicedTopUps :many
+-- Deposits and admin credits marked as top-ups since a point in time that
+-- have no invoice yet
+SELECT t.id, t.user_id::int as user_id, ROUND(t.amount * 100)::bigint as amount_cents, t.kind,
+    COALESCE(w.method, '')::text as method, t.created_at
+FROM transactions t
+LEFT JOIN wallet_requests w ON t.reference_type = 'wallet_request' AND w.id::text = t.reference_id
+WHERE t.type = 'credit' AND t.user_id IS NOT NULL AND t.amount > 0
+AND (t.kind = 'deposit' OR (t.kind = 'adjustment' AND EXISTS (
+    SELECT 1 FROM wallet_adjustments a
+    WHERE t.reference_type = 'admin_adjustment' AND a.id::text = t.reference_id AND a.top_up
+)))
+AND t.created_at >= sqlc.arg(since)
+AND NOT EXISTS (SELECT 1 FROM invoices i WHERE i.transaction_id = t.id)
+ORDER BY t.id
+LIMIT sqlc.arg(lim);
+
+-- name: RecordInvoiceFailure :exec
+INSERT INTO invoice_failures (transaction_id, user_id, error) VALUES ($1, $2, $3)
+ON CONFLICT (transaction_id) DO UPDATE SET error = EXCLUDED.error,
+    attempts = invoice_failures.attempts + 1, last_failed_at = CURRENT_TIMESTAMP;
+
+-- name: DeleteInvoiceFailure :exec
+DELETE FROM invoice_failures WHERE transaction_id = $1;
+
+-- name: ListInvoiceFailures :many
+SELECT f.transaction_id, f.user_id, COALESCE(u.username, '')::text as username,
+    ROUND(t.amount * 100)::bigint as amount_cents, f.error, f.attempts, f.first_failed_at, f.last_failed_at
+FROM invoice_failures f
+JOIN transactions t ON t.id = f.transaction_id
+LEFT JOIN users u ON u.id = f.user_id
+ORDER BY f.first_failed_at;
+
+-- name: NextInvoiceNumber :one
+INSERT INTO invoice_counters (financial_year, last_number) VALUES ($1, 1)
+ON CONFLICT (financial_year) DO UPDATE SET last_number = invoice_counters.last_number + 1
+RETURNING last_number;
+
+-- name: InsertInvoice :one
+INSERT INTO invoices (invoice_number, user_id, transaction_id, payment_method, customer_name, customer_email, customer_gstin,
+    customer_address, place_of_supply, seller_name, seller_gstin, seller_address, seller_state, sac_code, gst_pct,
+    total_cents, taxable_cents, cgst_cents, sgst_cents, igst_cents, issued_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+RETURNING *;
+
+-- name: GetInvoice :one
+SELECT * FROM invoices WHERE id = $1;
+
+-- name: ListInvoices :many
+SELECT * FROM invoices
+WHERE (sqlc.narg(user_id)::int IS NULL OR user_id = sqlc.narg(user_id))
+AND (sqlc.narg(issued_from)::timestamptz IS NULL OR issued_at >= sqlc.narg(issued_from))
+AND (sqlc.narg(issued_to)::timestamptz IS NULL OR issued_at < sqlc.narg(issued_to))
+ORDER BY issued_at DESC, id DESC
+LIMIT sqlc.arg(lim);
@@ -0,0 +1,30 @@
+-- name: TryJobXactLock :one
+-- Held until the claiming transaction ends
+SELECT pg_try_advisory_xact_lock(hashtext('job:' || sqlc.arg(name)::text)) as locked;
+
+-- name: ClaimJobLease :execrows
+-- Takes the job's lease unless a run holds one that has not run out, or a
+-- run started less than min_gap_seconds ago
+INSERT INTO job_leases (name, holder, running, started_at, finished_at, expires_at)
+VALUES (sqlc.arg(name), sqlc.arg(holder), TRUE, NOW(), NULL, NOW() + sqlc.arg(ttl_seconds)::int * INTERVAL '1 second')
+ON CONFLICT (name) DO UPDATE SET
+    holder = EXCLUDED.holder,
+    running = TRUE,
+    started_at = NOW(),
+    finished_at = NULL,
+    expires_at = EXCLUDED.expires_at
+WHERE NOT (job_leases.running AND job_leases.expires_at > NOW())
+  AND job_leases.started_at < NOW() - sqlc.arg(min_gap_seconds)::int * INTERVAL '1 second';
+
+-- name: RenewJobLease :execrows
+UPDATE job_leases
+SET expires_at = NOW() + sqlc.arg(ttl_seconds)::int * INTERVAL '1 second'
+WHERE name = sqlc.arg(name) AND holder = sqlc.arg(holder) AND running;
+
+-- name: FinishJobLease :exec
+UPDATE job_leases
+SET running = FALSE, finished_at = NOW()
+WHERE name = $1 AND holder = $2;
+
+-- name: ListJobLeases :many
+SELECT * FROM job_leases ORDER BY name;
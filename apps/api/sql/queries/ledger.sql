@@ -0,0 +1,44 @@
+-- name: EnsureLedgerAccount :one
+INSERT INTO ledger_accounts (code, kind, name, user_id)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (code) DO UPDATE SET code = EXCLUDED.code
+RETURNING id;
+
+-- name: InsertLedgerJournal :one
+INSERT INTO ledger_journals (kind, description, reference_type, reference_id)
+VALUES ($1, $2, $3, $4)
+RETURNING id;
+
+-- name: InsertLedgerEntry :exec
+INSERT INTO ledger_entries (journal_id, account_id, amount_cents)
+VALUES ($1, $2, $3);
+
+-- name: GetOrderHeldCents :one
+SELECT COALESCE(-SUM(e.amount_cents), 0)::bigint as held
+FROM ledger_entries e
+JOIN ledger_journals j ON j.id = e.journal_id
+JOIN ledger_accounts a ON a.id = e.account_id
+WHERE a.code = 'provider_payable' AND j.reference_type = 'order' AND j.reference_id = $1;
+
+-- name: ListLedgerSystemBalances :many
+SELECT a.code, a.kind, a.name, COALESCE(SUM(e.amount_cents), 0)::bigint as debit_balance
+FROM ledger_accounts a
+LEFT JOIN ledger_entries e ON e.account_id = a.id
+WHERE a.user_id IS NULL
+GROUP BY a.id
+ORDER BY a.code;
+
+-- name: GetWalletLedgerTotal :one
+SELECT COALESCE(SUM(e.amount_cents), 0)::bigint as debit_balance
+FROM ledger_entries e
+JOIN ledger_accounts a ON a.id = e.account_id
+WHERE a.user_id IS NOT NULL;
+
+-- name: GetWalletLedgerMismatches :many
+SELECT w.user_id, w.balance::bigint as cached_balance, COALESCE(-SUM(e.amount_cents), 0)::bigint as ledger_balance
+FROM wallets w
+LEFT JOIN ledger_accounts a ON a.user_id = w.user_id
+LEFT JOIN ledger_entries e ON e.account_id = a.id
+GROUP BY w.user_id, w.balance
+HAVING w.balance <> COALESCE(-SUM(e.amount_cents), 0)
+ORDER BY w.user_id;
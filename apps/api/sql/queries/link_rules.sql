@@ -0,0 +1,21 @@
+-- name: ListLinkRules :many
+SELECT * FROM link_rules
+ORDER BY platform, service_type, variant;
+
+-- name: GetLinkRulesForPlatform :many
+SELECT * FROM link_rules
+WHERE is_active = TRUE AND platform = $1 AND (service_type = $2 OR service_type = '*');
+
+-- name: CreateLinkRule :one
+INSERT INTO link_rules (platform, service_type, variant, allowed_kinds, message, is_active)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: UpdateLinkRule :one
+UPDATE link_rules
+SET platform = $2, service_type = $3, variant = $4, allowed_kinds = $5, message = $6, is_active = $7
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteLinkRule :exec
+DELETE FROM link_rules WHERE id = $1;
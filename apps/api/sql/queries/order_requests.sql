@@ -27,3 +27,79 @@ WHERE id = $1;
 UPDATE orders
 SET refills_remaining = refills_remaining - 1
 WHERE id = $1 AND refills_remaining > 0;
+
+-- name: InsertOrderRequestWithStatus :one
+INSERT INTO order_requests (order_id, user_id, request_type, provider_response, status)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id;
+
+-- name: SetOrderRequestRefill :exec
+UPDATE order_requests
+SET provider_refill_id = $2, refill_status = $3
+WHERE id = $1;
+
+-- name: SetOrderRequestResponse :exec
+UPDATE order_requests
+SET provider_response = $2, provider_outcome = $3
+WHERE id = $1;
+
+-- name: UpdateRefillStatus :execrows
+UPDATE order_requests
+SET refill_status = $2
+WHERE id = $1 AND refill_status IN ('pending', 'in_progress') AND refill_status <> $2;
+
+-- name: GetRefillsForPolling :many
+SELECT req.id, req.order_id,
+       COALESCE(req.provider_refill_id, '')::text as provider_refill_id,
+       COALESCE(req.refill_status, '')::text as refill_status,
+       COALESCE(o.provider_key, '')::text as provider_key
+FROM order_requests req
+JOIN orders o ON req.order_id = o.id
+WHERE req.request_type = 'refill'
+  AND req.provider_refill_id IS NOT NULL
+  AND req.refill_status IN ('pending', 'in_progress')
+  AND req.created_at > NOW() - INTERVAL '30 days'
+ORDER BY req.created_at ASC
+LIMIT $1;
+
+-- name: GetOrderRefills :many
+SELECT id, status, provider_refill_id, refill_status, created_at, updated_at, decision_reason
+FROM order_requests
+WHERE order_id = $1 AND request_type = 'refill'
+ORDER BY created_at DESC;
+
+-- name: IncrementOrderRefills :exec
+UPDATE orders
+SET refills_remaining = refills_remaining + 1
+WHERE id = $1;
+
+-- name: GetOrderRequestForDecision :one
+SELECT * FROM order_requests
+WHERE id = $1
+FOR UPDATE;
+
+-- name: DecideOrderRequest :execrows
+UPDATE order_requests
+SET status = $2, decision_reason = $3, decided_by = $4, decided_at = NOW()
+WHERE id = $1 AND status = 'pending';
+
+-- name: GetOrderForApproval :one
+SELECT
+	id,
+	user_id,
+	service_id,
+	status,
+	amount_cents,
+	quantity,
+	COALESCE(remains, 0)::int as remains,
+	COALESCE(refunded_amount, 0)::bigint as refunded_amount,
+	COALESCE(provider_order_id, '')::text as provider_order_id,
+	COALESCE(provider_key, '')::text as provider_key
+FROM orders
+WHERE id = $1
+FOR UPDATE;
+
+-- name: CancelOrderWithRefund :exec
+UPDATE orders
+SET status = 'canceled', refunded_amount = COALESCE(refunded_amount, 0) + $2
+WHERE id = $1;
@@ -12,7 +12,7 @@ SELECT
 	COALESCE(o.remains, 0)::int as remains,
 	COALESCE(o.start_count, 0)::int as start_count,
 	COALESCE(o.link, '')::text as link,
-	(SELECT COALESCE(balance, 0)::int FROM wallets WHERE user_id = o.user_id) as user_balance,
+	(SELECT COALESCE(balance, 0)::bigint FROM wallets WHERE user_id = o.user_id) as user_balance,
 	COALESCE(so.service_type, '')::text as service_type,
 	COALESCE(so.category, '')::text as category,
 	EXISTS(SELECT 1 FROM order_requests WHERE order_id = o.id AND request_type = 'cancel' AND status = 'pending')::boolean as pending_cancel
@@ -40,7 +40,7 @@ FOR UPDATE;
 UPDATE orders SET status='canceled' WHERE id=$1;
 
 -- name: GetOrderForRefundAdmin :one
-SELECT status, amount_cents, COALESCE(refunded_amount, 0)::int as refunded_amount, user_id, COALESCE(provider_key, '')::text as provider_key 
+SELECT status, amount_cents, COALESCE(refunded_amount, 0)::bigint as refunded_amount, user_id, COALESCE(provider_key, '')::text as provider_key 
 FROM orders WHERE id = $1 FOR UPDATE;
 
 -- name: UpdateOrderRefundAdmin :one
@@ -61,12 +61,22 @@ SELECT
 	COALESCE(o.start_count, 0)::int as start_count,
 	COALESCE(o.link, '')::text as link,
 	u.email,
-	COALESCE(o.refunded_amount, 0)::int as refunded_amount,
+	COALESCE(o.refunded_amount, 0)::bigint as refunded_amount,
 	COALESCE(o.provider_order_id, '')::text as provider_order_id,
-	COALESCE(o.refills_remaining, 3)::int as refills_remaining,
+	o.refills_remaining,
 	COALESCE(so.refill_limit, 3)::int as service_refill_limit,
-	COALESCE(so.refill, false)::boolean as service_refill_enabled
+	COALESCE(so.refill, false)::boolean as service_refill_enabled,
+	COALESCE(o.canonical_link, '')::text as canonical_link,
+	COALESCE(o.target_kind, '')::text as target_kind,
+	COALESCE(o.target_id, '')::text as target_id,
+	o.own_start_count,
+	COALESCE(o.provider_status, '')::text as provider_status,
+	o.sync_error_count,
+	COALESCE(o.last_sync_error, '')::text as last_sync_error,
+	o.discount_cents,
+	COALESCE(pc.code, '')::text as promo_code
 FROM orders o
+LEFT JOIN promo_codes pc ON pc.id = o.promo_code_id
 LEFT JOIN service_overrides so ON (
 	o.service_id = so.source_service_id 
 	OR split_part(o.service_id, ':', 2) = so.source_service_id
@@ -76,13 +86,23 @@ LEFT JOIN service_overrides so ON (
 JOIN users u ON o.user_id = u.id
 WHERE (sqlc.narg('status_filter')::text IS NULL OR o.status = sqlc.narg('status_filter'))
 AND (sqlc.narg('user_id')::int IS NULL OR o.user_id = sqlc.narg('user_id'))
+AND (sqlc.narg('target_id')::text IS NULL OR o.target_id = sqlc.narg('target_id'))
 ORDER BY o.created_at DESC;
 
 -- name: InsertOrder :one
-INSERT INTO orders (user_id, service_id, amount_cents, quantity, link, status, provider_order_id, provider_resp, refills_remaining, provider_key)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+INSERT INTO orders (user_id, service_id, amount_cents, quantity, link, status, provider_order_id, provider_resp, refills_remaining, provider_key, canonical_link, target_kind, target_id, own_start_count, promo_code_id, discount_cents)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 RETURNING id;
 
+-- name: CountActiveOrdersForTarget :one
+SELECT COUNT(*) FROM orders
+WHERE service_id = $1 AND target_id = $2
+AND status IN ('pending', 'submitted', 'active', 'processing', 'in_progress');
+
+-- name: LockOrderTarget :exec
+-- Serializes orders for one service and target until the transaction ends
+SELECT pg_advisory_xact_lock(hashtext(sqlc.arg(lock_key)::text));
+
 -- name: DeleteOrder :exec
 DELETE FROM orders WHERE id = $1;
 
@@ -102,7 +122,7 @@ SELECT
 	COALESCE(o.link, '')::text as link,
 	COALESCE(so.service_type, '')::text as service_type,
 	COALESCE(so.category, '')::text as category,
-	COALESCE(o.refills_remaining, 3)::int as refills_remaining
+	o.refills_remaining
 FROM orders o
 LEFT JOIN service_overrides so ON (
 	o.service_id = so.source_service_id 
@@ -0,0 +1,64 @@
+-- name: ListPromoCodes :many
+SELECT * FROM promo_codes
+ORDER BY created_at DESC, id DESC;
+
+-- name: GetPromoCodeByCode :one
+SELECT * FROM promo_codes WHERE code = $1;
+
+-- name: GetPromoCodeForUpdate :one
+SELECT * FROM promo_codes WHERE id = $1 FOR UPDATE;
+
+-- name: CreatePromoCode :one
+INSERT INTO promo_codes (code, description, applies_to, value_type, value_pct, value_cents, max_value_cents, min_amount_cents,
+    max_uses, max_uses_per_user, platforms, catalog_ids, starts_at, expires_at, is_active)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+RETURNING *;
+
+-- name: UpdatePromoCode :one
+UPDATE promo_codes
+SET code = $2, description = $3, applies_to = $4, value_type = $5, value_pct = $6, value_cents = $7, max_value_cents = $8,
+    min_amount_cents = $9, max_uses = $10, max_uses_per_user = $11, platforms = $12, catalog_ids = $13,
+    starts_at = $14, expires_at = $15, is_active = $16
+WHERE id = $1
+RETURNING *;
+
+-- name: DeletePromoCode :execrows
+-- Codes that have been used are kept for reporting; deactivate them instead
+DELETE FROM promo_codes p WHERE p.id = $1
+AND NOT EXISTS (SELECT 1 FROM promo_redemptions r WHERE r.promo_code_id = p.id);
+
+-- name: CountPromoRedemptions :one
+SELECT COUNT(*) as total, COUNT(*) FILTER (WHERE user_id = sqlc.arg(user_id)) as by_user
+FROM promo_redemptions
+WHERE promo_code_id = sqlc.arg(promo_code_id);
+
+-- name: InsertPromoRedemption :one
+INSERT INTO promo_redemptions (promo_code_id, user_id, order_id, wallet_request_id, base_cents, value_cents)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id;
+
+-- name: ListPromoRedemptions :many
+SELECT r.id, r.promo_code_id, p.code, r.user_id, u.email, r.order_id, r.wallet_request_id, r.base_cents, r.value_cents, r.created_at
+FROM promo_redemptions r
+JOIN promo_codes p ON p.id = r.promo_code_id
+JOIN users u ON u.id = r.user_id
+WHERE (sqlc.narg(promo_code_id)::int IS NULL OR r.promo_code_id = sqlc.narg(promo_code_id))
+AND (sqlc.narg(user_id)::int IS NULL OR r.user_id = sqlc.narg(user_id))
+ORDER BY r.created_at DESC, r.id DESC
+LIMIT sqlc.arg(lim);
+
+-- name: GetPromoCodeStats :many
+-- Use of every code since a point in time. For order codes base is the
+-- order value before discounts; for deposit codes it is the deposits.
+SELECT p.id, p.code, p.applies_to, p.is_active,
+    COUNT(r.id) as redemptions,
+    COUNT(DISTINCT r.user_id) as users,
+    COALESCE(SUM(r.base_cents), 0)::bigint as base_cents,
+    COALESCE(SUM(r.value_cents), 0)::bigint as value_cents
+FROM promo_codes p
+LEFT JOIN promo_redemptions r ON r.promo_code_id = p.id AND r.created_at >= sqlc.arg(since)
+GROUP BY p.id
+ORDER BY redemptions DESC, p.id;
+
+-- name: GetWalletRequestPromoCode :one
+SELECT promo_code_id FROM wallet_requests WHERE id = $1;
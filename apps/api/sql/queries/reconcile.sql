@@ -0,0 +1,130 @@
+-- name: InsertReconcileRun :one
+INSERT INTO reconcile_runs (trigger) VALUES ($1)
+RETURNING id;
+
+-- name: FinishReconcileRun :exec
+UPDATE reconcile_runs
+SET status = $2, checked_wallets = $3, discrepancy_count = $4, fixed_count = $5, error = $6, finished_at = NOW()
+WHERE id = $1;
+
+-- name: ListReconcileRuns :many
+SELECT * FROM reconcile_runs ORDER BY id DESC LIMIT $1;
+
+-- name: GetReconcileRun :one
+SELECT * FROM reconcile_runs WHERE id = $1;
+
+-- name: SupersedeOpenDiscrepancies :execrows
+UPDATE reconcile_discrepancies SET status = 'superseded' WHERE status = 'open';
+
+-- name: InsertReconcileDiscrepancy :one
+INSERT INTO reconcile_discrepancies (run_id, kind, user_id, reference_type, reference_id, expected_cents, actual_cents, message)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id;
+
+-- name: ListReconcileDiscrepancies :many
+SELECT d.*, COALESCE(u.email, '')::text as email
+FROM reconcile_discrepancies d
+LEFT JOIN users u ON u.id = d.user_id
+WHERE (sqlc.narg(run_id)::int IS NULL OR d.run_id = sqlc.narg(run_id))
+AND (sqlc.narg(status)::text IS NULL OR d.status = sqlc.narg(status))
+AND (sqlc.narg(kind)::text IS NULL OR d.kind = sqlc.narg(kind))
+ORDER BY d.id DESC
+LIMIT sqlc.arg(lim);
+
+-- name: GetReconcileDiscrepancy :one
+SELECT * FROM reconcile_discrepancies WHERE id = $1;
+
+-- name: ResolveReconcileDiscrepancy :execrows
+UPDATE reconcile_discrepancies
+SET status = $2, resolution_note = $3, resolved_by = $4, resolved_at = NOW()
+WHERE id = $1 AND status = 'open';
+
+-- name: CountWallets :one
+SELECT COUNT(*) FROM wallets;
+
+-- name: GetWalletLedgerBalance :one
+SELECT COALESCE(-SUM(e.amount_cents), 0)::bigint as ledger_balance
+FROM ledger_accounts a
+JOIN ledger_entries e ON e.account_id = a.id
+WHERE a.user_id = $1;
+
+-- name: FixWalletBalance :execrows
+-- Only applies when the cached balance is still the one the run observed
+UPDATE wallets SET balance = sqlc.arg(balance)
+WHERE user_id = sqlc.arg(user_id) AND balance = sqlc.arg(observed);
+
+-- name: GetOpeningBalanceMismatches :many
+-- Wallets whose opening ledger balance, copied from wallets.balance when the
+-- ledger started, differs from what came before: their transactions less
+-- the orders, which were charged without one. API orders that failed then
+-- were credited back without one too, so they are left out.
+WITH ledger_start AS (SELECT MIN(created_at) as at FROM ledger_journals)
+SELECT w.user_id, COALESCE(op.cents, 0)::bigint as opening_cents,
+    (COALESCE(t.cents, 0) - COALESCE(c.cents, 0))::bigint as expected_cents
+FROM wallets w
+CROSS JOIN ledger_start s
+LEFT JOIN LATERAL (
+    SELECT SUM(-e.amount_cents) as cents
+    FROM ledger_journals j
+    JOIN ledger_entries e ON e.journal_id = j.id
+    JOIN ledger_accounts a ON a.id = e.account_id AND a.user_id = w.user_id
+    WHERE j.kind = 'opening_balance' AND j.reference_type = 'wallet' AND j.reference_id = w.user_id::text
+) op ON TRUE
+LEFT JOIN LATERAL (
+    SELECT ROUND(SUM(CASE WHEN t.type = 'debit' THEN -t.amount ELSE t.amount END) * 100)::bigint as cents
+    FROM transactions t
+    WHERE t.user_id = w.user_id AND t.created_at < s.at
+) t ON TRUE
+LEFT JOIN LATERAL (
+    SELECT SUM(o.amount_cents) as cents
+    FROM orders o
+    WHERE o.user_id = w.user_id AND o.created_at < s.at AND o.status <> 'failed'
+) c ON TRUE
+WHERE s.at IS NOT NULL
+AND COALESCE(op.cents, 0) <> COALESCE(t.cents, 0) - COALESCE(c.cents, 0)
+ORDER BY w.user_id;
+
+-- name: GetRefundCreditMismatches :many
+-- Orders whose refunded_amount differs from the refund credits in the
+-- user's transactions, matched by order or by order_refunds row
+SELECT o.id, o.user_id, COALESCE(o.refunded_amount, 0)::bigint as refunded_cents, COALESCE(c.credited, 0)::bigint as credited_cents
+FROM orders o
+LEFT JOIN LATERAL (
+    SELECT ROUND(SUM(t.amount) * 100)::bigint as credited
+    FROM transactions t
+    WHERE t.kind = 'order_refund' AND t.user_id = o.user_id
+    AND (
+        (t.reference_type = 'order' AND t.reference_id = o.id::text)
+        OR (t.reference_type = 'refund' AND t.reference_id IN (SELECT r.id::text FROM order_refunds r WHERE r.order_id = o.id))
+    )
+) c ON TRUE
+WHERE (COALESCE(o.refunded_amount, 0) > 0 OR c.credited IS NOT NULL)
+AND COALESCE(o.refunded_amount, 0) <> COALESCE(c.credited, 0)
+ORDER BY o.id;
+
+-- name: GetDepositCreditMismatches :many
+-- Approved requests without exactly one deposit credit, reversed ones not
+-- debited back in full, and credits for requests that were never approved.
+-- credited_cents is net of reversals.
+SELECT r.id, COALESCE(r.user_id, 0)::int as user_id, COALESCE(r.status, '')::text as status,
+    ROUND(r.amount * 100)::bigint as amount_cents,
+    COUNT(t.id) FILTER (WHERE t.type = 'credit') as credit_count,
+    COALESCE(ROUND(SUM(CASE WHEN t.type = 'debit' THEN -t.amount ELSE t.amount END) * 100), 0)::bigint as credited_cents
+FROM wallet_requests r
+LEFT JOIN transactions t ON t.kind = 'deposit' AND t.reference_type = 'wallet_request' AND t.reference_id = r.id::text
+GROUP BY r.id
+HAVING (r.status = 'approved' AND r.updated_at >= sqlc.arg(since)::timestamptz AND COUNT(t.id) <> 1)
+    OR (r.status = 'reversed' AND r.updated_at >= sqlc.arg(since)::timestamptz
+        AND (COUNT(t.id) FILTER (WHERE t.type = 'credit') <> 1 OR SUM(CASE WHEN t.type = 'debit' THEN -t.amount ELSE t.amount END) <> 0))
+    OR (r.status NOT IN ('approved', 'reversed') AND COUNT(t.id) > 0)
+ORDER BY r.id;
+
+-- name: ListOrderRefundCredits :many
+SELECT t.* FROM transactions t
+JOIN orders o ON o.id = $1::int AND t.user_id = o.user_id
+WHERE t.kind = 'order_refund'
+AND (
+    (t.reference_type = 'order' AND t.reference_id = $1::int::text)
+    OR (t.reference_type = 'refund' AND t.reference_id IN (SELECT r.id::text FROM order_refunds r WHERE r.order_id = $1::int))
+)
+ORDER BY t.created_at;
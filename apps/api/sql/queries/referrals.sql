@@ -0,0 +1,142 @@
+-- name: GetReferrerByCode :one
+SELECT id, COALESCE(email, '')::text as email, COALESCE(mobile, '')::text as mobile, signup_ip
+FROM users WHERE referral_code = $1;
+
+-- name: GetReferralCode :one
+SELECT COALESCE(referral_code, '')::text FROM users WHERE id = $1;
+
+-- name: CountReferralsFromIP :one
+SELECT COUNT(*) FROM referrals WHERE referrer_id = $1 AND signup_ip = $2;
+
+-- name: InsertReferral :exec
+INSERT INTO referrals (referred_id, referrer_id, signup_ip, flags, status)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (referred_id) DO NOTHING;
+
+-- name: GetReferralForUpdate :one
+SELECT * FROM referrals WHERE referred_id = $1 FOR UPDATE;
+
+-- name: UpdateReferralStatus :exec
+UPDATE referrals SET status = $2, reviewed_at = NOW(), reviewed_by = $3
+WHERE referred_id = $1;
+
+-- name: ListReferrals :many
+SELECT r.referred_id, COALESCE(ru.email, '')::text as referred_email, r.referrer_id, COALESCE(u.email, '')::text as referrer_email,
+    r.signup_ip, r.flags, r.status, r.created_at, r.reviewed_at,
+    COALESCE((SELECT SUM(c.amount_cents) FROM referral_commissions c WHERE c.referred_id = r.referred_id AND c.status <> 'rejected'), 0)::bigint as commission_cents
+FROM referrals r
+JOIN users u ON u.id = r.referrer_id
+JOIN users ru ON ru.id = r.referred_id
+WHERE (sqlc.narg(referrer_id)::int IS NULL OR r.referrer_id = sqlc.narg(referrer_id))
+AND (sqlc.narg(status)::text IS NULL OR r.status = sqlc.narg(status))
+ORDER BY r.created_at DESC
+LIMIT sqlc.arg(lim);
+
+-- name: GetReferralSummary :one
+-- The affiliate balance is the available commissions less payouts that are
+-- pending or paid
+SELECT
+    (SELECT COUNT(*) FROM referrals r WHERE r.referrer_id = sqlc.arg(user_id)) as referrals,
+    COALESCE((SELECT SUM(c.amount_cents) FROM referral_commissions c WHERE c.referrer_id = sqlc.arg(user_id) AND c.status = 'available'), 0)::bigint as earned_cents,
+    COALESCE((SELECT SUM(c.amount_cents) FROM referral_commissions c WHERE c.referrer_id = sqlc.arg(user_id) AND c.status = 'held'), 0)::bigint as held_cents,
+    COALESCE((SELECT SUM(p.amount_cents) FROM referral_payouts p WHERE p.user_id = sqlc.arg(user_id) AND p.status = 'pending'), 0)::bigint as pending_cents,
+    COALESCE((SELECT SUM(p.amount_cents) FROM referral_payouts p WHERE p.user_id = sqlc.arg(user_id) AND p.status = 'paid'), 0)::bigint as paid_cents;
+
+-- name: LockUserForUpdate :exec
+SELECT id FROM users WHERE id = $1 FOR UPDATE;
+
+-- name: ListReferralDepositSources :many
+-- Approved deposits by referred users, made after they signed up through
+-- the referral, that have no commission yet. The base is what the wallet
+-- was credited.
+SELECT r.referrer_id, r.referred_id, r.status as referral_status, w.id as source_id, ROUND(t.amount * 100)::bigint as base_cents
+FROM wallet_requests w
+JOIN referrals r ON r.referred_id = w.user_id
+JOIN transactions t ON t.kind = 'deposit' AND t.type = 'credit' AND t.reference_type = 'wallet_request' AND t.reference_id = w.id::text
+WHERE w.status = 'approved' AND w.created_at >= r.created_at
+AND NOT EXISTS (SELECT 1 FROM referral_commissions c WHERE c.source_type = 'deposit' AND c.source_id = w.id)
+ORDER BY w.id
+LIMIT $1;
+
+-- name: ListReferralOrderSources :many
+-- Finished orders by referred users, net of refunds, that have no
+-- commission yet
+SELECT r.referrer_id, r.referred_id, r.status as referral_status, o.id as source_id, (o.amount_cents - COALESCE(o.refunded_amount, 0))::bigint as base_cents
+FROM orders o
+JOIN referrals r ON r.referred_id = o.user_id
+WHERE o.status IN ('completed', 'partial') AND o.created_at >= r.created_at
+AND NOT EXISTS (SELECT 1 FROM referral_commissions c WHERE c.source_type = 'order' AND c.source_id = o.id)
+ORDER BY o.id
+LIMIT $1;
+
+-- name: InsertReferralCommission :one
+INSERT INTO referral_commissions (referrer_id, referred_id, source_type, source_id, base_cents, rate_pct, amount_cents, status)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (source_type, source_id) DO NOTHING
+RETURNING id;
+
+-- name: ListHeldReferralCommissions :many
+SELECT * FROM referral_commissions
+WHERE referred_id = $1 AND status = 'held'
+ORDER BY id
+FOR UPDATE;
+
+-- name: SetReferralCommissionStatus :exec
+UPDATE referral_commissions SET status = $2, released_at = NOW() WHERE id = $1;
+
+-- name: ReverseReferralCommission :one
+-- Claws back the commission earned on a source, returning the status it had
+WITH c AS (
+    SELECT id, status FROM referral_commissions
+    WHERE source_type = $1 AND source_id = $2 AND status IN ('available', 'held')
+    FOR UPDATE
+)
+UPDATE referral_commissions rc SET status = 'reversed'
+FROM c WHERE rc.id = c.id
+RETURNING rc.id, rc.amount_cents, c.status as previous_status;
+
+-- name: ListReferralCommissions :many
+SELECT * FROM referral_commissions
+WHERE (sqlc.narg(referrer_id)::int IS NULL OR referrer_id = sqlc.narg(referrer_id))
+AND (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(lim);
+
+-- name: InsertReferralPayout :one
+INSERT INTO referral_payouts (user_id, amount_cents, method, details, status)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetReferralPayoutForUpdate :one
+SELECT * FROM referral_payouts WHERE id = $1 FOR UPDATE;
+
+-- name: UpdateReferralPayoutStatus :execrows
+UPDATE referral_payouts SET status = $2, admin_note = $3, processed_by = $4, processed_at = NOW()
+WHERE id = $1 AND status = 'pending';
+
+-- name: ListReferralPayouts :many
+SELECT p.id, p.user_id, COALESCE(u.email, '')::text as email, p.amount_cents, p.method, p.details, p.status, p.admin_note, p.processed_at, p.created_at
+FROM referral_payouts p
+JOIN users u ON u.id = p.user_id
+WHERE (sqlc.narg(user_id)::int IS NULL OR p.user_id = sqlc.narg(user_id))
+AND (sqlc.narg(status)::text IS NULL OR p.status = sqlc.narg(status))
+ORDER BY p.created_at DESC, p.id DESC
+LIMIT sqlc.arg(lim);
+
+-- name: GetReferralReport :many
+-- Per referrer: referrals by review state, commission earned and held, the
+-- referred value it was earned on, and what has been withdrawn
+SELECT u.id, COALESCE(u.email, '')::text as email, COALESCE(u.referral_code, '')::text as referral_code,
+    COUNT(*) as referrals,
+    COUNT(*) FILTER (WHERE cardinality(r.flags) > 0) as flagged,
+    COUNT(*) FILTER (WHERE r.status = 'held') as held,
+    COUNT(*) FILTER (WHERE r.status = 'blocked') as blocked,
+    COALESCE((SELECT SUM(c.base_cents) FROM referral_commissions c WHERE c.referrer_id = u.id AND c.status = 'available'), 0)::bigint as base_cents,
+    COALESCE((SELECT SUM(c.amount_cents) FROM referral_commissions c WHERE c.referrer_id = u.id AND c.status = 'available'), 0)::bigint as earned_cents,
+    COALESCE((SELECT SUM(c.amount_cents) FROM referral_commissions c WHERE c.referrer_id = u.id AND c.status = 'held'), 0)::bigint as held_cents,
+    COALESCE((SELECT SUM(p.amount_cents) FROM referral_payouts p WHERE p.user_id = u.id AND p.status IN ('pending', 'paid')), 0)::bigint as withdrawn_cents
+FROM referrals r
+JOIN users u ON u.id = r.referrer_id
+GROUP BY u.id
+ORDER BY earned_cents DESC, u.id
+LIMIT $1;
@@ -0,0 +1,55 @@
+-- name: GetCatalogRefillPolicy :one
+SELECT * FROM catalog_refill_policies WHERE catalog_id = $1;
+
+-- name: ListCatalogRefillPolicies :many
+SELECT * FROM catalog_refill_policies ORDER BY catalog_id;
+
+-- name: UpsertCatalogRefillPolicy :one
+INSERT INTO catalog_refill_policies (catalog_id, auto_refill_enabled, auto_refill_drop_pct, refill_period_days, min_hours_between, allowed_statuses, max_refills)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (catalog_id) DO UPDATE SET
+    auto_refill_enabled = EXCLUDED.auto_refill_enabled,
+    auto_refill_drop_pct = EXCLUDED.auto_refill_drop_pct,
+    refill_period_days = EXCLUDED.refill_period_days,
+    min_hours_between = EXCLUDED.min_hours_between,
+    allowed_statuses = EXCLUDED.allowed_statuses,
+    max_refills = EXCLUDED.max_refills
+RETURNING *;
+
+-- name: GetOrderForAutoRefill :one
+SELECT
+	id,
+	user_id,
+	service_id,
+	status,
+	COALESCE(provider_order_id, '')::text as provider_order_id,
+	COALESCE(provider_key, '')::text as provider_key,
+	refills_remaining,
+	completed_at,
+	last_refill_at
+FROM orders
+WHERE id = $1
+FOR UPDATE;
+
+-- name: MarkOrderRefilled :exec
+UPDATE orders SET last_refill_at = NOW() WHERE id = $1;
+
+-- name: InsertOrderEvent :exec
+INSERT INTO order_events (order_id, event_type, message)
+VALUES ($1, $2, $3);
+
+-- name: GetOrderEvents :many
+SELECT * FROM order_events
+WHERE order_id = $1
+ORDER BY created_at ASC;
+
+-- name: CountOrderRefills :one
+SELECT COUNT(*) FROM order_requests
+WHERE order_id = $1 AND request_type = 'refill'
+  AND COALESCE(status, '') <> 'rejected'
+  AND COALESCE(refill_status, '') <> 'rejected';
+
+-- name: GetRefillRequestForUser :one
+SELECT id, order_id, status, provider_refill_id, refill_status
+FROM order_requests
+WHERE id = $1 AND user_id = $2 AND request_type = 'refill';
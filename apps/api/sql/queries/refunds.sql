@@ -0,0 +1,31 @@
+-- name: GetRefundRuleForService :one
+SELECT * FROM refund_rules
+WHERE catalog_id = $1 OR catalog_id IS NULL
+ORDER BY catalog_id NULLS LAST
+LIMIT 1;
+
+-- name: ListRefundRules :many
+SELECT * FROM refund_rules ORDER BY catalog_id NULLS FIRST;
+
+-- name: UpsertRefundRule :one
+INSERT INTO refund_rules (catalog_id, cancel_fee_pct, min_non_refundable_cents, rounding, partial_formula)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT ((COALESCE(catalog_id, 0))) DO UPDATE SET
+    cancel_fee_pct = EXCLUDED.cancel_fee_pct,
+    min_non_refundable_cents = EXCLUDED.min_non_refundable_cents,
+    rounding = EXCLUDED.rounding,
+    partial_formula = EXCLUDED.partial_formula
+RETURNING *;
+
+-- name: DeleteRefundRule :exec
+DELETE FROM refund_rules WHERE catalog_id = $1;
+
+-- name: InsertOrderRefund :one
+INSERT INTO order_refunds (order_id, user_id, kind, amount_cents, formula, rule_id)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetOrderRefunds :many
+SELECT * FROM order_refunds
+WHERE order_id = $1
+ORDER BY created_at ASC;
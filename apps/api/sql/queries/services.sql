@@ -12,7 +12,7 @@ SELECT COALESCE(SUM(
         WHEN status IN ('failed', 'canceled', 'refunded') THEN 0 
         ELSE amount_cents - COALESCE(refunded_amount, 0) 
     END
-), 0)::int FROM orders WHERE user_id = $1;
+), 0)::bigint FROM orders WHERE user_id = $1;
 
 -- name: UpsertServiceOverride :exec
 INSERT INTO service_overrides (
@@ -0,0 +1,32 @@
+-- name: ListProviderStatusMappings :many
+SELECT * FROM provider_status_mappings
+ORDER BY provider_key, raw_status;
+
+-- name: UpsertProviderStatusMapping :one
+INSERT INTO provider_status_mappings (provider_key, raw_status, local_status)
+VALUES ($1, $2, $3)
+ON CONFLICT (provider_key, raw_status) DO UPDATE SET
+    local_status = EXCLUDED.local_status
+RETURNING *;
+
+-- name: DeleteProviderStatusMapping :execrows
+DELETE FROM provider_status_mappings WHERE id = $1;
+
+-- name: RecordUnmappedStatus :exec
+INSERT INTO provider_unmapped_statuses (provider_key, raw_status, last_order_id)
+VALUES ($1, $2, $3)
+ON CONFLICT (provider_key, raw_status) DO UPDATE SET
+    occurrences = provider_unmapped_statuses.occurrences + 1,
+    last_order_id = EXCLUDED.last_order_id,
+    last_seen_at = NOW();
+
+-- name: ListUnmappedStatuses :many
+SELECT * FROM provider_unmapped_statuses
+ORDER BY last_seen_at DESC;
+
+-- name: DeleteUnmappedStatuses :exec
+-- Clears the report once a mapping covers the status. An empty provider
+-- key clears the status for every provider.
+DELETE FROM provider_unmapped_statuses
+WHERE raw_status = sqlc.arg(raw_status)
+  AND (sqlc.arg(provider_key)::text = '' OR provider_key = sqlc.arg(provider_key)::text);
@@ -0,0 +1,45 @@
+-- name: InsertSubAccount :one
+INSERT INTO sub_accounts (owner_id, user_id) VALUES ($1, $2)
+RETURNING *;
+
+-- name: GetSubAccountByUser :one
+-- The agency link of an account, active or invited
+SELECT * FROM sub_accounts WHERE user_id = $1;
+
+-- name: CountOwnedSubAccounts :one
+SELECT COUNT(*) FROM sub_accounts WHERE owner_id = $1;
+
+-- name: AcceptSubAccount :execrows
+UPDATE sub_accounts SET status = 'active', accepted_at = NOW()
+WHERE id = $1 AND user_id = $2 AND status = 'invited';
+
+-- name: DeleteSubAccount :execrows
+-- Either side can end a link or turn down an invitation
+DELETE FROM sub_accounts
+WHERE id = sqlc.arg(id) AND (owner_id = sqlc.arg(user_id) OR user_id = sqlc.arg(user_id));
+
+-- name: ListSubAccounts :many
+-- Links a user owns or belongs to, invitations included
+SELECT a.id, a.owner_id, COALESCE(o.username, '')::text as owner_username,
+    a.user_id, COALESCE(u.username, '')::text as username, COALESCE(u.name, '')::text as name,
+    a.status, a.created_at, a.accepted_at
+FROM sub_accounts a
+JOIN users o ON o.id = a.owner_id
+JOIN users u ON u.id = a.user_id
+WHERE a.owner_id = $1 OR a.user_id = $1
+ORDER BY a.created_at DESC, a.id DESC;
+
+-- name: InSameAgency :one
+-- Whether two accounts are an agency and one of its active sub-accounts,
+-- or two active sub-accounts of the same agency
+SELECT EXISTS (
+    SELECT 1 FROM sub_accounts a
+    WHERE a.status = 'active' AND (
+        (a.owner_id = sqlc.arg(a_id) AND a.user_id = sqlc.arg(b_id))
+        OR (a.owner_id = sqlc.arg(b_id) AND a.user_id = sqlc.arg(a_id))
+        OR (a.user_id = sqlc.arg(a_id) AND EXISTS (
+            SELECT 1 FROM sub_accounts b
+            WHERE b.status = 'active' AND b.owner_id = a.owner_id AND b.user_id = sqlc.arg(b_id)
+        ))
+    )
+)::bool as linked;
@@ -1,21 +1,47 @@
 -- name: GetOrdersForSync :many
-SELECT id, provider_order_id, status, COALESCE(provider_key, '')::text as provider_key 
-FROM orders 
-WHERE status IN ('pending', 'processing', 'submitted', 'in_progress', 'active', 'canceled', 'failed', 'completed', 'refunded') 
-AND provider_order_id IS NOT NULL 
+SELECT id, COALESCE(provider_order_id, '')::text as provider_order_id, status, COALESCE(provider_key, '')::text as provider_key, created_at
+FROM orders
+WHERE status IN ('pending', 'processing', 'submitted', 'in_progress', 'active')
+AND provider_order_id IS NOT NULL
 AND provider_order_id != ''
-AND created_at > NOW() - INTERVAL '7 days'
-LIMIT 100;
+AND (next_sync_at IS NULL OR next_sync_at <= NOW())
+ORDER BY next_sync_at ASC NULLS FIRST
+LIMIT $1;
 
 -- name: GetOrderForSyncUpdate :one
-SELECT amount_cents, user_id, quantity, status FROM orders WHERE id = $1;
+SELECT amount_cents, user_id, quantity, status, service_id, COALESCE(refunded_amount, 0)::bigint as refunded_amount FROM orders WHERE id = $1 FOR UPDATE;
 
 -- name: UpdateOrderSyncWithRefund :exec
 UPDATE orders 
-SET status = $1, remains = $2, start_count = $3, refunded_amount = COALESCE(refunded_amount, 0) + $4
+SET status = $1, remains = $2, start_count = $3, refunded_amount = COALESCE(refunded_amount, 0) + $4,
+    provider_status = $6, sync_error_count = 0, last_sync_error = NULL,
+    completed_at = CASE WHEN $1 IN ('completed', 'partial') THEN COALESCE(completed_at, NOW()) ELSE completed_at END
 WHERE id = $5;
 
 -- name: UpdateOrderSyncNoRefund :exec
 UPDATE orders 
-SET status = $1, remains = $2, start_count = $3 
+SET status = $1, remains = $2, start_count = $3, provider_status = $5,
+    sync_error_count = 0, last_sync_error = NULL,
+    completed_at = CASE WHEN $1 IN ('completed', 'partial') THEN COALESCE(completed_at, NOW()) ELSE completed_at END
 WHERE id = $4;
+
+-- name: ScheduleOrderSync :exec
+UPDATE orders
+SET next_sync_at = $2, last_synced_at = NOW()
+WHERE id = $1;
+
+-- name: RecordOrderSyncError :one
+UPDATE orders
+SET sync_error_count = sync_error_count + 1, last_sync_error = $2
+WHERE id = $1
+RETURNING sync_error_count;
+
+-- name: MarkOrderNeedsAttention :execrows
+UPDATE orders
+SET status = 'needs_attention', next_sync_at = NULL
+WHERE id = $1 AND status IN ('pending', 'processing', 'submitted', 'in_progress', 'active');
+
+-- name: ResumeOrderSync :execrows
+UPDATE orders
+SET status = 'processing', sync_error_count = 0, last_sync_error = NULL, next_sync_at = NULL
+WHERE id = $1 AND status = 'needs_attention';
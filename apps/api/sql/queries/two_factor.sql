@@ -0,0 +1,22 @@
+-- name: GetTwoFactor :one
+SELECT * FROM user_two_factor WHERE user_id = $1;
+
+-- name: SetTwoFactorSecret :execrows
+-- Starts or restarts setting up two-factor authentication; a secret that is
+-- already turned on is left alone
+INSERT INTO user_two_factor (user_id, secret) VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_step = 0, created_at = NOW()
+WHERE user_two_factor.enabled_at IS NULL;
+
+-- name: EnableTwoFactor :execrows
+UPDATE user_two_factor SET enabled_at = NOW(), last_step = $2
+WHERE user_id = $1 AND enabled_at IS NULL AND last_step < $2;
+
+-- name: UseTwoFactorStep :execrows
+-- Records an accepted code; fails when a code of this or a later step was
+-- already used
+UPDATE user_two_factor SET last_step = $2
+WHERE user_id = $1 AND enabled_at IS NOT NULL AND last_step < $2;
+
+-- name: DeleteTwoFactor :exec
+DELETE FROM user_two_factor WHERE user_id = $1;
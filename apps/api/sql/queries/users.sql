@@ -1,14 +1,14 @@
 -- name: GetUsers :many
 SELECT 
 	u.id, u.name, COALESCE(u.username, '')::text as username, u.email, COALESCE(u.mobile, '')::text as mobile, u.role, COALESCE(u.currency, 'USD')::text as currency, u.created_at,
-	COALESCE(w.balance, 0)::int as balance,
+	COALESCE(w.balance, 0)::bigint as balance,
 	(SELECT COUNT(*)::int FROM orders o WHERE o.user_id = u.id) as order_count,
 	(SELECT COALESCE(SUM(
 		CASE 
 			WHEN o.status IN ('failed', 'canceled', 'refunded') THEN 0 
 			ELSE o.amount_cents 
 		END
-	), 0)::int FROM orders o WHERE o.user_id = u.id) as total_spend
+	), 0)::bigint FROM orders o WHERE o.user_id = u.id) as total_spend
 FROM users u
 LEFT JOIN wallets w ON u.id = w.user_id
 WHERE (@search::text = '' OR u.name ILIKE '%' || @search || '%' OR u.email ILIKE '%' || @search || '%')
@@ -18,14 +18,14 @@ LIMIT $1 OFFSET $2;
 -- name: GetUserAdmin :one
 SELECT 
 	u.id, u.name, COALESCE(u.username, '')::text as username, u.email, COALESCE(u.mobile, '')::text as mobile, u.role, COALESCE(u.currency, 'USD')::text as currency, u.created_at,
-	COALESCE(w.balance, 0)::int as balance,
+	COALESCE(w.balance, 0)::bigint as balance,
 	(SELECT COUNT(*)::int FROM orders o WHERE o.user_id = u.id) as order_count,
 	(SELECT COALESCE(SUM(
 		CASE 
 			WHEN o.status IN ('failed', 'canceled', 'refunded') THEN 0 
 			ELSE o.amount_cents 
 		END
-	), 0)::int FROM orders o WHERE o.user_id = u.id) as total_spend
+	), 0)::bigint FROM orders o WHERE o.user_id = u.id) as total_spend
 FROM users u
 LEFT JOIN wallets w ON u.id = w.user_id
 WHERE u.id = $1;
@@ -38,7 +38,7 @@ ORDER BY created_at DESC
 LIMIT 5;
 
 -- name: GetUserTransactionsAdmin :many
-SELECT id, amount, type, description, created_at 
+SELECT id, amount, type, description, created_at, kind, reference_type, reference_id
 FROM transactions 
 WHERE user_id = $1 
 ORDER BY created_at DESC 
@@ -62,5 +62,5 @@ WHERE id = $1;
 
 
 -- name: GetUserProfile :one
-SELECT u.id, u.name, u.email, u.role, COALESCE(u.api_key, '')::text as api_key, COALESCE(w.balance, 0)::int as balance
+SELECT u.id, u.name, u.email, u.role, COALESCE(u.api_key, '')::text as api_key, COALESCE(w.balance, 0)::bigint as balance
 FROM users u LEFT JOIN wallets w ON u.id = w.user_id WHERE u.email = $1;
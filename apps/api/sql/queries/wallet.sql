@@ -12,8 +12,8 @@ WHERE user_id = $1 AND status = 'pending';
 SELECT 1 FROM wallet_requests WHERE transaction_id=$1;
 
 -- name: InsertWalletRequest :one
-INSERT INTO wallet_requests (user_id, amount, unique_amount, method, transaction_id, status)
-VALUES ($1, $2, $3, $4, $5, 'pending')
+INSERT INTO wallet_requests (user_id, amount, unique_amount, method, transaction_id, promo_code_id, status)
+VALUES ($1, $2, $3, $4, $5, $6, 'pending')
 RETURNING id;
 
 -- name: UpdateDepositUTR :execrows
@@ -50,9 +50,17 @@ INSERT INTO wallets (user_id, balance) VALUES ($1, $2)
 ON CONFLICT (user_id)
 DO UPDATE SET balance = wallets.balance + $2, updated_at = NOW();
 
+-- name: InsertWalletAdjustment :one
+INSERT INTO wallet_adjustments (user_id, admin_id, amount_cents, description)
+VALUES ($1, $2, $3, $4)
+RETURNING id;
+
+-- name: MarkWalletAdjustmentTopUp :exec
+UPDATE wallet_adjustments SET top_up = TRUE WHERE id = $1;
+
 -- name: InsertTransaction :exec
-INSERT INTO transactions (user_id, amount, type, description)
-VALUES ($1, $2, $3, $4);
+INSERT INTO transactions (user_id, amount, type, kind, description, reference_type, reference_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
 
 -- name: InsertUPINotificationMatched :exec
 INSERT INTO upi_notifications (amount, utr, sender_upi, raw_text, matched_request_id, status)
@@ -65,22 +73,36 @@ JOIN users u ON r.user_id = u.id
 ORDER BY r.created_at DESC;
 
 -- name: GetWalletRequestForUpdateAdmin :one
-SELECT user_id, amount, status FROM wallet_requests WHERE id=$1 FOR UPDATE;
+SELECT user_id, amount, method, status FROM wallet_requests WHERE id=$1 FOR UPDATE;
 
 -- name: RejectWalletRequest :exec
 UPDATE wallet_requests SET status='rejected', updated_at=NOW() WHERE id=$1 AND status='pending';
 
+-- name: ReverseWalletRequest :execrows
+UPDATE wallet_requests SET status='reversed', updated_at=NOW() WHERE id=$1 AND status='approved';
+
 -- name: GetDepositStatus :one
 SELECT status FROM wallet_requests WHERE id=$1 AND user_id=$2;
 
--- name: DebitWallet :exec
-UPDATE wallets SET balance = balance - $1 WHERE user_id = $2;
+-- name: DebitWallet :execrows
+-- Only succeeds when the available balance covers the debit
+UPDATE wallets SET balance = balance - $1 WHERE user_id = $2 AND balance - held_cents >= $1;
 
 -- name: GetWalletTransactions :many
-SELECT * FROM transactions WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3;
+SELECT * FROM transactions
+WHERE user_id = sqlc.arg(user_id)
+AND (sqlc.narg(kind)::text IS NULL OR kind = sqlc.narg(kind))
+AND (sqlc.narg(reference_type)::text IS NULL OR reference_type = sqlc.narg(reference_type))
+AND (sqlc.narg(reference_id)::text IS NULL OR reference_id = sqlc.narg(reference_id))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(lim) OFFSET sqlc.arg(off);
 
 -- name: CountWalletTransactions :one
-SELECT COUNT(*) FROM transactions WHERE user_id = $1;
+SELECT COUNT(*) FROM transactions
+WHERE user_id = sqlc.arg(user_id)
+AND (sqlc.narg(kind)::text IS NULL OR kind = sqlc.narg(kind))
+AND (sqlc.narg(reference_type)::text IS NULL OR reference_type = sqlc.narg(reference_type))
+AND (sqlc.narg(reference_id)::text IS NULL OR reference_id = sqlc.narg(reference_id));
 
 -- name: GetUnmatchedUPINotification :one
 SELECT id, amount, utr, sender_upi FROM upi_notifications
@@ -104,8 +126,9 @@ UPDATE wallets SET balance = balance + $1 WHERE user_id = $2;
 SELECT balance FROM wallets WHERE user_id = $1;
 
 -- name: CreateCryptomusWalletRequest :one
-INSERT INTO wallet_requests (user_id, amount, method, status)
-VALUES ($1, $2, 'cryptomus', 'pending')
+-- amount is the INR credit, pay_amount what is charged in pay_currency
+INSERT INTO wallet_requests (user_id, amount, pay_amount, pay_currency, promo_code_id, method, status)
+VALUES ($1, $2, $3, $4, $5, 'cryptomus', 'pending')
 RETURNING id;
 
 -- name: UpdateCryptomusTransactionID :exec
@@ -114,21 +137,21 @@ UPDATE wallet_requests SET transaction_id=$1 WHERE id=$2;
 -- name: GetWalletRequestStatus :one
 SELECT status FROM wallet_requests WHERE id=$1;
 
--- name: ApproveCryptomusWalletRequest :exec
-UPDATE wallet_requests SET status='approved', updated_at=NOW() WHERE id=$1;
+-- name: ApproveCryptomusWalletRequest :execrows
+UPDATE wallet_requests SET status='approved', updated_at=NOW() WHERE id=$1 AND status='pending';
 
 -- name: GetWalletRequestAmount :one
 SELECT amount FROM wallet_requests WHERE id=$1;
 
 -- name: GetRecentMoneyTransactions :many
-SELECT id, amount, type, description, created_at
+SELECT id, amount, type, description, created_at, kind, reference_type, reference_id
 FROM transactions
 WHERE user_id = $1 AND type = 'credit'
 ORDER BY created_at DESC
 LIMIT 3;
 
 -- name: GetAllMoneyTransactions :many
-SELECT id, amount, type, description, created_at
+SELECT id, amount, type, description, created_at, kind, reference_type, reference_id
 FROM transactions
 WHERE user_id = $1 AND type = 'credit'
 ORDER BY created_at DESC
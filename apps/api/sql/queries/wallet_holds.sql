@@ -0,0 +1,43 @@
+-- name: ReserveWalletFunds :execrows
+-- Only succeeds when the available balance covers the hold
+UPDATE wallets SET held_cents = held_cents + $1
+WHERE user_id = $2 AND balance - held_cents >= $1;
+
+-- name: ReleaseWalletFunds :exec
+UPDATE wallets SET held_cents = held_cents - $1 WHERE user_id = $2;
+
+-- name: CaptureWalletFunds :exec
+-- Turns held funds into a debit
+UPDATE wallets SET held_cents = held_cents - $1, balance = balance - $1 WHERE user_id = $2;
+
+-- name: InsertWalletHold :one
+INSERT INTO wallet_holds (user_id, amount_cents, reference_type, reference_id, description)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id;
+
+-- name: GetActiveWalletHoldForUpdate :one
+SELECT * FROM wallet_holds
+WHERE reference_type = $1 AND reference_id = $2 AND status = 'active'
+FOR UPDATE;
+
+-- name: ResolveWalletHold :execrows
+UPDATE wallet_holds SET status = $2, resolved_at = NOW() WHERE id = $1 AND status = 'active';
+
+-- name: GetWalletFundsForUpdate :one
+SELECT balance, held_cents FROM wallets WHERE user_id = $1 FOR UPDATE;
+
+-- name: GetWalletFunds :one
+SELECT COALESCE(w.balance, 0)::bigint as balance, COALESCE(w.held_cents, 0)::bigint as held_cents
+FROM users u LEFT JOIN wallets w ON w.user_id = u.id
+WHERE u.id = $1;
+
+-- name: ListActiveWalletHolds :many
+SELECT * FROM wallet_holds WHERE user_id = $1 AND status = 'active' ORDER BY created_at DESC, id DESC;
+
+-- name: ListStaleOrderHolds :many
+-- Orders whose hold is still active well after they were placed
+SELECT reference_id::int as order_id FROM wallet_holds
+WHERE reference_type = 'order' AND status = 'active'
+AND created_at < NOW() - (sqlc.arg('min_age_minutes')::int * INTERVAL '1 minute')
+ORDER BY created_at ASC
+LIMIT sqlc.arg('lim');
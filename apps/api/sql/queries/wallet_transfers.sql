@@ -0,0 +1,38 @@
+-- name: FindTransferRecipient :one
+-- An exact username match wins over an email that happens to look like one
+SELECT id, COALESCE(username, '')::text as username, COALESCE(name, '')::text as name
+FROM users
+WHERE LOWER(username) = LOWER(sqlc.arg(login)::text) OR LOWER(email) = LOWER(sqlc.arg(login)::text)
+ORDER BY (LOWER(username) = LOWER(sqlc.arg(login)::text)) DESC
+LIMIT 1;
+
+-- name: GetTransferredSince :one
+-- What a user has sent, or is waiting to send, since a point in time
+SELECT COALESCE(SUM(amount_cents), 0)::bigint
+FROM wallet_transfers
+WHERE sender_id = sqlc.arg(sender_id) AND status IN ('pending', 'completed') AND created_at >= sqlc.arg(since);
+
+-- name: InsertWalletTransfer :one
+INSERT INTO wallet_transfers (sender_id, recipient_id, amount_cents, note, status)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetWalletTransferForUpdate :one
+SELECT * FROM wallet_transfers WHERE id = $1 FOR UPDATE;
+
+-- name: UpdateWalletTransferStatus :execrows
+UPDATE wallet_transfers SET status = $2, admin_note = $3, processed_by = $4, processed_at = NOW()
+WHERE id = $1 AND status = 'pending';
+
+-- name: ListWalletTransfers :many
+-- Transfers sent or received by a user, or everyone's without user_id
+SELECT t.id, t.sender_id, COALESCE(s.username, '')::text as sender_username, COALESCE(s.email, '')::text as sender_email,
+    t.recipient_id, COALESCE(rc.username, '')::text as recipient_username, COALESCE(rc.email, '')::text as recipient_email,
+    t.amount_cents, t.note, t.status, t.admin_note, t.processed_at, t.created_at
+FROM wallet_transfers t
+JOIN users s ON s.id = t.sender_id
+JOIN users rc ON rc.id = t.recipient_id
+WHERE (sqlc.narg(user_id)::int IS NULL OR t.sender_id = sqlc.narg(user_id) OR t.recipient_id = sqlc.narg(user_id))
+AND (sqlc.narg(status)::text IS NULL OR t.status = sqlc.narg(status))
+ORDER BY t.created_at DESC, t.id DESC
+LIMIT sqlc.arg(lim);
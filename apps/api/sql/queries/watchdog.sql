@@ -0,0 +1,55 @@
+-- name: GetWatchdogCandidates :many
+SELECT
+	id,
+	user_id,
+	service_id,
+	status,
+	quantity,
+	amount_cents,
+	COALESCE(remains, 0)::int as remains,
+	COALESCE(refunded_amount, 0)::bigint as refunded_amount,
+	COALESCE(provider_order_id, '')::text as provider_order_id,
+	COALESCE(provider_key, '')::text as provider_key,
+	COALESCE(watchdog_stage, '')::text as watchdog_stage,
+	created_at
+FROM orders
+WHERE status IN ('pending', 'submitted', 'processing', 'active', 'in_progress')
+  AND created_at < NOW() - (sqlc.arg('min_age_minutes')::int * INTERVAL '1 minute')
+  AND COALESCE(watchdog_stage, '') NOT IN ('escalated', 'refunded')
+  AND (watchdog_checked_at IS NULL OR watchdog_checked_at < NOW() - INTERVAL '30 minutes')
+ORDER BY created_at ASC
+LIMIT sqlc.arg('limit');
+
+-- name: SetOrderWatchdogStage :exec
+UPDATE orders
+SET watchdog_stage = $2, watchdog_checked_at = NOW()
+WHERE id = $1;
+
+-- name: TouchOrderWatchdogCheck :exec
+-- Orders looked at but not yet overdue wait out the re-check interval like
+-- the rest, so they do not crowd newer candidates out of the batch
+UPDATE orders
+SET watchdog_checked_at = NOW()
+WHERE id = ANY(sqlc.arg('ids')::int[]);
+
+-- name: InsertOrderEscalation :exec
+INSERT INTO order_escalations (order_id, reason)
+VALUES ($1, $2)
+ON CONFLICT (order_id) WHERE status = 'open' DO NOTHING;
+
+-- name: ListOrderEscalations :many
+SELECT e.id, e.order_id, e.reason, e.status, e.resolution_note, e.resolved_at, e.created_at,
+       o.service_id, o.status as order_status, o.quantity, o.amount_cents,
+       COALESCE(o.provider_order_id, '')::text as provider_order_id,
+       COALESCE(o.provider_key, '')::text as provider_key,
+       COALESCE(u.email, '')::text as email
+FROM order_escalations e
+JOIN orders o ON e.order_id = o.id
+LEFT JOIN users u ON o.user_id = u.id
+WHERE e.status = $1
+ORDER BY e.created_at ASC;
+
+-- name: ResolveOrderEscalation :execrows
+UPDATE order_escalations
+SET status = 'resolved', resolution_note = $2, resolved_by = $3, resolved_at = NOW()
+WHERE id = $1 AND status = 'open';
@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS canonical_link TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS target_kind TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS target_id TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_orders_target ON orders(target_id, service_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_orders_target;
+ALTER TABLE orders DROP COLUMN IF EXISTS target_id;
+ALTER TABLE orders DROP COLUMN IF EXISTS target_kind;
+ALTER TABLE orders DROP COLUMN IF EXISTS canonical_link;
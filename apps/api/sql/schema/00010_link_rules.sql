@@ -0,0 +1,47 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS link_rules (
+    id SERIAL PRIMARY KEY,
+    platform TEXT NOT NULL,
+    service_type TEXT NOT NULL DEFAULT '*',
+    variant TEXT NOT NULL DEFAULT '*',
+    allowed_kinds TEXT[] NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (platform, service_type, variant)
+);
+
+DROP TRIGGER IF EXISTS update_link_rules_updated_at ON link_rules;
+CREATE TRIGGER update_link_rules_updated_at
+BEFORE UPDATE ON link_rules
+FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+INSERT INTO link_rules (platform, service_type, variant, allowed_kinds, message) VALUES
+    ('instagram', 'followers', '*', '{profile}', 'Followers orders need an Instagram profile link, e.g. instagram.com/username'),
+    ('instagram', 'likes', '*', '{post,reel,video}', 'Likes orders need an Instagram post or reel link, not a profile'),
+    ('instagram', 'comments', '*', '{post,reel,video}', 'Comments orders need an Instagram post or reel link, not a profile'),
+    ('instagram', 'shares', '*', '{post,reel,video}', 'Shares orders need an Instagram post or reel link'),
+    ('instagram', 'saves', '*', '{post,reel,video}', 'Saves orders need an Instagram post or reel link'),
+    ('instagram', 'views', '*', '{reel,video,post,story,profile}', 'Views orders need an Instagram reel, video or story link'),
+    ('instagram', 'views', 'reel', '{reel}', 'Reel views need an Instagram reel link, e.g. instagram.com/reel/...'),
+    ('instagram', 'views', 'story', '{story,profile}', 'Story views need an Instagram profile or story link'),
+    ('facebook', 'followers', '*', '{profile,channel}', 'Followers orders need a Facebook page, profile or group link'),
+    ('facebook', 'likes', '*', '{profile,post,video,reel}', 'Likes orders need a Facebook page, post or video link'),
+    ('facebook', 'views', '*', '{video,reel}', 'Views orders need a Facebook video or reel link'),
+    ('youtube', 'followers', '*', '{channel}', 'Subscribers orders need a YouTube channel link, e.g. youtube.com/@channel'),
+    ('youtube', 'views', '*', '{video,short}', 'Views orders need a YouTube video or Shorts link'),
+    ('youtube', 'likes', '*', '{video,short,post}', 'Likes orders need a YouTube video, Shorts or community post link'),
+    ('youtube', 'comments', '*', '{video,short}', 'Comments orders need a YouTube video or Shorts link'),
+    ('tiktok', 'followers', '*', '{profile}', 'Followers orders need a TikTok profile link, e.g. tiktok.com/@username'),
+    ('tiktok', '*', '*', '{video,post}', 'This service needs a TikTok video link, not a profile'),
+    ('x', 'followers', '*', '{profile}', 'Followers orders need an X profile link, e.g. x.com/username'),
+    ('x', '*', '*', '{post}', 'This service needs a link to an X post, not a profile'),
+    ('telegram', 'followers', '*', '{channel}', 'Members orders need a public Telegram channel or group link, e.g. t.me/channelname. Private invite links are not supported'),
+    ('telegram', 'members', '*', '{channel}', 'Members orders need a public Telegram channel or group link, e.g. t.me/channelname. Private invite links are not supported'),
+    ('telegram', 'views', '*', '{post}', 'Views orders need a link to a public Telegram post, e.g. t.me/channelname/123'),
+    ('telegram', 'likes', '*', '{post}', 'Reactions orders need a link to a public Telegram post, e.g. t.me/channelname/123')
+ON CONFLICT (platform, service_type, variant) DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS link_rules CASCADE;
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS own_start_count INTEGER;
+
+INSERT INTO global_settings (key, value) VALUES ('preflight_target_check', 'false')
+ON CONFLICT (key) DO NOTHING;
+
+-- +goose Down
+DELETE FROM global_settings WHERE key = 'preflight_target_check';
+ALTER TABLE orders DROP COLUMN IF EXISTS own_start_count;
@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP WITH TIME ZONE;
+
+-- Orders completed before this have no record of when; they are left NULL
+-- rather than guessed.
+
+CREATE TABLE IF NOT EXISTS order_delivery_checks (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    start_count INTEGER NOT NULL,
+    delivered INTEGER NOT NULL,
+    expected_count INTEGER NOT NULL,
+    observed_count INTEGER NOT NULL,
+    drop_pct DOUBLE PRECISION NOT NULL DEFAULT 0,
+    checked_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_delivery_checks_order ON order_delivery_checks(order_id, checked_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS order_delivery_checks;
+ALTER TABLE orders DROP COLUMN IF EXISTS completed_at;
@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS catalog_refill_policies (
+    catalog_id INTEGER PRIMARY KEY REFERENCES pablo_catalog(id) ON DELETE CASCADE,
+    auto_refill_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    auto_refill_drop_pct DOUBLE PRECISION NOT NULL DEFAULT 10,
+    refill_period_days INTEGER NOT NULL DEFAULT 30,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+DROP TRIGGER IF EXISTS update_catalog_refill_policies_updated_at ON catalog_refill_policies;
+CREATE TRIGGER update_catalog_refill_policies_updated_at
+BEFORE UPDATE ON catalog_refill_policies
+FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+ALTER TABLE smm_providers ADD COLUMN IF NOT EXISTS refill_cooldown_hours INTEGER NOT NULL DEFAULT 24;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS last_refill_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS order_events (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    event_type TEXT NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_events_order ON order_events(order_id, created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS order_events;
+ALTER TABLE orders DROP COLUMN IF EXISTS last_refill_at;
+ALTER TABLE smm_providers DROP COLUMN IF EXISTS refill_cooldown_hours;
+DROP TABLE IF EXISTS catalog_refill_policies;
@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE order_requests ADD COLUMN IF NOT EXISTS provider_refill_id TEXT;
+ALTER TABLE order_requests ADD COLUMN IF NOT EXISTS refill_status TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_order_requests_refill_status ON order_requests(refill_status)
+WHERE provider_refill_id IS NOT NULL;
+
+ALTER TABLE smm_providers ADD COLUMN IF NOT EXISTS supports_multi_refill BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE smm_providers DROP COLUMN IF EXISTS supports_multi_refill;
+DROP INDEX IF EXISTS idx_order_requests_refill_status;
+ALTER TABLE order_requests DROP COLUMN IF EXISTS refill_status;
+ALTER TABLE order_requests DROP COLUMN IF EXISTS provider_refill_id;
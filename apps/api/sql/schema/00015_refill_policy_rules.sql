@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE catalog_refill_policies ADD COLUMN IF NOT EXISTS min_hours_between INTEGER NOT NULL DEFAULT 24;
+ALTER TABLE catalog_refill_policies ADD COLUMN IF NOT EXISTS allowed_statuses TEXT[] NOT NULL DEFAULT ARRAY['completed', 'partial'];
+ALTER TABLE catalog_refill_policies ADD COLUMN IF NOT EXISTS max_refills INTEGER NOT NULL DEFAULT 3;
+
+-- +goose Down
+ALTER TABLE catalog_refill_policies DROP COLUMN IF EXISTS max_refills;
+ALTER TABLE catalog_refill_policies DROP COLUMN IF EXISTS allowed_statuses;
+ALTER TABLE catalog_refill_policies DROP COLUMN IF EXISTS min_hours_between;
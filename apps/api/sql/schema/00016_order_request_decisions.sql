@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE order_requests ADD COLUMN IF NOT EXISTS decision_reason TEXT;
+ALTER TABLE order_requests ADD COLUMN IF NOT EXISTS decided_by INTEGER REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE order_requests ADD COLUMN IF NOT EXISTS decided_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+    id SERIAL PRIMARY KEY,
+    admin_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL,
+    entity_type TEXT NOT NULL,
+    entity_id TEXT NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_entity ON admin_audit_log(entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_created ON admin_audit_log(created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS admin_audit_log;
+ALTER TABLE order_requests DROP COLUMN IF EXISTS decided_at;
+ALTER TABLE order_requests DROP COLUMN IF EXISTS decided_by;
+ALTER TABLE order_requests DROP COLUMN IF EXISTS decision_reason;
@@ -0,0 +1,39 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS refund_rules (
+    id SERIAL PRIMARY KEY,
+    catalog_id INTEGER REFERENCES pablo_catalog(id) ON DELETE CASCADE,
+    cancel_fee_pct DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (cancel_fee_pct >= 0 AND cancel_fee_pct <= 100),
+    min_non_refundable_cents INTEGER NOT NULL DEFAULT 0 CHECK (min_non_refundable_cents >= 0),
+    rounding TEXT NOT NULL DEFAULT 'down' CHECK (rounding IN ('down', 'up', 'nearest')),
+    partial_formula TEXT NOT NULL DEFAULT 'proportional' CHECK (partial_formula IN ('proportional', 'proportional_after_fee', 'none')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One global rule (catalog_id NULL) and at most one rule per catalog service
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refund_rules_catalog ON refund_rules ((COALESCE(catalog_id, 0)));
+
+DROP TRIGGER IF EXISTS update_refund_rules_updated_at ON refund_rules;
+CREATE TRIGGER update_refund_rules_updated_at
+BEFORE UPDATE ON refund_rules
+FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+INSERT INTO refund_rules (catalog_id) VALUES (NULL)
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS order_refunds (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    amount_cents INTEGER NOT NULL,
+    formula TEXT NOT NULL,
+    rule_id INTEGER REFERENCES refund_rules(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_refunds_order ON order_refunds(order_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS order_refunds;
+DROP TABLE IF EXISTS refund_rules;
@@ -0,0 +1,33 @@
+-- +goose Up
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS watchdog_stage TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS watchdog_checked_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS order_escalations (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved')),
+    resolution_note TEXT,
+    resolved_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- At most one open escalation per order
+CREATE UNIQUE INDEX IF NOT EXISTS idx_order_escalations_open ON order_escalations(order_id) WHERE status = 'open';
+
+-- SLA = average_time (minutes) x factor, never below the minimum. Services
+-- without an average time use the default. The final action is either
+-- 'refund' or 'escalate'.
+INSERT INTO global_settings (key, value) VALUES
+    ('watchdog_sla_factor', '3'),
+    ('watchdog_min_sla_minutes', '60'),
+    ('watchdog_default_sla_minutes', '1440'),
+    ('watchdog_final_action', 'escalate')
+ON CONFLICT (key) DO NOTHING;
+
+-- +goose Down
+DELETE FROM global_settings WHERE key IN ('watchdog_sla_factor', 'watchdog_min_sla_minutes', 'watchdog_default_sla_minutes', 'watchdog_final_action');
+DROP TABLE IF EXISTS order_escalations;
+ALTER TABLE orders DROP COLUMN IF EXISTS watchdog_checked_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS watchdog_stage;
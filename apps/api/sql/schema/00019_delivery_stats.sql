@@ -0,0 +1,6 @@
+-- +goose Up
+-- Delivery statistics aggregate finished orders over a recent window
+CREATE INDEX IF NOT EXISTS idx_orders_created_status ON orders(created_at, status);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_orders_created_status;
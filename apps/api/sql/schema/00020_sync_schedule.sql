@@ -0,0 +1,16 @@
+-- +goose Up
+-- Orders are polled on a schedule that slows down as they age. NULL means
+-- the order has never been synced and is due now.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS next_sync_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS last_synced_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_orders_next_sync ON orders(next_sync_at)
+WHERE status IN ('pending', 'processing', 'submitted', 'in_progress', 'active');
+
+ALTER TABLE smm_providers ADD COLUMN IF NOT EXISTS max_concurrency INTEGER NOT NULL DEFAULT 2;
+
+-- +goose Down
+ALTER TABLE smm_providers DROP COLUMN IF EXISTS max_concurrency;
+DROP INDEX IF EXISTS idx_orders_next_sync;
+ALTER TABLE orders DROP COLUMN IF EXISTS last_synced_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS next_sync_at;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- provider_status keeps the panel's raw status string next to our mapped one
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS provider_status TEXT;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS sync_error_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS last_sync_error TEXT;
+
+-- Consecutive per-order provider errors before an order needs an admin
+INSERT INTO global_settings (key, value) VALUES ('sync_error_threshold', '5')
+ON CONFLICT (key) DO NOTHING;
+
+-- +goose Down
+DELETE FROM global_settings WHERE key = 'sync_error_threshold';
+ALTER TABLE orders DROP COLUMN IF EXISTS last_sync_error;
+ALTER TABLE orders DROP COLUMN IF EXISTS sync_error_count;
+ALTER TABLE orders DROP COLUMN IF EXISTS provider_status;
@@ -0,0 +1,50 @@
+-- +goose Up
+-- Maps a panel's raw order status (lowercased) to our order status. Rows
+-- with an empty provider_key apply to every provider unless overridden.
+CREATE TABLE IF NOT EXISTS provider_status_mappings (
+    id SERIAL PRIMARY KEY,
+    provider_key TEXT NOT NULL DEFAULT '',
+    raw_status TEXT NOT NULL,
+    local_status TEXT NOT NULL CHECK (local_status IN ('pending', 'processing', 'active', 'completed', 'partial', 'canceled', 'failed')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider_key, raw_status)
+);
+
+DROP TRIGGER IF EXISTS update_provider_status_mappings_updated_at ON provider_status_mappings;
+CREATE TRIGGER update_provider_status_mappings_updated_at
+BEFORE UPDATE ON provider_status_mappings
+FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+INSERT INTO provider_status_mappings (provider_key, raw_status, local_status) VALUES
+    ('', 'completed', 'completed'),
+    ('', 'complete', 'completed'),
+    ('', 'pending', 'pending'),
+    ('', 'processing', 'processing'),
+    ('', 'inprogress', 'active'),
+    ('', 'in progress', 'active'),
+    ('', 'active', 'active'),
+    ('', 'canceled', 'canceled'),
+    ('', 'cancelled', 'canceled'),
+    ('', 'refunded', 'canceled'),
+    ('', 'partial', 'partial'),
+    ('', 'partially completed', 'partial'),
+    ('', 'failed', 'failed'),
+    ('', 'fail', 'failed'),
+    ('', 'error', 'failed')
+ON CONFLICT (provider_key, raw_status) DO NOTHING;
+
+-- Raw statuses seen during sync that no mapping covers
+CREATE TABLE IF NOT EXISTS provider_unmapped_statuses (
+    provider_key TEXT NOT NULL,
+    raw_status TEXT NOT NULL,
+    occurrences INTEGER NOT NULL DEFAULT 1,
+    last_order_id INTEGER,
+    first_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (provider_key, raw_status)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS provider_unmapped_statuses;
+DROP TABLE IF EXISTS provider_status_mappings;
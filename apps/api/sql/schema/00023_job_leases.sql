@@ -0,0 +1,13 @@
+-- +goose Up
+-- Periodic jobs take a Postgres advisory lock per run so only one instance
+-- executes each job. This table records who ran it last, for health checks.
+CREATE TABLE IF NOT EXISTS job_leases (
+    name TEXT PRIMARY KEY,
+    holder TEXT NOT NULL,
+    running BOOLEAN NOT NULL DEFAULT FALSE,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS job_leases;
@@ -0,0 +1,118 @@
+-- +goose Up
+-- Double-entry ledger. Every movement of money is a journal whose entries
+-- sum to zero; amounts are in paise, debits positive and credits negative.
+-- wallets.balance stays as the cached balance and is checked against the
+-- wallet accounts here.
+CREATE TABLE IF NOT EXISTS ledger_accounts (
+    id SERIAL PRIMARY KEY,
+    code TEXT UNIQUE NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('asset', 'liability', 'equity', 'revenue', 'expense')),
+    name TEXT NOT NULL,
+    user_id INTEGER UNIQUE REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS ledger_journals (
+    id SERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    reference_type TEXT NOT NULL DEFAULT '',
+    reference_id TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_journals_reference ON ledger_journals(reference_type, reference_id);
+
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id SERIAL PRIMARY KEY,
+    journal_id INTEGER NOT NULL REFERENCES ledger_journals(id),
+    account_id INTEGER NOT NULL REFERENCES ledger_accounts(id),
+    amount_cents BIGINT NOT NULL CHECK (amount_cents <> 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_account ON ledger_entries(account_id);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_journal ON ledger_entries(journal_id);
+
+-- Entries and journals are append-only; mistakes are corrected by posting
+-- a reversing journal
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION ledger_reject_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'ledger rows are immutable';
+END;
+$$ language 'plpgsql';
+-- +goose StatementEnd
+
+CREATE TRIGGER ledger_entries_immutable BEFORE UPDATE OR DELETE ON ledger_entries FOR EACH ROW EXECUTE PROCEDURE ledger_reject_change();
+CREATE TRIGGER ledger_journals_immutable BEFORE UPDATE OR DELETE ON ledger_journals FOR EACH ROW EXECUTE PROCEDURE ledger_reject_change();
+
+-- A journal must balance by the time its transaction commits
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION ledger_check_balanced()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF (SELECT COALESCE(SUM(amount_cents), 0) FROM ledger_entries WHERE journal_id = NEW.journal_id) <> 0 THEN
+        RAISE EXCEPTION 'ledger journal % is not balanced', NEW.journal_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+-- +goose StatementEnd
+
+CREATE CONSTRAINT TRIGGER ledger_entries_balanced AFTER INSERT ON ledger_entries
+DEFERRABLE INITIALLY DEFERRED FOR EACH ROW EXECUTE PROCEDURE ledger_check_balanced();
+
+INSERT INTO ledger_accounts (code, kind, name) VALUES
+    ('deposits_clearing', 'asset', 'Deposits clearing'),
+    ('provider_payable', 'liability', 'Provider payable'),
+    ('revenue', 'revenue', 'Revenue'),
+    ('adjustments', 'expense', 'Manual adjustments'),
+    ('opening_balances', 'equity', 'Opening balances')
+ON CONFLICT (code) DO NOTHING;
+
+-- Open an account for every existing wallet and carry its balance over
+INSERT INTO ledger_accounts (code, kind, name, user_id)
+SELECT 'wallet:' || user_id, 'liability', 'Wallet of user ' || user_id, user_id FROM wallets
+ON CONFLICT (code) DO NOTHING;
+
+WITH j AS (
+    INSERT INTO ledger_journals (kind, description, reference_type, reference_id)
+    SELECT 'opening_balance', 'Opening wallet balance', 'wallet', user_id::text
+    FROM wallets WHERE balance <> 0
+    RETURNING id, reference_id
+)
+INSERT INTO ledger_entries (journal_id, account_id, amount_cents)
+SELECT j.id, a.id, -w.balance
+FROM j
+JOIN wallets w ON w.user_id::text = j.reference_id
+JOIN ledger_accounts a ON a.user_id = w.user_id
+UNION ALL
+SELECT j.id, (SELECT id FROM ledger_accounts WHERE code = 'opening_balances'), w.balance
+FROM j
+JOIN wallets w ON w.user_id::text = j.reference_id;
+
+-- Orders still in flight hold their charge in provider_payable until they settle
+WITH j AS (
+    INSERT INTO ledger_journals (kind, description, reference_type, reference_id)
+    SELECT 'opening_balance', 'Opening balance of order #' || id, 'order', id::text
+    FROM orders
+    WHERE status IN ('pending', 'processing', 'submitted', 'in_progress', 'active', 'needs_attention')
+    AND amount_cents - COALESCE(refunded_amount, 0) > 0
+    RETURNING id, reference_id
+)
+INSERT INTO ledger_entries (journal_id, account_id, amount_cents)
+SELECT j.id, (SELECT id FROM ledger_accounts WHERE code = 'provider_payable'), -(o.amount_cents - COALESCE(o.refunded_amount, 0))
+FROM j
+JOIN orders o ON o.id::text = j.reference_id
+UNION ALL
+SELECT j.id, (SELECT id FROM ledger_accounts WHERE code = 'opening_balances'), o.amount_cents - COALESCE(o.refunded_amount, 0)
+FROM j
+JOIN orders o ON o.id::text = j.reference_id;
+
+-- +goose Down
+DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS ledger_journals;
+DROP TABLE IF EXISTS ledger_accounts;
+DROP FUNCTION IF EXISTS ledger_check_balanced;
+DROP FUNCTION IF EXISTS ledger_reject_change;
@@ -0,0 +1,36 @@
+-- +goose Up
+-- Typed links from wallet transactions to whatever caused them, instead of
+-- parsing "Refund for Order #12" out of the description
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'adjustment';
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS reference_type TEXT NOT NULL DEFAULT '';
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS reference_id TEXT NOT NULL DEFAULT '';
+
+-- Before this change only deposits, order refunds and admin adjustments
+-- were logged; tell them apart by their descriptions
+UPDATE transactions SET kind = 'deposit'
+WHERE type = 'credit' AND description ILIKE '%deposit%';
+
+UPDATE transactions
+SET kind = 'order_refund', reference_type = 'order', reference_id = substring(description FROM 'Order #([0-9]+)')
+WHERE type = 'credit' AND kind = 'adjustment' AND description ~ 'Order #[0-9]+';
+
+UPDATE transactions SET reference_type = 'admin_adjustment'
+WHERE kind = 'adjustment';
+
+ALTER TABLE transactions ADD CONSTRAINT transactions_kind_check
+    CHECK (kind IN ('deposit', 'order_charge', 'order_refund', 'adjustment', 'bonus', 'referral'));
+ALTER TABLE transactions ADD CONSTRAINT transactions_reference_type_check
+    CHECK (reference_type IN ('', 'order', 'wallet_request', 'refund', 'admin_adjustment', 'bonus', 'referral'));
+ALTER TABLE transactions ALTER COLUMN kind DROP DEFAULT;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_user_kind ON transactions(user_id, kind, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_transactions_reference ON transactions(reference_type, reference_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_transactions_reference;
+DROP INDEX IF EXISTS idx_transactions_user_kind;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_reference_type_check;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_kind_check;
+ALTER TABLE transactions DROP COLUMN IF EXISTS reference_id;
+ALTER TABLE transactions DROP COLUMN IF EXISTS reference_type;
+ALTER TABLE transactions DROP COLUMN IF EXISTS kind;
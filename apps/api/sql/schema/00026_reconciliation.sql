@@ -0,0 +1,49 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS reconcile_runs (
+    id SERIAL PRIMARY KEY,
+    trigger TEXT NOT NULL CHECK (trigger IN ('schedule', 'manual', 'cli')),
+    status TEXT NOT NULL DEFAULT 'running' CHECK (status IN ('running', 'completed', 'failed')),
+    checked_wallets INTEGER NOT NULL DEFAULT 0,
+    discrepancy_count INTEGER NOT NULL DEFAULT 0,
+    fixed_count INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+-- One row per problem found by a run. Open rows from earlier runs are
+-- superseded when a new run starts, so the open set is always current.
+CREATE TABLE IF NOT EXISTS reconcile_discrepancies (
+    id SERIAL PRIMARY KEY,
+    run_id INTEGER NOT NULL REFERENCES reconcile_runs(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('balance_mismatch', 'refund_credit_mismatch', 'deposit_credit_mismatch')),
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+    reference_type TEXT NOT NULL DEFAULT '',
+    reference_id TEXT NOT NULL DEFAULT '',
+    expected_cents BIGINT NOT NULL,
+    actual_cents BIGINT NOT NULL,
+    message TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'fixed', 'ignored', 'superseded')),
+    resolution_note TEXT,
+    resolved_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reconcile_discrepancies_run ON reconcile_discrepancies(run_id);
+CREATE INDEX IF NOT EXISTS idx_reconcile_discrepancies_status ON reconcile_discrepancies(status, kind);
+
+-- Scheduled runs only correct cached wallet balances when autofix is on,
+-- and never by more than the cap. Deposits approved before typed
+-- transaction references existed cannot be matched, so the deposit check
+-- starts at this migration.
+INSERT INTO global_settings (key, value) VALUES
+    ('reconcile_autofix', 'false'),
+    ('reconcile_autofix_max_cents', '10000'),
+    ('reconcile_deposits_since', to_char(NOW() AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"'))
+ON CONFLICT (key) DO NOTHING;
+
+-- +goose Down
+DELETE FROM global_settings WHERE key IN ('reconcile_autofix', 'reconcile_autofix_max_cents', 'reconcile_deposits_since');
+DROP TABLE IF EXISTS reconcile_discrepancies;
+DROP TABLE IF EXISTS reconcile_runs;
@@ -0,0 +1,36 @@
+-- +goose Up
+-- Reservations against a wallet. A hold keeps part of the balance from
+-- being spent until it is captured as a charge or released; held_cents
+-- caches the sum of the wallet's active holds, so the available balance is
+-- balance - held_cents.
+ALTER TABLE wallets ADD COLUMN IF NOT EXISTS held_cents INTEGER NOT NULL DEFAULT 0 CHECK (held_cents >= 0);
+
+CREATE TABLE IF NOT EXISTS wallet_holds (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount_cents INTEGER NOT NULL CHECK (amount_cents > 0),
+    reference_type TEXT NOT NULL DEFAULT '',
+    reference_id TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'captured', 'released')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_holds_user_active ON wallet_holds(user_id) WHERE status = 'active';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_wallet_holds_reference_active ON wallet_holds(reference_type, reference_id) WHERE status = 'active';
+
+-- API orders rejected by the provider used to be refunded without
+-- recording it on the order; they are now released before any charge
+UPDATE orders o SET refunded_amount = c.credited
+FROM (
+    SELECT reference_id, ROUND(SUM(amount) * 100)::int as credited
+    FROM transactions
+    WHERE kind = 'order_refund' AND reference_type = 'order'
+    GROUP BY reference_id
+) c
+WHERE c.reference_id = o.id::text AND o.status = 'failed' AND COALESCE(o.refunded_amount, 0) = 0;
+
+-- +goose Down
+DROP TABLE IF EXISTS wallet_holds;
+ALTER TABLE wallets DROP COLUMN IF EXISTS held_cents;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- Money columns hold minor units (paise) as 64-bit integers; INTEGER capped
+-- balances at about ₹2.1 crore
+ALTER TABLE wallets ALTER COLUMN balance TYPE BIGINT;
+ALTER TABLE wallets ALTER COLUMN held_cents TYPE BIGINT;
+ALTER TABLE wallet_holds ALTER COLUMN amount_cents TYPE BIGINT;
+ALTER TABLE orders ALTER COLUMN amount_cents TYPE BIGINT;
+ALTER TABLE orders ALTER COLUMN refunded_amount TYPE BIGINT;
+ALTER TABLE order_refunds ALTER COLUMN amount_cents TYPE BIGINT;
+ALTER TABLE refund_rules ALTER COLUMN min_non_refundable_cents TYPE BIGINT;
+
+-- +goose Down
+ALTER TABLE refund_rules ALTER COLUMN min_non_refundable_cents TYPE INTEGER;
+ALTER TABLE order_refunds ALTER COLUMN amount_cents TYPE INTEGER;
+ALTER TABLE orders ALTER COLUMN refunded_amount TYPE INTEGER;
+ALTER TABLE orders ALTER COLUMN amount_cents TYPE INTEGER;
+ALTER TABLE wallet_holds ALTER COLUMN amount_cents TYPE INTEGER;
+ALTER TABLE wallets ALTER COLUMN held_cents TYPE INTEGER;
+ALTER TABLE wallets ALTER COLUMN balance TYPE INTEGER;
@@ -0,0 +1,57 @@
+-- +goose Up
+-- Promotional credit on deposits, e.g. "add ₹5000, get 5% extra". An
+-- approved deposit earns the bonus of the highest tier it reaches among the
+-- active tiers that are within their window and accept its payment method.
+CREATE TABLE IF NOT EXISTS deposit_bonus_tiers (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL DEFAULT '',
+    min_deposit_cents BIGINT NOT NULL CHECK (min_deposit_cents > 0),
+    bonus_pct DOUBLE PRECISION NOT NULL CHECK (bonus_pct > 0 AND bonus_pct <= 100),
+    max_bonus_cents BIGINT CHECK (max_bonus_cents > 0),
+    -- Lowercase wallet_requests.method values; empty applies to every method
+    methods TEXT[] NOT NULL DEFAULT '{}',
+    starts_at TIMESTAMP WITH TIME ZONE,
+    ends_at TIMESTAMP WITH TIME ZONE,
+    withdrawable BOOLEAN NOT NULL DEFAULT FALSE,
+    expires_after_days INTEGER CHECK (expires_after_days > 0),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (starts_at IS NULL OR ends_at IS NULL OR ends_at > starts_at)
+);
+
+DROP TRIGGER IF EXISTS update_deposit_bonus_tiers_updated_at ON deposit_bonus_tiers;
+CREATE TRIGGER update_deposit_bonus_tiers_updated_at
+BEFORE UPDATE ON deposit_bonus_tiers
+FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+-- Bonuses credited to wallets. One per deposit; the tier's settings are
+-- copied so editing a tier does not change bonuses already granted.
+CREATE TABLE IF NOT EXISTS bonus_grants (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tier_id INTEGER REFERENCES deposit_bonus_tiers(id) ON DELETE SET NULL,
+    wallet_request_id INTEGER UNIQUE REFERENCES wallet_requests(id) ON DELETE SET NULL,
+    deposit_cents BIGINT NOT NULL,
+    amount_cents BIGINT NOT NULL CHECK (amount_cents > 0),
+    withdrawable BOOLEAN NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'expired')),
+    expired_cents BIGINT NOT NULL DEFAULT 0 CHECK (expired_cents >= 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expired_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_bonus_grants_user ON bonus_grants(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_bonus_grants_expiry ON bonus_grants(expires_at) WHERE status = 'active';
+
+-- Bonuses are paid out of, and expired bonuses returned to, this account
+INSERT INTO ledger_accounts (code, kind, name) VALUES
+    ('promotions', 'expense', 'Promotional credit')
+ON CONFLICT (code) DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS bonus_grants;
+DROP TABLE IF EXISTS deposit_bonus_tiers;
+DELETE FROM ledger_accounts a WHERE a.code = 'promotions'
+AND NOT EXISTS (SELECT 1 FROM ledger_entries e WHERE e.account_id = a.id);
@@ -0,0 +1,76 @@
+-- +goose Up
+-- Codes users enter for a discount on an order or extra credit on a
+-- deposit. Codes are stored uppercase and matched case-insensitively.
+CREATE TABLE IF NOT EXISTS promo_codes (
+    id SERIAL PRIMARY KEY,
+    code TEXT NOT NULL UNIQUE CHECK (code <> '' AND code = UPPER(code)),
+    description TEXT NOT NULL DEFAULT '',
+    applies_to TEXT NOT NULL CHECK (applies_to IN ('order', 'deposit')),
+    value_type TEXT NOT NULL CHECK (value_type IN ('percent', 'fixed')),
+    value_pct DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (value_pct >= 0 AND value_pct <= 100),
+    value_cents BIGINT NOT NULL DEFAULT 0 CHECK (value_cents >= 0),
+    max_value_cents BIGINT CHECK (max_value_cents > 0),
+    -- Smallest order price or deposit the code can be used on
+    min_amount_cents BIGINT NOT NULL DEFAULT 0 CHECK (min_amount_cents >= 0),
+    max_uses INTEGER CHECK (max_uses > 0),
+    max_uses_per_user INTEGER CHECK (max_uses_per_user > 0),
+    -- Order codes only; empty applies to every platform / catalog service
+    platforms TEXT[] NOT NULL DEFAULT '{}',
+    catalog_ids INTEGER[] NOT NULL DEFAULT '{}',
+    starts_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((value_type = 'percent' AND value_pct > 0) OR (value_type = 'fixed' AND value_cents > 0)),
+    CHECK (starts_at IS NULL OR expires_at IS NULL OR expires_at > starts_at)
+);
+
+DROP TRIGGER IF EXISTS update_promo_codes_updated_at ON promo_codes;
+CREATE TRIGGER update_promo_codes_updated_at
+BEFORE UPDATE ON promo_codes
+FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+-- Each use of a code. An order removed after the provider rejects it takes
+-- its redemption with it, so the use is not counted.
+CREATE TABLE IF NOT EXISTS promo_redemptions (
+    id SERIAL PRIMARY KEY,
+    promo_code_id INTEGER NOT NULL REFERENCES promo_codes(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    order_id INTEGER UNIQUE REFERENCES orders(id) ON DELETE CASCADE,
+    wallet_request_id INTEGER UNIQUE REFERENCES wallet_requests(id) ON DELETE CASCADE,
+    -- Order price before the discount, or the deposit
+    base_cents BIGINT NOT NULL,
+    -- Discount given or credit paid
+    value_cents BIGINT NOT NULL CHECK (value_cents > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((order_id IS NULL) <> (wallet_request_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_promo_redemptions_code ON promo_redemptions(promo_code_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_promo_redemptions_created ON promo_redemptions(created_at);
+
+-- Orders keep the discount they were charged with; amount_cents is the
+-- price after it
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS promo_code_id INTEGER REFERENCES promo_codes(id) ON DELETE SET NULL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount_cents BIGINT NOT NULL DEFAULT 0 CHECK (discount_cents >= 0);
+
+-- The code entered with a deposit, applied when the deposit is approved
+ALTER TABLE wallet_requests ADD COLUMN IF NOT EXISTS promo_code_id INTEGER REFERENCES promo_codes(id) ON DELETE SET NULL;
+
+-- Deposit promo credit is a bonus grant, so a deposit can now carry a tier
+-- bonus and a promo code bonus
+ALTER TABLE bonus_grants ADD COLUMN IF NOT EXISTS promo_code_id INTEGER REFERENCES promo_codes(id) ON DELETE SET NULL;
+ALTER TABLE bonus_grants DROP CONSTRAINT IF EXISTS bonus_grants_wallet_request_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bonus_grants_request ON bonus_grants(wallet_request_id, (promo_code_id IS NOT NULL));
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_bonus_grants_request;
+DELETE FROM bonus_grants WHERE promo_code_id IS NOT NULL;
+ALTER TABLE bonus_grants ADD CONSTRAINT bonus_grants_wallet_request_id_key UNIQUE (wallet_request_id);
+ALTER TABLE bonus_grants DROP COLUMN IF EXISTS promo_code_id;
+ALTER TABLE wallet_requests DROP COLUMN IF EXISTS promo_code_id;
+ALTER TABLE orders DROP COLUMN IF EXISTS discount_cents;
+ALTER TABLE orders DROP COLUMN IF EXISTS promo_code_id;
+DROP TABLE IF EXISTS promo_redemptions;
+DROP TABLE IF EXISTS promo_codes;
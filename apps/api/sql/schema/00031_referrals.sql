@@ -0,0 +1,109 @@
+-- +goose Up
+-- Every user gets a referral code: a random prefix followed by the user id,
+-- so codes are unique without retries. signup_ip is kept to spot
+-- self-referrals.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS referral_code TEXT UNIQUE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS signup_ip TEXT NOT NULL DEFAULT '';
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION set_referral_code()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.referral_code IS NULL THEN
+        NEW.referral_code := UPPER(SUBSTRING(MD5(random()::text) FOR 4)) || NEW.id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP TRIGGER IF EXISTS set_users_referral_code ON users;
+CREATE TRIGGER set_users_referral_code
+BEFORE INSERT ON users
+FOR EACH ROW EXECUTE PROCEDURE set_referral_code();
+
+UPDATE users SET referral_code = UPPER(SUBSTRING(MD5(random()::text) FOR 4)) || id
+WHERE referral_code IS NULL;
+
+-- Who referred whom. A referral whose signup looks like the referrer's own
+-- (flags) is held: its commissions wait for an admin to activate or block it.
+CREATE TABLE IF NOT EXISTS referrals (
+    referred_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    referrer_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    signup_ip TEXT NOT NULL DEFAULT '',
+    flags TEXT[] NOT NULL DEFAULT '{}',
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'held', 'blocked')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    reviewed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    CHECK (referrer_id <> referred_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_referrals_referrer ON referrals(referrer_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_referrals_status ON referrals(status) WHERE status <> 'active';
+
+-- Commission earned on one deposit or order of a referred user. Available
+-- commissions make up the affiliate balance; held ones wait for review and
+-- rejected ones never pay.
+CREATE TABLE IF NOT EXISTS referral_commissions (
+    id SERIAL PRIMARY KEY,
+    referrer_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    referred_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    source_type TEXT NOT NULL CHECK (source_type IN ('deposit', 'order')),
+    source_id INTEGER NOT NULL,
+    base_cents BIGINT NOT NULL,
+    rate_pct DOUBLE PRECISION NOT NULL,
+    amount_cents BIGINT NOT NULL CHECK (amount_cents >= 0),
+    status TEXT NOT NULL CHECK (status IN ('available', 'held', 'rejected')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    released_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (source_type, source_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_referral_commissions_referrer ON referral_commissions(referrer_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_referral_commissions_referred ON referral_commissions(referred_id) WHERE status = 'held';
+
+-- Withdrawals from the affiliate balance: straight into the wallet, or a
+-- payout request an admin pays outside the platform
+CREATE TABLE IF NOT EXISTS referral_payouts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount_cents BIGINT NOT NULL CHECK (amount_cents > 0),
+    method TEXT NOT NULL CHECK (method IN ('wallet', 'payout')),
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL CHECK (status IN ('pending', 'paid', 'rejected')),
+    admin_note TEXT NOT NULL DEFAULT '',
+    processed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    processed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_referral_payouts_user ON referral_payouts(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_referral_payouts_pending ON referral_payouts(created_at) WHERE status = 'pending';
+
+-- Commissions are expensed as they are earned and owed to affiliates until
+-- converted to wallet credit or paid out
+INSERT INTO ledger_accounts (code, kind, name) VALUES
+    ('referral_commissions', 'expense', 'Referral commissions'),
+    ('affiliate_payable', 'liability', 'Affiliate payable')
+ON CONFLICT (code) DO NOTHING;
+
+-- Commission is a percentage of referred users' approved deposits or of
+-- their finished order spend (after refunds)
+INSERT INTO global_settings (key, value) VALUES
+    ('referral_commission_basis', 'deposit'),
+    ('referral_commission_pct', '5'),
+    ('referral_min_payout_cents', '50000')
+ON CONFLICT (key) DO NOTHING;
+
+-- +goose Down
+DELETE FROM global_settings WHERE key IN ('referral_commission_basis', 'referral_commission_pct', 'referral_min_payout_cents');
+DELETE FROM ledger_accounts a WHERE a.code IN ('referral_commissions', 'affiliate_payable')
+AND NOT EXISTS (SELECT 1 FROM ledger_entries e WHERE e.account_id = a.id);
+DROP TABLE IF EXISTS referral_payouts;
+DROP TABLE IF EXISTS referral_commissions;
+DROP TABLE IF EXISTS referrals;
+DROP TRIGGER IF EXISTS set_users_referral_code ON users;
+DROP FUNCTION IF EXISTS set_referral_code();
+ALTER TABLE users DROP COLUMN IF EXISTS signup_ip;
+ALTER TABLE users DROP COLUMN IF EXISTS referral_code;
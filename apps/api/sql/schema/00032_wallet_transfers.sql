@@ -0,0 +1,54 @@
+-- +goose Up
+-- Money sent from one user's wallet to another's. Transfers above the
+-- approval threshold wait as pending, with the amount held in the sender's
+-- wallet, until an admin approves or rejects them.
+CREATE TABLE IF NOT EXISTS wallet_transfers (
+    id SERIAL PRIMARY KEY,
+    sender_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    recipient_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount_cents BIGINT NOT NULL CHECK (amount_cents > 0),
+    note TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL CHECK (status IN ('pending', 'completed', 'rejected')),
+    admin_note TEXT NOT NULL DEFAULT '',
+    processed_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    processed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (sender_id <> recipient_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_transfers_sender ON wallet_transfers(sender_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_wallet_transfers_recipient ON wallet_transfers(recipient_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_wallet_transfers_pending ON wallet_transfers(created_at) WHERE status = 'pending';
+
+DROP TRIGGER IF EXISTS update_wallet_transfers_updated_at ON wallet_transfers;
+CREATE TRIGGER update_wallet_transfers_updated_at
+BEFORE UPDATE ON wallet_transfers
+FOR EACH ROW EXECUTE PROCEDURE update_updated_at_column();
+
+-- Both sides of a transfer show in the users' transaction history
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_kind_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_kind_check
+    CHECK (kind IN ('deposit', 'order_charge', 'order_refund', 'adjustment', 'bonus', 'referral', 'transfer'));
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_reference_type_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_reference_type_check
+    CHECK (reference_type IN ('', 'order', 'wallet_request', 'refund', 'admin_adjustment', 'bonus', 'referral', 'wallet_transfer'));
+
+-- Limits on what a user can send: per transfer, in total over the last 24
+-- hours, and the amount above which an admin has to approve (0 turns
+-- approval off)
+INSERT INTO global_settings (key, value) VALUES
+    ('transfer_min_cents', '100'),
+    ('transfer_daily_limit_cents', '2500000'),
+    ('transfer_approval_cents', '1000000')
+ON CONFLICT (key) DO NOTHING;
+
+-- +goose Down
+DELETE FROM global_settings WHERE key IN ('transfer_min_cents', 'transfer_daily_limit_cents', 'transfer_approval_cents');
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_reference_type_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_reference_type_check
+    CHECK (reference_type IN ('', 'order', 'wallet_request', 'refund', 'admin_adjustment', 'bonus', 'referral'));
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_kind_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_kind_check
+    CHECK (kind IN ('deposit', 'order_charge', 'order_refund', 'adjustment', 'bonus', 'referral'));
+DROP TABLE IF EXISTS wallet_transfers;
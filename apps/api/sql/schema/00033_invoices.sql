@@ -0,0 +1,70 @@
+-- +goose Up
+-- Billing details printed on a user's invoices. billing_state is the
+-- two-digit GST state code, which decides between CGST + SGST and IGST.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS billing_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS gstin TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS billing_address TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS billing_state TEXT NOT NULL DEFAULT '';
+
+-- Last invoice number issued per financial year; incrementing the row
+-- inside the issuing transaction keeps numbers sequential without gaps
+CREATE TABLE IF NOT EXISTS invoice_counters (
+    financial_year TEXT PRIMARY KEY,
+    last_number INTEGER NOT NULL
+);
+
+-- A tax invoice for a wallet top-up: an approved deposit or an admin
+-- credit, by the transaction that credited it. Amounts are GST inclusive;
+-- seller and customer details are copied so an invoice never changes once
+-- issued.
+CREATE TABLE IF NOT EXISTS invoices (
+    id SERIAL PRIMARY KEY,
+    invoice_number TEXT NOT NULL UNIQUE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    transaction_id INTEGER NOT NULL UNIQUE REFERENCES transactions(id),
+    payment_method TEXT NOT NULL,
+    customer_name TEXT NOT NULL DEFAULT '',
+    customer_email TEXT NOT NULL DEFAULT '',
+    customer_gstin TEXT NOT NULL DEFAULT '',
+    customer_address TEXT NOT NULL DEFAULT '',
+    place_of_supply TEXT NOT NULL DEFAULT '',
+    seller_name TEXT NOT NULL DEFAULT '',
+    seller_gstin TEXT NOT NULL DEFAULT '',
+    seller_address TEXT NOT NULL DEFAULT '',
+    seller_state TEXT NOT NULL DEFAULT '',
+    sac_code TEXT NOT NULL DEFAULT '',
+    gst_pct DOUBLE PRECISION NOT NULL,
+    total_cents BIGINT NOT NULL CHECK (total_cents > 0),
+    taxable_cents BIGINT NOT NULL,
+    cgst_cents BIGINT NOT NULL DEFAULT 0,
+    sgst_cents BIGINT NOT NULL DEFAULT 0,
+    igst_cents BIGINT NOT NULL DEFAULT 0,
+    issued_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CHECK (taxable_cents + cgst_cents + sgst_cents + igst_cents = total_cents)
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoices_user_issued ON invoices(user_id, issued_at DESC);
+
+-- Seller details and GST terms. Only top-ups from invoices_since on are
+-- invoiced, so history is not numbered retroactively.
+INSERT INTO global_settings (key, value) VALUES
+    ('invoice_prefix', 'INV'),
+    ('invoice_gst_pct', '18'),
+    ('invoice_sac_code', ''),
+    ('invoice_seller_name', ''),
+    ('invoice_seller_gstin', ''),
+    ('invoice_seller_address', ''),
+    ('invoice_seller_state', ''),
+    ('invoices_since', to_char(NOW() AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"'))
+ON CONFLICT (key) DO NOTHING;
+
+-- +goose Down
+DELETE FROM global_settings WHERE key IN ('invoice_prefix', 'invoice_gst_pct', 'invoice_sac_code', 'invoice_seller_name',
+    'invoice_seller_gstin', 'invoice_seller_address', 'invoice_seller_state', 'invoices_since');
+DROP TABLE IF EXISTS invoices;
+DROP TABLE IF EXISTS invoice_counters;
+ALTER TABLE users DROP COLUMN IF EXISTS billing_state;
+ALTER TABLE users DROP COLUMN IF EXISTS billing_address;
+ALTER TABLE users DROP COLUMN IF EXISTS gstin;
+ALTER TABLE users DROP COLUMN IF EXISTS billing_name;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- Link rules are looked up by the platform key targets use, where Twitter
+-- is "x"; move rules saved under "twitter" unless an X rule already covers
+-- the same services
+UPDATE link_rules r SET platform = 'x'
+WHERE r.platform = 'twitter'
+AND NOT EXISTS (
+    SELECT 1 FROM link_rules x
+    WHERE x.platform = 'x' AND x.service_type = r.service_type AND x.variant = r.variant
+);
+
+-- +goose Down
+-- The rules keep the "x" key, which is the only one looked up
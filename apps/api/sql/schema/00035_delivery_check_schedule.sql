@@ -0,0 +1,18 @@
+-- +goose Up
+-- 00012 backfilled completed_at for orders that were already complete with
+-- their creation time, as orders.updated_at is never changed. Use the last
+-- sync, which saw the order finish, where there is one and leave the rest
+-- unknown.
+UPDATE orders SET completed_at = CASE WHEN last_synced_at > created_at THEN last_synced_at END
+WHERE completed_at = COALESCE(updated_at, created_at);
+
+-- When delivery was last attempted, whether or not it could be measured,
+-- so orders that cannot be checked do not come back every run
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS delivery_checked_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE orders o SET delivery_checked_at = c.checked_at
+FROM (SELECT order_id, MAX(checked_at) AS checked_at FROM order_delivery_checks GROUP BY order_id) c
+WHERE c.order_id = o.id;
+
+-- +goose Down
+ALTER TABLE orders DROP COLUMN IF EXISTS delivery_checked_at;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- A NULL refills_remaining now means unlimited refills. Orders that had
+-- none were shown and treated as having the default of 3, so they keep it.
+UPDATE orders SET refills_remaining = 3 WHERE refills_remaining IS NULL;
+ALTER TABLE orders ALTER COLUMN refills_remaining DROP DEFAULT;
+
+-- Unlimited refills are set on the catalog refill policy with a NULL
+-- max_refills. A max_refills of 0 did not cap refills until now, so those
+-- policies become unlimited; from here on 0 means no refills.
+ALTER TABLE catalog_refill_policies ALTER COLUMN max_refills DROP NOT NULL;
+UPDATE catalog_refill_policies SET max_refills = NULL WHERE max_refills = 0;
+
+-- +goose Down
+UPDATE catalog_refill_policies SET max_refills = 0 WHERE max_refills IS NULL;
+ALTER TABLE catalog_refill_policies ALTER COLUMN max_refills SET NOT NULL;
+UPDATE orders SET refills_remaining = 3 WHERE refills_remaining IS NULL;
+ALTER TABLE orders ALTER COLUMN refills_remaining SET DEFAULT 3;
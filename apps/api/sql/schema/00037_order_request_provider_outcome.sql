@@ -0,0 +1,11 @@
+-- +goose Up
+-- What the provider made of a request sent to it: accepted, refused or
+-- failed (the call itself did not go through). NULL until it is sent.
+ALTER TABLE order_requests ADD COLUMN IF NOT EXISTS provider_outcome TEXT;
+
+UPDATE order_requests SET provider_outcome = 'accepted'
+WHERE request_type = 'refill'
+AND (provider_refill_id IS NOT NULL OR (provider_response IS NOT NULL AND provider_response NOT LIKE '%"error"%'));
+
+-- +goose Down
+ALTER TABLE order_requests DROP COLUMN IF EXISTS provider_outcome;
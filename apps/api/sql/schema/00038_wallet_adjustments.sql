@@ -0,0 +1,18 @@
+-- +goose Up
+-- Each manual admin credit or debit, which its transaction and journal
+-- reference. Adjustments made before this table reference the admin who
+-- made them instead; journals cannot be rewritten, so they are left as is.
+CREATE TABLE IF NOT EXISTS wallet_adjustments (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    admin_id INTEGER REFERENCES users(id),
+    amount_cents BIGINT NOT NULL CHECK (amount_cents <> 0),
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_wallet_adjustments_user ON wallet_adjustments(user_id);
+CREATE INDEX IF NOT EXISTS idx_wallet_adjustments_admin ON wallet_adjustments(admin_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS wallet_adjustments;
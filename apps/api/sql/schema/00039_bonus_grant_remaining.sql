@@ -0,0 +1,27 @@
+-- +goose Up
+-- The unspent part of each bonus. Bonus credit is spent after deposited
+-- money, so debits reduce it once the balance no longer covers it; expiry
+-- takes back and the withdrawal lock covers only what is left.
+ALTER TABLE bonus_grants ADD COLUMN IF NOT EXISTS remaining_cents BIGINT NOT NULL DEFAULT 0 CHECK (remaining_cents >= 0);
+
+-- Start active bonuses at their full amount, then cut them down to what
+-- each wallet's balance still covers, spending the soonest to expire first
+UPDATE bonus_grants SET remaining_cents = amount_cents WHERE status = 'active';
+
+UPDATE bonus_grants g
+SET remaining_cents = GREATEST(s.balance - s.kept_after, 0)
+FROM (
+    SELECT b.id, COALESCE(w.balance, 0) AS balance,
+        COALESCE(SUM(b.remaining_cents) OVER (
+            PARTITION BY b.user_id
+            ORDER BY b.expires_at DESC NULLS FIRST, b.id DESC
+            ROWS BETWEEN UNBOUNDED PRECEDING AND 1 PRECEDING
+        ), 0) AS kept_after
+    FROM bonus_grants b
+    LEFT JOIN wallets w ON w.user_id = b.user_id
+    WHERE b.status = 'active'
+) s
+WHERE g.id = s.id AND g.remaining_cents > GREATEST(s.balance - s.kept_after, 0);
+
+-- +goose Down
+ALTER TABLE bonus_grants DROP COLUMN IF EXISTS remaining_cents;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Accounts an agency manages for its clients. The agency invites an
+-- account and the link is active once that account accepts. Wallet
+-- transfers only move money between an agency and its sub-accounts, or
+-- between sub-accounts of the same agency. An account belongs to at most
+-- one agency, and a sub-account cannot have sub-accounts of its own.
+CREATE TABLE IF NOT EXISTS sub_accounts (
+    id SERIAL PRIMARY KEY,
+    owner_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'invited' CHECK (status IN ('invited', 'active')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    accepted_at TIMESTAMP WITH TIME ZONE,
+    CHECK (owner_id <> user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sub_accounts_owner ON sub_accounts(owner_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS sub_accounts;
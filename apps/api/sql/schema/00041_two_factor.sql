@@ -0,0 +1,14 @@
+-- +goose Up
+-- Authenticator app (TOTP) secrets. A secret is set up first and only
+-- turned on once the user enters a code from it. last_step is the time step
+-- of the last accepted code, so a code cannot be used twice.
+CREATE TABLE IF NOT EXISTS user_two_factor (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    enabled_at TIMESTAMP WITH TIME ZONE,
+    last_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_two_factor;
@@ -0,0 +1,26 @@
+-- +goose Up
+-- Cryptomus payments are made in USD while wallets are kept in INR. amount
+-- is what the wallet is credited, in INR; pay_amount and pay_currency are
+-- what the user is asked to pay. The USD amount is converted when the
+-- payment is created, at the cryptomus_usd_inr_rate setting.
+ALTER TABLE wallet_requests ADD COLUMN IF NOT EXISTS pay_amount DECIMAL(15, 2);
+ALTER TABLE wallet_requests ADD COLUMN IF NOT EXISTS pay_currency TEXT;
+
+INSERT INTO global_settings (key, value) VALUES
+    ('cryptomus_usd_inr_rate', '85')
+ON CONFLICT (key) DO NOTHING;
+
+-- Earlier Cryptomus requests stored the USD amount. Approved ones were
+-- credited that figure as rupees and keep it, so they still match the
+-- ledger; pending ones are converted so they credit the right amount.
+UPDATE wallet_requests SET pay_amount = amount, pay_currency = 'USD'
+WHERE method = 'cryptomus' AND pay_amount IS NULL;
+UPDATE wallet_requests SET amount = ROUND(pay_amount * (SELECT value::numeric FROM global_settings WHERE key = 'cryptomus_usd_inr_rate'), 2)
+WHERE method = 'cryptomus' AND status = 'pending';
+
+-- +goose Down
+UPDATE wallet_requests SET amount = pay_amount
+WHERE method = 'cryptomus' AND status = 'pending' AND pay_amount IS NOT NULL;
+ALTER TABLE wallet_requests DROP COLUMN IF EXISTS pay_currency;
+ALTER TABLE wallet_requests DROP COLUMN IF EXISTS pay_amount;
+DELETE FROM global_settings WHERE key = 'cryptomus_usd_inr_rate';
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Admin credits are only invoiced when the admin marks them as a top-up
+-- paid for outside the panel; corrections, goodwill credits and the like
+-- are not supplies.
+ALTER TABLE wallet_adjustments ADD COLUMN IF NOT EXISTS top_up BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Top-ups the invoicing job could not invoice, for admins to look into.
+-- Rows are retried on every run and removed once they are invoiced.
+CREATE TABLE IF NOT EXISTS invoice_failures (
+    transaction_id INTEGER PRIMARY KEY REFERENCES transactions(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    error TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 1,
+    first_failed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_failed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS invoice_failures;
+ALTER TABLE wallet_adjustments DROP COLUMN IF EXISTS top_up;
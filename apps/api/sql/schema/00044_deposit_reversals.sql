@@ -0,0 +1,18 @@
+-- +goose Up
+-- An approved deposit can be reversed when the payment is refunded, charged
+-- back or turns out to be fraudulent. The wallet is debited and the
+-- referral commission earned on it is clawed back.
+ALTER TABLE wallet_requests DROP CONSTRAINT IF EXISTS wallet_requests_status_check;
+ALTER TABLE wallet_requests ADD CONSTRAINT wallet_requests_status_check
+    CHECK (status IN ('pending', 'approved', 'rejected', 'reversed'));
+ALTER TABLE referral_commissions DROP CONSTRAINT IF EXISTS referral_commissions_status_check;
+ALTER TABLE referral_commissions ADD CONSTRAINT referral_commissions_status_check
+    CHECK (status IN ('available', 'held', 'rejected', 'reversed'));
+
+-- +goose Down
+ALTER TABLE referral_commissions DROP CONSTRAINT IF EXISTS referral_commissions_status_check;
+ALTER TABLE referral_commissions ADD CONSTRAINT referral_commissions_status_check
+    CHECK (status IN ('available', 'held', 'rejected'));
+ALTER TABLE wallet_requests DROP CONSTRAINT IF EXISTS wallet_requests_status_check;
+ALTER TABLE wallet_requests ADD CONSTRAINT wallet_requests_status_check
+    CHECK (status IN ('pending', 'approved', 'rejected'));
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Jobs no longer hold an advisory lock on a connection for the whole run.
+-- An instance claims the lease in a short transaction and keeps renewing it
+-- while the job runs; a lease that runs out belongs to a crashed instance
+-- and can be taken over.
+ALTER TABLE job_leases ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE job_leases DROP COLUMN IF EXISTS expires_at;
@@ -17,7 +17,7 @@ export type AdminOrder = {
     link: string;
     refundedAmount?: number;
     providerOrderId?: string;
-    refillsRemaining?: number;
+    refillsRemaining?: number | null;
     serviceRefillLimit?: number;
     serviceRefillEnabled?: boolean;
 };
@@ -97,8 +97,9 @@ export function OrderDetailsDialog({ order, open, onOpenChange, onSuccess }: Ord
     };
 
     const handleSaveRefills = async () => {
-        const val = parseInt(refillsValue);
-        if (isNaN(val) || val < 0) {
+        // Left empty, the order gets unlimited refills
+        const val = refillsValue.trim() === "" ? null : parseInt(refillsValue);
+        if (val !== null && (isNaN(val) || val < 0)) {
             toast.error("Please enter a valid number of refills");
             return;
         }
@@ -191,9 +192,9 @@ export function OrderDetailsDialog({ order, open, onOpenChange, onSuccess }: Ord
                                         </>
                                     ) : (
                                         <>
-                                            <span className="font-mono">{order.refillsRemaining ?? 0}</span>
+                                            <span className="font-mono">{order.refillsRemaining ?? "Unlimited"}</span>
                                             <Button size="sm" variant="outline" className="h-6 px-2 text-[10px]" onClick={() => {
-                                                setRefillsValue((order.refillsRemaining ?? 0).toString());
+                                                setRefillsValue(order.refillsRemaining?.toString() ?? "");
                                                 setIsEditingRefills(true);
                                             }}>
                                                 Edit
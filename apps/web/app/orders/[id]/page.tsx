@@ -118,7 +118,7 @@ export default function OrderDetailPage() {
       toast.error("You already have a pending refill request.");
       return;
     }
-    if ((order.refillsRemaining ?? 3) <= 0) {
+    if (order.refillsRemaining != null && order.refillsRemaining <= 0) {
       toast.error("You have no refills left for this order.");
       return;
     }
@@ -543,7 +543,7 @@ export default function OrderDetailPage() {
         return (
           <>
             {/* Refill Card */}
-            {matchingService?.refill && (matchingService?.refillLimit === undefined || matchingService?.refillLimit > 0) && (
+            {matchingService?.refill && (
               <div className="refill-card">
                 <div className="refill-card-top">
                   <div className="refill-info-group">
@@ -576,7 +576,7 @@ export default function OrderDetailPage() {
                     </div>
                     <div className="item-detail-wrapper">
                       <span>Refill Left</span>
-                      <p>{order?.refillsRemaining ?? 3} Refills</p>
+                      <p>{order?.refillsRemaining ?? "Unlimited"} Refills</p>
                     </div>
                   </div>
 